@@ -0,0 +1,39 @@
+// Command fake-ollama serves a fakeollama script on the Ollama API, so the
+// servers can be run and cassettes recorded without a model.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"mcp-server/internal/fakeollama"
+)
+
+func main() {
+	scriptPath := flag.String("script", "", "YAML script of prompt substrings and responses")
+	addr := flag.String("addr", "localhost:11434", "address to listen on")
+	flag.Parse()
+
+	if *scriptPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	script, err := fakeollama.LoadScript(*scriptPath)
+	if err != nil {
+		log.Fatalf("Failed to load script: %v", err)
+	}
+
+	server := fakeollama.NewServer(script)
+	server.OnCall = func(call fakeollama.Call) {
+		rule := call.Rule
+		if rule == "" {
+			rule = "default"
+		}
+		log.Printf("%s %s -> %s", call.Endpoint, call.Model, rule)
+	}
+
+	log.Printf("Fake Ollama serving %s on %s (%d rules)", *scriptPath, *addr, len(script.Rules))
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/eval"
+	"mcp-server/internal/llm"
+)
+
+// runEval implements "mcp-server eval": it runs the task catalog under each model
+// configuration and prints a comparison table
+func runEval(args []string, stdout, stderr io.Writer) int {
+	defaultConfig := "/app/config/agents.toml"
+	if _, err := os.Stat(defaultConfig); os.IsNotExist(err) {
+		defaultConfig = "config/agents.toml"
+	}
+	defaultTasks := "/app/eval/tasks"
+	if _, err := os.Stat(defaultTasks); os.IsNotExist(err) {
+		defaultTasks = "eval/tasks"
+	}
+	defaultOllama := os.Getenv("OLLAMA_URL")
+	if defaultOllama == "" {
+		defaultOllama = "http://localhost:11434"
+	}
+
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	catalog := flags.String("tasks", defaultTasks, "directory holding the task catalog")
+	only := flags.String("task", "", "comma-separated tasks to run (default: all)")
+	configs := flags.String("config", defaultConfig, "comma-separated workflow configs to compare")
+	models := flags.String("models", "", "comma-separated models; each runs every config with all agents on that model")
+	runs := flags.Int("runs", 3, "runs per task and configuration")
+	ollamaURL := flags.String("ollama", defaultOllama, "Ollama URL (OLLAMA_URL)")
+	jsonOut := flags.String("json", "", "also write every run and the summaries as JSON to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mcp-server eval [-tasks DIR] [-task NAMES] [-config FILES] [-models MODELS] [-runs N] [-json FILE]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	tasks, err := eval.LoadCatalog(*catalog)
+	if err == nil {
+		tasks, err = eval.SelectTasks(tasks, splitList(*only))
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "eval: %v\n", err)
+		return 1
+	}
+	configurations, err := eval.Configurations(splitList(*configs), splitList(*models))
+	if err != nil {
+		fmt.Fprintf(stderr, "eval: %v\n", err)
+		return 1
+	}
+
+	// Ctrl-C stops after the current run and still reports what finished
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := eval.Run(ctx, tasks, configurations, eval.Options{
+		Runs: *runs,
+		NewClient: func(cfg *config.WorkflowConfig, agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+			return llm.NewOllamaAgentClient(*ollamaURL, agentCfg, cfg)
+		},
+		Progress: stderr,
+	})
+	summaries := eval.Summarize(results)
+	if err := eval.WriteTable(stdout, summaries); err != nil {
+		fmt.Fprintf(stderr, "eval: %v\n", err)
+		return 1
+	}
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"runs":      results,
+			"summaries": summaries,
+		}, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*jsonOut, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "eval: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Wrote %s\n", *jsonOut)
+	}
+	return 0
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"mcp-server/internal/agent"
 	"mcp-server/internal/config"
 	"mcp-server/internal/debug"
+	"mcp-server/internal/langpack"
 	"mcp-server/internal/llm"
+	"mcp-server/internal/metrics"
 	"mcp-server/internal/orchestrator"
+	"mcp-server/internal/telemetry"
 	"mcp-server/internal/tools"
 )
 
@@ -52,6 +57,14 @@ type MCPError struct {
 }
 
 func main() {
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "trace" {
+		os.Exit(runTrace(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		os.Exit(runEval(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Get working directory
 	workingDir := os.Getenv("PROJECT_ROOT")
 	if workingDir == "" {
@@ -68,6 +81,14 @@ func main() {
 		workingDir: workingDir,
 	}
 
+	// Export OpenTelemetry spans when an exporter is configured
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), config.GetTelemetryConfig(), os.Stderr)
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+	} else {
+		telemetry.ShutdownOnSignal(shutdownTelemetry)
+	}
+
 	// Try to load workflow configuration first
 	workflowConfigPath := "/app/config/agents.toml"
 	if _, err := os.Stat(workflowConfigPath); os.IsNotExist(err) {
@@ -91,23 +112,35 @@ func main() {
 		
 		server.config = cfg
 		
-		// Initialize single agent setup
-		llmClient := llm.NewOllamaClient(ollamaURL, cfg.Model)
+		// Initialize single agent setup, with the retries and breaker workflow agents get
+		singleAgentConfig := cfg.WorkflowConfig()
+		engineerConfig := singleAgentConfig.Agents[cfg.Agent.Role]
+		llmClient := llm.NewOllamaAgentClient(ollamaURL, engineerConfig, singleAgentConfig)
 		toolSet := tools.NewToolSet(cfg.Commands, cfg.Restrictions, workingDir)
 		server.toolSet = toolSet
-		// Create a config for the single engineer agent
-		engineerConfig := config.WorkflowAgentConfig{
-			Role:          cfg.Agent.Role,
-			Model:         cfg.Agent.Model,
-			PerAgentTimeoutMinutes: cfg.Agent.PerAgentTimeoutMinutes,
-		}
 		engineer := agent.NewSeniorEngineer(llmClient, toolSet, toolSet, engineerConfig)
 		server.agent = engineer
+
+		// Pull and load the agent's model the way workflow mode does for its agents
+		go func() {
+			if err := llm.PrepareModels(context.Background(), ollamaURL, singleAgentConfig, log.Printf); err != nil {
+				log.Printf("Model preparation incomplete: %v", err)
+			}
+		}()
 		
 		fmt.Println("Running in single-agent mode")
 	} else {
 		// Initialize multi-agent workflow
 		server.workflowConfig = workflowConfig
+
+		// Prompt templates in the config directory replace the built-in ones
+		overridden, err := agent.LoadPromptTemplates(workflowConfig.Workflow.PromptsDir)
+		if err != nil {
+			log.Fatalf("Failed to load prompt templates: %v", err)
+		}
+		if len(overridden) > 0 {
+			log.Printf("Prompt templates from %s: %s", workflowConfig.Workflow.PromptsDir, strings.Join(overridden, ", "))
+		}
 		
 		// Create shared toolset
 		toolSet := tools.NewToolSet(workflowConfig.Commands, workflowConfig.Restrictions, workingDir)
@@ -124,28 +157,34 @@ func main() {
 		}
 		
 		llmClient := llm.NewOllamaClient(ollamaURL, defaultModel)
-		orchestratorInstance := orchestrator.NewWorkflowOrchestrator(llmClient, toolSet, workflowConfig)
+		orchestratorInstance, err := orchestrator.NewWorkflowOrchestrator(llmClient, toolSet, workflowConfig)
+		if err != nil {
+			log.Fatalf("Failed to create orchestrator: %v", err)
+		}
 		
 		// Initialize debug logger
 		debugConfig := config.GetDebugConfig()
-		debugLogger := debug.NewDebugLogger(debugConfig.Enabled, debugConfig.LogDir)
+		debugLogger := debug.NewDebugLogger(debugConfig.Enabled, debugConfig.LogDir, debugConfig.MaxLogMB)
+		orchestratorInstance.SetDebugLogger(debugLogger)
 		
-		// Register all agents
+		// Every workflow run gets its own ToolSet and agents
 		agentFactory := agent.NewAgentFactory(debugLogger)
-		for roleName, agentConfig := range workflowConfig.Agents {
-			role := agent.AgentRole(agentConfig.Role)
-			agentLLMClient := llm.NewOllamaClient(ollamaURL, agentConfig.Model)
-			
-			agentInstance, err := agentFactory.CreateAgent(role, agentLLMClient, toolSet, toolSet, agentConfig)
-			if err != nil {
-				log.Fatalf("Failed to create agent %s: %v", roleName, err)
-			}
-			
-			orchestratorInstance.RegisterAgent(role, agentInstance)
+		agentBuilder := orchestrator.NewAgentBuilder(workflowConfig, agentFactory, func(agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+			return llm.NewOllamaAgentClient(ollamaURL, agentCfg, workflowConfig)
+		})
+		if err := orchestratorInstance.SetAgentBuilder(agentBuilder); err != nil {
+			log.Fatalf("Failed to create agents: %v", err)
 		}
 		
 		server.orchestrator = orchestratorInstance
 		
+		// Pull and load the agents' models without holding up the server
+		go func() {
+			if err := llm.PrepareModels(context.Background(), ollamaURL, workflowConfig, log.Printf); err != nil {
+				log.Printf("Model preparation incomplete: %v", err)
+			}
+		}()
+		
 		fmt.Println("Running in multi-agent workflow mode")
 	}
 
@@ -153,6 +192,7 @@ func main() {
 	http.HandleFunc("/tools", server.handleToolsRequest)
 	http.HandleFunc("/call", server.handleToolCall)
 	http.HandleFunc("/health", server.handleHealth)
+	http.Handle("/metrics", metrics.Handler())
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -181,15 +221,15 @@ func (s *MCPServer) handleToolsRequest(w http.ResponseWriter, r *http.Request) {
 					},
 					"project_type": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"go", "typescript", "python"},
-						"description": "Project type for language-specific handling",
+						"enum":        langpack.Names(),
+						"description": "Project type for language-specific handling; inferred from the working directory when omitted",
 					},
 					"working_directory": map[string]interface{}{
 						"type":        "string",
 						"description": "Project root directory path",
 					},
 				},
-				"required": []string{"description", "project_type"},
+				"required": []string{"description"},
 			},
 		},
 	}
@@ -208,15 +248,20 @@ func (s *MCPServer) handleToolsRequest(w http.ResponseWriter, r *http.Request) {
 					},
 					"project_type": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"go", "typescript", "python"},
-						"description": "Project type for language-specific handling",
+						"enum":        langpack.Names(),
+						"description": "Project type for language-specific handling; inferred from the working directory when omitted",
 					},
 					"working_directory": map[string]interface{}{
 						"type":        "string",
 						"description": "Project root directory path",
 					},
+					"flow": map[string]interface{}{
+						"type":        "string",
+						"enum":        s.orchestrator.FlowNames(),
+						"description": "Named workflow flow from config (defaults to the full EM -> Engineer -> QA -> Tech Lead flow)",
+					},
 				},
-				"required": []string{"description", "project_type"},
+				"required": []string{"description"},
 			},
 		}
 		tools = append(tools, workflowTool)
@@ -224,7 +269,7 @@ func (s *MCPServer) handleToolsRequest(w http.ResponseWriter, r *http.Request) {
 		// Add project initialization tool
 		initTool := MCPTool{
 			Name:        "initialize_project_patterns",
-			Description: "Analyze existing project and generate pattern documentation for agent coordination",
+			Description: "Analyze existing project and generate pattern documentation for agent coordination. Only files changed since the last run are re-analyzed, and hand-written sections outside the generated regions are kept",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -294,6 +339,37 @@ func (s *MCPServer) handleToolsRequest(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 		tools = append(tools, sequentialThinkingTool)
+
+		// Add prompt debugging tool
+		renderPromptTool := MCPTool{
+			Name:        "render_prompt",
+			Description: "Show the prompt an agent would send to its model for a request, rendered from its template with any config or project overrides, without calling the model",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"role": map[string]interface{}{
+						"type":        "string",
+						"enum":        s.orchestrator.RoleNames(),
+						"description": "Agent whose prompt to render",
+					},
+					"description": map[string]interface{}{
+						"type":        "string",
+						"description": "Task the agent is handed, such as a feature request for the EM or a brief for the engineer",
+					},
+					"project_type": map[string]interface{}{
+						"type":        "string",
+						"enum":        langpack.Names(),
+						"description": "Project type for language-specific handling; inferred from the working directory when omitted",
+					},
+					"working_directory": map[string]interface{}{
+						"type":        "string",
+						"description": "Project root directory path",
+					},
+				},
+				"required": []string{"role", "description"},
+			},
+		}
+		tools = append(tools, renderPromptTool)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -325,6 +401,8 @@ func (s *MCPServer) handleToolCall(w http.ResponseWriter, r *http.Request) {
 		s.handleInitializeProjectPatterns(w, r, req.Params.Arguments)
 	case "sequential_thinking":
 		s.handleSequentialThinking(w, r, req.Params.Arguments)
+	case "render_prompt":
+		s.handleRenderPrompt(w, r, req.Params.Arguments)
 	default:
 		s.sendError(w, 404, "Tool not found")
 	}
@@ -348,9 +426,6 @@ func (s *MCPServer) handleImplementFeature(w http.ResponseWriter, r *http.Reques
 
 	if projType, ok := args["project_type"].(string); ok {
 		implReq.ProjectType = agent.ProjectType(projType)
-	} else {
-		s.sendError(w, 400, "Missing or invalid project_type")
-		return
 	}
 
 	if workDir, ok := args["working_directory"].(string); ok {
@@ -359,6 +434,15 @@ func (s *MCPServer) handleImplementFeature(w http.ResponseWriter, r *http.Reques
 		implReq.WorkingDirectory = s.workingDir
 	}
 
+	// Infer the project type from the working directory when the caller left it out,
+	// as workflows do
+	if implReq.ProjectType == "" {
+		files := tools.NewToolSet(s.config.Commands, s.config.Restrictions, implReq.WorkingDirectory)
+		detection := agent.DetectProject(files, "")
+		implReq.ProjectType = detection.ProjectType
+		log.Printf("Detected project type %q (mixed: %v) from %d stack(s)", implReq.ProjectType, detection.Mixed, len(detection.Stacks))
+	}
+
 	// Execute feature implementation
 	result, err := s.agent.ImplementFeature(r.Context(), implReq)
 	if err != nil {
@@ -388,9 +472,6 @@ func (s *MCPServer) handleImplementFeatureWorkflow(w http.ResponseWriter, r *htt
 
 	if projType, ok := args["project_type"].(string); ok {
 		workflowReq.ProjectType = agent.ProjectType(projType)
-	} else {
-		s.sendError(w, 400, "Missing or invalid project_type")
-		return
 	}
 
 	if workDir, ok := args["working_directory"].(string); ok {
@@ -399,6 +480,10 @@ func (s *MCPServer) handleImplementFeatureWorkflow(w http.ResponseWriter, r *htt
 		workflowReq.WorkingDirectory = s.workingDir
 	}
 
+	if flow, ok := args["flow"].(string); ok {
+		workflowReq.Flow = flow
+	}
+
 	// Execute workflow
 	result, err := s.orchestrator.ExecuteWorkflow(r.Context(), workflowReq)
 	if err != nil {
@@ -410,6 +495,46 @@ func (s *MCPServer) handleImplementFeatureWorkflow(w http.ResponseWriter, r *htt
 	json.NewEncoder(w).Encode(MCPResponse{Result: result})
 }
 
+func (s *MCPServer) handleRenderPrompt(w http.ResponseWriter, r *http.Request, args map[string]interface{}) {
+	if s.orchestrator == nil {
+		s.sendError(w, 503, "Workflow orchestrator not available")
+		return
+	}
+
+	role, ok := args["role"].(string)
+	if !ok {
+		s.sendError(w, 400, "Missing or invalid role")
+		return
+	}
+
+	var workflowReq agent.WorkflowRequest
+	if desc, ok := args["description"].(string); ok {
+		workflowReq.Description = desc
+	} else {
+		s.sendError(w, 400, "Missing or invalid description")
+		return
+	}
+
+	if projType, ok := args["project_type"].(string); ok {
+		workflowReq.ProjectType = agent.ProjectType(projType)
+	}
+
+	if workDir, ok := args["working_directory"].(string); ok {
+		workflowReq.WorkingDirectory = workDir
+	} else {
+		workflowReq.WorkingDirectory = s.workingDir
+	}
+
+	rendered, err := s.orchestrator.RenderPrompt(r.Context(), agent.AgentRole(role), workflowReq)
+	if err != nil {
+		s.sendError(w, 400, fmt.Sprintf("Rendering prompt failed: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MCPResponse{Result: rendered})
+}
+
 func (s *MCPServer) handleInitializeProjectPatterns(w http.ResponseWriter, r *http.Request, args map[string]interface{}) {
 	if s.toolSet == nil {
 		s.sendError(w, 503, "ToolSet not available")
@@ -498,6 +623,11 @@ func (s *MCPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 		status["timeout_minutes"] = s.workflowConfig.Workflow.TimeoutMinutes
 	}
 
+	status["llm"] = llm.BreakerStates()
+	if llm.AnyBreakerOpen() {
+		status["status"] = "degraded"
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/testutil"
+)
+
+func TestHTTPWorkflow(t *testing.T) {
+	env := testutil.Setup(t)
+	port := testutil.FreePort(t)
+	cmd := env.Command(t, testutil.Build(t, "mcp-server"), "PORT="+port)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting mcp-server: %v", err)
+	}
+	testutil.WaitForPort(t, port)
+	base := "http://127.0.0.1:" + port
+
+	var tools struct {
+		Tools []MCPTool `json:"tools"`
+	}
+	getJSON(t, base+"/tools", &tools)
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	if !strings.Contains(strings.Join(names, ","), "implement_feature_workflow") {
+		t.Fatalf("tools = %v, want implement_feature_workflow", names)
+	}
+
+	body := `{"method":"tools/call","params":{"name":"implement_feature_workflow","arguments":{"description":"` + testutil.SubtractRequest + `","working_directory":"` + env.Project + `"}}}`
+	resp, err := http.Post(base+"/call", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /call: %v", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Result struct {
+			Success       bool   `json:"success"`
+			FailureReason string `json:"failure_reason"`
+		} `json:"result"`
+		Error *MCPError `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decoding /call response: %v", err)
+	}
+	if result.Error != nil || !result.Result.Success {
+		t.Fatalf("workflow failed: error %+v, failure reason %q", result.Error, result.Result.FailureReason)
+	}
+	env.CheckSubtract(t)
+
+	metrics, err := http.Get(base + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer metrics.Body.Close()
+	text, _ := io.ReadAll(metrics.Body)
+	if !strings.Contains(string(text), `agent_workflows_finished_total{failure_reason="",flow="default",status="succeeded"} 1`) {
+		t.Errorf("/metrics does not count the successful run:\n%s", text)
+	}
+
+	// The project's own template takes precedence over the built-in one
+	override := filepath.Join(env.Project, "agents", "prompts", "qa.tmpl")
+	if err := os.MkdirAll(filepath.Dir(override), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(override, []byte("Test {{.Description}} with {{.TestingFramework}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	body = `{"method":"tools/call","params":{"name":"render_prompt","arguments":{"role":"senior_qa","description":"Subtract","working_directory":"` + env.Project + `"}}}`
+	rendered, err := http.Post(base+"/call", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /call: %v", err)
+	}
+	defer rendered.Body.Close()
+	var prompt struct {
+		Result struct {
+			Template string `json:"template"`
+			Source   string `json:"source"`
+			Prompt   string `json:"prompt"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(rendered.Body).Decode(&prompt); err != nil {
+		t.Fatalf("decoding render_prompt response: %v", err)
+	}
+	if prompt.Result.Source != override || !strings.HasPrefix(prompt.Result.Prompt, "Test Subtract") {
+		t.Errorf("render_prompt = %+v, want the project's qa template rendered", prompt.Result)
+	}
+}
+
+// requestRecorder is a single agent that records the request it was given
+type requestRecorder struct {
+	req agent.ImplementFeatureRequest
+}
+
+func (rr *requestRecorder) ImplementFeature(ctx context.Context, req agent.ImplementFeatureRequest) (*agent.ImplementFeatureResponse, error) {
+	rr.req = req
+	return &agent.ImplementFeatureResponse{Success: true}, nil
+}
+
+func (rr *requestRecorder) DocumentTask(ctx context.Context, result *agent.WorkflowResult) error {
+	return nil
+}
+
+func TestImplementFeatureInfersProjectType(t *testing.T) {
+	project := t.TempDir()
+	for _, file := range []string{"pyproject.toml", "frontend/package.json"} {
+		path := filepath.Join(project, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want agent.ProjectType
+	}{
+		{"omitted", map[string]interface{}{"description": "add a route"}, agent.ProjectTypePython},
+		{"given", map[string]interface{}{"description": "add a route", "project_type": "typescript"}, "typescript"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &requestRecorder{}
+			server := &MCPServer{agent: recorder, config: &config.AgentConfig{}, workingDir: project}
+
+			response := httptest.NewRecorder()
+			server.handleImplementFeature(response, httptest.NewRequest(http.MethodPost, "/call", nil), tt.args)
+			if response.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", response.Code, response.Body)
+			}
+			if recorder.req.ProjectType != tt.want || recorder.req.WorkingDirectory != project {
+				t.Errorf("agent got project type %q in %q, want %q in %q", recorder.req.ProjectType, recorder.req.WorkingDirectory, tt.want, project)
+			}
+		})
+	}
+}
+
+func getJSON(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decoding %s: %v", url, err)
+	}
+}
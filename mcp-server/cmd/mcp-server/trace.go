@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/debug"
+)
+
+// runTrace implements "mcp-server trace": it lists traced runs or renders one as a
+// timeline in the terminal or as a static HTML page
+func runTrace(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("trace", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", config.GetDebugConfig().LogDir, "directory holding the trace files (AGENT_DEBUG_DIR)")
+	run := flags.String("run", "", "run ID to render, as reported in trace_run (default: the latest run)")
+	list := flags.Bool("list", false, "list the traced runs instead of rendering one")
+	htmlOut := flags.String("html", "", "write the timeline as HTML to this file instead of the terminal")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mcp-server trace [-dir DIR] [-list] [-run ID] [-html FILE]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	events, err := debug.ReadEvents(*dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "trace: %v\n", err)
+		return 1
+	}
+
+	if *list {
+		for _, summary := range debug.ListRuns(events) {
+			status := "unfinished"
+			if summary.Finished {
+				status = "ok, " + summary.Duration.Round(time.Millisecond).String()
+				if summary.Error != "" {
+					status = "failed, " + summary.Duration.Round(time.Millisecond).String()
+				}
+			}
+			fmt.Fprintf(stdout, "%s  %s  %-20s  %s\n", summary.ID, summary.Start.Format(time.RFC3339), status, firstLineOf(summary.Name, 80))
+		}
+		return 0
+	}
+
+	trace, err := debug.BuildTrace(events, *run)
+	if err != nil {
+		fmt.Fprintf(stderr, "trace: %v\n", err)
+		return 1
+	}
+
+	if *htmlOut == "" {
+		if err := debug.RenderText(stdout, trace); err != nil {
+			fmt.Fprintf(stderr, "trace: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	file, err := os.Create(*htmlOut)
+	if err != nil {
+		fmt.Fprintf(stderr, "trace: %v\n", err)
+		return 1
+	}
+	if err := debug.RenderHTML(file, trace); err != nil {
+		file.Close()
+		fmt.Fprintf(stderr, "trace: %v\n", err)
+		return 1
+	}
+	if err := file.Close(); err != nil {
+		fmt.Fprintf(stderr, "trace: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Wrote %s\n", *htmlOut)
+	return 0
+}
+
+// firstLineOf shortens a task description to its first line of at most n bytes
+func firstLineOf(s string, n int) string {
+	for i, r := range s {
+		if r == '\n' {
+			s = s[:i]
+			break
+		}
+	}
+	if len(s) > n {
+		s = s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTrace(t *testing.T) {
+	const dir = "testdata/trace"
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "list",
+			args: []string{"-dir", dir, "-list"},
+			want: `run1  2024-01-01T10:00:00Z  failed, 4s            Add a Subtract function
+run2  2024-01-01T11:00:00Z  unfinished            Document Add
+`,
+		},
+		{
+			name: "timeline",
+			args: []string{"-dir", dir, "-run", "run1"},
+			want: `Run run1 started 2024-01-01T10:00:00Z, took 4.0s, FAILED: maximum total iterations (1) exceeded
+
+      +0s     4.0s  run        Add a Subtract function  ERROR: maximum total iterations (1) exceeded
+   +100ms     2.9s    agent      senior_engineer (failed)
+   +100ms     2.0s      llm_call   qwen3:14b, 120 prompt + 30 completion tokens
+    +2.2s    400ms      tool_call  execute_command go build ./...  ERROR: exit status 1 ...
+    +3.1s       0s    transition senior_engineer -> senior_engineer
+`,
+		},
+		{
+			name: "latest run",
+			args: []string{"-dir", dir},
+			want: `Run run2 started 2024-01-01T11:00:00Z, took 0s, unfinished
+
+      +0s       0s  run        Document Add
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runTrace(tt.args, &stdout, &stderr); code != 0 {
+				t.Fatalf("exit code %d: %s", code, stderr.String())
+			}
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTraceHTML(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "run1.html")
+	var stdout, stderr bytes.Buffer
+	if code := runTrace([]string{"-dir", "testdata/trace", "-run", "run1", "-html", out}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != "Wrote "+out+"\n" {
+		t.Errorf("output = %q", got)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<title>Run run1</title>",
+		"<h1>Add a Subtract function</h1>",
+		`<span class="error">failed: maximum total iterations (1) exceeded</span>`,
+		`<tr class="llm_call">`,
+		`<tr class="tool_call failed">`,
+		// Prompts are escaped and folded under their call
+		"<summary>prompt</summary><pre>Implement &lt;Subtract&gt;</pre>",
+		"<summary>details</summary><pre>calc.go:3: undefined: Subtract</pre>",
+		// The LLM call starts 2.5% into the run and takes half of it
+		`<div style="left: 2.50%; width: 50.00%">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML does not contain %q", want)
+		}
+	}
+}
+
+func TestRunTraceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no trace files", []string{"-dir", t.TempDir()}, "trace: no trace files in"},
+		{"unknown run", []string{"-dir", "testdata/trace", "-run", "run9"}, "trace: run run9 not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runTrace(tt.args, &stdout, &stderr); code != 1 {
+				t.Errorf("exit code = %d, want 1", code)
+			}
+			if !strings.HasPrefix(stderr.String(), tt.want) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.want)
+			}
+		})
+	}
+}
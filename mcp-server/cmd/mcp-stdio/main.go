@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -11,8 +12,10 @@ import (
 	"mcp-server/internal/agent"
 	"mcp-server/internal/config"
 	"mcp-server/internal/debug"
+	"mcp-server/internal/langpack"
 	"mcp-server/internal/llm"
 	"mcp-server/internal/orchestrator"
+	"mcp-server/internal/telemetry"
 	"mcp-server/internal/tools"
 )
 
@@ -69,6 +72,15 @@ func main() {
 		workingDir: workingDir,
 	}
 
+	// Export OpenTelemetry spans when an exporter is configured; the console
+	// exporter writes to stderr so it stays out of the protocol stream
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), config.GetTelemetryConfig(), os.Stderr)
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+	} else {
+		defer shutdownTelemetry(context.Background())
+	}
+
 	// Load workflow configuration
 	workflowConfigPath := "/app/config/agents.toml"
 	if _, err := os.Stat(workflowConfigPath); os.IsNotExist(err) {
@@ -81,6 +93,15 @@ func main() {
 	}
 	
 	server.workflowConfig = workflowConfig
+
+	// Prompt templates in the config directory replace the built-in ones
+	overridden, err := agent.LoadPromptTemplates(workflowConfig.Workflow.PromptsDir)
+	if err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+	if len(overridden) > 0 {
+		log.Printf("Prompt templates from %s: %s", workflowConfig.Workflow.PromptsDir, strings.Join(overridden, ", "))
+	}
 	
 	// Create shared toolset
 	toolSet := tools.NewToolSet(workflowConfig.Commands, workflowConfig.Restrictions, workingDir)
@@ -96,28 +117,34 @@ func main() {
 	}
 	
 	llmClient := llm.NewOllamaClient(ollamaURL, defaultModel)
-	orchestratorInstance := orchestrator.NewWorkflowOrchestrator(llmClient, toolSet, workflowConfig)
+	orchestratorInstance, err := orchestrator.NewWorkflowOrchestrator(llmClient, toolSet, workflowConfig)
+	if err != nil {
+		log.Fatalf("Failed to create orchestrator: %v", err)
+	}
 	
 	// Initialize debug logger
 	debugConfig := config.GetDebugConfig()
-	debugLogger := debug.NewDebugLogger(debugConfig.Enabled, debugConfig.LogDir)
+	debugLogger := debug.NewDebugLogger(debugConfig.Enabled, debugConfig.LogDir, debugConfig.MaxLogMB)
+	orchestratorInstance.SetDebugLogger(debugLogger)
 	
-	// Register all agents
+	// Every workflow run gets its own ToolSet and agents
 	agentFactory := agent.NewAgentFactory(debugLogger)
-	for roleName, agentConfig := range workflowConfig.Agents {
-		role := agent.AgentRole(agentConfig.Role)
-		agentLLMClient := llm.NewOllamaClient(ollamaURL, agentConfig.Model)
-		
-		agentInstance, err := agentFactory.CreateAgent(role, agentLLMClient, toolSet, toolSet, agentConfig)
-		if err != nil {
-			log.Fatalf("Failed to create agent %s: %v", roleName, err)
-		}
-		
-		orchestratorInstance.RegisterAgent(role, agentInstance)
+	agentBuilder := orchestrator.NewAgentBuilder(workflowConfig, agentFactory, func(agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+		return llm.NewOllamaAgentClient(ollamaURL, agentCfg, workflowConfig)
+	})
+	if err := orchestratorInstance.SetAgentBuilder(agentBuilder); err != nil {
+		log.Fatalf("Failed to create agents: %v", err)
 	}
 	
 	server.orchestrator = orchestratorInstance
 	
+	// Pull and load the agents' models without holding up the server
+	go func() {
+		if err := llm.PrepareModels(context.Background(), ollamaURL, workflowConfig, log.Printf); err != nil {
+			log.Printf("Model preparation incomplete: %v", err)
+		}
+	}()
+	
 	log.Printf("MCP Server initialized in stdio mode")
 	log.Printf("Ollama URL: %s", ollamaURL)
 	log.Printf("Working Directory: %s", workingDir)
@@ -191,15 +218,48 @@ func (s *MCPServer) handleToolsList(req MCPRequest) {
 					},
 					"project_type": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"go", "typescript", "python"},
-						"description": "Project type for language-specific handling",
+						"enum":        langpack.Names(),
+						"description": "Project type for language-specific handling; inferred from the working directory when omitted",
 					},
 					"working_directory": map[string]interface{}{
 						"type":        "string",
 						"description": "Project root directory path",
 					},
+					"flow": map[string]interface{}{
+						"type":        "string",
+						"enum":        s.orchestrator.FlowNames(),
+						"description": "Named workflow flow from config (defaults to the full EM -> Engineer -> QA -> Tech Lead flow)",
+					},
 				},
-				"required": []string{"description", "project_type"},
+				"required": []string{"description"},
+			},
+		},
+		{
+			Name:        "render_prompt",
+			Description: "Show the prompt an agent would send to its model for a request, rendered from its template with any config or project overrides, without calling the model",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"role": map[string]interface{}{
+						"type":        "string",
+						"enum":        s.orchestrator.RoleNames(),
+						"description": "Agent whose prompt to render",
+					},
+					"description": map[string]interface{}{
+						"type":        "string",
+						"description": "Task the agent is handed, such as a feature request for the EM or a brief for the engineer",
+					},
+					"project_type": map[string]interface{}{
+						"type":        "string",
+						"enum":        langpack.Names(),
+						"description": "Project type for language-specific handling; inferred from the working directory when omitted",
+					},
+					"working_directory": map[string]interface{}{
+						"type":        "string",
+						"description": "Project root directory path",
+					},
+				},
+				"required": []string{"role", "description"},
 			},
 		},
 	}
@@ -225,6 +285,8 @@ func (s *MCPServer) handleToolsCall(req MCPRequest) {
 	switch params.Name {
 	case "implement_feature_workflow":
 		s.handleImplementFeatureWorkflow(req, params.Arguments)
+	case "render_prompt":
+		s.handleRenderPrompt(req, params.Arguments)
 	default:
 		s.sendError(req.ID, -32601, "Tool not found", params.Name)
 	}
@@ -243,9 +305,6 @@ func (s *MCPServer) handleImplementFeatureWorkflow(req MCPRequest, args map[stri
 
 	if projType, ok := args["project_type"].(string); ok {
 		workflowReq.ProjectType = agent.ProjectType(projType)
-	} else {
-		s.sendError(req.ID, -32602, "Missing or invalid project_type", nil)
-		return
 	}
 
 	if workDir, ok := args["working_directory"].(string); ok {
@@ -254,8 +313,12 @@ func (s *MCPServer) handleImplementFeatureWorkflow(req MCPRequest, args map[stri
 		workflowReq.WorkingDirectory = s.workingDir
 	}
 
+	if flow, ok := args["flow"].(string); ok {
+		workflowReq.Flow = flow
+	}
+
 	// Execute workflow
-	result, err := s.orchestrator.ExecuteWorkflow(nil, workflowReq)
+	result, err := s.orchestrator.ExecuteWorkflow(context.Background(), workflowReq)
 	if err != nil {
 		s.sendError(req.ID, -32603, "Workflow execution failed", err.Error())
 		return
@@ -264,6 +327,40 @@ func (s *MCPServer) handleImplementFeatureWorkflow(req MCPRequest, args map[stri
 	s.sendResponse(req.ID, result)
 }
 
+func (s *MCPServer) handleRenderPrompt(req MCPRequest, args map[string]interface{}) {
+	role, ok := args["role"].(string)
+	if !ok {
+		s.sendError(req.ID, -32602, "Missing or invalid role", nil)
+		return
+	}
+
+	var workflowReq agent.WorkflowRequest
+	if desc, ok := args["description"].(string); ok {
+		workflowReq.Description = desc
+	} else {
+		s.sendError(req.ID, -32602, "Missing or invalid description", nil)
+		return
+	}
+
+	if projType, ok := args["project_type"].(string); ok {
+		workflowReq.ProjectType = agent.ProjectType(projType)
+	}
+
+	if workDir, ok := args["working_directory"].(string); ok {
+		workflowReq.WorkingDirectory = workDir
+	} else {
+		workflowReq.WorkingDirectory = s.workingDir
+	}
+
+	rendered, err := s.orchestrator.RenderPrompt(context.Background(), agent.AgentRole(role), workflowReq)
+	if err != nil {
+		s.sendError(req.ID, -32603, "Rendering prompt failed", err.Error())
+		return
+	}
+
+	s.sendResponse(req.ID, rendered)
+}
+
 func (s *MCPServer) sendResponse(id interface{}, result interface{}) {
 	response := MCPResponse{
 		Jsonrpc: "2.0",
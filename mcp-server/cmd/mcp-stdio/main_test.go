@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"testing"
+
+	"mcp-server/internal/testutil"
+)
+
+func TestStdioWorkflow(t *testing.T) {
+	env := testutil.Setup(t)
+	cmd := env.Command(t, testutil.Build(t, "mcp-stdio"))
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("stdin: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting mcp-stdio: %v", err)
+	}
+	responses := bufio.NewScanner(stdout)
+	responses.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+
+	call := func(id int, method string, params interface{}) MCPResponse {
+		t.Helper()
+		line, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+		if err != nil {
+			t.Fatalf("encoding %s: %v", method, err)
+		}
+		if _, err := stdin.Write(append(line, '\n')); err != nil {
+			t.Fatalf("writing %s: %v", method, err)
+		}
+		if !responses.Scan() {
+			t.Fatalf("no response to %s: %v", method, responses.Err())
+		}
+		var resp MCPResponse
+		if err := json.Unmarshal(responses.Bytes(), &resp); err != nil {
+			t.Fatalf("decoding response to %s: %v\n%s", method, err, responses.Text())
+		}
+		if resp.Error != nil {
+			t.Fatalf("%s failed: %+v", method, resp.Error)
+		}
+		if got, ok := resp.ID.(float64); !ok || int(got) != id {
+			t.Fatalf("%s answered with id %v", method, resp.ID)
+		}
+		return resp
+	}
+
+	call(1, "initialize", map[string]interface{}{})
+	call(2, "tools/list", map[string]interface{}{})
+	resp := call(3, "tools/call", map[string]interface{}{
+		"name": "implement_feature_workflow",
+		"arguments": map[string]interface{}{
+			"description":       testutil.SubtractRequest,
+			"working_directory": env.Project,
+		},
+	})
+
+	result, _ := resp.Result.(map[string]interface{})
+	if success, _ := result["success"].(bool); !success {
+		t.Fatalf("workflow failed: %v", result["failure_reason"])
+	}
+	env.CheckSubtract(t)
+}
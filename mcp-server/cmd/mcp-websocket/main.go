@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,7 +16,9 @@ import (
 	"mcp-server/internal/config"
 	"mcp-server/internal/debug"
 	"mcp-server/internal/llm"
+	"mcp-server/internal/metrics"
 	"mcp-server/internal/orchestrator"
+	"mcp-server/internal/telemetry"
 	"mcp-server/internal/tools"
 )
 
@@ -98,6 +101,14 @@ func main() {
 		ollamaURL = "http://ollama:11434"
 	}
 
+	// Export OpenTelemetry spans when an exporter is configured
+	shutdownTelemetry, err := telemetry.Setup(context.Background(), config.GetTelemetryConfig(), os.Stderr)
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+	} else {
+		telemetry.ShutdownOnSignal(shutdownTelemetry)
+	}
+
 	port := os.Getenv("WS_PORT")
 	if port == "" {
 		port = "8766"
@@ -125,6 +136,15 @@ func main() {
 	}
 	
 	server.workflowConfig = workflowConfig
+
+	// Prompt templates in the config directory replace the built-in ones
+	overridden, err := agent.LoadPromptTemplates(workflowConfig.Workflow.PromptsDir)
+	if err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+	if len(overridden) > 0 {
+		log.Printf("Prompt templates from %s: %s", workflowConfig.Workflow.PromptsDir, strings.Join(overridden, ", "))
+	}
 	
 	// Create shared toolset
 	toolSet := tools.NewToolSet(workflowConfig.Commands, workflowConfig.Restrictions, workingDir)
@@ -140,41 +160,60 @@ func main() {
 	}
 	
 	llmClient := llm.NewOllamaClient(ollamaURL, defaultModel)
-	orchestratorInstance := orchestrator.NewWorkflowOrchestrator(llmClient, toolSet, workflowConfig)
+	orchestratorInstance, err := orchestrator.NewWorkflowOrchestrator(llmClient, toolSet, workflowConfig)
+	if err != nil {
+		log.Fatalf("Failed to create orchestrator: %v", err)
+	}
 	
 	// Initialize debug logger
 	debugConfig := config.GetDebugConfig()
-	debugLogger := debug.NewDebugLogger(debugConfig.Enabled, debugConfig.LogDir)
+	debugLogger := debug.NewDebugLogger(debugConfig.Enabled, debugConfig.LogDir, debugConfig.MaxLogMB)
+	orchestratorInstance.SetDebugLogger(debugLogger)
 	
-	// Register all agents with interactive callbacks
+	// Every session's workflow gets its own ToolSet and agents with interactive callbacks
 	agentFactory := agent.NewAgentFactory(debugLogger)
-	for roleName, agentConfig := range workflowConfig.Agents {
-		role := agent.AgentRole(agentConfig.Role)
-		agentLLMClient := llm.NewOllamaClient(ollamaURL, agentConfig.Model)
-		
-		agentInstance, err := agentFactory.CreateAgent(role, agentLLMClient, toolSet, toolSet, agentConfig)
+	agentBuilder := orchestrator.NewAgentBuilder(workflowConfig, agentFactory, func(agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+		return llm.NewOllamaAgentClient(ollamaURL, agentCfg, workflowConfig)
+	})
+	err = orchestratorInstance.SetAgentBuilder(func(role agent.AgentRole, toolSet orchestrator.AgentTools) (agent.Agent, error) {
+		agentInstance, err := agentBuilder(role, toolSet)
 		if err != nil {
-			log.Fatalf("Failed to create agent %s: %v", roleName, err)
+			return nil, err
 		}
-		
 		// Wrap agent with interactive capabilities
-		interactiveAgent := server.wrapAgentWithInteractive(agentInstance, string(role))
-		orchestratorInstance.RegisterAgent(role, interactiveAgent)
+		return server.wrapAgentWithInteractive(agentInstance, string(role)), nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to create agents: %v", err)
 	}
 	
 	server.orchestrator = orchestratorInstance
 	
+	// Pull and load the agents' models without holding up the server
+	go func() {
+		if err := llm.PrepareModels(context.Background(), ollamaURL, workflowConfig, log.Printf); err != nil {
+			log.Printf("Model preparation incomplete: %v", err)
+		}
+	}()
+	
 	// Start session cleanup routine
 	go server.sessionCleanup()
 	
 	// Setup HTTP routes
 	http.HandleFunc("/ws", server.handleWebSocket)
 	http.HandleFunc("/health", server.handleHealth)
+	http.Handle("/metrics", metrics.Handler())
+	metrics.RegisterActiveSessions(func() int {
+		server.sessionsMutex.RLock()
+		defer server.sessionsMutex.RUnlock()
+		return len(server.sessions)
+	})
 	
 	log.Printf("Interactive MCP Server starting on port %s", port)
 	log.Printf("Ollama URL: %s", ollamaURL)
 	log.Printf("Working Directory: %s", workingDir)
 	log.Printf("WebSocket endpoint: ws://localhost:%s/ws", port)
+	log.Printf("Metrics endpoint: http://localhost:%s/metrics", port)
 	
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
@@ -274,13 +313,6 @@ func (s *InteractiveServer) handleWorkflowRequest(session *Session, data map[str
 
 	if projType, ok := data["project_type"].(string); ok {
 		workflowReq.ProjectType = agent.ProjectType(projType)
-	} else {
-		s.sendUpdate(session, ProgressUpdate{
-			SessionID: session.ID,
-			Type:      "error",
-			Message:   "Missing or invalid project_type",
-		})
-		return
 	}
 
 	if workDir, ok := data["working_directory"].(string); ok {
@@ -294,6 +326,10 @@ func (s *InteractiveServer) handleWorkflowRequest(session *Session, data map[str
 		}
 	}
 
+	if flow, ok := data["flow"].(string); ok {
+		workflowReq.Flow = flow
+	}
+
 	// Send workflow started
 	s.sendUpdate(session, ProgressUpdate{
 		SessionID: session.ID,
@@ -306,6 +342,7 @@ func (s *InteractiveServer) handleWorkflowRequest(session *Session, data map[str
 			"description":       workflowReq.Description,
 			"project_type":      workflowReq.ProjectType,
 			"working_directory": workflowReq.WorkingDirectory,
+			"flow":              workflowReq.Flow,
 		},
 	})
 
@@ -406,6 +443,10 @@ func (s *InteractiveServer) handleHealth(w http.ResponseWriter, r *http.Request)
 		"agents":         len(s.workflowConfig.Agents),
 		"active_sessions": sessionCount,
 		"websocket_endpoint": "/ws",
+		"llm":            llm.BreakerStates(),
+	}
+	if llm.AnyBreakerOpen() {
+		status["status"] = "degraded"
 	}
 
 	w.Header().Set("Content-Type", "application/json")
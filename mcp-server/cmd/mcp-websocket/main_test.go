@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"mcp-server/internal/testutil"
+)
+
+func TestWebSocketWorkflow(t *testing.T) {
+	env := testutil.Setup(t)
+	port := testutil.FreePort(t)
+	cmd := env.Command(t, testutil.Build(t, "mcp-websocket"), "WS_PORT="+port)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting mcp-websocket: %v", err)
+	}
+	testutil.WaitForPort(t, port)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://127.0.0.1:"+port+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dialing /ws: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Minute))
+
+	var update ProgressUpdate
+	if err := conn.ReadJSON(&update); err != nil || update.Type != "session_started" {
+		t.Fatalf("first update = %+v, %v, want session_started", update, err)
+	}
+
+	err = conn.WriteJSON(WebSocketRequest{
+		Type: "start_workflow",
+		Data: map[string]interface{}{
+			"description":       testutil.SubtractRequest,
+			"working_directory": env.Project,
+		},
+	})
+	if err != nil {
+		t.Fatalf("sending start_workflow: %v", err)
+	}
+
+	var statuses []string
+	for {
+		update = ProgressUpdate{}
+		if err := conn.ReadJSON(&update); err != nil {
+			t.Fatalf("reading updates after %v: %v", statuses, err)
+		}
+		statuses = append(statuses, update.Type+":"+update.Status)
+		if update.Type == "error" {
+			t.Fatalf("workflow error: %s", update.Message)
+		}
+		if update.Type == "complete" {
+			break
+		}
+	}
+	if statuses[0] != "progress:workflow_started" {
+		t.Errorf("updates = %v, want workflow_started first", statuses)
+	}
+
+	result, _ := update.Data["result"].(map[string]interface{})
+	if success, _ := result["success"].(bool); !success {
+		t.Fatalf("workflow failed: %v", result["failure_reason"])
+	}
+	env.CheckSubtract(t)
+}
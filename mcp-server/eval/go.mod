@@ -0,0 +1,5 @@
+// The eval task catalog: fixture repositories and their hidden acceptance tests.
+// This module keeps them out of mcp-server's packages; nothing builds it.
+module mcp-server/eval
+
+go 1.21
@@ -0,0 +1,17 @@
+package calculator
+
+import "testing"
+
+func TestAcceptanceSubtract(t *testing.T) {
+	cases := []struct{ a, b, want int }{
+		{5, 3, 2},
+		{3, 5, -2},
+		{0, 0, 0},
+		{-4, -6, 2},
+	}
+	for _, c := range cases {
+		if got := Subtract(c.a, c.b); got != c.want {
+			t.Errorf("Subtract(%d, %d) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAcceptanceRemove(t *testing.T) {
+	inv := New()
+	inv.Add("apple", 5)
+
+	if err := inv.Remove("apple", 3); err != nil {
+		t.Fatalf("Remove(apple, 3) = %v, want nil", err)
+	}
+	if got := inv.Quantity("apple"); got != 2 {
+		t.Errorf("Quantity(apple) = %d after removing 3 of 5, want 2", got)
+	}
+
+	err := inv.Remove("apple", 3)
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("Remove(apple, 3) with 2 in stock = %v, want ErrInsufficientStock", err)
+	}
+	if got := inv.Quantity("apple"); got != 2 {
+		t.Errorf("Quantity(apple) = %d after a failed remove, want 2", got)
+	}
+
+	if err := inv.Remove("pear", 1); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("Remove(pear, 1) of an unknown item = %v, want ErrInsufficientStock", err)
+	}
+}
@@ -0,0 +1,22 @@
+// Package inventory tracks stock levels by item name.
+package inventory
+
+// Inventory holds the quantity in stock of each item.
+type Inventory struct {
+	stock map[string]int
+}
+
+// New returns an empty inventory.
+func New() *Inventory {
+	return &Inventory{stock: make(map[string]int)}
+}
+
+// Add puts quantity more of item in stock.
+func (inv *Inventory) Add(item string, quantity int) {
+	inv.stock[item] += quantity
+}
+
+// Quantity returns how many of item are in stock.
+func (inv *Inventory) Quantity(item string) int {
+	return inv.stock[item]
+}
@@ -0,0 +1,12 @@
+package inventory
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	inv := New()
+	inv.Add("apple", 3)
+	inv.Add("apple", 2)
+	if got := inv.Quantity("apple"); got != 5 {
+		t.Errorf("Quantity(apple) = %d, want 5", got)
+	}
+}
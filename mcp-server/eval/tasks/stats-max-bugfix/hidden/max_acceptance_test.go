@@ -0,0 +1,20 @@
+package stats
+
+import "testing"
+
+func TestAcceptanceMax(t *testing.T) {
+	cases := []struct {
+		values []int
+		want   int
+	}{
+		{[]int{-3, -1, -7}, -1},
+		{[]int{3, 9, 4}, 9},
+		{[]int{-5}, -5},
+		{nil, 0},
+	}
+	for _, c := range cases {
+		if got := Max(c.values); got != c.want {
+			t.Errorf("Max(%v) = %d, want %d", c.values, got, c.want)
+		}
+	}
+}
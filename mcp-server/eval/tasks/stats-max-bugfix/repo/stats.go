@@ -0,0 +1,22 @@
+// Package stats computes summary statistics over integer samples.
+package stats
+
+// Sum returns the total of values.
+func Sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Max returns the largest of values, or 0 when there are none.
+func Max(values []int) int {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
@@ -0,0 +1,18 @@
+package stringutil
+
+import "testing"
+
+func TestAcceptanceReverse(t *testing.T) {
+	cases := map[string]string{
+		"":      "",
+		"a":     "a",
+		"hello": "olleh",
+		"héllo": "olléh",
+		"日本語":   "語本日",
+	}
+	for in, want := range cases {
+		if got := Reverse(in); got != want {
+			t.Errorf("Reverse(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module example.com/stringutil
+
+go 1.21
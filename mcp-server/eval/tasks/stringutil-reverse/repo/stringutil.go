@@ -0,0 +1,9 @@
+// Package stringutil holds small string helpers.
+package stringutil
+
+import "strings"
+
+// Shout returns s in upper case with an exclamation mark.
+func Shout(s string) string {
+	return strings.ToUpper(s) + "!"
+}
@@ -0,0 +1,9 @@
+package stringutil
+
+import "testing"
+
+func TestShout(t *testing.T) {
+	if got := Shout("hi"); got != "HI!" {
+		t.Errorf("Shout(%q) = %q, want %q", "hi", got, "HI!")
+	}
+}
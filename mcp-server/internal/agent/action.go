@@ -1,5 +1,7 @@
 package agent
 
+import "strings"
+
 // Action represents a structured action that agents can parse from LLM responses
 type Action struct {
 	Type       string
@@ -8,4 +10,104 @@ type Action struct {
 	Command    string
 	Pattern    string
 	SearchPath string
-}
\ No newline at end of file
+	Symbol     string
+}
+
+// actionFields maps the single-line action headers onto their Action field
+var actionFields = map[string]func(a *Action) *string{
+	"PATH:":        func(a *Action) *string { return &a.Path },
+	"COMMAND:":     func(a *Action) *string { return &a.Command },
+	"PATTERN:":     func(a *Action) *string { return &a.Pattern },
+	"SEARCH_PATH:": func(a *Action) *string { return &a.SearchPath },
+	"SYMBOL:":      func(a *Action) *string { return &a.Symbol },
+}
+
+// parseActions reads the ACTION blocks of an LLM response. Headers are matched on
+// trimmed lines, but CONTENT lines are kept verbatim so indentation survives; a
+// fenced block ends at its closing fence, an unfenced one at the next ACTION.
+func parseActions(response string) []Action {
+	var actions []Action
+	var current *Action
+	var content []string
+	inContent := false
+	fence := ""     // Opening fence of the content block being read, if any
+	fenced := false // Whether the current content was fenced
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		if content != nil {
+			current.Content = joinContent(content, fenced)
+		}
+		actions = append(actions, *current)
+	}
+
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if trimmed == fence {
+				fence = ""
+				inContent = false
+				continue
+			}
+			content = append(content, line)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "ACTION:") {
+			finish()
+			current = &Action{Type: strings.TrimSpace(strings.TrimPrefix(trimmed, "ACTION:"))}
+			content = nil
+			inContent = false
+			fenced = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if inContent {
+			// The first line decides whether the content is fenced
+			if len(content) == 0 && strings.HasPrefix(trimmed, "```") {
+				fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "`"))]
+				fenced = true
+				continue
+			}
+			if len(content) == 0 && trimmed == "" {
+				continue
+			}
+			content = append(content, line)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "CONTENT:") {
+			inContent = true
+			content = []string{}
+			continue
+		}
+		for prefix, field := range actionFields {
+			if strings.HasPrefix(trimmed, prefix) {
+				*field(current) = strings.TrimSpace(strings.TrimPrefix(trimmed, prefix))
+				break
+			}
+		}
+	}
+	finish()
+
+	return actions
+}
+
+// joinContent drops trailing blank lines, and for unfenced content a stray closing
+// fence, keeping indentation
+func joinContent(lines []string, fenced bool) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if !fenced && len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```") {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), " \t\n")
+}
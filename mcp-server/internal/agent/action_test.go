@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []Action
+	}{
+		{
+			name: "fenced content keeps indentation",
+			response: "I'll add the handler.\n\n" +
+				"ACTION: WRITE_FILE\n" +
+				"PATH: app/handler.py\n" +
+				"CONTENT:\n" +
+				"```python\n" +
+				"def handle(event):\n" +
+				"    if event:\n" +
+				"        return True\n" +
+				"\n" +
+				"    return False\n" +
+				"```\n" +
+				"\n" +
+				"ACTION: EXECUTE_COMMAND\n" +
+				"COMMAND: python -m pytest\n",
+			want: []Action{
+				{Type: "WRITE_FILE", Path: "app/handler.py", Content: "def handle(event):\n    if event:\n        return True\n\n    return False"},
+				{Type: "EXECUTE_COMMAND", Command: "python -m pytest"},
+			},
+		},
+		{
+			name: "unfenced content ends at the next action",
+			response: "ACTION: WRITE_FILE\r\n" +
+				"PATH: config.yaml\r\n" +
+				"CONTENT:\r\n" +
+				"\r\n" +
+				"server:\r\n" +
+				"  port: 8080\r\n" +
+				"  hosts:\r\n" +
+				"    - a\r\n" +
+				"\r\n" +
+				"ACTION: READ_FILE\r\n" +
+				"PATH: README.md\r\n",
+			want: []Action{
+				{Type: "WRITE_FILE", Path: "config.yaml", Content: "server:\n  port: 8080\n  hosts:\n    - a"},
+				{Type: "READ_FILE", Path: "README.md"},
+			},
+		},
+		{
+			name: "fenced content may contain action markers",
+			response: "ACTION: WRITE_FILE\n" +
+				"PATH: docs/format.md\n" +
+				"CONTENT:\n" +
+				"````markdown\n" +
+				"Write actions as:\n" +
+				"```\n" +
+				"ACTION: WRITE_FILE\n" +
+				"```\n" +
+				"````\n",
+			want: []Action{
+				{Type: "WRITE_FILE", Path: "docs/format.md", Content: "Write actions as:\n```\nACTION: WRITE_FILE\n```"},
+			},
+		},
+		{
+			name: "indented headers and search fields",
+			response: "  ACTION: SEARCH_CODE\n" +
+				"  PATTERN: func Add\n" +
+				"  SEARCH_PATH: internal\n" +
+				"ACTION: FIND_SYMBOL\n" +
+				"SYMBOL: Calculator\n",
+			want: []Action{
+				{Type: "SEARCH_CODE", Pattern: "func Add", SearchPath: "internal"},
+				{Type: "FIND_SYMBOL", Symbol: "Calculator"},
+			},
+		},
+		{
+			name:     "no actions",
+			response: "PATH: ignored because no action started\nAll done.",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseActions(tt.response); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseActions =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"mcp-server/internal/prompt"
+	"mcp-server/internal/tools"
+)
+
+// addFileSections adds a section per file in name order; each file's outline stands in
+// for it when most of its content would not fit
+func addFileSections(asm *prompt.Assembler, files map[string]string, priority prompt.Priority) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		heading := fmt.Sprintf("\n--- %s ---\n", name)
+		section := prompt.Section{
+			Name:     name,
+			Content:  heading + files[name] + "\n",
+			Priority: priority,
+			Trim:     prompt.KeepHead,
+		}
+		if outline := tools.Outline(name, files[name]); outline != "" {
+			section.Summary = heading + "(outline only, read the file for its content)\n" + outline
+		}
+		asm.Add(section)
+	}
+}
+
+// addDiffSection adds a git diff that keeps both its ends when trimmed and falls
+// back to a per-file count of changed lines
+func addDiffSection(asm *prompt.Assembler, title, diff string) {
+	if diff == "" {
+		return
+	}
+	heading := fmt.Sprintf("\n**%s:**\n", title)
+	asm.Add(prompt.Section{
+		Name:     "git diff",
+		Content:  heading + diff + "\n",
+		Priority: prompt.High,
+		Trim:     prompt.KeepEnds,
+		Summary:  heading + diffStat(diff),
+	})
+}
+
+// diffStat summarizes a unified diff as added and removed lines per file
+func diffStat(diff string) string {
+	type counts struct{ added, removed int }
+	var files []string
+	stats := make(map[string]*counts)
+	var current *counts
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			fields := strings.Fields(line)
+			name := strings.TrimPrefix(fields[len(fields)-1], "b/")
+			if stats[name] == nil {
+				stats[name] = &counts{}
+				files = append(files, name)
+			}
+			current = stats[name]
+		case current == nil, strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			current.added++
+		case strings.HasPrefix(line, "-"):
+			current.removed++
+		}
+	}
+
+	var stat strings.Builder
+	for _, name := range files {
+		fmt.Fprintf(&stat, "%s | +%d -%d\n", name, stats[name].added, stats[name].removed)
+	}
+	return stat.String()
+}
+
+// renderFittedPrompt renders the template name, whose data has its Sections
+// field at sections, with the sections added to asm fitted to the budget the
+// rest of the prompt leaves, and logs any section that had to give way
+func renderFittedPrompt(agentName, name, workingDir string, asm *prompt.Assembler, data interface{}, sections *string) (string, error) {
+	loaded, err := lookupPrompt(name, workingDir)
+	if err != nil {
+		return "", err
+	}
+	*sections = ""
+	frame, err := executePrompt(loaded, data)
+	if err != nil {
+		return "", err
+	}
+
+	asm.Reserve(frame)
+	fitted, report := asm.Fit()
+	if changed := report.Changed(); len(changed) > 0 {
+		log.Printf("%s prompt fitted to %d of %d tokens: %s", agentName, report.Tokens, report.Budget, strings.Join(changed, ", "))
+	}
+	if report.Overflow {
+		log.Printf("%s prompt needs %d tokens, more than its budget of %d", agentName, report.Tokens, report.Budget)
+	}
+
+	var assembled strings.Builder
+	for _, section := range fitted {
+		assembled.WriteString(section.Content)
+	}
+	*sections = assembled.String()
+	return executePrompt(loaded, data)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"mcp-server/internal/config"
+	"mcp-server/internal/llm"
 	"mcp-server/internal/tools"
 	"strings"
 	"time"
@@ -65,6 +66,11 @@ func (se *SeniorEngineer) ImplementFeature(
 	maxAttempts := 8          // Increased for 14B model
 	maxSameErrorAttempts := 3 // Reset when error type changes
 
+	// Whether a response was right only shows once its build and tests run, and
+	// the same prompt may come back after they failed, so the engineer always
+	// asks the model afresh
+	llmCtx := llm.WithoutCache(ctx)
+
 	for attempts < maxAttempts {
 		select {
 		case <-ctx.Done():
@@ -81,10 +87,16 @@ func (se *SeniorEngineer) ImplementFeature(
 		}
 
 		// Build system prompt with context and last error
-		prompt := se.buildSystemPrompt(req, gitStatus, lastError)
+		prompt, err := se.buildSystemPrompt(req, gitStatus, lastError)
+		if err != nil {
+			return &ImplementFeatureResponse{
+				Success: false,
+				Error:   fmt.Sprintf("Failed to build prompt: %v", err),
+			}, nil
+		}
 
 		// Generate implementation plan from LLM
-		llmResponse, err := se.llmClient.Generate(ctx, prompt)
+		llmResponse, err := se.llmClient.Generate(llmCtx, prompt)
 		if err != nil {
 			return &ImplementFeatureResponse{
 				Success: false,
@@ -147,6 +159,23 @@ func (se *SeniorEngineer) ImplementFeature(
 	return result, nil
 }
 
+// RenderPrompt builds the prompt of the first attempt ImplementFeature would
+// make for req without sending it
+func (se *SeniorEngineer) RenderPrompt(ctx context.Context, req ImplementFeatureRequest) (*RenderedPrompt, error) {
+	if req.WorkingDirectory != "" {
+		se.tools.SetWorkingDirectory(req.WorkingDirectory)
+	}
+	gitStatus, err := se.tools.GetGitStatus()
+	if err != nil {
+		gitStatus = "No git repository detected or git error occurred"
+	}
+	text, err := se.buildSystemPrompt(req, gitStatus, "")
+	if err != nil {
+		return nil, err
+	}
+	return newRenderedPrompt(PromptEngineer, se.tools.GetWorkingDirectory(), text)
+}
+
 // attemptErrorRecovery tries to automatically fix common errors
 func (se *SeniorEngineer) attemptErrorRecovery(ctx context.Context, errorMsg string, attempts int) *ImplementFeatureResponse {
 	errorCategory := se.categorizeError(errorMsg)
@@ -467,168 +496,41 @@ func (se *SeniorEngineer) trySolution(ctx context.Context, solution string) *Imp
 func (se *SeniorEngineer) buildSystemPrompt(
 	req ImplementFeatureRequest,
 	gitStatus, lastError string,
-) string {
-	// Parse EM brief first
-	emBrief := se.parseEMBrief(req.Description)
-	
-	correctionPrompt := ""
-	if lastError != "" {
-		correctionPrompt = fmt.Sprintf(`
-**Previous Attempt Failed!**
-Your last attempt failed with the following error. Analyze the error and the code you produced, then generate a new plan to fix it.
-
-**Error:**
-%s
-`, lastError)
-	}
-
-	// Build briefing section based on parsed EM brief
-	briefingSection := ""
-	if emBrief.Task != "" {
-		briefingSection = fmt.Sprintf(`
-**ENGINEERING MANAGER'S BRIEF:**
-Task: %s
-Project Context: %s
-Suggested Approach: %s
-Files to Examine: %s
-Known Issues to Avoid: %s
-Success Criteria: %s
-
-**YOUR IMPLEMENTATION STRATEGY:**
-1. FIRST: Read the files suggested by your EM to understand existing patterns
-2. THEN: Explore project structure if needed (LIST_FILES, FIND_FILES)
-3. FINALLY: Implement following the suggested approach
-
-**Implementation Guidelines:**
-- Follow the EM's suggested approach unless you find a compelling reason not to
-- If you deviate from the EM's suggestion, document why in your actions
-- Read the suggested files BEFORE implementing to understand patterns
-- Use existing project patterns and conventions
-`, 
-			emBrief.Task, 
-			emBrief.Context, 
-			emBrief.ImplementationApproach,
-			strings.Join(emBrief.FilesToExamine, ", "), 
-			strings.Join(emBrief.PotentialIssues, ", "), 
-			emBrief.SuccessCriteria)
-	} else {
-		// Fallback for non-structured descriptions
-		briefingSection = fmt.Sprintf(`
-**TASK DESCRIPTION:**
-%s
-
-**YOUR IMPLEMENTATION STRATEGY:**
-1. FIRST: Explore the project structure to understand existing patterns
-2. THEN: Read relevant files to understand conventions
-3. FINALLY: Implement the requested feature
-
-**Implementation Guidelines:**
-- Analyze the project structure before implementing
-- Follow existing code patterns and conventions
-- Use proper error handling and best practices
-`, req.Description)
-	}
-
-	return fmt.Sprintf(
-		`You are a Senior Software Engineer implementing a feature based on your Engineering Manager's brief.
-%s
-**Project Type:** %s
-**Working Directory:** %s
-%s
-**Current Git Status:**
-%s
-
-**Your Responsibilities:**
-1. **Setup**: Ensure working directory exists, create if needed (mkdir -p)
-2. **Project Initialization**: Set up project structure (go mod init, npm init, etc.)
-3. **Analysis**: Analyze the requested feature and determine implementation approach
-4. **Implementation**: Create or modify files to implement the feature
-5. **Validation**: Follow best practices, ensure code builds and runs correctly
-
-**Available Actions:**
-- READ_FILE: Read existing code files
-- WRITE_FILE: Create or modify files
-- EXECUTE_COMMAND: Run build, test, and git commands
-- GET_GIT_DIFF: Check current changes
-- LIST_FILES: List files and directories in a path
-- FIND_FILES: Search for files by name pattern
-- SEQUENTIAL_THINKING: Break down complex implementation into step-by-step thinking
-
-**When to Use Sequential Thinking:**
-Use sequential thinking for complex implementations that involve:
-- Setting up new projects from scratch
-- Multiple files that need to work together
-- Understanding existing patterns before implementation
-- Complex logic that requires careful reasoning
-- Debugging syntax errors or build failures
-- Planning implementation steps that depend on each other
-- When you encounter "no such file or directory" errors
-
-**Sequential Thinking Usage:**
-ACTION: SEQUENTIAL_THINKING
-THOUGHT: [Your current thinking step]
-THOUGHT_NUMBER: [Current step number]
-TOTAL_THOUGHTS: [Estimated total steps needed]
-NEXT_THOUGHT_NEEDED: [true/false]
-
-Example for complex feature implementation:
-ACTION: SEQUENTIAL_THINKING
-THOUGHT: I need to implement user management endpoints. Let me first understand the existing project structure and patterns by examining the current codebase.
-THOUGHT_NUMBER: 1
-TOTAL_THOUGHTS: 5
-NEXT_THOUGHT_NEEDED: true
-
-**Guidelines:**
-- Write clean, maintainable code
-- Follow existing code patterns and conventions
-- Include proper error handling
-- Add minimal comments only for complex logic
-- Ensure changes build without errors
-- **For Go projects: Remove unused imports, handle all declared variables**
-- **If you get "imported and not used" errors, remove the unused import**
-- **NEVER use compound commands with && or ;** - use single commands only
-- **NEVER use cd commands** - the working directory is already set correctly
-- **Run commands directly without path changes** (e.g., use "go mod init myproject" not "cd /path && go mod init myproject")
-- **If you are unable to fix a build error after an attempt, or if you believe you cannot complete the task, respond with a single line: ACTION: GIVE_UP**
-
-**Response Format:**
-Please respond with a structured plan using these action markers:
-
-ACTION: READ_FILE
-PATH: path/to/file
-
-ACTION: WRITE_FILE
-PATH: path/to/new/file
-CONTENT:
-`+"```"+`
-file content here
-`+"```"+`
-
-ACTION: EXECUTE_COMMAND
-COMMAND: build command here
-
-ACTION: LIST_FILES
-PATH: directory/path
-
-ACTION: FIND_FILES
-PATTERN: filename_pattern
-SEARCH_PATH: directory/to/search (optional)
-
-ACTION: SEQUENTIAL_THINKING
-THOUGHT: Your thinking step here
-THOUGHT_NUMBER: 1
-TOTAL_THOUGHTS: 3
-NEXT_THOUGHT_NEEDED: true
-
-**Start by using sequential thinking for complex features, then proceed with implementation actions.**
-
-Begin by following your implementation strategy and implementing the requested feature.`,
-		briefingSection,
-		req.ProjectType,
-		req.WorkingDirectory,
-		correctionPrompt,
-		gitStatus,
-	)
+) (string, error) {
+	return renderPrompt(PromptEngineer, se.tools.GetWorkingDirectory(), EngineerPromptData{
+		Description:      req.Description,
+		ProjectType:      req.ProjectType,
+		WorkingDirectory: req.WorkingDirectory,
+		Brief:            *se.parseEMBrief(req.Description),
+		Dependencies:     se.promptDependencies(),
+		LastError:        lastError,
+		GitStatus:        gitStatus,
+	})
+}
+
+// searchContextLines is how many lines SEARCH_CODE shows around each match
+const searchContextLines = 2
+
+// maxPromptDependencies caps how many declared dependencies are listed in the prompt
+const maxPromptDependencies = 60
+
+// promptDependencies lists the project's declared dependencies so the engineer
+// reuses what is already available instead of adding new packages
+func (se *SeniorEngineer) promptDependencies() []string {
+	deps, _ := tools.ReadDependencies(se.tools)
+
+	var lines []string
+	for _, dep := range deps {
+		if dep.Scope == "indirect" {
+			continue
+		}
+		if len(lines) == maxPromptDependencies {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s)", dep, dep.Source))
+	}
+	return lines
 }
 
 func (se *SeniorEngineer) executeImplementation(
@@ -645,7 +547,8 @@ func (se *SeniorEngineer) executeImplementation(
 	}
 
 	// Parse LLM response for actions
-	actions := se.parseActions(llmResponse)
+	actions := parseActions(llmResponse)
+	reportOutput(se.llmClient, len(actions) > 0)
 
 	for _, action := range actions {
 		if action.Type == "GIVE_UP" {
@@ -703,6 +606,34 @@ func (se *SeniorEngineer) executeImplementation(
 				result.BuildOutput += fmt.Sprintf("  %s\n", file)
 			}
 
+		case "SEARCH_CODE":
+			matches, err := se.tools.SearchCode(action.Pattern, action.SearchPath, searchContextLines)
+			if err != nil {
+				result.Success = false
+				result.Error = fmt.Sprintf("Failed to search code for '%s': %v", action.Pattern, err)
+				return result, nil
+			}
+			result.BuildOutput += fmt.Sprintf("Code matching '%s':\n", action.Pattern)
+			for _, match := range matches {
+				result.BuildOutput += match.String() + "\n--\n"
+			}
+
+		case "FIND_SYMBOL":
+			lookup, err := se.tools.FindSymbol(action.Symbol)
+			if err != nil {
+				result.Success = false
+				result.Error = fmt.Sprintf("Failed to find symbol %s: %v", action.Symbol, err)
+				return result, nil
+			}
+			result.BuildOutput += fmt.Sprintf("Definitions of %s:\n", action.Symbol)
+			for _, definition := range lookup.Definitions {
+				result.BuildOutput += fmt.Sprintf("  %s: %s\n", definition, definition.Signature)
+			}
+			result.BuildOutput += fmt.Sprintf("References to %s:\n", action.Symbol)
+			for _, reference := range lookup.References {
+				result.BuildOutput += fmt.Sprintf("  %s\n", reference)
+			}
+
 		case "EXECUTE_COMMAND":
 			if err := se.restrictions.ValidateCommand(action.Command); err != nil {
 				result.Success = false
@@ -726,11 +657,11 @@ func (se *SeniorEngineer) executeImplementation(
 		}
 	}
 
-	// Try to run a build command based on project type
-	buildCommand := se.getBuildCommand(req.ProjectType)
-	if buildCommand != "" {
-		if err := se.restrictions.ValidateCommand(buildCommand); err == nil {
-			output, err := se.tools.ExecuteCommand(buildCommand)
+	// Build every sub-project the changes touched with its language pack
+	targets := resolvePacks(se.tools, req.ProjectType, result.FilesModified)
+	for _, buildCommand := range packCommands(targets, buildCommandOf) {
+		if err := se.restrictions.ValidateCommand(buildCommand.Command); err == nil {
+			output, err := runPackCommand(se.tools, buildCommand)
 			log.Printf(
 				"Engineer: Build Command (%s) result - Error: %v, Output: %s",
 				buildCommand,
@@ -743,7 +674,7 @@ func (se *SeniorEngineer) executeImplementation(
 				result.BuildOutput += "\nBuild Output:\n" + output
 				return result, nil
 			}
-			result.CommandsExecuted = append(result.CommandsExecuted, buildCommand)
+			result.CommandsExecuted = append(result.CommandsExecuted, buildCommand.String())
 			result.BuildOutput += "\nBuild Output:\n" + output
 		}
 	}
@@ -760,76 +691,6 @@ func (se *SeniorEngineer) executeImplementation(
 	return result, nil
 }
 
-func (se *SeniorEngineer) parseActions(response string) []Action {
-	var actions []Action
-	lines := strings.Split(response, "\n")
-
-	var currentAction *Action
-	var inContent bool
-	var contentBuilder strings.Builder
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "ACTION:") {
-			// Save previous action
-			if currentAction != nil {
-				if inContent {
-					currentAction.Content = strings.TrimSpace(contentBuilder.String())
-				}
-				actions = append(actions, *currentAction)
-			}
-
-			// Start new action
-			actionType := strings.TrimSpace(strings.TrimPrefix(line, "ACTION:"))
-			currentAction = &Action{Type: actionType}
-			inContent = false
-			contentBuilder.Reset()
-		} else if currentAction != nil {
-			if strings.HasPrefix(line, "PATH:") {
-				currentAction.Path = strings.TrimSpace(strings.TrimPrefix(line, "PATH:"))
-			} else if strings.HasPrefix(line, "COMMAND:") {
-				currentAction.Command = strings.TrimSpace(strings.TrimPrefix(line, "COMMAND:"))
-			} else if strings.HasPrefix(line, "PATTERN:") {
-				currentAction.Pattern = strings.TrimSpace(strings.TrimPrefix(line, "PATTERN:"))
-			} else if strings.HasPrefix(line, "SEARCH_PATH:") {
-				currentAction.SearchPath = strings.TrimSpace(strings.TrimPrefix(line, "SEARCH_PATH:"))
-			} else if strings.HasPrefix(line, "CONTENT:") {
-				inContent = true
-				contentBuilder.Reset()
-			} else if inContent && !strings.HasPrefix(line, "```") {
-				if contentBuilder.Len() > 0 {
-					contentBuilder.WriteString("\n")
-				}
-				contentBuilder.WriteString(line)
-			}
-		}
-	}
-
-	// Save final action
-	if currentAction != nil {
-		if inContent {
-			currentAction.Content = strings.TrimSpace(contentBuilder.String())
-		}
-		actions = append(actions, *currentAction)
-	}
-
-	return actions
-}
-
-func (se *SeniorEngineer) getBuildCommand(projectType ProjectType) string {
-	switch projectType {
-	case ProjectTypeGo:
-		return "go build ."
-	case ProjectTypeTypeScript:
-		return "npm run build"
-	case ProjectTypePython:
-		return "python -m py_compile *.py"
-	default:
-		return ""
-	}
-}
-
 // categorizeError categorizes errors into broad types to detect progress vs stuck patterns
 func (se *SeniorEngineer) categorizeError(errorMsg string) string {
 	errorLower := strings.ToLower(errorMsg)
@@ -0,0 +1,41 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/llm"
+	"mcp-server/internal/tools"
+)
+
+// cacheProbeClient records whether each call may be answered from the cache
+type cacheProbeClient struct {
+	cacheAllowed []bool
+}
+
+func (c *cacheProbeClient) Generate(ctx context.Context, text string) (string, error) {
+	c.cacheAllowed = append(c.cacheAllowed, llm.CacheAllowed(ctx))
+	return "", errors.New("model unavailable")
+}
+
+func TestEngineerBypassesResponseCache(t *testing.T) {
+	project := t.TempDir()
+	toolSet := tools.NewToolSet(config.CommandsSection{}, config.RestrictionsSection{}, project)
+	client := &cacheProbeClient{}
+	engineer := agent.NewSeniorEngineer(client, toolSet, toolSet, config.WorkflowAgentConfig{PerAgentTimeoutMinutes: 1})
+
+	result, err := engineer.ImplementFeature(context.Background(), agent.ImplementFeatureRequest{
+		Description:      "add Subtract",
+		ProjectType:      agent.ProjectTypeGo,
+		WorkingDirectory: project,
+	})
+	if err != nil || result.Success {
+		t.Fatalf("ImplementFeature = %+v, %v; want the model failure reported", result, err)
+	}
+	if len(client.cacheAllowed) != 1 || client.cacheAllowed[0] {
+		t.Errorf("cache allowed per call = %v, want the first attempt to bypass the cache", client.cacheAllowed)
+	}
+}
@@ -17,6 +17,9 @@ func NewAgentFactory(debugLogger *debug.DebugLogger) AgentFactory {
 }
 
 func (f *DefaultAgentFactory) CreateAgent(role AgentRole, llmClient LLMClient, toolSet ToolSet, restrictions CommandRestrictions, cfg config.WorkflowAgentConfig) (Agent, error) {
+	// Meter every call and size prompts for the agent's model
+	llmClient = newMeteredClient(llmClient, role, cfg)
+
 	switch role {
 	case AgentRoleEM:
 		return NewEngineeringManager(llmClient, toolSet, restrictions, f.debugLogger), nil
@@ -25,8 +28,12 @@ func (f *DefaultAgentFactory) CreateAgent(role AgentRole, llmClient LLMClient, t
 	case AgentRoleQA:
 		return NewSeniorQAEngineer(llmClient, toolSet, restrictions), nil
 	case AgentRoleTechLead:
-		return NewSeniorTechLead(llmClient, toolSet, restrictions), nil
+		return NewSeniorTechLead(llmClient, toolSet, restrictions, cfg), nil
 	default:
+		// Custom roles are driven entirely by their configured prompt
+		if cfg.Prompt != "" {
+			return NewPromptAgent(role, llmClient, toolSet, restrictions, cfg), nil
+		}
 		return nil, fmt.Errorf("unknown agent role: %s", role)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"fmt"
+
+	"mcp-server/internal/langpack"
+)
+
+// packCommand is a language pack command bound to the directory it runs in
+type packCommand struct {
+	Dir     string
+	Command string
+}
+
+// String labels commands outside the project root with their directory
+func (pc packCommand) String() string {
+	if pc.Dir == "" || pc.Dir == "." {
+		return pc.Command
+	}
+	return fmt.Sprintf("%s (in %s)", pc.Command, pc.Dir)
+}
+
+// projectSurveyDepth is how many directory levels below the working directory are
+// searched for sub-projects, enough for layouts such as services/api/go.mod
+const projectSurveyDepth = 2
+
+// DetectProject surveys the working directory for language stacks. A requested type
+// is kept as is; otherwise the stack at the root wins, and without one the first
+// sub-project found, searching shallow directories first.
+func DetectProject(files langpack.Files, requested ProjectType) *ProjectDetection {
+	detection := &ProjectDetection{ProjectType: requested}
+
+	kinds := make(map[string]bool)
+	for _, found := range langpack.Survey(files, projectSurveyDepth) {
+		kinds[found.Pack.Name] = true
+		detection.Stacks = append(detection.Stacks, DetectedStack{
+			ProjectType: ProjectType(found.Pack.Name),
+			Directory:   found.Dir,
+			Evidence:    found.Evidence,
+		})
+	}
+	detection.Mixed = len(kinds) > 1
+
+	if requested == "" && len(detection.Stacks) > 0 {
+		detection.ProjectType = detection.Stacks[0].ProjectType
+		detection.Inferred = true
+	}
+	return detection
+}
+
+// resolvePacks picks the language pack for every sub-project the touched files belong to
+func resolvePacks(tools ToolSet, projectType ProjectType, touched []string) []langpack.Target {
+	return langpack.Resolve(tools, string(projectType), touched)
+}
+
+// packCommands collects the commands pick selects from each target's pack
+func packCommands(targets []langpack.Target, pick func(*langpack.Pack) []string) []packCommand {
+	var commands []packCommand
+	for _, target := range targets {
+		for _, command := range pick(target.Pack) {
+			if command != "" {
+				commands = append(commands, packCommand{Dir: target.Dir, Command: command})
+			}
+		}
+	}
+	return commands
+}
+
+func buildCommandOf(pack *langpack.Pack) []string { return []string{pack.Build} }
+
+func testCommandOf(pack *langpack.Pack) []string { return []string{pack.Test} }
+
+func autoFixCommandsOf(pack *langpack.Pack) []string { return pack.AutoFix }
+
+// runPackCommand executes pc in its directory; the command itself is validated by the ToolSet
+func runPackCommand(tools ToolSet, pc packCommand) (string, error) {
+	if pc.Dir == "" || pc.Dir == "." {
+		return tools.ExecuteCommand(pc.Command)
+	}
+	return tools.ExecuteCommandIn(pc.Dir, pc.Command)
+}
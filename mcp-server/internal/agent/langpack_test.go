@@ -0,0 +1,91 @@
+package agent_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/tools"
+)
+
+func TestDetectProject(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []string
+		requested agent.ProjectType
+		want      agent.ProjectType
+		inferred  bool
+		mixed     bool
+		stacks    []string
+	}{
+		{
+			name:     "single stack at the root",
+			files:    []string{"go.mod", "main.go"},
+			want:     agent.ProjectTypeGo,
+			inferred: true,
+			stacks:   []string{"go@."},
+		},
+		{
+			name:     "root stack wins over sub-projects",
+			files:    []string{"go.mod", "web/package.json", "web/src/app.ts"},
+			want:     agent.ProjectTypeGo,
+			inferred: true,
+			mixed:    true,
+			stacks:   []string{"go@.", "typescript@web"},
+		},
+		{
+			name:     "shallowest sub-project without a root stack",
+			files:    []string{"README.md", "services/api/go.mod", "web/package.json"},
+			want:     "typescript",
+			inferred: true,
+			mixed:    true,
+			stacks:   []string{"typescript@web", "go@services/api"},
+		},
+		{
+			name:     "same stack in several directories is not mixed",
+			files:    []string{"api/go.mod", "worker/go.mod"},
+			want:     agent.ProjectTypeGo,
+			inferred: true,
+			stacks:   []string{"go@api", "go@worker"},
+		},
+		{
+			name:      "requested type is kept",
+			files:     []string{"go.mod", "web/package.json"},
+			requested: "python",
+			want:      "python",
+			mixed:     true,
+			stacks:    []string{"go@.", "typescript@web"},
+		},
+		{
+			name:   "dependency and hidden directories are skipped",
+			files:  []string{"node_modules/left-pad/package.json", ".tools/go.mod", "notes.txt"},
+			want:   "",
+			stacks: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			for _, file := range tt.files {
+				writeFile(t, filepath.Join(project, file), "")
+			}
+			toolSet := tools.NewToolSet(config.CommandsSection{}, config.RestrictionsSection{}, project)
+
+			detection := agent.DetectProject(toolSet, tt.requested)
+			if detection.ProjectType != tt.want || detection.Inferred != tt.inferred || detection.Mixed != tt.mixed {
+				t.Errorf("detection = %s (inferred %v, mixed %v), want %s (inferred %v, mixed %v)",
+					detection.ProjectType, detection.Inferred, detection.Mixed, tt.want, tt.inferred, tt.mixed)
+			}
+			var stacks []string
+			for _, stack := range detection.Stacks {
+				stacks = append(stacks, string(stack.ProjectType)+"@"+stack.Directory)
+			}
+			if !reflect.DeepEqual(stacks, tt.stacks) {
+				t.Errorf("stacks = %v, want %v", stacks, tt.stacks)
+			}
+		})
+	}
+}
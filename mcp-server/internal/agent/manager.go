@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"mcp-server/internal/debug"
+	"mcp-server/internal/tools"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -30,7 +33,7 @@ func (em *EngineeringManager) ImplementFeature(ctx context.Context, req Implemen
 	}
 
 	// Get minimal project context
-	context, err := em.gatherProjectContext()
+	context, err := em.gatherProjectContext(req)
 	if err != nil {
 		return &ImplementFeatureResponse{
 			Success: false,
@@ -39,7 +42,13 @@ func (em *EngineeringManager) ImplementFeature(ctx context.Context, req Implemen
 	}
 
 	// Generate simple task for engineer
-	prompt := em.buildSystemPrompt(req, context)
+	prompt, err := em.buildSystemPrompt(req, context)
+	if err != nil {
+		return &ImplementFeatureResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to build prompt: %v", err),
+		}, nil
+	}
 	response, err := em.llmClient.Generate(ctx, prompt)
 	if err != nil {
 		return &ImplementFeatureResponse{
@@ -52,6 +61,22 @@ func (em *EngineeringManager) ImplementFeature(ctx context.Context, req Implemen
 	return em.processManagerResponse(ctx, req, response, context)
 }
 
+// RenderPrompt builds the prompt ImplementFeature would send for req without sending it
+func (em *EngineeringManager) RenderPrompt(ctx context.Context, req ImplementFeatureRequest) (*RenderedPrompt, error) {
+	if req.WorkingDirectory != "" {
+		em.tools.SetWorkingDirectory(req.WorkingDirectory)
+	}
+	projectCtx, err := em.gatherProjectContext(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather project context: %w", err)
+	}
+	text, err := em.buildSystemPrompt(req, projectCtx)
+	if err != nil {
+		return nil, err
+	}
+	return newRenderedPrompt(em.promptName(req), em.tools.GetWorkingDirectory(), text)
+}
+
 type ProjectContext struct {
 	GitStatus        string
 	GitLog           string
@@ -59,9 +84,11 @@ type ProjectContext struct {
 	AgentsMd         string
 	ProjectStructure string
 	ExistingFiles    map[string]string
+	RelevantSymbols  []tools.Symbol // definitions in the code index matching the request
+	RelevantFiles    []string       // files holding RelevantSymbols, most matches first
 }
 
-func (em *EngineeringManager) gatherProjectContext() (*ProjectContext, error) {
+func (em *EngineeringManager) gatherProjectContext(req ImplementFeatureRequest) (*ProjectContext, error) {
 	ctx := &ProjectContext{
 		ExistingFiles: make(map[string]string),
 	}
@@ -78,40 +105,97 @@ func (em *EngineeringManager) gatherProjectContext() (*ProjectContext, error) {
 		}
 	}
 
+	em.findRelevantCode(req.Description, ctx)
 	return ctx, nil
 }
 
-func (em *EngineeringManager) buildSystemPrompt(req ImplementFeatureRequest, context *ProjectContext) string {
-	// Check if this is a feedback scenario
-	isFeedbackScenario := strings.Contains(strings.ToLower(req.Description), "feedback") ||
-		strings.Contains(strings.ToLower(req.Description), "failed") ||
-		strings.Contains(strings.ToLower(req.Description), "error") ||
-		strings.Contains(strings.ToLower(req.Description), "issue")
-
-	if isFeedbackScenario {
-		return fmt.Sprintf(`You are the Engineering Manager handling engineer feedback.
-
-**Your Job:** Give the engineer a clearer, simpler task based on the feedback.
+const (
+	maxRelevantSymbols = 20
+	maxRelevantFiles   = 8
+)
 
-**Feedback:** %s
+// requestTerm matches identifier-like words worth looking up in the code index
+var requestTerm = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]{3,}`)
+
+// commonRequestWords are words of feature requests that say nothing about the code
+var commonRequestWords = map[string]bool{
+	"about": true, "add": true, "after": true, "also": true, "before": true, "build": true,
+	"code": true, "could": true, "create": true, "does": true, "each": true, "ensure": true,
+	"feature": true, "file": true, "files": true, "from": true, "have": true, "implement": true,
+	"into": true, "like": true, "make": true, "more": true, "need": true, "needs": true,
+	"only": true, "please": true, "should": true, "some": true, "support": true, "that": true,
+	"their": true, "then": true, "there": true, "these": true, "this": true, "update": true,
+	"using": true, "want": true, "what": true, "when": true, "where": true, "which": true,
+	"will": true, "with": true, "would": true, "your": true,
+}
 
-**Response Format:**
-TASK: [Tell the engineer exactly what to build in one simple sentence. The engineer will handle any setup needed.]
+// findRelevantCode looks the request's identifier-like words up in the code index so
+// the brief can point the engineer at the code that already exists
+func (em *EngineeringManager) findRelevantCode(description string, ctx *ProjectContext) {
+	seenTerms := make(map[string]bool)
+	seenSymbols := make(map[string]bool)
+	hits := make(map[string]int)
+	for _, term := range requestTerm.FindAllString(description, -1) {
+		term = strings.ToLower(term)
+		if commonRequestWords[term] || seenTerms[term] {
+			continue
+		}
+		seenTerms[term] = true
 
-Keep it simple. The engineer will figure out the implementation details and any setup needed.`, req.Description)
+		symbols, err := em.tools.SearchSymbols(term, 5)
+		if err != nil {
+			return
+		}
+		for _, symbol := range symbols {
+			if len(ctx.RelevantSymbols) == maxRelevantSymbols {
+				break
+			}
+			if key := symbol.String(); !seenSymbols[key] {
+				seenSymbols[key] = true
+				ctx.RelevantSymbols = append(ctx.RelevantSymbols, symbol)
+				if hits[symbol.File] == 0 {
+					ctx.RelevantFiles = append(ctx.RelevantFiles, symbol.File)
+				}
+				hits[symbol.File]++
+			}
+		}
 	}
 
-	// Normal scenario - just tell the engineer what to build
-	return fmt.Sprintf(`You are the Engineering Manager giving a task to your Senior Engineer.
-
-**User Request:** %s
+	sort.SliceStable(ctx.RelevantFiles, func(i, j int) bool {
+		return hits[ctx.RelevantFiles[i]] > hits[ctx.RelevantFiles[j]]
+	})
+	if len(ctx.RelevantFiles) > maxRelevantFiles {
+		ctx.RelevantFiles = ctx.RelevantFiles[:maxRelevantFiles]
+	}
+}
 
-**Your Job:** Tell the engineer exactly what to build
+// isFeedback tells whether the request sends earlier work back to the EM
+func isFeedback(description string) bool {
+	description = strings.ToLower(description)
+	return strings.Contains(description, "feedback") ||
+		strings.Contains(description, "failed") ||
+		strings.Contains(description, "error") ||
+		strings.Contains(description, "issue")
+}
 
-**Response Format:**
-TASK: [Tell the engineer exactly what to build in one simple sentence. The engineer will handle any setup needed.]
+// promptName picks the template for req: feedback gets a simpler, narrower task
+func (em *EngineeringManager) promptName(req ImplementFeatureRequest) string {
+	if isFeedback(req.Description) {
+		return PromptEMFeedback
+	}
+	return PromptEMBrief
+}
 
-Keep it simple. The engineer will figure out the implementation details and any project setup.`, req.Description)
+func (em *EngineeringManager) buildSystemPrompt(req ImplementFeatureRequest, context *ProjectContext) (string, error) {
+	// Point the brief at the indexed definitions matching the request
+	var relevantCode []string
+	for _, symbol := range context.RelevantSymbols {
+		relevantCode = append(relevantCode, symbol.String())
+	}
+	return renderPrompt(em.promptName(req), em.tools.GetWorkingDirectory(), ManagerPromptData{
+		Description:  req.Description,
+		RelevantCode: relevantCode,
+	})
 }
 
 func (em *EngineeringManager) processManagerResponse(ctx context.Context, req ImplementFeatureRequest, llmResponse string, projectCtx *ProjectContext) (*ImplementFeatureResponse, error) {
@@ -123,8 +207,17 @@ func (em *EngineeringManager) processManagerResponse(ctx context.Context, req Im
 		Message:          "Task assigned to engineer",
 	}
 
-	// Extract the task description and pass it to the engineer
+	// Extract the task description and pass it to the engineer, pointing at the files
+	// to read first when the EM named them or the code index found related code
 	taskDescription := em.extractTaskDescription(llmResponse)
+	reportOutput(em.llmClient, strings.Contains(llmResponse, "TASK:"))
+	files := em.extractFilesToExamine(llmResponse)
+	if len(files) == 0 {
+		files = projectCtx.RelevantFiles
+	}
+	if len(files) > 0 {
+		taskDescription = fmt.Sprintf("TASK: %s\nFILES_TO_EXAMINE: %s", taskDescription, strings.Join(files, ", "))
+	}
 	result.NextSteps = taskDescription
 	return result, nil
 }
@@ -151,6 +244,24 @@ func (em *EngineeringManager) extractTaskDescription(llmResponse string) string
 	return taskDescription
 }
 
+// extractFilesToExamine reads the optional FILES_TO_EXAMINE line of the EM's response
+func (em *EngineeringManager) extractFilesToExamine(llmResponse string) []string {
+	for _, line := range strings.Split(llmResponse, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "FILES_TO_EXAMINE:") {
+			continue
+		}
+		var files []string
+		for _, file := range strings.Split(strings.TrimPrefix(line, "FILES_TO_EXAMINE:"), ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				files = append(files, file)
+			}
+		}
+		return files
+	}
+	return nil
+}
+
 // DocumentTask is called at the end of a successful workflow to update the knowledge base.
 func (em *EngineeringManager) DocumentTask(ctx context.Context, result *WorkflowResult) error {
 	// 1. Read existing AGENTS.md (try new location first, then fallback)
@@ -170,7 +281,10 @@ func (em *EngineeringManager) DocumentTask(ctx context.Context, result *Workflow
 	}
 
 	// 2. Build a prompt to ask the LLM to summarize and update the knowledge base
-	prompt := em.buildDocumentationPrompt(result, currentKnowledge)
+	prompt, err := em.buildDocumentationPrompt(result, currentKnowledge)
+	if err != nil {
+		return err
+	}
 
 	// 3. Generate the updated knowledge base from the LLM
 	updatedKnowledge, err := em.llmClient.Generate(ctx, prompt)
@@ -182,78 +296,22 @@ func (em *EngineeringManager) DocumentTask(ctx context.Context, result *Workflow
 	return em.tools.WriteFile(agentsFile, updatedKnowledge)
 }
 
-// buildReplanningPrompt creates an enhanced replanning prompt based on specific engineer feedback
-func (em *EngineeringManager) buildReplanningPrompt(engineerFeedback string, originalBrief string, context *ProjectContext) string {
-	return fmt.Sprintf(`You are the Engineering Manager receiving feedback from your Senior Engineer.
-
-**ORIGINAL BRIEF:** %s
-
-**ENGINEER FEEDBACK/ERROR:** %s
-
-**PROJECT CONTEXT:**
-%s
-
-**YOUR REPLANNING TASK:**
-1. Analyze what went wrong with your original brief
-2. Identify if this was a project setup issue, approach issue, or missing context
-3. Provide a REVISED brief with better guidance
-
-**If the issue was:**
-- Missing files/setup: Use EXECUTE_COMMAND to fix project structure
-- Wrong approach: Revise IMPLEMENTATION_APPROACH with better strategy  
-- Missing context: Add more specific FILES_TO_EXAMINE and CONTEXT
-- Pattern mismatch: Update approach to match existing project patterns
-- Structure issue: Create necessary directories and organization
-
-**REVISED IMPLEMENTATION BRIEF FORMAT:**
-Your response must follow this structured format to provide clear guidance to the Senior Engineer:
-
-TASK: [One clear sentence describing what to implement]
-CONTEXT: [Enhanced project patterns/architecture the engineer should know]
-FILES_TO_EXAMINE: [Specific files to read for patterns/examples, comma-separated]
-IMPLEMENTATION_APPROACH: [Revised technical approach addressing the feedback]
-POTENTIAL_ISSUES: [Known pitfalls including lessons from the failure, comma-separated]
-SUCCESS_CRITERIA: [How to verify the implementation works]
-
-**Response Format:**
-If cleanup/setup needed:
-ACTION: EXECUTE_COMMAND
-COMMAND: [cleanup command]
-
-ACTION: WRITE_FILE  
-PATH: [project setup file]
-CONTENT: [file content]
-
-TASK: [Your revised structured implementation brief addressing the feedback]
-
-If no cleanup needed:
-TASK: [Your revised structured implementation brief addressing the feedback]
-
-Provide a complete revised brief that addresses the specific feedback received.`, originalBrief, engineerFeedback, context.ProjectStructure)
-}
-
-func (em *EngineeringManager) buildDocumentationPrompt(result *WorkflowResult, currentKnowledge string) string {
-	var summary strings.Builder
-	summary.WriteString("**Workflow Summary:**\n")
-	summary.WriteString(fmt.Sprintf("- Success: %v\n", result.Success))
-	if !result.Success {
-		summary.WriteString(fmt.Sprintf("- Failure Reason: %s\n", result.FailureReason))
+func (em *EngineeringManager) buildDocumentationPrompt(result *WorkflowResult, currentKnowledge string) (string, error) {
+	data := KnowledgePromptData{
+		Success:       result.Success,
+		FailureReason: result.FailureReason,
+		FilesModified: result.FilesModified,
+		Knowledge:     currentKnowledge,
 	}
-	summary.WriteString(fmt.Sprintf("- Files Modified: %s\n", strings.Join(result.FilesModified, ", ")))
-	summary.WriteString("\n**Agent Contributions:**\n")
-	for role, agentSummary := range result.AgentSummaries {
-		summary.WriteString(fmt.Sprintf("- **%s**: %s (Success: %v)\n", role, agentSummary.TaskCompleted, agentSummary.Success))
+	// List agents in a fixed order so the same workflow always yields the same prompt
+	roles := make([]string, 0, len(result.AgentSummaries))
+	for role := range result.AgentSummaries {
+		roles = append(roles, role)
 	}
-
-	return fmt.Sprintf(`You are the Engineering Manager, responsible for maintaining the team's collective knowledge.\n\n**Your Task:**\nUpdate the Agent Knowledge Base (` + "`AGENTS.md`" + `) with the results of the last workflow. \n- Integrate new learnings, architectural decisions, or coding patterns.\n- Do NOT remove existing valuable information unless it is explicitly replaced by a new standard.\n- Keep the document concise and well-organized.\n\n**Summary of Completed Workflow:**\n%s\n\n**Current Knowledge Base (AGENTS.md):**\n--- (start of file) ---\n%s\n--- (end of file) ---\n\n**Your Response:**\nRespond with ONLY the complete, updated content for ` + "`AGENTS.md`" + `.\n`,
-		summary.String(), currentKnowledge)
-}
-
-// truncateString truncates a string to maxLen characters
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	sort.Strings(roles)
+	for _, role := range roles {
+		agentSummary := result.AgentSummaries[role]
+		data.Agents = append(data.Agents, AgentContribution{Role: role, Task: agentSummary.TaskCompleted, Success: agentSummary.Success})
 	}
-	return s[:maxLen] + "... [truncated]"
+	return renderPrompt(PromptEMKnowledge, em.tools.GetWorkingDirectory(), data)
 }
-
@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mcp-server/internal/config"
+)
+
+// PromptAgent backs custom roles: its behaviour comes entirely from the
+// prompt and context files configured for the role
+type PromptAgent struct {
+	role         AgentRole
+	llmClient    LLMClient
+	tools        ToolSet
+	restrictions CommandRestrictions
+	config       config.WorkflowAgentConfig
+}
+
+func NewPromptAgent(role AgentRole, llmClient LLMClient, tools ToolSet, restrictions CommandRestrictions, cfg config.WorkflowAgentConfig) *PromptAgent {
+	return &PromptAgent{
+		role:         role,
+		llmClient:    llmClient,
+		tools:        tools,
+		restrictions: restrictions,
+		config:       cfg,
+	}
+}
+
+func (pa *PromptAgent) ImplementFeature(ctx context.Context, req ImplementFeatureRequest) (*ImplementFeatureResponse, error) {
+	if req.WorkingDirectory != "" {
+		pa.tools.SetWorkingDirectory(req.WorkingDirectory)
+	}
+
+	prompt, err := pa.buildPrompt(req)
+	if err != nil {
+		return &ImplementFeatureResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to build prompt: %v", err),
+		}, nil
+	}
+	response, err := pa.llmClient.Generate(ctx, prompt)
+	if err != nil {
+		return &ImplementFeatureResponse{
+			Success: false,
+			Error:   fmt.Sprintf("LLM generation failed: %v", err),
+		}, nil
+	}
+
+	return pa.executeActions(response), nil
+}
+
+func (pa *PromptAgent) buildPrompt(req ImplementFeatureRequest) (string, error) {
+	data := CustomRolePromptData{
+		Role:         string(pa.role),
+		Instructions: strings.TrimSpace(pa.config.Prompt),
+		Description:  req.Description,
+		ProjectType:  req.ProjectType,
+	}
+	for _, path := range pa.config.Context {
+		content, err := pa.tools.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if len(content) > 4000 {
+			content = content[:4000] + "... (truncated)"
+		}
+		data.ContextFiles = append(data.ContextFiles, ContextFile{Path: path, Content: content})
+	}
+	if gitStatus, err := pa.tools.GetGitStatus(); err == nil {
+		data.GitStatus = gitStatus
+	}
+	return renderPrompt(PromptCustomRole, pa.tools.GetWorkingDirectory(), data)
+}
+
+// RenderPrompt builds the prompt ImplementFeature would send for req without sending it
+func (pa *PromptAgent) RenderPrompt(ctx context.Context, req ImplementFeatureRequest) (*RenderedPrompt, error) {
+	if req.WorkingDirectory != "" {
+		pa.tools.SetWorkingDirectory(req.WorkingDirectory)
+	}
+	text, err := pa.buildPrompt(req)
+	if err != nil {
+		return nil, err
+	}
+	return newRenderedPrompt(PromptCustomRole, pa.tools.GetWorkingDirectory(), text)
+}
+
+func (pa *PromptAgent) executeActions(llmResponse string) *ImplementFeatureResponse {
+	result := &ImplementFeatureResponse{
+		Success:          true,
+		Message:          fmt.Sprintf("%s completed the task", pa.role),
+		FilesModified:    []string{},
+		CommandsExecuted: []string{},
+	}
+
+	for _, action := range parseActions(llmResponse) {
+		switch action.Type {
+		case "READ_FILE":
+			// Reads only inform the model's own reasoning
+			continue
+
+		case "WRITE_FILE":
+			if err := pa.tools.WriteFile(action.Path, action.Content); err != nil {
+				result.Success = false
+				result.Error = fmt.Sprintf("Failed to write file %s: %v", action.Path, err)
+				return result
+			}
+			result.FilesModified = append(result.FilesModified, action.Path)
+
+		case "EXECUTE_COMMAND":
+			if err := pa.restrictions.ValidateCommand(action.Command); err != nil {
+				result.Success = false
+				result.Error = fmt.Sprintf("Command validation failed: %v", err)
+				return result
+			}
+
+			output, err := pa.tools.ExecuteCommand(action.Command)
+			result.CommandsExecuted = append(result.CommandsExecuted, action.Command)
+			result.BuildOutput += output + "\n"
+			if err != nil {
+				result.Success = false
+				result.Error = fmt.Sprintf("Command %q failed: %v", action.Command, err)
+				return result
+			}
+		}
+	}
+
+	if failed, reason := pa.parseOutcome(llmResponse); failed {
+		result.Success = false
+		result.Error = reason
+		result.NextSteps = reason
+		return result
+	}
+
+	result.NextSteps = summaryText(llmResponse)
+	return result
+}
+
+// parseOutcome looks for the RESULT: FAILED / REASON: trailer the prompt asks for
+func (pa *PromptAgent) parseOutcome(response string) (bool, string) {
+	failed := false
+	reason := ""
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "RESULT:") {
+			failed = strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(line, "RESULT:")), "FAILED")
+		} else if strings.HasPrefix(line, "REASON:") {
+			reason = strings.TrimSpace(strings.TrimPrefix(line, "REASON:"))
+		}
+	}
+	if failed && reason == "" {
+		reason = fmt.Sprintf("%s could not complete the task", pa.role)
+	}
+	return failed, reason
+}
+
+// summaryText keeps the prose outside of action blocks so the next agent sees what was done
+func summaryText(response string) string {
+	var summary []string
+	inAction, inFence := false, false
+	for _, line := range strings.Split(response, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "ACTION:") {
+			inAction = true
+			continue
+		}
+		if inAction {
+			if strings.HasPrefix(trimmed, "```") {
+				inFence = !inFence
+			} else if trimmed == "" && !inFence {
+				inAction = false
+			}
+			continue
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "RESULT:") && !strings.HasPrefix(trimmed, "REASON:") {
+			summary = append(summary, trimmed)
+		}
+	}
+	return strings.Join(summary, "\n")
+}
+
+// DocumentTask for PromptAgent is a no-op
+func (pa *PromptAgent) DocumentTask(ctx context.Context, result *WorkflowResult) error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package agent
+
+import "testing"
+
+func TestPromptAgentParseOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   string
+		wantFailed bool
+		wantReason string
+	}{
+		{"no trailer", "Updated the README.", false, ""},
+		{"success trailer", "Done.\nRESULT: SUCCESS", false, ""},
+		{"failure with reason", "RESULT: FAILED\nREASON: docs/ does not exist", true, "docs/ does not exist"},
+		{"case-insensitive and indented", "  RESULT: failed  \n  REASON:  no README ", true, "no README"},
+		{"failure without reason", "RESULT: FAILED", true, "technical_writer could not complete the task"},
+		{"last result wins", "RESULT: FAILED\nREASON: first try\nRESULT: SUCCESS", false, "first try"},
+	}
+
+	pa := &PromptAgent{role: "technical_writer"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed, reason := pa.parseOutcome(tt.response)
+			if failed != tt.wantFailed || reason != tt.wantReason {
+				t.Errorf("parseOutcome = (%v, %q), want (%v, %q)", failed, reason, tt.wantFailed, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSummaryText(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"prose only", "Updated the README.\n\nAdded a usage section.", "Updated the README.\nAdded a usage section."},
+		{
+			name:     "action blocks dropped",
+			response: "Documenting Subtract.\n\nACTION: WRITE_FILE\nPATH: README.md\nCONTENT:\n```\n# Calc\n\nSubtract(a, b)\n```\n\nREADME now lists Subtract.",
+			want:     "Documenting Subtract.\nREADME now lists Subtract.",
+		},
+		{
+			name:     "action ends at a blank line",
+			response: "ACTION: READ_FILE\nPATH: README.md\n\nRead the README first.",
+			want:     "Read the README first.",
+		},
+		{"trailer dropped", "Nothing to document.\nRESULT: FAILED\nREASON: no public API changed", "Nothing to document."},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summaryText(tt.response); got != tt.want {
+				t.Errorf("summaryText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
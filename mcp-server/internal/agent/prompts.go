@@ -0,0 +1,342 @@
+package agent
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Prompt template names; a file overriding one is named after it with a .tmpl
+// extension
+const (
+	PromptEMBrief     = "em_brief"
+	PromptEMFeedback  = "em_feedback"
+	PromptEMKnowledge = "em_knowledge"
+	PromptEngineer    = "engineer"
+	PromptQA          = "qa"
+	PromptTechLead    = "tech_lead"
+	PromptCustomRole  = "custom_role"
+)
+
+// ProjectPromptsDir holds a project's own templates, relative to its root; they
+// take precedence over the config directory's and the defaults
+const ProjectPromptsDir = "agents/prompts"
+
+// promptSourceEmbedded is the source of a template built into the binary
+const promptSourceEmbedded = "embedded"
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// ManagerPromptData fills em_brief and em_feedback
+type ManagerPromptData struct {
+	Description  string   // the request, or the feedback that sent the work back
+	RelevantCode []string // indexed definitions matching the request, as "file:line kind name"
+}
+
+// KnowledgePromptData fills em_knowledge, which asks for an updated AGENTS.md
+type KnowledgePromptData struct {
+	Success       bool
+	FailureReason string // set when the workflow failed
+	FilesModified []string
+	Agents        []AgentContribution // in role order
+	Knowledge     string              // the current AGENTS.md
+}
+
+// AgentContribution is what one agent did during a workflow
+type AgentContribution struct {
+	Role    string
+	Task    string
+	Success bool
+}
+
+// EngineerPromptData fills engineer
+type EngineerPromptData struct {
+	Description      string
+	ProjectType      ProjectType
+	WorkingDirectory string
+	Brief            EMBrief  // parsed from Description; Brief.Task is empty without a structured brief
+	Dependencies     []string // declared direct dependencies as "name version (manifest)", ending in "..." when capped
+	LastError        string   // why the previous attempt failed, empty on the first
+	GitStatus        string
+}
+
+// QAPromptData fills qa
+type QAPromptData struct {
+	Description      string
+	ProjectType      ProjectType
+	TestingFramework string
+	Sections         string // the modified files, git diff and existing tests, fitted to the context window
+}
+
+// TechLeadPromptData fills tech_lead
+type TechLeadPromptData struct {
+	Description string
+	ProjectType ProjectType
+	Brief       EMBrief // parsed from Description; Brief.Task is empty without a structured brief
+	Sections    string  // pattern docs, changed files, test files, git diff and quality tools, fitted to the context window
+}
+
+// CustomRolePromptData fills custom_role, shared by every role configured with a prompt
+type CustomRolePromptData struct {
+	Role         string
+	Instructions string // the role's configured prompt
+	Description  string
+	ProjectType  ProjectType
+	ContextFiles []ContextFile // the role's context files that could be read
+	GitStatus    string
+}
+
+// ContextFile is a file given to a custom role, cut to its first 4000 bytes
+type ContextFile struct {
+	Path    string
+	Content string
+}
+
+// promptData is the data type each template is executed with
+var promptData = map[string]interface{}{
+	PromptEMBrief:     ManagerPromptData{},
+	PromptEMFeedback:  ManagerPromptData{},
+	PromptEMKnowledge: KnowledgePromptData{},
+	PromptEngineer:    EngineerPromptData{},
+	PromptQA:          QAPromptData{},
+	PromptTechLead:    TechLeadPromptData{},
+	PromptCustomRole:  CustomRolePromptData{},
+}
+
+// promptFuncs are the functions templates can call besides the text/template builtins
+var promptFuncs = template.FuncMap{"join": strings.Join}
+
+type promptTemplate struct {
+	tmpl   *template.Template
+	source string
+}
+
+var (
+	promptsMu sync.RWMutex
+	prompts   = loadEmbeddedPrompts()
+
+	projectPromptsMu sync.Mutex
+	projectPrompts   = make(map[string]map[string]promptTemplate) // by project directory
+)
+
+// PromptNames lists the prompt templates in name order
+func PromptNames() []string {
+	names := make([]string, 0, len(promptData))
+	for name := range promptData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func loadEmbeddedPrompts() map[string]promptTemplate {
+	loaded := make(map[string]promptTemplate, len(promptData))
+	for _, name := range PromptNames() {
+		text, err := embeddedPrompts.ReadFile("prompts/" + name + ".tmpl")
+		if err != nil {
+			panic(fmt.Sprintf("prompt template %s is not embedded: %v", name, err))
+		}
+		tmpl, err := parsePrompt(name, promptSourceEmbedded, string(text))
+		if err != nil {
+			panic(err)
+		}
+		loaded[name] = promptTemplate{tmpl: tmpl, source: promptSourceEmbedded}
+	}
+	return loaded
+}
+
+// LoadPromptTemplates makes the templates in dir replace the built-in ones,
+// undoing any earlier load, and returns the names it replaced. A missing dir
+// replaces nothing; a template that doesn't parse, refers to unknown variables
+// or has an unknown name is an error and leaves the templates as they were.
+func LoadPromptTemplates(dir string) ([]string, error) {
+	overrides, err := readPromptDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := loadEmbeddedPrompts()
+	var names []string
+	for name, override := range overrides {
+		loaded[name] = override
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	promptsMu.Lock()
+	prompts = loaded
+	promptsMu.Unlock()
+	return names, nil
+}
+
+// LoadProjectPrompts parses and validates the templates the project at
+// workingDir keeps in ProjectPromptsDir, so that a broken one fails the workflow
+// up front, and keeps them for the project's prompts until it is loaded again
+func LoadProjectPrompts(workingDir string) error {
+	_, err := loadProjectPrompts(workingDir)
+	return err
+}
+
+func loadProjectPrompts(workingDir string) (map[string]promptTemplate, error) {
+	overrides, err := readPromptDir(filepath.Join(workingDir, ProjectPromptsDir))
+	if err != nil {
+		return nil, err
+	}
+	projectPromptsMu.Lock()
+	projectPrompts[filepath.Clean(workingDir)] = overrides
+	projectPromptsMu.Unlock()
+	return overrides, nil
+}
+
+// projectPromptsFor returns the project templates loaded for workingDir,
+// loading them first for an agent used without a workflow
+func projectPromptsFor(workingDir string) (map[string]promptTemplate, error) {
+	projectPromptsMu.Lock()
+	overrides, ok := projectPrompts[filepath.Clean(workingDir)]
+	projectPromptsMu.Unlock()
+	if ok {
+		return overrides, nil
+	}
+	return loadProjectPrompts(workingDir)
+}
+
+// readPromptDir parses and validates every .tmpl file in dir
+func readPromptDir(dir string) (map[string]promptTemplate, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt templates: %w", err)
+	}
+
+	loaded := make(map[string]promptTemplate)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tmpl" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		tmpl, err := parsePrompt(name, path, string(text))
+		if err != nil {
+			return nil, err
+		}
+		loaded[name] = promptTemplate{tmpl: tmpl, source: path}
+	}
+	return loaded, nil
+}
+
+// parsePrompt parses the template text of name read from source, dropping the
+// newline editors add at the end of a file, and checks it against the data the
+// template is executed with
+func parsePrompt(name, source, text string) (*template.Template, error) {
+	data, ok := promptData[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown prompt template %q, want one of %s", source, name, strings.Join(PromptNames(), ", "))
+	}
+	tmpl, err := template.New(name).Funcs(promptFuncs).Parse(strings.TrimSuffix(text, "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	// A filled in and an empty value between them take both branches of most
+	// conditions, so a misspelt variable fails here rather than mid-workflow
+	for _, sample := range []interface{}{sampleValue(reflect.TypeOf(data)).Interface(), data} {
+		if err := tmpl.Execute(io.Discard, sample); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// sampleValue returns a value of t with every string, number and flag set and
+// every slice holding one element
+func sampleValue(t reflect.Type) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Slice:
+		v.Set(reflect.Append(v, sampleValue(t.Elem())))
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			v.Field(i).Set(sampleValue(t.Field(i).Type))
+		}
+	}
+	return v
+}
+
+// lookupPrompt returns the template for name, preferring the one loaded from the
+// project at workingDir over the config directory's or the default
+func lookupPrompt(name, workingDir string) (promptTemplate, error) {
+	if workingDir != "" {
+		overrides, err := projectPromptsFor(workingDir)
+		if err != nil {
+			return promptTemplate{}, err
+		}
+		if loaded, ok := overrides[name]; ok {
+			return loaded, nil
+		}
+	}
+
+	promptsMu.RLock()
+	defer promptsMu.RUnlock()
+	loaded, ok := prompts[name]
+	if !ok {
+		return promptTemplate{}, fmt.Errorf("unknown prompt template %q", name)
+	}
+	return loaded, nil
+}
+
+// renderPrompt executes the template name for the project at workingDir
+func renderPrompt(name, workingDir string, data interface{}) (string, error) {
+	loaded, err := lookupPrompt(name, workingDir)
+	if err != nil {
+		return "", err
+	}
+	return executePrompt(loaded, data)
+}
+
+func executePrompt(loaded promptTemplate, data interface{}) (string, error) {
+	var text strings.Builder
+	if err := loaded.tmpl.Execute(&text, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt from %s: %w", loaded.source, err)
+	}
+	return text.String(), nil
+}
+
+// RenderedPrompt is the prompt an agent would send for a request
+type RenderedPrompt struct {
+	Template string `json:"template"`
+	Source   string `json:"source"` // "embedded", or the file overriding the default
+	Prompt   string `json:"prompt"`
+}
+
+// newRenderedPrompt describes text rendered from the template name for the
+// project at workingDir
+func newRenderedPrompt(name, workingDir, text string) (*RenderedPrompt, error) {
+	loaded, err := lookupPrompt(name, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	return &RenderedPrompt{Template: name, Source: loaded.source, Prompt: text}, nil
+}
@@ -0,0 +1,113 @@
+package agent_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/tools"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func renderEngineerPrompt(t *testing.T, project, description string) *agent.RenderedPrompt {
+	t.Helper()
+	toolSet := tools.NewToolSet(config.CommandsSection{}, config.RestrictionsSection{}, project)
+	engineer := agent.NewSeniorEngineer(nil, toolSet, toolSet, config.WorkflowAgentConfig{})
+	rendered, err := engineer.RenderPrompt(context.Background(), agent.ImplementFeatureRequest{
+		Description:      description,
+		ProjectType:      agent.ProjectTypeGo,
+		WorkingDirectory: project,
+	})
+	if err != nil {
+		t.Fatalf("RenderPrompt: %v", err)
+	}
+	return rendered
+}
+
+func TestPromptOverridePrecedence(t *testing.T) {
+	t.Cleanup(func() { agent.LoadPromptTemplates("") })
+	configDir, project := t.TempDir(), t.TempDir()
+	description := "TASK: add Subtract\nFILES_TO_EXAMINE: calc.go, calc_test.go"
+
+	rendered := renderEngineerPrompt(t, project, description)
+	if rendered.Source != "embedded" || !strings.Contains(rendered.Prompt, "Task: add Subtract\n") {
+		t.Fatalf("default prompt from %s:\n%s", rendered.Source, rendered.Prompt)
+	}
+
+	configTemplate := filepath.Join(configDir, "engineer.tmpl")
+	writeFile(t, configTemplate, "config: {{.Brief.Task}} in {{join .Brief.FilesToExamine \" and \"}}\n")
+	names, err := agent.LoadPromptTemplates(configDir)
+	if err != nil || strings.Join(names, ",") != "engineer" {
+		t.Fatalf("LoadPromptTemplates = %v, %v; want engineer replaced", names, err)
+	}
+	rendered = renderEngineerPrompt(t, project, description)
+	if rendered.Source != configTemplate || rendered.Prompt != "config: add Subtract in calc.go and calc_test.go" {
+		t.Fatalf("prompt from %s = %q, want the config directory's", rendered.Source, rendered.Prompt)
+	}
+
+	projectTemplate := filepath.Join(project, agent.ProjectPromptsDir, "engineer.tmpl")
+	writeFile(t, projectTemplate, "project: {{.ProjectType}}\n")
+	if err := agent.LoadProjectPrompts(project); err != nil {
+		t.Fatalf("LoadProjectPrompts: %v", err)
+	}
+	rendered = renderEngineerPrompt(t, project, description)
+	if rendered.Source != projectTemplate || rendered.Prompt != "project: go" {
+		t.Fatalf("prompt from %s = %q, want the project's", rendered.Source, rendered.Prompt)
+	}
+
+	// A run keeps the templates it loaded; an edit shows up in the next one
+	writeFile(t, projectTemplate, "edited: {{.ProjectType}}\n")
+	if rendered = renderEngineerPrompt(t, project, description); rendered.Prompt != "project: go" {
+		t.Fatalf("prompt = %q after an edit, want the loaded template", rendered.Prompt)
+	}
+	if err := agent.LoadProjectPrompts(project); err != nil {
+		t.Fatalf("LoadProjectPrompts: %v", err)
+	}
+	if rendered = renderEngineerPrompt(t, project, description); rendered.Prompt != "edited: go" {
+		t.Fatalf("prompt = %q after reloading, want the edited template", rendered.Prompt)
+	}
+}
+
+func TestPromptTemplatesAreValidated(t *testing.T) {
+	t.Cleanup(func() { agent.LoadPromptTemplates("") })
+	for _, tc := range []struct {
+		name, file, text, want string
+	}{
+		{"syntax", "qa.tmpl", "{{if .Description}}unclosed", "unexpected EOF"},
+		{"unknown variable", "qa.tmpl", "{{.Diff}}", "can't evaluate field Diff"},
+		{"unknown variable in a branch", "tech_lead.tmpl", "{{if .Brief.Task}}{{.Brief.Owner}}{{end}}", "can't evaluate field Owner"},
+		{"unknown template", "reviewer.tmpl", "hello", `unknown prompt template "reviewer"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, tc.file), tc.text)
+			if _, err := agent.LoadPromptTemplates(dir); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("LoadPromptTemplates = %v, want an error containing %q", err, tc.want)
+			}
+
+			project := t.TempDir()
+			writeFile(t, filepath.Join(project, agent.ProjectPromptsDir, tc.file), tc.text)
+			if err := agent.LoadProjectPrompts(project); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("LoadProjectPrompts = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+
+	// A rejected directory leaves the templates in use as they were
+	if rendered := renderEngineerPrompt(t, t.TempDir(), "add Subtract"); rendered.Source != "embedded" {
+		t.Errorf("prompt from %s after rejected overrides, want embedded", rendered.Source)
+	}
+}
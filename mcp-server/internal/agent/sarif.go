@@ -0,0 +1,279 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReviewFinding is a Tech Lead finding in a tool-neutral form, used for the
+// JSON sidecar in WorkflowResult and for the SARIF export
+type ReviewFinding struct {
+	RuleID      string `json:"rule_id"`
+	Category    string `json:"category"` // security, duplication, pattern or lint
+	Severity    string `json:"severity"` // CRITICAL, HIGH, MEDIUM or LOW
+	Message     string `json:"message"`
+	File        string `json:"file"`
+	Line        int    `json:"line,omitempty"`
+	Column      int    `json:"column,omitempty"`
+	RelatedFile string `json:"related_file,omitempty"`
+	RelatedLine int    `json:"related_line,omitempty"`
+}
+
+// collectFindings flattens the review evidence into findings
+func (tl *SeniorTechLead) collectFindings(ev *reviewEvidence, duplications []DuplicationIssue) []ReviewFinding {
+	var findings []ReviewFinding
+
+	for _, issue := range ev.securityIssues {
+		findings = append(findings, ReviewFinding{
+			RuleID:   "security/" + ruleSlug(issue.Type),
+			Category: "security",
+			Severity: issue.Severity,
+			Message:  issue.Description,
+			File:     issue.File,
+			Line:     issue.Line,
+		})
+	}
+
+	for _, issue := range duplications {
+		severity := "LOW"
+		if issue.SimilarityScore >= 85 {
+			severity = "MEDIUM"
+		}
+		findings = append(findings, ReviewFinding{
+			RuleID:      "duplication/" + ruleSlug(issue.Type),
+			Category:    "duplication",
+			Severity:    severity,
+			Message:     fmt.Sprintf("%s (%d%% similar)", issue.Description, issue.SimilarityScore),
+			File:        issue.CurrentFile,
+			Line:        issue.CurrentLine,
+			RelatedFile: issue.ExistingFile,
+			RelatedLine: issue.ExistingLine,
+		})
+	}
+
+	if ev.patterns != nil {
+		for _, deviation := range ev.patterns.Deviations {
+			message := deviation.Description
+			if deviation.Expected != "" {
+				message += fmt.Sprintf(" (expected: %s)", deviation.Expected)
+			}
+			findings = append(findings, ReviewFinding{
+				RuleID:   "pattern/" + ruleSlug(deviation.Type),
+				Category: "pattern",
+				Severity: "LOW",
+				Message:  message,
+				File:     deviation.File,
+			})
+		}
+	}
+
+	for _, issue := range ev.lintIssues {
+		ruleID := "lint/" + ruleSlug(issue.Linter)
+		if issue.Rule != "" {
+			ruleID += "/" + issue.Rule
+		}
+		findings = append(findings, ReviewFinding{
+			RuleID:   ruleID,
+			Category: "lint",
+			Severity: issue.Severity,
+			Message:  issue.Message,
+			File:     issue.File,
+			Line:     issue.Line,
+			Column:   issue.Column,
+		})
+	}
+
+	return findings
+}
+
+// ruleSlug turns SQL_INJECTION or "go vet" into sql-injection or go-vet
+func ruleSlug(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return strings.NewReplacer("_", "-", " ", "-").Replace(value)
+}
+
+type sarifReport struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactURI `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name"`
+	ShortDescription     sarifText              `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConfiguration `json:"defaultConfiguration"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string                 `json:"ruleId"`
+	RuleIndex        int                    `json:"ruleIndex"`
+	Level            string                 `json:"level"`
+	Message          sarifText              `json:"message"`
+	Locations        []sarifLocation        `json:"locations"`
+	RelatedLocations []sarifLocation        `json:"relatedLocations,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               int                   `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactURI `json:"artifactLocation"`
+	Region           *sarifRegion     `json:"region,omitempty"`
+}
+
+type sarifArtifactURI struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLevel maps review severities onto SARIF result levels
+func sarifLevel(severity string) string {
+	switch severity {
+	case "CRITICAL", "HIGH":
+		return "error"
+	case "MEDIUM":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// securitySeverityScore is the CVSS-like score code-scanning UIs use to rank security rules
+func securitySeverityScore(severity string) string {
+	switch severity {
+	case "CRITICAL":
+		return "9.5"
+	case "HIGH":
+		return "8.0"
+	case "MEDIUM":
+		return "5.0"
+	default:
+		return "2.0"
+	}
+}
+
+func sarifFileLocation(file string, line, column int) sarifLocation {
+	location := sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactURI{URI: file, URIBaseID: "%SRCROOT%"},
+		},
+	}
+	if line > 0 {
+		location.PhysicalLocation.Region = &sarifRegion{StartLine: line, StartColumn: column}
+	}
+	return location
+}
+
+// BuildSARIF renders findings as a SARIF 2.1.0 log with paths relative to projectRoot
+func BuildSARIF(findings []ReviewFinding, projectRoot string) ([]byte, error) {
+	// One rule per rule ID, keeping the most severe level seen for it
+	ruleSeverity := make(map[string]string)
+	ruleCategory := make(map[string]string)
+	rank := map[string]int{"LOW": 0, "MEDIUM": 1, "HIGH": 2, "CRITICAL": 3}
+	for _, finding := range findings {
+		if current, ok := ruleSeverity[finding.RuleID]; !ok || rank[finding.Severity] > rank[current] {
+			ruleSeverity[finding.RuleID] = finding.Severity
+		}
+		ruleCategory[finding.RuleID] = finding.Category
+	}
+
+	ruleIDs := make([]string, 0, len(ruleSeverity))
+	for id := range ruleSeverity {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	ruleIndex := make(map[string]int, len(ruleIDs))
+	for i, id := range ruleIDs {
+		ruleIndex[id] = i
+		properties := map[string]interface{}{"tags": []string{ruleCategory[id]}}
+		if ruleCategory[id] == "security" {
+			properties["security-severity"] = securitySeverityScore(ruleSeverity[id])
+		}
+		rules = append(rules, sarifRule{
+			ID:                   id,
+			Name:                 id,
+			ShortDescription:     sarifText{Text: fmt.Sprintf("Tech Lead %s check %s", ruleCategory[id], id)},
+			DefaultConfiguration: sarifRuleConfiguration{Level: sarifLevel(ruleSeverity[id])},
+			Properties:           properties,
+		})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		result := sarifResult{
+			RuleID:     finding.RuleID,
+			RuleIndex:  ruleIndex[finding.RuleID],
+			Level:      sarifLevel(finding.Severity),
+			Message:    sarifText{Text: finding.Message},
+			Locations:  []sarifLocation{sarifFileLocation(finding.File, finding.Line, finding.Column)},
+			Properties: map[string]interface{}{"severity": finding.Severity},
+		}
+		if finding.RelatedFile != "" {
+			related := sarifFileLocation(finding.RelatedFile, finding.RelatedLine, 0)
+			related.ID = 1
+			result.RelatedLocations = []sarifLocation{related}
+			result.Message.Text += " See [existing implementation](1)."
+		}
+		results = append(results, result)
+	}
+
+	report := sarifReport{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:  "mcp-server-tech-lead",
+				Rules: rules,
+			}},
+			Results: results,
+		}},
+	}
+	if projectRoot != "" {
+		root := (&url.URL{Scheme: "file", Path: filepath.ToSlash(projectRoot)}).String()
+		if !strings.HasSuffix(root, "/") {
+			root += "/"
+		}
+		report.Runs[0].OriginalURIBaseIDs = map[string]sarifArtifactURI{"%SRCROOT%": {URI: root}}
+	}
+
+	return json.MarshalIndent(report, "", "  ")
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseChangedLines(t *testing.T) {
+	diff := `diff --git a/calc.go b/calc.go
+index 1111111..2222222 100644
+--- a/calc.go
++++ b/calc.go
+@@ -3,4 +3,6 @@ package calc
+ func Add(a, b int) int {
+-	return a - b
++	return a + b
+ }
++
++func Subtract(a, b int) int { return a - b }
+@@ -20 +22 @@ func Mul(a, b int) int {
+-	return 0
++	return a * b
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package calc
+-
+diff --git a/calc_test.go b/calc_test.go
+new file mode 100644
+--- /dev/null
++++ b/calc_test.go
+@@ -0,0 +1,2 @@
++package calc
++
+`
+
+	want := map[string]map[int]bool{
+		"calc.go":      {4: true, 6: true, 7: true, 22: true},
+		"calc_test.go": {1: true, 2: true},
+	}
+	if got := parseChangedLines(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChangedLines = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		file string
+		line int
+		want bool
+	}{
+		{"calc.go", 4, true},
+		{"calc.go", 5, false},
+		{"calc.go", 0, true}, // File-level finding in a changed file
+		{"other.go", 0, false},
+		{"old.go", 1, false},
+		{"stats.go", 40, true}, // Untracked files are new in full
+	}
+	withUntracked := (&ReviewContext{GitDiff: diff, UntrackedFiles: []string{"stats.go", "calc.go"}}).changedLines()
+	if !reflect.DeepEqual(withUntracked["calc.go"], want["calc.go"]) {
+		t.Errorf("untracked listing replaced the diff lines of calc.go: %v", withUntracked["calc.go"])
+	}
+	for _, tt := range tests {
+		if got := onChangedLine(withUntracked, tt.file, tt.line); got != tt.want {
+			t.Errorf("onChangedLine(%s, %d) = %v, want %v", tt.file, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSARIF(t *testing.T) {
+	findings := []ReviewFinding{
+		{RuleID: "security/sql-injection", Category: "security", Severity: "MEDIUM", Message: "query built with Sprintf", File: "db.go", Line: 12},
+		{RuleID: "security/sql-injection", Category: "security", Severity: "CRITICAL", Message: "query concatenated", File: "db.go", Line: 30, Column: 4},
+		{RuleID: "duplication/function-duplication", Category: "duplication", Severity: "LOW", Message: "Body of f duplicates g", File: "a.go", Line: 3, RelatedFile: "b.go", RelatedLine: 8},
+		{RuleID: "pattern/naming", Category: "pattern", Severity: "LOW", Message: "naming", File: "a.go"},
+	}
+
+	data, err := BuildSARIF(findings, "/work/my project")
+	if err != nil {
+		t.Fatalf("BuildSARIF: %v", err)
+	}
+	var report sarifReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decoding SARIF: %v", err)
+	}
+
+	if report.Version != "2.1.0" || len(report.Runs) != 1 {
+		t.Fatalf("report = version %q with %d runs, want one 2.1.0 run", report.Version, len(report.Runs))
+	}
+	run := report.Runs[0]
+	if got := run.OriginalURIBaseIDs["%SRCROOT%"].URI; got != "file:///work/my%20project/" {
+		t.Errorf("%%SRCROOT%% = %q, want the escaped project root with a trailing slash", got)
+	}
+
+	// Rules are sorted and keep the most severe level seen
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	wantRules := []string{"duplication/function-duplication", "pattern/naming", "security/sql-injection"}
+	if !reflect.DeepEqual(ruleIDs, wantRules) {
+		t.Fatalf("rules = %v, want %v", ruleIDs, wantRules)
+	}
+	security := run.Tool.Driver.Rules[2]
+	if security.DefaultConfiguration.Level != "error" || security.Properties["security-severity"] != "9.5" {
+		t.Errorf("security rule = %+v, want level error with security-severity 9.5", security)
+	}
+
+	if len(run.Results) != len(findings) {
+		t.Fatalf("%d results, want %d", len(run.Results), len(findings))
+	}
+	first := run.Results[0]
+	if first.RuleIndex != 2 || first.Level != "warning" || first.Locations[0].PhysicalLocation.Region.StartLine != 12 {
+		t.Errorf("first result = %+v, want rule index 2, level warning at line 12", first)
+	}
+	duplication := run.Results[2]
+	if len(duplication.RelatedLocations) != 1 || duplication.RelatedLocations[0].PhysicalLocation.ArtifactLocation.URI != "b.go" {
+		t.Errorf("duplication result = %+v, want a related location in b.go", duplication)
+	}
+	if pattern := run.Results[3]; pattern.Level != "note" || pattern.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("pattern result = %+v, want a file-level note", pattern)
+	}
+}
+
+func TestCollectFindings(t *testing.T) {
+	ev := &reviewEvidence{
+		securityIssues: []SecurityIssue{{Type: "SQL_INJECTION", Severity: "CRITICAL", File: "db.go", Line: 4}},
+		lintIssues:     []LintIssue{{Linter: "go vet", Rule: "", File: "a.go", Line: 2, Severity: "MEDIUM"}, {Linter: "ruff", Rule: "S608", File: "a.py", Severity: "HIGH"}},
+	}
+	duplications := []DuplicationIssue{{Type: "FUNCTION_DUPLICATION", CurrentFile: "a.go", ExistingFile: "b.go", SimilarityScore: 90}}
+
+	var ruleIDs, severities []string
+	for _, finding := range (&SeniorTechLead{}).collectFindings(ev, duplications) {
+		ruleIDs = append(ruleIDs, finding.RuleID)
+		severities = append(severities, finding.Severity)
+	}
+	wantRules := []string{"security/sql-injection", "duplication/function-duplication", "lint/go-vet", "lint/ruff/S608"}
+	if !reflect.DeepEqual(ruleIDs, wantRules) {
+		t.Errorf("rule IDs = %v, want %v", ruleIDs, wantRules)
+	}
+	wantSeverities := []string{"CRITICAL", "MEDIUM", "MEDIUM", "HIGH"}
+	if !reflect.DeepEqual(severities, wantSeverities) {
+		t.Errorf("severities = %v, want %v", severities, wantSeverities)
+	}
+}
@@ -3,6 +3,10 @@ package agent
 import (
 	"context"
 	"fmt"
+	"mcp-server/internal/config"
+	"mcp-server/internal/langpack"
+	"mcp-server/internal/prompt"
+	"mcp-server/internal/tools"
 	"strings"
 	"regexp"
 	"path/filepath"
@@ -12,13 +16,16 @@ type SeniorTechLead struct {
 	llmClient    LLMClient
 	tools        ToolSet
 	restrictions CommandRestrictions
+	config       config.WorkflowAgentConfig // Agent-specific config
+	baseline     map[string]int             // Lint findings present before the workflow
 }
 
-func NewSeniorTechLead(llmClient LLMClient, tools ToolSet, restrictions CommandRestrictions) *SeniorTechLead {
+func NewSeniorTechLead(llmClient LLMClient, tools ToolSet, restrictions CommandRestrictions, cfg config.WorkflowAgentConfig) *SeniorTechLead {
 	return &SeniorTechLead{
 		llmClient:    llmClient,
 		tools:        tools,
 		restrictions: restrictions,
+		config:       cfg,
 	}
 }
 
@@ -38,7 +45,13 @@ func (tl *SeniorTechLead) ImplementFeature(ctx context.Context, req ImplementFea
 	}
 
 	// Step 3: Build system prompt with context
-	prompt := tl.buildSystemPrompt(req, reviewContext)
+	prompt, err := tl.buildSystemPrompt(req, reviewContext)
+	if err != nil {
+		return &ImplementFeatureResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to build prompt: %v", err),
+		}, nil
+	}
 
 	// Step 4: Generate quality review from LLM
 	response, err := tl.llmClient.Generate(ctx, prompt)
@@ -53,8 +66,25 @@ func (tl *SeniorTechLead) ImplementFeature(ctx context.Context, req ImplementFea
 	return tl.executeQualityReview(ctx, req, response)
 }
 
+// RenderPrompt builds the prompt ImplementFeature would send for req without sending it
+func (tl *SeniorTechLead) RenderPrompt(ctx context.Context, req ImplementFeatureRequest) (*RenderedPrompt, error) {
+	if req.WorkingDirectory != "" {
+		tl.tools.SetWorkingDirectory(req.WorkingDirectory)
+	}
+	reviewContext, err := tl.analyzeCompleteWork()
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze work for review: %w", err)
+	}
+	text, err := tl.buildSystemPrompt(req, reviewContext)
+	if err != nil {
+		return nil, err
+	}
+	return newRenderedPrompt(PromptTechLead, tl.tools.GetWorkingDirectory(), text)
+}
+
 type ReviewContext struct {
 	GitDiff         string
+	UntrackedFiles  []string
 	AllChangedFiles []string
 	FileContents    map[string]string
 	TestFiles       []string
@@ -72,6 +102,9 @@ const (
 	RejectionSecurity     RejectionReason = "security_concerns"
 	RejectionDuplication  RejectionReason = "unnecessary_duplication"
 	RejectionPatterns     RejectionReason = "pattern_deviation"
+	RejectionLint         RejectionReason = "lint_findings"
+	RejectionBuild        RejectionReason = "build_or_tests_failing"
+	RejectionScore        RejectionReason = "quality_score_below_threshold"
 )
 
 type SecurityIssue struct {
@@ -87,6 +120,8 @@ type DuplicationIssue struct {
 	Description     string
 	CurrentFile     string
 	ExistingFile    string
+	CurrentLine     int
+	ExistingLine    int
 	SimilarityScore int
 }
 
@@ -117,6 +152,11 @@ func (tl *SeniorTechLead) analyzeCompleteWork() (*ReviewContext, error) {
 	}
 	ctx.GitDiff = gitDiff
 
+	// New files stay out of git diff until they are added
+	if untracked, err := tl.tools.GetGitUntrackedFiles(); err == nil {
+		ctx.UntrackedFiles = untracked
+	}
+
 	// Parse all changed files
 	ctx.AllChangedFiles = tl.parseAllChangedFiles(gitDiff)
 
@@ -189,42 +229,19 @@ func (tl *SeniorTechLead) parseAllChangedFiles(gitDiff string) []string {
 
 func (tl *SeniorTechLead) identifyTestFiles(files []string) []string {
 	var testFiles []string
-	
-	testPatterns := []string{
-		"_test.go", ".test.js", ".test.ts", ".spec.js", ".spec.ts",
-		"test_", "_test.py", "Test.java", "Tests.cs",
-	}
-	
 	for _, file := range files {
-		for _, pattern := range testPatterns {
-			if strings.Contains(strings.ToLower(file), strings.ToLower(pattern)) {
-				testFiles = append(testFiles, file)
-				break
-			}
+		if langpack.IsTestFile(file) {
+			testFiles = append(testFiles, file)
 		}
 	}
-	
 	return testFiles
 }
 
 func (tl *SeniorTechLead) detectQualityTools() []string {
 	var tools []string
-	
-	// Check for Go tools
-	if _, err := tl.tools.ReadFile("go.mod"); err == nil {
-		tools = append(tools, "go fmt", "go vet", "go mod tidy")
+	for _, linter := range tl.activeLinters() {
+		tools = append(tools, linter.Command)
 	}
-	
-	// Check for Node.js tools
-	if _, err := tl.tools.ReadFile("package.json"); err == nil {
-		tools = append(tools, "npm run lint", "npm audit")
-	}
-	
-	// Check for Python tools
-	if _, err := tl.tools.ReadFile("requirements.txt"); err == nil {
-		tools = append(tools, "python -m flake8", "python -m black --check")
-	}
-	
 	return tools
 }
 
@@ -418,6 +435,14 @@ func (tl *SeniorTechLead) evaluateSecurityConcerns(ctx *ReviewContext) []Securit
 	var issues []SecurityIssue
 
 	for filename, content := range ctx.FileContents {
+		// Go files get precise AST checks; fall back to regexes if parsing fails
+		if strings.HasSuffix(filename, ".go") {
+			if src, err := parseGoSource(filename, content); err == nil {
+				issues = append(issues, tl.checkGoSecurity(src)...)
+				continue
+			}
+		}
+
 		issues = append(issues, tl.checkSQLInjection(filename, content)...)
 		issues = append(issues, tl.checkPathTraversal(filename, content)...)
 		issues = append(issues, tl.checkInputValidation(filename, content)...)
@@ -429,26 +454,44 @@ func (tl *SeniorTechLead) evaluateSecurityConcerns(ctx *ReviewContext) []Securit
 	return issues
 }
 
+// Patterns for the regex-based checks used on non-Go files
+var (
+	// String concatenation in SQL queries
+	sqlConcatPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`fmt\.Sprintf.*SELECT`),
+		regexp.MustCompile(`fmt\.Sprintf.*INSERT`),
+		regexp.MustCompile(`fmt\.Sprintf.*UPDATE`),
+		regexp.MustCompile(`fmt\.Sprintf.*DELETE`),
+		regexp.MustCompile(`".*SELECT.*".*\+`),
+		regexp.MustCompile(`".*INSERT.*".*\+`),
+	}
+	// File operations with user input
+	pathTraversalPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`filepath\.Join.*req\.`),
+		regexp.MustCompile(`os\.Open.*req\.`),
+		regexp.MustCompile(`ioutil\.ReadFile.*req\.`),
+		regexp.MustCompile(`".*\.\./`),
+	}
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`"[A-Za-z0-9]{32,}"`), // Long alphanumeric strings
+		regexp.MustCompile(`password.*=.*"`),
+		regexp.MustCompile(`secret.*=.*"`),
+		regexp.MustCompile(`key.*=.*"[A-Za-z0-9+/]{20,}"`),
+		regexp.MustCompile(`token.*=.*"[A-Za-z0-9+/]{20,}"`),
+	}
+)
+
 // checkSQLInjection detects potential SQL injection vulnerabilities
 func (tl *SeniorTechLead) checkSQLInjection(filename, content string) []SecurityIssue {
 	var issues []SecurityIssue
 
-	// Look for string concatenation in SQL queries
-	sqlConcatPatterns := []string{
-		`fmt\.Sprintf.*SELECT`,
-		`fmt\.Sprintf.*INSERT`,
-		`fmt\.Sprintf.*UPDATE`,
-		`fmt\.Sprintf.*DELETE`,
-		`".*SELECT.*".*\+`,
-		`".*INSERT.*".*\+`,
-	}
-
 	for _, pattern := range sqlConcatPatterns {
-		if matched, _ := regexp.MatchString(pattern, content); matched {
+		if loc := pattern.FindStringIndex(content); loc != nil {
 			issues = append(issues, SecurityIssue{
 				Type:        "SQL_INJECTION",
 				Description: "Potential SQL injection vulnerability: string concatenation in SQL query",
 				File:        filename,
+				Line:        lineOfOffset(content, loc[0]),
 				Severity:    "CRITICAL",
 			})
 		}
@@ -461,20 +504,13 @@ func (tl *SeniorTechLead) checkSQLInjection(filename, content string) []Security
 func (tl *SeniorTechLead) checkPathTraversal(filename, content string) []SecurityIssue {
 	var issues []SecurityIssue
 
-	// Look for file operations with user input
-	pathTraversalPatterns := []string{
-		`filepath\.Join.*req\.`,
-		`os\.Open.*req\.`,
-		`ioutil\.ReadFile.*req\.`,
-		`".*\.\./`,
-	}
-
 	for _, pattern := range pathTraversalPatterns {
-		if matched, _ := regexp.MatchString(pattern, content); matched {
+		if loc := pattern.FindStringIndex(content); loc != nil {
 			issues = append(issues, SecurityIssue{
 				Type:        "PATH_TRAVERSAL",
 				Description: "Potential path traversal vulnerability: file operation with user input",
 				File:        filename,
+				Line:        lineOfOffset(content, loc[0]),
 				Severity:    "HIGH",
 			})
 		}
@@ -494,6 +530,7 @@ func (tl *SeniorTechLead) checkInputValidation(filename, content string) []Secur
 				Type:        "MISSING_VALIDATION",
 				Description: "Request binding without validation detected",
 				File:        filename,
+				Line:        lineOfOffset(content, strings.Index(content, "Bind")),
 				Severity:    "MEDIUM",
 			})
 		}
@@ -506,20 +543,13 @@ func (tl *SeniorTechLead) checkInputValidation(filename, content string) []Secur
 func (tl *SeniorTechLead) checkHardcodedSecrets(filename, content string) []SecurityIssue {
 	var issues []SecurityIssue
 
-	secretPatterns := []string{
-		`"[A-Za-z0-9]{32,}"`, // Long alphanumeric strings
-		`password.*=.*"`,
-		`secret.*=.*"`,
-		`key.*=.*"[A-Za-z0-9+/]{20,}"`,
-		`token.*=.*"[A-Za-z0-9+/]{20,}"`,
-	}
-
 	for _, pattern := range secretPatterns {
-		if matched, _ := regexp.MatchString(pattern, content); matched {
+		if loc := pattern.FindStringIndex(content); loc != nil {
 			issues = append(issues, SecurityIssue{
 				Type:        "HARDCODED_SECRET",
 				Description: "Potential hardcoded secret or credential detected",
 				File:        filename,
+				Line:        lineOfOffset(content, loc[0]),
 				Severity:    "CRITICAL",
 			})
 		}
@@ -535,10 +565,15 @@ func (tl *SeniorTechLead) checkResourceLeaks(filename, content string) []Securit
 	// Check for opened files without defer close
 	if strings.Contains(content, "os.Open") || strings.Contains(content, "os.Create") {
 		if !strings.Contains(content, "defer") || !strings.Contains(content, "Close()") {
+			offset := strings.Index(content, "os.Open")
+			if offset < 0 {
+				offset = strings.Index(content, "os.Create")
+			}
 			issues = append(issues, SecurityIssue{
 				Type:        "RESOURCE_LEAK",
 				Description: "File opened without proper cleanup (missing defer close)",
 				File:        filename,
+				Line:        lineOfOffset(content, offset),
 				Severity:    "MEDIUM",
 			})
 		}
@@ -558,6 +593,7 @@ func (tl *SeniorTechLead) checkUnsafeDeserialization(filename, content string) [
 				Type:        "UNSAFE_DESERIALIZATION",
 				Description: "Deserialization without validation detected",
 				File:        filename,
+				Line:        lineOfOffset(content, strings.Index(content, ".Unmarshal")),
 				Severity:    "MEDIUM",
 			})
 		}
@@ -566,13 +602,18 @@ func (tl *SeniorTechLead) checkUnsafeDeserialization(filename, content string) [
 	return issues
 }
 
-// detectDuplication detects unnecessary code duplication
+// detectDuplication detects unnecessary code duplication. Go files are parsed
+// once up front rather than once for every pair they are compared in.
 func (tl *SeniorTechLead) detectDuplication(ctx *ReviewContext) []DuplicationIssue {
 	var issues []DuplicationIssue
 
+	currentSources := parseGoSources(ctx.FileContents)
+	existingSources := parseGoSources(ctx.RelatedFiles)
 	for currentFile, currentContent := range ctx.FileContents {
 		for existingFile, existingContent := range ctx.RelatedFiles {
-			if duplications := tl.findCodeDuplication(currentFile, currentContent, existingFile, existingContent); len(duplications) > 0 {
+			current := codeFile{name: currentFile, content: currentContent, src: currentSources[currentFile]}
+			existing := codeFile{name: existingFile, content: existingContent, src: existingSources[existingFile]}
+			if duplications := tl.findCodeDuplication(current, existing); len(duplications) > 0 {
 				issues = append(issues, duplications...)
 			}
 		}
@@ -581,13 +622,43 @@ func (tl *SeniorTechLead) detectDuplication(ctx *ReviewContext) []DuplicationIss
 	return issues
 }
 
+// codeFile is a file compared for duplication, with its parsed form when it is Go that parses
+type codeFile struct {
+	name    string
+	content string
+	src     *goSourceFile
+}
+
+// parseGoSources parses the Go files among contents, leaving out those that do not parse
+func parseGoSources(contents map[string]string) map[string]*goSourceFile {
+	sources := make(map[string]*goSourceFile)
+	for filename, content := range contents {
+		if !strings.HasSuffix(filename, ".go") {
+			continue
+		}
+		if src, err := parseGoSource(filename, content); err == nil {
+			sources[filename] = src
+		}
+	}
+	return sources
+}
+
 // findCodeDuplication finds specific duplications between two files
-func (tl *SeniorTechLead) findCodeDuplication(currentFile, currentContent, existingFile, existingContent string) []DuplicationIssue {
+func (tl *SeniorTechLead) findCodeDuplication(current, existing codeFile) []DuplicationIssue {
 	var issues []DuplicationIssue
 
+	if current.name == existing.name {
+		return issues
+	}
+
+	// Go files are compared by normalized function bodies rather than names
+	if current.src != nil && existing.src != nil {
+		return tl.findGoCodeDuplication(current.src, existing.src)
+	}
+
 	// Look for similar function signatures
-	currentFunctions := tl.extractFunctions(currentContent)
-	existingFunctions := tl.extractFunctions(existingContent)
+	currentFunctions := tl.extractFunctions(current.content)
+	existingFunctions := tl.extractFunctions(existing.content)
 
 	for _, currentFunc := range currentFunctions {
 		for _, existingFunc := range existingFunctions {
@@ -596,8 +667,8 @@ func (tl *SeniorTechLead) findCodeDuplication(currentFile, currentContent, exist
 				issues = append(issues, DuplicationIssue{
 					Type:            "FUNCTION_DUPLICATION",
 					Description:     fmt.Sprintf("Similar function found: %s", currentFunc.Name),
-					CurrentFile:     currentFile,
-					ExistingFile:    existingFile,
+					CurrentFile:     current.name,
+					ExistingFile:    existing.name,
 					SimilarityScore: similarity,
 				})
 			}
@@ -654,6 +725,15 @@ func (tl *SeniorTechLead) createRejectionFeedback(reason RejectionReason, issues
 	case RejectionPatterns:
 		feedback.WriteString("- Follow existing code patterns and conventions\n")
 		feedback.WriteString("- Update implementation to match project standards\n")
+	case RejectionLint:
+		feedback.WriteString("- Fix the new linter findings listed above\n")
+		feedback.WriteString("- Do not suppress findings without a documented reason\n")
+	case RejectionBuild:
+		feedback.WriteString("- Make the build and the test suite pass\n")
+		feedback.WriteString("- Fix the failures shown above rather than skipping tests\n")
+	case RejectionScore:
+		feedback.WriteString("- Address the low-scoring signals listed above\n")
+		feedback.WriteString("- Add tests and follow existing patterns where missing\n")
 	}
 
 	feedback.WriteString("\nROUTE_TO: engineering_manager\n")
@@ -663,6 +743,14 @@ func (tl *SeniorTechLead) createRejectionFeedback(reason RejectionReason, issues
 
 // Helper methods for analysis
 
+var (
+	funcPattern      = regexp.MustCompile(`func\s+(\w+)\s*\([^)]*\)[^{]*\{`)
+	requestPattern   = regexp.MustCompile(`type\s+\w+Request\s+struct`)
+	responsePattern  = regexp.MustCompile(`type\s+\w+Response\s+struct`)
+	dtoPattern       = regexp.MustCompile(`type\s+\w+DTO\s+struct`)
+	interfacePattern = regexp.MustCompile(`type\s+\w+(?:Service|Repository|Client|Handler|Manager|Interface)\s+interface`)
+)
+
 type FunctionInfo struct {
 	Name      string
 	Signature string
@@ -672,8 +760,7 @@ type FunctionInfo struct {
 func (tl *SeniorTechLead) extractFunctions(content string) []FunctionInfo {
 	// Simple function extraction for Go
 	var functions []FunctionInfo
-	funcRegex := regexp.MustCompile(`func\s+(\w+)\s*\([^)]*\)[^{]*\{`)
-	matches := funcRegex.FindAllStringSubmatch(content, -1)
+	matches := funcPattern.FindAllStringSubmatch(content, -1)
 	
 	for _, match := range matches {
 		if len(match) > 1 {
@@ -709,18 +796,61 @@ func (tl *SeniorTechLead) checkPatternConsistency(filename, content string, ctx
 		deviations = append(deviations, tl.validateProjectPatterns(filename, content, projectPatterns)...)
 	}
 	
-	// Dynamically validate against all pattern files found in patterns/ directory
+	// Dynamically validate against all pattern files found in patterns/ directory.
+	// Documents with discovered rules are enforced on the AST, the rest heuristically.
+	rules := make(map[string]string)
+	ruleSources := make(map[string]string)
 	for patternFile, patternContent := range ctx.PatternFiles {
 		if strings.HasPrefix(patternFile, "patterns/") && strings.HasSuffix(patternFile, ".md") {
+			if fileRules := tools.ParsePatternRules(patternContent); len(fileRules) > 0 {
+				for rule, value := range fileRules {
+					rules[rule] = value
+					ruleSources[rule] = patternFile
+				}
+				continue
+			}
 			// Extract pattern type from filename (e.g., "patterns/handler.md" -> "handler")
 			patternType := strings.TrimSuffix(filepath.Base(patternFile), ".md")
 			deviations = append(deviations, tl.validateSpecificPattern(filename, content, patternType, patternContent)...)
 		}
 	}
+	if strings.HasSuffix(filename, ".go") {
+		deviations = append(deviations, tl.checkGoConventions(filename, content, rules, ruleSources, ctx)...)
+	}
 	
 	return deviations
 }
 
+// checkGoConventions reports departures from the discovered Go conventions on the lines
+// the change touched; files missing from the diff are new and checked in full
+func (tl *SeniorTechLead) checkGoConventions(filename, content string, rules, ruleSources map[string]string, ctx *ReviewContext) []PatternDeviation {
+	violations, err := tools.CheckGoConventions(filename, content, rules)
+	if err != nil {
+		return nil
+	}
+
+	changed := ctx.changedLines()
+	_, inDiff := changed[filename]
+	var deviations []PatternDeviation
+	for _, violation := range violations {
+		if inDiff && !onChangedLine(changed, filename, violation.Line) {
+			continue
+		}
+		expected := violation.Expected
+		if source, ok := ruleSources[violation.Rule]; ok {
+			expected = fmt.Sprintf("%s (%s)", expected, source)
+		}
+		deviations = append(deviations, PatternDeviation{
+			Type:        strings.ToUpper(violation.Rule) + "_DEVIATION",
+			Description: fmt.Sprintf("%s at line %d doesn't follow the project's %s convention", violation.Name, violation.Line, strings.ReplaceAll(violation.Rule, "_", " ")),
+			File:        filename,
+			Expected:    expected,
+			Actual:      violation.Actual,
+		})
+	}
+	return deviations
+}
+
 func (tl *SeniorTechLead) validateProjectPatterns(filename, content, projectPatterns string) []PatternDeviation {
 	var deviations []PatternDeviation
 	
@@ -798,10 +928,6 @@ func (tl *SeniorTechLead) validateModelPatterns(filename, content, patterns stri
 
 func (tl *SeniorTechLead) followsNamingConvention(content string) bool {
 	// Check for proper naming patterns like XxxRequest, XxxResponse, XxxDTO
-	requestPattern := regexp.MustCompile(`type\s+\w+Request\s+struct`)
-	responsePattern := regexp.MustCompile(`type\s+\w+Response\s+struct`) 
-	dtoPattern := regexp.MustCompile(`type\s+\w+DTO\s+struct`)
-	
 	hasProperNaming := requestPattern.MatchString(content) || 
 		responsePattern.MatchString(content) || 
 		dtoPattern.MatchString(content)
@@ -900,7 +1026,6 @@ func (tl *SeniorTechLead) validateInterfacePatterns(filename, content, patterns
 // followsInterfaceNaming checks interface naming conventions
 func (tl *SeniorTechLead) followsInterfaceNaming(content string) bool {
 	// Check for common interface naming patterns
-	interfacePattern := regexp.MustCompile(`type\s+\w+(?:Service|Repository|Client|Handler|Manager|Interface)\s+interface`)
 	return interfacePattern.MatchString(content)
 }
 
@@ -1028,176 +1153,45 @@ func (tl *SeniorTechLead) formatPatternSummary(ctx *ReviewContext) string {
 	return summary.String()
 }
 
-func (tl *SeniorTechLead) buildSystemPrompt(req ImplementFeatureRequest, ctx *ReviewContext) string {
-	var prompt strings.Builder
-
+func (tl *SeniorTechLead) buildSystemPrompt(req ImplementFeatureRequest, ctx *ReviewContext) (string, error) {
 	// Parse EM brief from description if available
 	ctx.EMBrief = parseEMBrief(req.Description)
 
-	prompt.WriteString(fmt.Sprintf(`You are a Senior Tech Lead responsible for comprehensive code quality review and final approval.
-
-**Current Task:** Review and approve feature: %s
-**Project Type:** %s
-
-**Review Methodology:**
-1. **Requirements Validation**: Verify implementation meets EM brief requirements
-2. **Security Analysis**: Static security vulnerability scanning
-3. **Duplication Detection**: Check for unnecessary code duplication
-4. **Pattern Consistency**: Validate against established project patterns
-5. **Auto-Fix**: Apply formatting and linting fixes
-6. **Final Decision**: Approve or create structured rejection feedback
-
-**Engineering Manager's Brief:**`, req.Description, req.ProjectType))
-
-	if ctx.EMBrief != nil && ctx.EMBrief.Task != "" {
-		prompt.WriteString(fmt.Sprintf(`
-Task: %s
-Context: %s
-Implementation Approach: %s
-Files to Examine: %s
-Potential Issues: %s
-Success Criteria: %s
-`, ctx.EMBrief.Task, ctx.EMBrief.Context, ctx.EMBrief.ImplementationApproach,
-			strings.Join(ctx.EMBrief.FilesToExamine, ", "), 
-			strings.Join(ctx.EMBrief.PotentialIssues, ", "), 
-			ctx.EMBrief.SuccessCriteria))
-	} else {
-		prompt.WriteString("\nNo structured EM brief found in description.")
-	}
-
-	prompt.WriteString(fmt.Sprintf(`
-
-**Pattern Documentation Available:**
-%s
-
-**Complete Implementation Review:**
-The following files were changed during implementation:
-`, tl.formatPatternSummary(ctx)))
-
-	// Show all changed files with content
-	for filename, content := range ctx.FileContents {
-		if len(content) > 1000 {
-			content = content[:1000] + "... (truncated)"
-		}
-		prompt.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", filename, content))
-	}
-
+	// The brief is never trimmed; the diff outranks pattern docs and whole files
+	asm := promptAssembler(tl.llmClient)
+	asm.Add(prompt.Section{
+		Name:     "pattern documentation",
+		Content:  fmt.Sprintf("\n\n**Pattern Documentation Available:**\n%s\n", tl.formatPatternSummary(ctx)),
+		Priority: prompt.Medium,
+	})
+	asm.Add(prompt.Section{
+		Name:     "review heading",
+		Content:  "\n**Complete Implementation Review:**\nThe following files were changed during implementation:\n",
+		Priority: prompt.Required,
+	})
+	addFileSections(asm, ctx.FileContents, prompt.Medium)
 	if len(ctx.TestFiles) > 0 {
-		prompt.WriteString(fmt.Sprintf("\n**Test Files Created:**\n%s\n", strings.Join(ctx.TestFiles, ", ")))
+		asm.Add(prompt.Section{
+			Name:     "test files",
+			Content:  fmt.Sprintf("\n**Test Files Created:**\n%s\n", strings.Join(ctx.TestFiles, ", ")),
+			Priority: prompt.Low,
+		})
 	}
-
-	if ctx.GitDiff != "" {
-		prompt.WriteString(fmt.Sprintf("\n**Git Diff Summary:**\n%s\n", ctx.GitDiff))
+	addDiffSection(asm, "Git Diff Summary", ctx.GitDiff)
+	if len(ctx.QualityTools) > 0 {
+		asm.Add(prompt.Section{
+			Name:     "quality tools",
+			Content:  fmt.Sprintf("\n**Available Quality Tools:**\n%s\n", strings.Join(ctx.QualityTools, ", ")),
+			Priority: prompt.Low,
+		})
 	}
 
-	if len(ctx.QualityTools) > 0 {
-		prompt.WriteString(fmt.Sprintf("\n**Available Quality Tools:**\n%s\n", strings.Join(ctx.QualityTools, ", ")))
-	}
-
-	prompt.WriteString(`
-
-**Your Enhanced Review Process:**
-1. **Requirements Analysis**: Validate against EM brief success criteria
-2. **Security Scanning**: Check for SQL injection, path traversal, hardcoded secrets, etc.
-3. **Duplication Analysis**: Scan related files for unnecessary code duplication
-4. **Pattern Validation**: Compare against established project patterns
-5. **Auto-Fix Application**: Run formatting and linting tools
-6. **Final Assessment**: Approve or create structured rejection feedback
-
-**Review Criteria (ZERO TOLERANCE):**
-- **Security Issues**: SQL injection, path traversal, hardcoded secrets, unsafe deserialization
-- **Requirements Gaps**: Missing functionality specified in EM brief success criteria
-- **Unnecessary Duplication**: Code that duplicates existing functionality
-- **Pattern Deviations**: Code that doesn't follow established project patterns
-
-**Available Actions:**
-- READ_FILE: Read additional files for pattern analysis
-- WRITE_FILE: Apply auto-fixes for formatting issues
-- EXECUTE_COMMAND: Run linting, formatting, and security tools
-- LIST_FILES: Explore related files for duplication analysis
-- FIND_FILES: Search for similar functionality
-- SEQUENTIAL_THINKING: Use for comprehensive analysis requiring systematic review
-
-**When to Use Sequential Thinking:**
-Use sequential thinking for complex reviews that require:
-- Systematic analysis of multiple security vectors
-- Comprehensive pattern validation across multiple files
-- Detailed requirements validation against complex EM briefs
-- Multi-step duplication analysis across related modules
-- Complex architectural review requiring step-by-step reasoning
-
-**Sequential Thinking for Code Review:**
-SEQUENTIAL_THINKING:
-THOUGHT: I need to perform a comprehensive review of this user management implementation. Let me start by validating the EM requirements systematically, then move through security, duplication, and patterns.
-THOUGHT_NUMBER: 1
-TOTAL_THOUGHTS: 6
-NEXT_THOUGHT_NEEDED: true
-
-**Recommended Review Process with Sequential Thinking:**
-1. Start with sequential thinking to plan your comprehensive review approach
-2. Use subsequent thoughts to work through each review criteria systematically
-3. Document findings and reasoning in each thought step
-4. Conclude with clear approval or structured rejection feedback
-
-**Response Format for APPROVAL:**
-REQUIREMENTS_VALIDATION: [PASSED/FAILED]
-- EM brief requirement check results
-
-SECURITY_ANALYSIS: [PASSED/FAILED]  
-- Security vulnerability scan results
-
-DUPLICATION_CHECK: [PASSED/FAILED]
-- Code duplication analysis results
-
-PATTERN_CONSISTENCY: [PASSED/FAILED]
-- Project pattern compliance results
-
-AUTO_FIXES_APPLIED:
-ACTION: EXECUTE_COMMAND
-COMMAND: go fmt
-ACTION: EXECUTE_COMMAND  
-COMMAND: go mod tidy
-
-FINAL_DECISION: APPROVED
-REASONING: All criteria passed, code ready for production
-
-**Response Format for REJECTION:**
-REQUIREMENTS_VALIDATION: FAILED
-- [Specific missing requirements]
-
-SECURITY_ANALYSIS: FAILED
-- [Specific security issues found]
-
-DUPLICATION_CHECK: FAILED
-- [Specific duplications detected]
-
-PATTERN_CONSISTENCY: FAILED
-- [Specific pattern deviations]
-
-REJECTION_REASON: [requirements_not_met/security_concerns/unnecessary_duplication/pattern_deviation]
-SPECIFIC_ISSUES:
-- [Issue 1]
-- [Issue 2]
-
-EXISTING_PATTERNS:
-- [Example from codebase]
-
-REQUIRED_ACTIONS:
-- [Action 1]  
-- [Action 2]
-
-ROUTE_TO: engineering_manager
-
-**Critical Standards:**
-- ZERO tolerance for security vulnerabilities (all must be fixed)
-- Requirements from EM brief MUST be fully implemented
-- NO unnecessary code duplication (reuse existing functionality)
-- STRICT adherence to established patterns
-- Auto-fix formatting issues, don't reject for them
-
-Begin your comprehensive technical review now.`)
-
-	return prompt.String()
+	data := &TechLeadPromptData{
+		Description: req.Description,
+		ProjectType: req.ProjectType,
+		Brief:       *ctx.EMBrief,
+	}
+	return renderFittedPrompt(string(AgentRoleTechLead), PromptTechLead, tl.tools.GetWorkingDirectory(), asm, data, &data.Sections)
 }
 
 func (tl *SeniorTechLead) executeQualityReview(ctx context.Context, req ImplementFeatureRequest, llmResponse string) (*ImplementFeatureResponse, error) {
@@ -1209,6 +1203,11 @@ func (tl *SeniorTechLead) executeQualityReview(ctx context.Context, req Implemen
 		NextSteps:        "Quality review complete - ready for deployment",
 	}
 
+	// A review without an explicit verdict counts as output the model got wrong,
+	// whichever way the policy decides
+	verdict, _ := parseLLMVerdict(llmResponse)
+	reportOutput(tl.llmClient, verdict != 0.5)
+
 	// Get review context for analysis
 	reviewCtx, err := tl.analyzeCompleteWork()
 	if err != nil {
@@ -1220,84 +1219,44 @@ func (tl *SeniorTechLead) executeQualityReview(ctx context.Context, req Implemen
 	// Parse EM brief from request
 	reviewCtx.EMBrief = parseEMBrief(req.Description)
 
-	// Step 1: Requirements Validation
-	requirementGaps := tl.validateRequirements(reviewCtx.EMBrief, reviewCtx)
-	if len(requirementGaps) > 0 {
-		rejectionFeedback := tl.createRejectionFeedback(
-			RejectionRequirements,
-			requirementGaps,
-			[]string{})
-		result.Success = false
-		result.Error = rejectionFeedback
-		result.NextSteps = "Route to Engineering Manager for requirements clarification"
-		return result, nil
-	}
-
-	// Step 2: Security Analysis
-	securityIssues := tl.evaluateSecurityConcerns(reviewCtx)
-	criticalSecurityIssues := tl.filterCriticalSecurityIssues(securityIssues)
-	if len(criticalSecurityIssues) > 0 {
-		securityMessages := make([]string, len(criticalSecurityIssues))
-		for i, issue := range criticalSecurityIssues {
-			securityMessages[i] = fmt.Sprintf("%s in %s: %s", issue.Type, issue.File, issue.Description)
-		}
-		rejectionFeedback := tl.createRejectionFeedback(
-			RejectionSecurity,
-			securityMessages,
-			[]string{"Use parameterized queries", "Validate all user input", "Use environment variables for secrets"})
+	// Step 1: Collect evidence for the review policy
+	duplications := tl.detectDuplication(reviewCtx)
+	evidence := &reviewEvidence{
+		projectType:     req.ProjectType,
+		requirementGaps: tl.validateRequirements(reviewCtx.EMBrief, reviewCtx),
+		securityIssues:  tl.evaluateSecurityConcerns(reviewCtx),
+		duplications:    tl.filterSignificantDuplications(duplications),
+		patterns:        tl.analyzePatternConsistency(reviewCtx),
+		changedLines:    reviewCtx.changedLines(),
+		targets:         resolvePacks(tl.tools, req.ProjectType, reviewCtx.AllChangedFiles),
+		testFiles:       reviewCtx.TestFiles,
+		llmResponse:     llmResponse,
+	}
+
+	// Step 2: External linters, ignoring findings that predate the workflow
+	lintRun := tl.runLinters(ctx)
+	result.BuildOutput += fmt.Sprintf("\n=== Linters ===\n%s", lintRun.log)
+	evidence.lintIssues = tl.newLintIssues(lintRun.issues)
+
+	// Step 3: Apply the review policy; hard gates first, then weighted signals
+	decision := tl.evaluateReviewPolicy(evidence)
+	result.Review = decision
+	result.Findings = tl.collectFindings(evidence, duplications)
+	result.CommandsExecuted = append(result.CommandsExecuted, evidence.commandsRun...)
+	result.BuildOutput += evidence.commandOutput
+	if !decision.Approved {
 		result.Success = false
-		result.Error = rejectionFeedback
-		result.NextSteps = "Route to Engineering Manager - Security vulnerabilities must be fixed"
+		result.Error = tl.rejectionFromDecision(decision, evidence)
+		result.NextSteps = fmt.Sprintf("Route to Engineering Manager - %s", decision.Summary)
 		return result, nil
 	}
 
-	// Step 3: Duplication Detection
-	duplicationIssues := tl.detectDuplication(reviewCtx)
-	significantDuplications := tl.filterSignificantDuplications(duplicationIssues)
-	if len(significantDuplications) > 0 {
-		duplicationMessages := make([]string, len(significantDuplications))
-		examples := []string{}
-		for i, issue := range significantDuplications {
-			duplicationMessages[i] = fmt.Sprintf("%s: %s duplicates %s", issue.Type, issue.CurrentFile, issue.ExistingFile)
-			examples = append(examples, fmt.Sprintf("Existing implementation in %s", issue.ExistingFile))
-		}
-		rejectionFeedback := tl.createRejectionFeedback(
-			RejectionDuplication,
-			duplicationMessages,
-			examples)
-		result.Success = false
-		result.Error = rejectionFeedback
-		result.NextSteps = "Route to Engineering Manager - Remove code duplication"
-		return result, nil
-	}
-
-	// Step 4: Pattern Consistency Analysis
-	patternAnalysis := tl.analyzePatternConsistency(reviewCtx)
-	if !patternAnalysis.Consistent {
-		patternMessages := make([]string, len(patternAnalysis.Deviations))
-		examples := []string{}
-		for i, deviation := range patternAnalysis.Deviations {
-			patternMessages[i] = fmt.Sprintf("%s in %s: %s", deviation.Type, deviation.File, deviation.Description)
-			if deviation.Expected != "" {
-				examples = append(examples, fmt.Sprintf("Expected: %s", deviation.Expected))
-			}
-		}
-		rejectionFeedback := tl.createRejectionFeedback(
-			RejectionPatterns,
-			patternMessages,
-			examples)
-		result.Success = false
-		result.Error = rejectionFeedback
-		result.NextSteps = "Route to Engineering Manager - Fix pattern deviations"
-		return result, nil
-	}
-
-	// Step 5: Auto-fix application (formatting, linting)
-	autoFixCommands := tl.getAutoFixCommands(req.ProjectType)
+	// Step 4: Auto-fix application (formatting, linting)
+	autoFixCommands := packCommands(evidence.targets, autoFixCommandsOf)
 	for _, command := range autoFixCommands {
-		if err := tl.restrictions.ValidateCommand(command); err == nil {
-			output, err := tl.tools.ExecuteCommand(command)
-			result.CommandsExecuted = append(result.CommandsExecuted, command)
+		if err := tl.restrictions.ValidateCommand(command.Command); err == nil {
+			output, err := runPackCommand(tl.tools, command)
+			result.CommandsExecuted = append(result.CommandsExecuted, command.String())
 			result.BuildOutput += fmt.Sprintf("\n=== Auto-fix: %s ===\n%s", command, output)
 			if err != nil {
 				// Log auto-fix failures but don't fail the review
@@ -1306,8 +1265,8 @@ func (tl *SeniorTechLead) executeQualityReview(ctx context.Context, req Implemen
 		}
 	}
 
-	// Step 6: Parse and execute any additional actions from LLM response
-	actions := tl.parseActions(llmResponse)
+	// Step 5: Parse and execute any additional actions from LLM response
+	actions := parseActions(llmResponse)
 	for _, action := range actions {
 		switch action.Type {
 		case "READ_FILE":
@@ -1336,25 +1295,14 @@ func (tl *SeniorTechLead) executeQualityReview(ctx context.Context, req Implemen
 		}
 	}
 
-	// Step 7: Final approval
+	// Step 6: Final approval
 	result.Success = true
-	result.Message = "Comprehensive code review passed - implementation approved for production"
+	result.Message = fmt.Sprintf("Comprehensive code review passed - %s", decision.Summary)
 	result.NextSteps = "Ready for deployment"
 
 	return result, nil
 }
 
-// filterCriticalSecurityIssues filters for critical and high severity security issues
-func (tl *SeniorTechLead) filterCriticalSecurityIssues(issues []SecurityIssue) []SecurityIssue {
-	var critical []SecurityIssue
-	for _, issue := range issues {
-		if issue.Severity == "CRITICAL" || issue.Severity == "HIGH" {
-			critical = append(critical, issue)
-		}
-	}
-	return critical
-}
-
 // filterSignificantDuplications filters for duplications above threshold
 func (tl *SeniorTechLead) filterSignificantDuplications(issues []DuplicationIssue) []DuplicationIssue {
 	var significant []DuplicationIssue
@@ -1366,135 +1314,6 @@ func (tl *SeniorTechLead) filterSignificantDuplications(issues []DuplicationIssu
 	return significant
 }
 
-// getAutoFixCommands returns commands that should be auto-applied for formatting
-func (tl *SeniorTechLead) getAutoFixCommands(projectType ProjectType) []string {
-	switch projectType {
-	case ProjectTypeGo:
-		return []string{"go fmt", "go mod tidy"}
-	case ProjectTypeTypeScript:
-		return []string{"npm run lint --fix"}
-	case ProjectTypePython:
-		return []string{"python -m black ."}
-	default:
-		return []string{}
-	}
-}
-
-type QualityAnalysis struct {
-	HasCriticalIssues bool
-	HasWarnings      bool
-	Issues           string
-}
-
-func (tl *SeniorTechLead) analyzeQualityOutput(command, output, errorText string) QualityAnalysis {
-	analysis := QualityAnalysis{}
-	
-	combinedText := strings.ToLower(output + " " + errorText)
-	
-	// Critical issues that block approval
-	criticalPatterns := []string{
-		"security", "vulnerability", "sql injection", "xss",
-		"hardcoded password", "hardcoded secret", "api key",
-		"syntax error", "compile error", "build failed",
-		"fatal", "critical", "severe",
-	}
-	
-	for _, pattern := range criticalPatterns {
-		if strings.Contains(combinedText, pattern) {
-			analysis.HasCriticalIssues = true
-			analysis.Issues += fmt.Sprintf("Critical issue in %s: %s. ", command, pattern)
-		}
-	}
-	
-	// Warnings that should be noted but don't block
-	warningPatterns := []string{
-		"warning", "unused", "deprecated", "inefficient",
-		"complexity", "style", "convention", "lint",
-	}
-	
-	for _, pattern := range warningPatterns {
-		if strings.Contains(combinedText, pattern) {
-			analysis.HasWarnings = true
-			analysis.Issues += fmt.Sprintf("Warning in %s: %s. ", command, pattern)
-		}
-	}
-	
-	return analysis
-}
-
-type FinalDecision struct {
-	IsApproved bool
-	Issues     string
-}
-
-func (tl *SeniorTechLead) analyzeFinalDecision(result *ImplementFeatureResponse, llmResponse string) FinalDecision {
-	decision := FinalDecision{}
-	
-	lowerResponse := strings.ToLower(llmResponse)
-	
-	// Look for explicit approval/rejection
-	if strings.Contains(lowerResponse, "final_decision: approved") {
-		decision.IsApproved = true
-	} else if strings.Contains(lowerResponse, "final_decision: needs_revision") {
-		decision.IsApproved = false
-	} else {
-		// Analyze overall quality indicators
-		qualityIndicators := []string{
-			"quality", "standards", "security", "performance",
-			"architecture", "maintainable", "clean", "solid",
-		}
-		
-		positiveCount := 0
-		for _, indicator := range qualityIndicators {
-			if strings.Contains(lowerResponse, "good "+indicator) || 
-			   strings.Contains(lowerResponse, indicator+" is good") ||
-			   strings.Contains(lowerResponse, "excellent "+indicator) {
-				positiveCount++
-			}
-		}
-		
-		// Check for blockers
-		blockers := []string{
-			"security issue", "critical", "major concern", "blocker",
-			"must fix", "cannot approve", "needs revision",
-		}
-		
-		hasBlockers := false
-		for _, blocker := range blockers {
-			if strings.Contains(lowerResponse, blocker) {
-				hasBlockers = true
-				decision.Issues += blocker + "; "
-				break
-			}
-		}
-		
-		// Make decision based on analysis
-		if !hasBlockers && positiveCount >= 2 {
-			decision.IsApproved = true
-		} else {
-			decision.IsApproved = false
-			if decision.Issues == "" {
-				decision.Issues = "Quality review indicates issues that need addressing"
-			}
-		}
-	}
-	
-	return decision
-}
-
-func (tl *SeniorTechLead) getQualityTools(projectType ProjectType) []string {
-	switch projectType {
-	case ProjectTypeGo:
-		return []string{"go fmt", "go vet", "go mod tidy"}
-	case ProjectTypeTypeScript:
-		return []string{"npm run lint", "npm audit"}
-	case ProjectTypePython:
-		return []string{"python -m flake8", "python -m black --check"}
-	default:
-		return []string{}
-	}
-}
-
 func (tl *SeniorTechLead) commandAlreadyExecuted(commands []string, target string) bool {
 	for _, cmd := range commands {
 		if strings.Contains(cmd, target) {
@@ -1504,60 +1323,6 @@ func (tl *SeniorTechLead) commandAlreadyExecuted(commands []string, target strin
 	return false
 }
 
-
-func (tl *SeniorTechLead) parseActions(response string) []Action {
-	var actions []Action
-	lines := strings.Split(response, "\n")
-	
-	var currentAction *Action
-	var inContent bool
-	var contentBuilder strings.Builder
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "ACTION:") {
-			// Save previous action
-			if currentAction != nil {
-				if inContent {
-					currentAction.Content = strings.TrimSpace(contentBuilder.String())
-				}
-				actions = append(actions, *currentAction)
-			}
-
-			// Start new action
-			actionType := strings.TrimSpace(strings.TrimPrefix(line, "ACTION:"))
-			currentAction = &Action{Type: actionType}
-			inContent = false
-			contentBuilder.Reset()
-		} else if currentAction != nil {
-			if strings.HasPrefix(line, "PATH:") {
-				currentAction.Path = strings.TrimSpace(strings.TrimPrefix(line, "PATH:"))
-			} else if strings.HasPrefix(line, "COMMAND:") {
-				currentAction.Command = strings.TrimSpace(strings.TrimPrefix(line, "COMMAND:"))
-			} else if strings.HasPrefix(line, "CONTENT:") {
-				inContent = true
-				contentBuilder.Reset()
-			} else if inContent && !strings.HasPrefix(line, "```") {
-				if contentBuilder.Len() > 0 {
-					contentBuilder.WriteString("\n")
-				}
-				contentBuilder.WriteString(line)
-			}
-		}
-	}
-
-	// Save final action
-	if currentAction != nil {
-		if inContent {
-			currentAction.Content = strings.TrimSpace(contentBuilder.String())
-		}
-		actions = append(actions, *currentAction)
-	}
-
-	return actions
-}
-
 // DocumentTask for SeniorTechLead is a no-op
 func (tl *SeniorTechLead) DocumentTask(ctx context.Context, result *WorkflowResult) error {
 	return nil
@@ -0,0 +1,880 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// goSourceFile is a parsed Go file used by the Tech Lead's AST-based checks
+type goSourceFile struct {
+	name      string
+	fset      *token.FileSet
+	file      *ast.File
+	constants map[string]bool
+	info      *types.Info
+
+	bodies          []goFunctionBody // normalized function bodies, extracted on first use
+	bodiesExtracted bool
+}
+
+// goFunctionBody is the normalized form of a function body used for duplication detection
+type goFunctionBody struct {
+	Name       string
+	Line       int
+	Hash       string
+	StmtHashes []string
+}
+
+// minDuplicateStatements is the smallest body, counting nested statements, compared for
+// duplication; shorter bodies such as "call, check err, return" match by accident
+const minDuplicateStatements = 5
+
+// goImporter resolves standard library imports for type checking; the gc importer
+// caches packages and is not safe for concurrent use
+var (
+	goImporter   = importer.Default()
+	goImporterMu sync.Mutex
+)
+
+type lockedImporter struct{}
+
+func (lockedImporter) Import(path string) (*types.Package, error) {
+	goImporterMu.Lock()
+	defer goImporterMu.Unlock()
+	return goImporter.Import(path)
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// sqlSinkMethods maps database/sql style methods to the index of their query argument
+var sqlSinkMethods = map[string]int{
+	"Query":           0,
+	"QueryRow":        0,
+	"Exec":            0,
+	"Prepare":         0,
+	"QueryContext":    1,
+	"QueryRowContext": 1,
+	"ExecContext":     1,
+	"PrepareContext":  1,
+}
+
+// fileOpenFuncs are calls returning a handle that must be closed
+var fileOpenFuncs = map[string]bool{
+	"os.Open":     true,
+	"os.Create":   true,
+	"os.OpenFile": true,
+}
+
+// httpResponseFuncs are calls returning an *http.Response whose Body must be closed
+var httpResponseFuncs = map[string]bool{
+	"http.Get":      true,
+	"http.Post":     true,
+	"http.Head":     true,
+	"http.PostForm": true,
+}
+
+// pathSinkFuncs are file operations that must not receive request-derived paths
+var pathSinkFuncs = map[string]bool{
+	"os.Open":         true,
+	"os.OpenFile":     true,
+	"os.Create":       true,
+	"os.ReadFile":     true,
+	"os.WriteFile":    true,
+	"os.Remove":       true,
+	"os.RemoveAll":    true,
+	"ioutil.ReadFile": true,
+	"http.ServeFile":  true,
+}
+
+// errorOnlyFuncs return only an error, so calling them as a bare statement drops it
+var errorOnlyFuncs = map[string]bool{
+	"os.Remove":      true,
+	"os.RemoveAll":   true,
+	"os.Rename":      true,
+	"os.Mkdir":       true,
+	"os.MkdirAll":    true,
+	"os.WriteFile":   true,
+	"os.Chdir":       true,
+	"os.Setenv":      true,
+	"json.Unmarshal": true,
+	"xml.Unmarshal":  true,
+}
+
+// requestAccessors are methods that read user-controlled request data
+var requestAccessors = map[string]bool{
+	"FormValue":     true,
+	"PostFormValue": true,
+	"PathValue":     true,
+	"Query":         true,
+	"Param":         true,
+	"Params":        true,
+	"Cookies":       true,
+}
+
+var (
+	secretNamePattern  = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|credential)`)
+	secretValuePattern = regexp.MustCompile(`^[A-Za-z0-9+/=_\-]{32,}$`)
+	// Env var and header names such as "DB_PASSWORD" or "X-Auth-Token" are not secrets
+	secretPlaceholderPattern = regexp.MustCompile(`^([A-Z][A-Z0-9_]*|[A-Z][A-Za-z0-9]*(-[A-Z][A-Za-z0-9]*)+)$`)
+)
+
+// parseGoSource parses Go source for AST-based review
+func parseGoSource(filename, content string) (*goSourceFile, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &goSourceFile{
+		name:      filename,
+		fset:      fset,
+		file:      file,
+		constants: make(map[string]bool),
+	}
+
+	// Package-level constants are safe to concatenate into queries
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.CONST {
+			for _, spec := range gen.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					src.constants[name.Name] = true
+				}
+			}
+		}
+	}
+
+	// Type information is best effort: imports outside the standard library do not
+	// resolve, and expressions depending on them are left without a type
+	src.info = &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{Importer: lockedImporter{}, Error: func(error) {}}
+	conf.Check(file.Name.Name, fset, []*ast.File{file}, src.info)
+
+	return src, nil
+}
+
+// resultTypes returns the result types of call, or nil when they could not be resolved
+func (src *goSourceFile) resultTypes(call *ast.CallExpr) []types.Type {
+	tv, ok := src.info.Types[call]
+	if !ok || tv.Type == nil {
+		return nil
+	}
+	switch t := tv.Type.(type) {
+	case *types.Tuple:
+		results := make([]types.Type, t.Len())
+		for i := range results {
+			results[i] = t.At(i).Type()
+		}
+		return results
+	case *types.Basic:
+		if t.Kind() == types.Invalid {
+			return nil
+		}
+	}
+	return []types.Type{tv.Type}
+}
+
+func isErrorType(t types.Type) bool {
+	return t != nil && types.Identical(t, errorType)
+}
+
+func (src *goSourceFile) line(pos token.Pos) int {
+	return src.fset.Position(pos).Line
+}
+
+// checkGoSecurity runs all AST-based security checks over a Go file
+func (tl *SeniorTechLead) checkGoSecurity(src *goSourceFile) []SecurityIssue {
+	var issues []SecurityIssue
+
+	issues = append(issues, tl.checkGoSQLInjection(src)...)
+	issues = append(issues, tl.checkGoPathTraversal(src)...)
+	issues = append(issues, tl.checkGoResourceLeaks(src)...)
+	issues = append(issues, tl.checkGoIgnoredErrors(src)...)
+	issues = append(issues, tl.checkGoHardcodedSecrets(src)...)
+	issues = append(issues, tl.checkGoUnvalidatedInput(src)...)
+
+	return issues
+}
+
+// stringFlow tracks which locals in a function hold constant or dynamically built strings
+type stringFlow struct {
+	src      *goSourceFile
+	dynamic  map[string]bool
+	constant map[string]bool
+}
+
+func (f *stringFlow) assign(name string, value ast.Expr) {
+	f.dynamic[name] = f.isDynamic(value)
+	f.constant[name] = f.isConstant(value)
+}
+
+// isDynamic reports whether expr builds a string from non-constant parts
+func (f *stringFlow) isDynamic(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return f.isDynamic(e.X)
+	case *ast.Ident:
+		return f.dynamic[e.Name]
+	case *ast.CallExpr:
+		name := callName(e)
+		return (name == "fmt.Sprintf" || name == "fmt.Sprint") && len(e.Args) > 1
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return false
+		}
+		if f.isDynamic(e.X) || f.isDynamic(e.Y) {
+			return true
+		}
+		// Concatenating a literal with anything non-constant builds a dynamic string
+		return (isStringLit(e.X) || isStringLit(e.Y)) && !(f.isConstant(e.X) && f.isConstant(e.Y))
+	}
+	return false
+}
+
+// isConstant reports whether expr is a literal, a constant, or a local holding only literals
+func (f *stringFlow) isConstant(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Kind == token.STRING
+	case *ast.ParenExpr:
+		return f.isConstant(e.X)
+	case *ast.Ident:
+		return f.src.constants[e.Name] || f.constant[e.Name]
+	case *ast.BinaryExpr:
+		return e.Op == token.ADD && f.isConstant(e.X) && f.isConstant(e.Y)
+	}
+	return false
+}
+
+func isStringLit(expr ast.Expr) bool {
+	lit, ok := expr.(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING
+}
+
+// checkGoSQLInjection finds queries built with fmt.Sprintf or + that reach db.Query/Exec
+func (tl *SeniorTechLead) checkGoSQLInjection(src *goSourceFile) []SecurityIssue {
+	var issues []SecurityIssue
+
+	for _, body := range functionBodies(src.file) {
+		flow := &stringFlow{
+			src:      src,
+			dynamic:  make(map[string]bool),
+			constant: make(map[string]bool),
+		}
+
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) != len(node.Rhs) {
+					return true
+				}
+				for i, lhs := range node.Lhs {
+					ident, ok := lhs.(*ast.Ident)
+					if !ok {
+						continue
+					}
+					if node.Tok == token.ADD_ASSIGN {
+						if !flow.isConstant(node.Rhs[i]) {
+							flow.dynamic[ident.Name] = true
+							flow.constant[ident.Name] = false
+						}
+						continue
+					}
+					flow.assign(ident.Name, node.Rhs[i])
+				}
+			case *ast.ValueSpec:
+				for i, name := range node.Names {
+					if i < len(node.Values) {
+						flow.assign(name.Name, node.Values[i])
+					}
+				}
+			case *ast.CallExpr:
+				sel, ok := node.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				argIndex, isSink := sqlSinkMethods[sel.Sel.Name]
+				if !isSink || argIndex >= len(node.Args) {
+					return true
+				}
+				if flow.isDynamic(node.Args[argIndex]) {
+					issues = append(issues, SecurityIssue{
+						Type:        "SQL_INJECTION",
+						Description: fmt.Sprintf("Query passed to %s is built with string formatting or concatenation; use placeholders", sel.Sel.Name),
+						File:        src.name,
+						Line:        src.line(node.Pos()),
+						Severity:    "CRITICAL",
+					})
+				}
+			}
+			return true
+		})
+	}
+
+	return issues
+}
+
+// checkGoPathTraversal finds file operations whose path derives from request data
+func (tl *SeniorTechLead) checkGoPathTraversal(src *goSourceFile) []SecurityIssue {
+	var issues []SecurityIssue
+
+	for _, body := range functionBodies(src.file) {
+		tainted := make(map[string]bool)
+
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range node.Lhs {
+					ident, ok := lhs.(*ast.Ident)
+					if !ok {
+						continue
+					}
+					rhs := node.Rhs[0]
+					if len(node.Rhs) == len(node.Lhs) {
+						rhs = node.Rhs[i]
+					}
+					tainted[ident.Name] = isRequestDerived(rhs, tainted)
+				}
+			case *ast.CallExpr:
+				name := callName(node)
+				if !pathSinkFuncs[name] {
+					return true
+				}
+				for _, arg := range node.Args {
+					if isRequestDerived(arg, tainted) {
+						issues = append(issues, SecurityIssue{
+							Type:        "PATH_TRAVERSAL",
+							Description: fmt.Sprintf("%s called with a path derived from request input", name),
+							File:        src.name,
+							Line:        src.line(node.Pos()),
+							Severity:    "HIGH",
+						})
+						break
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	return issues
+}
+
+// isRequestDerived reports whether expr reads user-controlled request data without sanitizing it
+func isRequestDerived(expr ast.Expr, tainted map[string]bool) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.CallExpr:
+			// filepath.Base strips directory components, which neutralizes traversal
+			if callName(node) == "filepath.Base" {
+				return false
+			}
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && requestAccessors[sel.Sel.Name] {
+				found = true
+			}
+		case *ast.SelectorExpr:
+			if root, ok := node.X.(*ast.Ident); ok {
+				switch root.Name {
+				case "r", "req", "request":
+					found = node.Sel.Name != "Context" && node.Sel.Name != "Method"
+				}
+			}
+		case *ast.Ident:
+			found = tainted[node.Name]
+		}
+		return !found
+	})
+	return found
+}
+
+// checkGoResourceLeaks finds os.Open and http responses whose handles are never closed
+func (tl *SeniorTechLead) checkGoResourceLeaks(src *goSourceFile) []SecurityIssue {
+	var issues []SecurityIssue
+
+	for _, body := range functionBodies(src.file) {
+		ast.Inspect(body, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignStmt)
+			if !ok || len(assign.Rhs) != 1 || len(assign.Lhs) == 0 {
+				return true
+			}
+			call, ok := assign.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			handle, ok := assign.Lhs[0].(*ast.Ident)
+			if !ok || handle.Name == "_" {
+				return true
+			}
+
+			name := callName(call)
+			switch {
+			case fileOpenFuncs[name]:
+				if !closesHandle(body, handle.Name, "") && !escapesFunction(body, handle.Name) {
+					issues = append(issues, SecurityIssue{
+						Type:        "RESOURCE_LEAK",
+						Description: fmt.Sprintf("%s result %q is never closed", name, handle.Name),
+						File:        src.name,
+						Line:        src.line(call.Pos()),
+						Severity:    "MEDIUM",
+					})
+				}
+			case httpResponseFuncs[name] || isClientDo(call):
+				if !closesHandle(body, handle.Name, "Body") && !escapesFunction(body, handle.Name) {
+					issues = append(issues, SecurityIssue{
+						Type:        "RESOURCE_LEAK",
+						Description: fmt.Sprintf("HTTP response %q body is never closed", handle.Name),
+						File:        src.name,
+						Line:        src.line(call.Pos()),
+						Severity:    "MEDIUM",
+					})
+				}
+			}
+			return true
+		})
+	}
+
+	return issues
+}
+
+func isClientDo(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Do" && len(call.Args) == 1
+}
+
+// closesHandle reports whether body calls name.Close() or name.field.Close()
+func closesHandle(body *ast.BlockStmt, name, field string) bool {
+	closed := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || closed {
+			return !closed
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Close" {
+			return true
+		}
+		target := sel.X
+		if field != "" {
+			inner, ok := target.(*ast.SelectorExpr)
+			if !ok || inner.Sel.Name != field {
+				return true
+			}
+			target = inner.X
+		}
+		if ident, ok := target.(*ast.Ident); ok && ident.Name == name {
+			closed = true
+		}
+		return true
+	})
+	return closed
+}
+
+// escapesFunction reports whether name is returned, so closing is the caller's job
+func escapesFunction(body *ast.BlockStmt, name string) bool {
+	escapes := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		ret, ok := n.(*ast.ReturnStmt)
+		if !ok {
+			return !escapes
+		}
+		for _, result := range ret.Results {
+			if ident, ok := result.(*ast.Ident); ok && ident.Name == name {
+				escapes = true
+			}
+		}
+		return !escapes
+	})
+	return escapes
+}
+
+// checkGoIgnoredErrors finds errors discarded with _ or by calling error-returning functions
+// as statements; only results whose type resolves to error are reported
+func (tl *SeniorTechLead) checkGoIgnoredErrors(src *goSourceFile) []SecurityIssue {
+	var issues []SecurityIssue
+
+	for _, body := range functionBodies(src.file) {
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				// Comma-ok forms (map lookups, type assertions, receives) are not calls
+				if len(node.Rhs) != 1 || len(node.Lhs) < 2 {
+					return true
+				}
+				call, ok := node.Rhs[0].(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				results := src.resultTypes(call)
+				if len(results) != len(node.Lhs) {
+					return true
+				}
+				for i, lhs := range node.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "_" && isErrorType(results[i]) {
+						issues = append(issues, SecurityIssue{
+							Type:        "IGNORED_ERROR",
+							Description: fmt.Sprintf("Error returned by %s is discarded", callName(call)),
+							File:        src.name,
+							Line:        src.line(node.Pos()),
+							Severity:    "MEDIUM",
+						})
+						break
+					}
+				}
+			case *ast.ExprStmt:
+				call, ok := node.X.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				name := callName(call)
+				results := src.resultTypes(call)
+				unchecked := errorOnlyFuncs[name]
+				if results != nil {
+					unchecked = len(results) == 1 && isErrorType(results[0])
+				}
+				if unchecked {
+					issues = append(issues, SecurityIssue{
+						Type:        "IGNORED_ERROR",
+						Description: fmt.Sprintf("Error returned by %s is not checked", name),
+						File:        src.name,
+						Line:        src.line(node.Pos()),
+						Severity:    "MEDIUM",
+					})
+				}
+			}
+			return true
+		})
+	}
+
+	return issues
+}
+
+// checkGoHardcodedSecrets finds string literals assigned to credential-like names
+func (tl *SeniorTechLead) checkGoHardcodedSecrets(src *goSourceFile) []SecurityIssue {
+	var issues []SecurityIssue
+
+	report := func(name string, value ast.Expr) {
+		lit, ok := value.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		text, err := strconv.Unquote(lit.Value)
+		if err != nil || len(text) < 8 || strings.ContainsAny(text, " \t\n") || secretPlaceholderPattern.MatchString(text) {
+			return
+		}
+		if secretNamePattern.MatchString(name) || secretValuePattern.MatchString(text) {
+			issues = append(issues, SecurityIssue{
+				Type:        "HARDCODED_SECRET",
+				Description: fmt.Sprintf("Hardcoded credential assigned to %s", name),
+				File:        src.name,
+				Line:        src.line(lit.Pos()),
+				Severity:    "CRITICAL",
+			})
+		}
+	}
+
+	ast.Inspect(src.file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ValueSpec:
+			for i, name := range node.Names {
+				if i < len(node.Values) {
+					report(name.Name, node.Values[i])
+				}
+			}
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				return true
+			}
+			for i, lhs := range node.Lhs {
+				if name := exprName(lhs); name != "" {
+					report(name, node.Rhs[i])
+				}
+			}
+		case *ast.KeyValueExpr:
+			if name := exprName(node.Key); name != "" {
+				report(name, node.Value)
+			}
+		}
+		return true
+	})
+
+	return issues
+}
+
+// checkGoUnvalidatedInput finds request binding and deserialization in files with no validation
+func (tl *SeniorTechLead) checkGoUnvalidatedInput(src *goSourceFile) []SecurityIssue {
+	var issues []SecurityIssue
+
+	hasValidation := false
+	var bindCalls, unmarshalCalls []*ast.CallExpr
+
+	ast.Inspect(src.file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.Field:
+			if node.Tag != nil && strings.Contains(node.Tag.Value, "validate:") {
+				hasValidation = true
+			}
+		case *ast.CallExpr:
+			sel, ok := node.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			switch sel.Sel.Name {
+			case "Validate", "Struct", "ValidateStruct":
+				hasValidation = true
+			case "BindJSON", "ShouldBindJSON", "Bind", "BodyParser":
+				bindCalls = append(bindCalls, node)
+			case "Unmarshal":
+				unmarshalCalls = append(unmarshalCalls, node)
+			}
+		}
+		return true
+	})
+
+	if hasValidation {
+		return issues
+	}
+
+	for _, call := range bindCalls {
+		issues = append(issues, SecurityIssue{
+			Type:        "MISSING_VALIDATION",
+			Description: "Request binding without validation detected",
+			File:        src.name,
+			Line:        src.line(call.Pos()),
+			Severity:    "MEDIUM",
+		})
+	}
+	for _, call := range unmarshalCalls {
+		issues = append(issues, SecurityIssue{
+			Type:        "UNSAFE_DESERIALIZATION",
+			Description: "Deserialization without validation detected",
+			File:        src.name,
+			Line:        src.line(call.Pos()),
+			Severity:    "MEDIUM",
+		})
+	}
+
+	return issues
+}
+
+// extractGoFunctionBodies returns normalized hashes for every function body in the file
+func extractGoFunctionBodies(src *goSourceFile) []goFunctionBody {
+	var bodies []goFunctionBody
+
+	for _, decl := range src.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || countStatements(fn.Body) < minDuplicateStatements {
+			continue
+		}
+
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			name = fmt.Sprintf("(%s).%s", exprName(fn.Recv.List[0].Type), name)
+		}
+
+		body := goFunctionBody{
+			Name: name,
+			Line: src.line(fn.Pos()),
+			Hash: normalizedHash(fn.Body),
+		}
+		for _, stmt := range fn.Body.List {
+			body.StmtHashes = append(body.StmtHashes, normalizedHash(stmt))
+		}
+		bodies = append(bodies, body)
+	}
+
+	return bodies
+}
+
+// countStatements counts the statements in body, including nested ones
+func countStatements(body *ast.BlockStmt) int {
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		if _, ok := n.(ast.Stmt); ok {
+			if _, isBlock := n.(*ast.BlockStmt); !isBlock {
+				count++
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// normalizedHash hashes node structure with local identifiers renamed in order of appearance,
+// so bodies that differ only in variable names hash identically. Callee and selector names
+// and literal values are kept, so unrelated code sharing a shape does not match.
+func normalizedHash(node ast.Node) string {
+	var sb strings.Builder
+	names := make(map[string]int)
+
+	// Identifiers naming what is called or selected are part of the code's meaning
+	kept := make(map[*ast.Ident]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.CallExpr:
+			if ident, ok := node.Fun.(*ast.Ident); ok {
+				kept[ident] = true
+			}
+		case *ast.SelectorExpr:
+			kept[node.Sel] = true
+			if pkg, ok := node.X.(*ast.Ident); ok && pkg.Obj == nil {
+				kept[pkg] = true // Unresolved roots are package names
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		fmt.Fprintf(&sb, "%T", n)
+		switch node := n.(type) {
+		case *ast.Ident:
+			if kept[node] {
+				sb.WriteString(node.Name)
+				break
+			}
+			if _, ok := names[node.Name]; !ok {
+				names[node.Name] = len(names)
+			}
+			fmt.Fprintf(&sb, "#%d", names[node.Name])
+		case *ast.BasicLit:
+			sb.WriteString(node.Value)
+		case *ast.BinaryExpr:
+			sb.WriteString(node.Op.String())
+		case *ast.UnaryExpr:
+			sb.WriteString(node.Op.String())
+		case *ast.AssignStmt:
+			sb.WriteString(node.Tok.String())
+		case *ast.IncDecStmt:
+			sb.WriteString(node.Tok.String())
+		case *ast.BranchStmt:
+			sb.WriteString(node.Tok.String())
+		}
+		sb.WriteByte(';')
+		return true
+	})
+
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// bodySimilarity returns the percentage of matching top-level statements between two bodies
+func bodySimilarity(a, b goFunctionBody) int {
+	if a.Hash == b.Hash {
+		return 100
+	}
+
+	remaining := make(map[string]int)
+	for _, h := range b.StmtHashes {
+		remaining[h]++
+	}
+	common := 0
+	for _, h := range a.StmtHashes {
+		if remaining[h] > 0 {
+			remaining[h]--
+			common++
+		}
+	}
+
+	total := len(a.StmtHashes) + len(b.StmtHashes)
+	if total == 0 {
+		return 0
+	}
+	return 200 * common / total
+}
+
+// findGoCodeDuplication compares normalized function bodies between two Go files
+func (tl *SeniorTechLead) findGoCodeDuplication(current, existing *goSourceFile) []DuplicationIssue {
+	var issues []DuplicationIssue
+
+	existingBodies := existing.functionBodies()
+	for _, currentBody := range current.functionBodies() {
+		for _, existingBody := range existingBodies {
+			similarity := bodySimilarity(currentBody, existingBody)
+			if similarity > 80 {
+				issues = append(issues, DuplicationIssue{
+					Type:            "FUNCTION_DUPLICATION",
+					Description:     fmt.Sprintf("Body of %s duplicates %s (%d%% similar)", currentBody.Name, existingBody.Name, similarity),
+					CurrentFile:     current.name,
+					ExistingFile:    existing.name,
+					CurrentLine:     currentBody.Line,
+					ExistingLine:    existingBody.Line,
+					SimilarityScore: similarity,
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// functionBodies returns the normalized bodies of the file's functions, extracting
+// them once however many files the file is compared with
+func (src *goSourceFile) functionBodies() []goFunctionBody {
+	if !src.bodiesExtracted {
+		src.bodies = extractGoFunctionBodies(src)
+		src.bodiesExtracted = true
+	}
+	return src.bodies
+}
+
+// functionBodies returns the bodies of all function declarations; ast.Inspect
+// still descends into any function literals they contain
+func functionBodies(file *ast.File) []*ast.BlockStmt {
+	var bodies []*ast.BlockStmt
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			bodies = append(bodies, fn.Body)
+		}
+	}
+	return bodies
+}
+
+// callName returns "pkg.Func" or "Method" for a call expression
+func callName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := fun.X.(*ast.Ident); ok {
+			return pkg.Name + "." + fun.Sel.Name
+		}
+		return fun.Sel.Name
+	}
+	return ""
+}
+
+// exprName returns the trailing identifier of an expression such as x, s.x or *T
+func exprName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.StarExpr:
+		return exprName(e.X)
+	case *ast.IndexExpr:
+		return exprName(e.X)
+	}
+	return ""
+}
+
+// lineOfOffset converts a byte offset in content to a 1-based line number
+func lineOfOffset(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustParseGo(t *testing.T, name, content string) *goSourceFile {
+	t.Helper()
+	src, err := parseGoSource(name, content)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return src
+}
+
+func issueTypes(issues []SecurityIssue) string {
+	var types []string
+	for _, issue := range issues {
+		types = append(types, issue.Type)
+	}
+	return strings.Join(types, ",")
+}
+
+func TestGoSecurityChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "sprintf query",
+			body: `func f(db *sql.DB, id string) {
+	q := fmt.Sprintf("SELECT * FROM users WHERE id = %s", id)
+	rows, err := db.Query(q)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+}`,
+			want: "SQL_INJECTION",
+		},
+		{
+			name: "concatenated query across lines",
+			body: `func f(db *sql.DB, id string) {
+	db.Exec("DELETE FROM users WHERE id = " +
+		id)
+}`,
+			want: "SQL_INJECTION",
+		},
+		{
+			name: "placeholder query",
+			body: `const table = "users"
+
+func f(db *sql.DB, id string) {
+	q := "SELECT * FROM " + table + " WHERE id = ?"
+	db.Query(q, id)
+}`,
+			want: "",
+		},
+		{
+			name: "query in a comment",
+			body: `// db.Query("SELECT * FROM users WHERE id = " + id)
+func f() {}`,
+			want: "",
+		},
+		{
+			name: "request path",
+			body: `func f(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("file")
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return
+	}
+	w.Write(data)
+}`,
+			want: "PATH_TRAVERSAL",
+		},
+		{
+			name: "request path through filepath.Base",
+			body: `func f(w http.ResponseWriter, r *http.Request) {
+	data, err := os.ReadFile(filepath.Join("static", filepath.Base(r.FormValue("file"))))
+	if err != nil {
+		return
+	}
+	w.Write(data)
+}`,
+			want: "",
+		},
+		{
+			name: "unclosed file",
+			body: `func f() error {
+	file, err := os.Open("data.txt")
+	if err != nil {
+		return err
+	}
+	_ = file
+	return nil
+}`,
+			want: "RESOURCE_LEAK",
+		},
+		{
+			name: "returned file",
+			body: `func f() (*os.File, error) {
+	file, err := os.Open("data.txt")
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}`,
+			want: "",
+		},
+		{
+			name: "unclosed response body",
+			body: `func f() error {
+	resp, err := http.Get("http://example.com")
+	if err != nil {
+		return err
+	}
+	_ = resp.StatusCode
+	return nil
+}`,
+			want: "RESOURCE_LEAK",
+		},
+		{
+			name: "closed response body",
+			body: `func f() error {
+	resp, err := http.Get("http://example.com")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return nil
+}`,
+			want: "",
+		},
+		{
+			name: "discarded error",
+			body: `func f() {
+	file, _ := os.Open("data.txt")
+	defer file.Close()
+}`,
+			want: "IGNORED_ERROR",
+		},
+		{
+			name: "unchecked error statement",
+			body: `func f() {
+	os.Remove("data.txt")
+}`,
+			want: "IGNORED_ERROR",
+		},
+		{
+			name: "comma-ok forms",
+			body: `func lookup(m map[string]int, k string) (int, bool) {
+	v, ok := m[k]
+	return v, ok
+}
+
+func f(m map[string]int, x interface{}, ch chan int) int {
+	a, _ := m["a"]
+	s, _ := x.(string)
+	n, _ := <-ch
+	b, _ := lookup(m, "b")
+	return a + len(s) + n + b
+}`,
+			want: "",
+		},
+		{
+			name: "discarded error from a multi-result call",
+			body: `func f(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}`,
+			want: "IGNORED_ERROR",
+		},
+		{
+			name: "hardcoded secret",
+			body: `func f() {
+	apiKey := "sk_live_1234567890abcdef"
+	_ = apiKey
+}`,
+			want: "HARDCODED_SECRET",
+		},
+		{
+			name: "env var name",
+			body: `func f() string {
+	password := os.Getenv("DB_PASSWORD")
+	const passwordEnv = "DB_PASSWORD"
+	return password + passwordEnv
+}`,
+			want: "",
+		},
+		{
+			name: "unvalidated binding",
+			body: `func f(c *gin.Context) {
+	var req struct{ Name string }
+	c.BindJSON(&req)
+}`,
+			want: "MISSING_VALIDATION",
+		},
+	}
+
+	tl := &SeniorTechLead{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "package p\n\nimport (\n\t\"database/sql\"\n\t\"fmt\"\n\t\"net/http\"\n\t\"os\"\n\t\"path/filepath\"\n\t\"strconv\"\n)\n\n" +
+				"var _ = sql.Open\nvar _ = fmt.Sprint\nvar _ = http.Get\nvar _ = os.Open\nvar _ = filepath.Base\nvar _ = strconv.Itoa\n\n" + tt.body + "\n"
+			src := mustParseGo(t, "p.go", content)
+			if got := issueTypes(tl.checkGoSecurity(src)); got != tt.want {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoCodeDuplication(t *testing.T) {
+	const openFile = `package store
+
+import "os"
+
+func openFile(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+`
+	const dialDB = `package store
+
+func dial(dsn string) (*Conn, error) {
+	conn, err := db.Dial(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+`
+	const openLogged = `package store
+
+func openLogged(path string) (*os.File, error) {
+	log.Printf("opening %s", path)
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("open failed: %v", err)
+		return nil, err
+	}
+	return f, nil
+}
+`
+	const dialLogged = `package store
+
+func dialLogged(dsn string) (*Conn, error) {
+	log.Printf("opening %s", dsn)
+	conn, err := db.Dial(dsn)
+	if err != nil {
+		log.Printf("open failed: %v", err)
+		return nil, err
+	}
+	return conn, nil
+}
+`
+	const sumPositive = `package store
+
+func sumPositive(values []int) int {
+	total := 0
+	for _, v := range values {
+		if v > 0 {
+			total += v
+		}
+	}
+	return total
+}
+`
+	const renamedSum = `package other
+
+func addPositives(nums []int) int {
+	acc := 0
+	for _, n := range nums {
+		if n > 0 {
+			acc += n
+		}
+	}
+	return acc
+}
+`
+	const sumNegative = `package other
+
+func sumNegative(nums []int) int {
+	acc := 0
+	for _, n := range nums {
+		if n < 0 {
+			acc += n
+		}
+	}
+	return acc
+}
+`
+
+	tests := []struct {
+		name              string
+		current, existing string
+		want              int
+	}{
+		{"unrelated error handling", openFile, dialDB, 0},
+		{"same shape with different callees", openLogged, dialLogged, 0},
+		{"renamed copy", renamedSum, sumPositive, 1},
+		{"different operator", sumNegative, sumPositive, 0},
+	}
+
+	tl := &SeniorTechLead{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ReviewContext{
+				FileContents: map[string]string{"current.go": tt.current},
+				RelatedFiles: map[string]string{"existing.go": tt.existing},
+			}
+			issues := tl.detectDuplication(ctx)
+			if len(issues) != tt.want {
+				t.Errorf("duplications = %+v, want %d", issues, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/langpack"
+)
+
+// LintIssue is a single finding reported by an external linter
+type LintIssue struct {
+	Linter   string `json:"linter"`
+	Rule     string `json:"rule,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// fingerprint identifies a finding independently of its line so that
+// pre-existing findings still match after unrelated edits shift the file
+func (li LintIssue) fingerprint() string {
+	return strings.Join([]string{li.Linter, li.Rule, li.File, li.Message}, "|")
+}
+
+// defaultLinters is used when the tech lead config declares no linters
+func defaultLinters() []config.LinterConfig {
+	return langpack.Linters()
+}
+
+// activeLinters returns the configured linters whose detection files exist in the project
+func (tl *SeniorTechLead) activeLinters() []config.LinterConfig {
+	linters := tl.config.Linters
+	if len(linters) == 0 {
+		linters = defaultLinters()
+	}
+
+	var active []config.LinterConfig
+	for _, linter := range linters {
+		if len(linter.Detect) == 0 {
+			active = append(active, linter)
+			continue
+		}
+		for _, file := range linter.Detect {
+			if _, err := tl.tools.ReadFile(file); err == nil {
+				active = append(active, linter)
+				break
+			}
+		}
+	}
+	return active
+}
+
+// RecordBaseline runs the linters before the workflow changes anything so
+// that only findings introduced by the workflow count against approval. A linter
+// that fails here leaves no baseline, so its existing findings would count as new;
+// that is returned as an error for the workflow to report.
+func (tl *SeniorTechLead) RecordBaseline(ctx context.Context, workingDirectory string) error {
+	if workingDirectory != "" {
+		tl.tools.SetWorkingDirectory(workingDirectory)
+	}
+
+	run := tl.runLinters(ctx)
+	tl.baseline = make(map[string]int)
+	for _, issue := range run.issues {
+		tl.baseline[issue.fingerprint()]++
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("lint baseline interrupted: %w", err)
+	}
+	if len(run.failed) > 0 {
+		return fmt.Errorf("no lint baseline from %s, so their existing findings will count as new:\n%s",
+			strings.Join(run.failed, ", "), run.log)
+	}
+	return nil
+}
+
+// lintRun is the outcome of running the active linters
+type lintRun struct {
+	issues []LintIssue
+	log    string   // what was run, for the review output
+	failed []string // linters that ran but whose findings are unknown
+}
+
+// runLinters executes every active linter and returns the parsed findings along
+// with a log of what was run; once ctx is done no further linter is started
+func (tl *SeniorTechLead) runLinters(ctx context.Context) lintRun {
+	var run lintRun
+	var log strings.Builder
+
+	for _, linter := range tl.activeLinters() {
+		if err := ctx.Err(); err != nil {
+			log.WriteString(fmt.Sprintf("Linters stopped: %v\n", err))
+			break
+		}
+		if err := tl.restrictions.ValidateCommand(linter.Command); err != nil {
+			log.WriteString(fmt.Sprintf("Linter %s skipped: %v\n", linter.Name, err))
+			continue
+		}
+
+		// Linters exit non-zero when they report findings, so the
+		// error is only interesting if nothing could be parsed
+		output, execErr := tl.tools.ExecuteCommand(linter.Command)
+		found, parseErr := parseLinterOutput(linter, output)
+		if parseErr != nil {
+			log.WriteString(fmt.Sprintf("Linter %s output could not be parsed: %v\n", linter.Name, parseErr))
+			run.failed = append(run.failed, linter.Name)
+			continue
+		}
+		if execErr != nil && len(found) == 0 {
+			log.WriteString(fmt.Sprintf("Linter %s failed without findings: %v\n", linter.Name, execErr))
+			run.failed = append(run.failed, linter.Name)
+			continue
+		}
+
+		for i := range found {
+			found[i].File = tl.relativeLintPath(found[i].File)
+		}
+		run.issues = append(run.issues, found...)
+		log.WriteString(fmt.Sprintf("Linter %s: %d findings\n", linter.Name, len(found)))
+	}
+
+	run.log = log.String()
+	return run
+}
+
+// newLintIssues drops findings that were already present in the baseline
+func (tl *SeniorTechLead) newLintIssues(issues []LintIssue) []LintIssue {
+	remaining := make(map[string]int, len(tl.baseline))
+	for fingerprint, count := range tl.baseline {
+		remaining[fingerprint] = count
+	}
+
+	var fresh []LintIssue
+	for _, issue := range issues {
+		fingerprint := issue.fingerprint()
+		if remaining[fingerprint] > 0 {
+			remaining[fingerprint]--
+			continue
+		}
+		fresh = append(fresh, issue)
+	}
+	return fresh
+}
+
+// filterBlockingLintIssues filters for critical and high severity lint findings
+func (tl *SeniorTechLead) filterBlockingLintIssues(issues []LintIssue) []LintIssue {
+	var blocking []LintIssue
+	for _, issue := range issues {
+		if issue.Severity == "CRITICAL" || issue.Severity == "HIGH" {
+			blocking = append(blocking, issue)
+		}
+	}
+	return blocking
+}
+
+// relativeLintPath makes linter paths comparable with git diff paths
+func (tl *SeniorTechLead) relativeLintPath(path string) string {
+	path = strings.TrimPrefix(path, "file://")
+	if workingDir := tl.tools.GetWorkingDirectory(); workingDir != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// parseLinterOutput converts raw linter output into findings according to the configured format
+func parseLinterOutput(linter config.LinterConfig, output string) ([]LintIssue, error) {
+	var issues []LintIssue
+	var err error
+
+	switch strings.ToLower(linter.Format) {
+	case "sarif":
+		issues, err = parseSARIFOutput(linter, output)
+	case "checkstyle":
+		issues, err = parseCheckstyleOutput(linter, output)
+	case "json":
+		issues, err = parseJSONLintOutput(linter, output)
+	case "regex":
+		issues, err = parseRegexLintOutput(linter, output)
+	default:
+		return nil, fmt.Errorf("unsupported linter format %q", linter.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range issues {
+		issues[i].Linter = linter.Name
+		issues[i].Severity = lintSeverity(linter, issues[i].Rule, issues[i].Severity)
+	}
+	return issues, nil
+}
+
+// lintSeverity maps a rule or linter level onto the review severities
+func lintSeverity(linter config.LinterConfig, rule, level string) string {
+	if severity, ok := linter.Severity[rule]; ok && rule != "" {
+		return strings.ToUpper(severity)
+	}
+	if severity, ok := linter.Severity[level]; ok && level != "" {
+		return strings.ToUpper(severity)
+	}
+	if severity, ok := linter.Severity[strings.ToLower(level)]; ok && level != "" {
+		return strings.ToUpper(severity)
+	}
+	if linter.DefaultSeverity != "" {
+		return strings.ToUpper(linter.DefaultSeverity)
+	}
+	return "MEDIUM"
+}
+
+// trimToDocument skips anything a tool printed before its structured output
+func trimToDocument(output string, openers string) string {
+	if idx := strings.IndexAny(output, openers); idx > 0 {
+		return output[idx:]
+	}
+	return output
+}
+
+type sarifLog struct {
+	Runs []struct {
+		Results []struct {
+			RuleID  string `json:"ruleId"`
+			Level   string `json:"level"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine   int `json:"startLine"`
+						StartColumn int `json:"startColumn"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func parseSARIFOutput(linter config.LinterConfig, output string) ([]LintIssue, error) {
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(trimToDocument(output, "{")), &log); err != nil {
+		return nil, fmt.Errorf("invalid SARIF: %w", err)
+	}
+
+	var issues []LintIssue
+	for _, run := range log.Runs {
+		for _, result := range run.Results {
+			issue := LintIssue{
+				Rule:     result.RuleID,
+				Severity: result.Level,
+				Message:  result.Message.Text,
+			}
+			if len(result.Locations) > 0 {
+				location := result.Locations[0].PhysicalLocation
+				issue.File = location.ArtifactLocation.URI
+				issue.Line = location.Region.StartLine
+				issue.Column = location.Region.StartColumn
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+type checkstyleReport struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Column   int    `xml:"column,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+func parseCheckstyleOutput(linter config.LinterConfig, output string) ([]LintIssue, error) {
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+
+	var report checkstyleReport
+	if err := xml.Unmarshal([]byte(trimToDocument(output, "<")), &report); err != nil {
+		return nil, fmt.Errorf("invalid checkstyle XML: %w", err)
+	}
+
+	var issues []LintIssue
+	for _, file := range report.Files {
+		for _, e := range file.Errors {
+			issues = append(issues, LintIssue{
+				Rule:     e.Source,
+				File:     file.Name,
+				Line:     e.Line,
+				Column:   e.Column,
+				Severity: e.Severity,
+				Message:  e.Message,
+			})
+		}
+	}
+	return issues, nil
+}
+
+func parseJSONLintOutput(linter config.LinterConfig, output string) ([]LintIssue, error) {
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+
+	// Accept a single document or one JSON object per line
+	var documents []interface{}
+	var doc interface{}
+	if err := json.Unmarshal([]byte(trimToDocument(output, "{[")), &doc); err == nil {
+		documents = append(documents, doc)
+	} else {
+		for _, line := range strings.Split(output, "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "{") {
+				continue
+			}
+			var item interface{}
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			documents = append(documents, item)
+		}
+		if len(documents) == 0 {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+
+	var issues []LintIssue
+	for _, document := range documents {
+		items := jsonPath(document, linter.JSONItems)
+		list, ok := items.([]interface{})
+		if !ok {
+			if items == nil {
+				continue
+			}
+			list = []interface{}{items}
+		}
+
+		for _, item := range list {
+			issues = append(issues, LintIssue{
+				Rule:     jsonString(jsonPath(item, linter.JSONFields["rule"])),
+				File:     jsonString(jsonPath(item, linter.JSONFields["file"])),
+				Line:     jsonInt(jsonPath(item, linter.JSONFields["line"])),
+				Column:   jsonInt(jsonPath(item, linter.JSONFields["col"])),
+				Severity: jsonString(jsonPath(item, linter.JSONFields["severity"])),
+				Message:  jsonString(jsonPath(item, linter.JSONFields["message"])),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// jsonPath walks a dotted path such as "location.file" through decoded JSON
+func jsonPath(value interface{}, path string) interface{} {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jsonInt(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	default:
+		return 0
+	}
+}
+
+// linterPatterns caches compiled regex-format patterns, which are fixed by config
+var linterPatterns sync.Map
+
+func compileLinterPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := linterPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	linterPatterns.Store(pattern, re)
+	return re, nil
+}
+
+func parseRegexLintOutput(linter config.LinterConfig, output string) ([]LintIssue, error) {
+	re, err := compileLinterPattern(linter.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	group := func(match []string, name string) string {
+		if idx := re.SubexpIndex(name); idx >= 0 && idx < len(match) {
+			return strings.TrimSpace(match[idx])
+		}
+		return ""
+	}
+
+	var issues []LintIssue
+	for _, line := range strings.Split(output, "\n") {
+		match := re.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		lineNumber, _ := strconv.Atoi(group(match, "line"))
+		column, _ := strconv.Atoi(group(match, "col"))
+		issues = append(issues, LintIssue{
+			Rule:     group(match, "rule"),
+			File:     group(match, "file"),
+			Line:     lineNumber,
+			Column:   column,
+			Severity: group(match, "severity"),
+			Message:  group(match, "message"),
+		})
+	}
+	return issues, nil
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/tools"
+)
+
+func TestParseLinterOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		linter config.LinterConfig
+		output string
+		want   []LintIssue
+	}{
+		{
+			name: "sarif",
+			linter: config.LinterConfig{
+				Name:     "semgrep",
+				Format:   "sarif",
+				Severity: map[string]string{"error": "high"},
+			},
+			output: `Scanning 3 files...
+{"runs":[{"results":[{"ruleId":"sql-injection","level":"error","message":{"text":"query built from input"},
+"locations":[{"physicalLocation":{"artifactLocation":{"uri":"db/users.go"},"region":{"startLine":12,"startColumn":3}}}]}]}]}`,
+			want: []LintIssue{{Linter: "semgrep", Rule: "sql-injection", File: "db/users.go", Line: 12, Column: 3, Severity: "HIGH", Message: "query built from input"}},
+		},
+		{
+			name:   "checkstyle",
+			linter: config.LinterConfig{Name: "eslint", Format: "checkstyle", DefaultSeverity: "low"},
+			output: `<?xml version="1.0" encoding="utf-8"?><checkstyle version="4.3">
+<file name="src/app.ts"><error line="4" column="7" severity="warning" message="Unexpected any" source="no-explicit-any"/></file>
+</checkstyle>`,
+			want: []LintIssue{{Linter: "eslint", Rule: "no-explicit-any", File: "src/app.ts", Line: 4, Column: 7, Severity: "LOW", Message: "Unexpected any"}},
+		},
+		{
+			name: "json document",
+			linter: config.LinterConfig{
+				Name:       "ruff",
+				Format:     "json",
+				JSONFields: map[string]string{"file": "filename", "line": "location.row", "col": "location.column", "rule": "code", "message": "message"},
+				Severity:   map[string]string{"S608": "critical"},
+			},
+			output: `[{"filename":"app.py","code":"S608","message":"Possible SQL injection","location":{"row":9,"column":5}}]`,
+			want:   []LintIssue{{Linter: "ruff", Rule: "S608", File: "app.py", Line: 9, Column: 5, Severity: "CRITICAL", Message: "Possible SQL injection"}},
+		},
+		{
+			name: "json lines",
+			linter: config.LinterConfig{
+				Name:       "staticcheck",
+				Format:     "json",
+				JSONFields: map[string]string{"file": "location.file", "line": "location.line", "rule": "code", "severity": "severity", "message": "message"},
+				Severity:   map[string]string{"error": "high"},
+			},
+			output: `{"code":"SA4006","severity":"error","location":{"file":"main.go","line":"7"},"message":"value never used"}
+{"code":"S1002","severity":"warning","location":{"file":"main.go","line":9},"message":"omit comparison"}`,
+			want: []LintIssue{
+				{Linter: "staticcheck", Rule: "SA4006", File: "main.go", Line: 7, Severity: "HIGH", Message: "value never used"},
+				{Linter: "staticcheck", Rule: "S1002", File: "main.go", Line: 9, Severity: "MEDIUM", Message: "omit comparison"},
+			},
+		},
+		{
+			name: "regex",
+			linter: config.LinterConfig{
+				Name:    "go vet",
+				Format:  "regex",
+				Pattern: `^(?:vet: )?(?P<file>[^\s:]+\.go):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?P<message>.+)$`,
+			},
+			output: "# example.com/calc\nvet: calc.go:10:2: unreachable code\r\nmain.go:3: missing return\n",
+			want: []LintIssue{
+				{Linter: "go vet", File: "calc.go", Line: 10, Column: 2, Severity: "MEDIUM", Message: "unreachable code"},
+				{Linter: "go vet", File: "main.go", Line: 3, Severity: "MEDIUM", Message: "missing return"},
+			},
+		},
+		{
+			name:   "empty output",
+			linter: config.LinterConfig{Name: "semgrep", Format: "sarif"},
+			output: "  \n",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLinterOutput(tt.linter, tt.output)
+			if err != nil {
+				t.Fatalf("parseLinterOutput: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLinterOutputErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		linter config.LinterConfig
+		output string
+	}{
+		{"unknown format", config.LinterConfig{Format: "text"}, "anything"},
+		{"invalid sarif", config.LinterConfig{Format: "sarif"}, "{not json"},
+		{"invalid checkstyle", config.LinterConfig{Format: "checkstyle"}, "<checkstyle><file"},
+		{"invalid json", config.LinterConfig{Format: "json"}, "no findings here"},
+		{"invalid pattern", config.LinterConfig{Format: "regex", Pattern: "("}, "x.go:1: y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if issues, err := parseLinterOutput(tt.linter, tt.output); err == nil {
+				t.Errorf("parseLinterOutput = %+v, want an error", issues)
+			}
+		})
+	}
+}
+
+func TestNewLintIssuesIgnoresBaseline(t *testing.T) {
+	existing := LintIssue{Linter: "go vet", File: "calc.go", Line: 4, Message: "unreachable code"}
+	tl := &SeniorTechLead{baseline: map[string]int{existing.fingerprint(): 1}}
+
+	moved := existing
+	moved.Line = 9 // An unrelated edit above shifted the old finding
+	fresh := LintIssue{Linter: "go vet", File: "calc.go", Line: 12, Message: "unreachable code"}
+
+	got := tl.newLintIssues([]LintIssue{moved, fresh})
+	if !reflect.DeepEqual(got, []LintIssue{fresh}) {
+		t.Errorf("newLintIssues = %+v, want only the second occurrence", got)
+	}
+}
+
+func TestRecordBaseline(t *testing.T) {
+	vet := config.LinterConfig{
+		Name:    "vet",
+		Command: "echo 'calc.go:3: unreachable code'",
+		Format:  "regex",
+		Pattern: `^(?P<file>[^:]+):(?P<line>\d+): (?P<message>.+)$`,
+	}
+	broken := config.LinterConfig{Name: "broken", Command: "echo '{not json'", Format: "json"}
+	failing := config.LinterConfig{Name: "failing", Command: "false", Format: "regex", Pattern: `^(?P<message>.+)$`}
+
+	tests := []struct {
+		name    string
+		linters []config.LinterConfig
+		ctx     func() context.Context
+		wantErr string
+	}{
+		{"every linter ran", []config.LinterConfig{vet}, context.Background, ""},
+		{"unparseable output", []config.LinterConfig{vet, broken}, context.Background, "no lint baseline from broken"},
+		{"linter failed without findings", []config.LinterConfig{failing, vet}, context.Background, "no lint baseline from failing"},
+		{"cancelled", []config.LinterConfig{vet}, func() context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx
+		}, "lint baseline interrupted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands := config.CommandsSection{Allowed: []string{"echo", "false"}}
+			toolSet := tools.NewToolSet(commands, config.RestrictionsSection{}, t.TempDir())
+			tl := NewSeniorTechLead(nil, toolSet, toolSet, config.WorkflowAgentConfig{Linters: tt.linters})
+
+			err := tl.RecordBaseline(tt.ctx(), "")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("RecordBaseline: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("RecordBaseline error = %v, want %q", err, tt.wantErr)
+			}
+
+			// Linters that did run still form the baseline
+			recorded := tl.baseline[LintIssue{Linter: "vet", File: "calc.go", Message: "unreachable code"}.fingerprint()]
+			if want := 1; tt.name == "cancelled" {
+				want = 0
+				if recorded != want {
+					t.Errorf("cancelled baseline recorded %d findings, want none", recorded)
+				}
+			} else if recorded != want {
+				t.Errorf("baseline has %d vet findings, want %d", recorded, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,456 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/langpack"
+)
+
+// ReviewDecision explains how the review policy reached its verdict
+type ReviewDecision struct {
+	Approved    bool           `json:"approved"`
+	FailedGates []string       `json:"failed_gates,omitempty"`
+	Gates       []GateResult   `json:"gates"`
+	Signals     []SignalResult `json:"signals,omitempty"`
+	Score       float64        `json:"score"`
+	Threshold   float64        `json:"threshold"`
+	Summary     string         `json:"summary"`
+}
+
+// GateResult is the outcome of a single hard gate
+type GateResult struct {
+	Name    string   `json:"name"`
+	Passed  bool     `json:"passed"`
+	Skipped bool     `json:"skipped,omitempty"`
+	Detail  string   `json:"detail"`
+	Issues  []string `json:"issues,omitempty"`
+}
+
+// SignalResult is a soft signal scored between 0 and 1
+type SignalResult struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Value  float64 `json:"value"`
+	Detail string  `json:"detail"`
+}
+
+// reviewEvidence collects everything the policy gates and signals look at
+type reviewEvidence struct {
+	projectType     ProjectType
+	requirementGaps []string
+	securityIssues  []SecurityIssue
+	duplications    []DuplicationIssue
+	patterns        *PatternAnalysis
+	lintIssues      []LintIssue // findings introduced by the workflow
+	changedLines    map[string]map[int]bool
+	targets         []langpack.Target // language pack per sub-project the change touched
+	testFiles       []string
+	llmResponse     string
+	commandsRun     []string
+	commandOutput   string
+}
+
+// scoreTolerance absorbs float rounding so a score equal to the threshold passes
+const scoreTolerance = 1e-9
+
+// defaultReviewPolicy is used when the tech lead config declares no policy
+func defaultReviewPolicy() config.ReviewPolicyConfig {
+	return config.ReviewPolicyConfig{
+		Gates: []string{
+			"requirements", "build", "tests",
+			"no_critical_security", "no_high_security",
+			"no_new_blocking_lint", "lint_clean_changed_lines",
+		},
+		Signals: map[string]float64{
+			"no_duplication":      0.35,
+			"pattern_consistency": 0.25,
+			"llm_verdict":         0.2,
+			"tests_added":         0.2,
+		},
+		ApprovalThreshold: 0.7,
+	}
+}
+
+func (tl *SeniorTechLead) reviewPolicy() config.ReviewPolicyConfig {
+	if tl.config.Policy != nil {
+		return *tl.config.Policy
+	}
+	return defaultReviewPolicy()
+}
+
+// evaluateReviewPolicy applies the gates and signals to the collected evidence
+func (tl *SeniorTechLead) evaluateReviewPolicy(ev *reviewEvidence) *ReviewDecision {
+	policy := tl.reviewPolicy()
+	decision := &ReviewDecision{Approved: true, Threshold: policy.ApprovalThreshold}
+
+	for _, gate := range policy.Gates {
+		result := tl.evaluateGate(gate, ev, policy)
+		decision.Gates = append(decision.Gates, result)
+		if !result.Passed {
+			decision.Approved = false
+			decision.FailedGates = append(decision.FailedGates, gate)
+		}
+	}
+
+	// Iterate in the declared order so the decision is reproducible
+	var totalWeight, weighted float64
+	for _, name := range config.ReviewSignals {
+		weight, ok := policy.Signals[name]
+		if !ok || weight == 0 {
+			continue
+		}
+		signal := tl.evaluateSignal(name, ev)
+		signal.Weight = weight
+		decision.Signals = append(decision.Signals, signal)
+		totalWeight += weight
+		weighted += weight * signal.Value
+	}
+
+	decision.Score = 1
+	if totalWeight > 0 {
+		decision.Score = weighted / totalWeight
+	}
+	scorePassed := decision.Score >= policy.ApprovalThreshold-scoreTolerance
+	if !scorePassed {
+		decision.Approved = false
+	}
+
+	switch {
+	case len(decision.FailedGates) > 0:
+		decision.Summary = fmt.Sprintf("Failed gates: %s", strings.Join(decision.FailedGates, ", "))
+	case !scorePassed:
+		decision.Summary = fmt.Sprintf("Quality score %.2f is below the approval threshold %.2f", decision.Score, policy.ApprovalThreshold)
+	default:
+		decision.Summary = fmt.Sprintf("All gates passed, quality score %.2f", decision.Score)
+	}
+
+	return decision
+}
+
+// evaluateGate runs a single hard gate
+func (tl *SeniorTechLead) evaluateGate(name string, ev *reviewEvidence, policy config.ReviewPolicyConfig) GateResult {
+	result := GateResult{Name: name, Passed: true}
+
+	switch name {
+	case "requirements":
+		result.Issues = ev.requirementGaps
+
+	case "build":
+		return tl.runGateCommands(name, tl.getBuildCommands(ev, policy), ev)
+
+	case "tests":
+		return tl.runGateCommands(name, tl.getTestCommands(ev, policy), ev)
+
+	case "no_critical_security", "no_high_security":
+		for _, issue := range ev.securityIssues {
+			if issue.Severity == "CRITICAL" || (name == "no_high_security" && issue.Severity == "HIGH") {
+				result.Issues = append(result.Issues, fmt.Sprintf("%s in %s:%d: %s", issue.Type, issue.File, issue.Line, issue.Description))
+			}
+		}
+
+	case "no_duplication":
+		for _, issue := range ev.duplications {
+			result.Issues = append(result.Issues, fmt.Sprintf("%s: %s:%d duplicates %s:%d", issue.Type, issue.CurrentFile, issue.CurrentLine, issue.ExistingFile, issue.ExistingLine))
+		}
+
+	case "pattern_consistency":
+		if ev.patterns != nil {
+			for _, deviation := range ev.patterns.Deviations {
+				result.Issues = append(result.Issues, fmt.Sprintf("%s in %s: %s", deviation.Type, deviation.File, deviation.Description))
+			}
+		}
+
+	case "no_new_blocking_lint":
+		for _, issue := range tl.filterBlockingLintIssues(ev.lintIssues) {
+			result.Issues = append(result.Issues, formatLintIssue(issue))
+		}
+
+	case "lint_clean_changed_lines":
+		for _, issue := range ev.lintIssues {
+			if onChangedLine(ev.changedLines, issue.File, issue.Line) {
+				result.Issues = append(result.Issues, formatLintIssue(issue))
+			}
+		}
+
+	default:
+		result.Skipped = true
+		result.Detail = "unknown gate"
+		return result
+	}
+
+	result.Passed = len(result.Issues) == 0
+	if result.Passed {
+		result.Detail = "passed"
+	} else {
+		result.Detail = fmt.Sprintf("%d issue(s)", len(result.Issues))
+	}
+	return result
+}
+
+// runGateCommands runs build or test commands and turns their exit status into a gate
+// result; commands the restrictions do not allow are skipped
+func (tl *SeniorTechLead) runGateCommands(name string, commands []packCommand, ev *reviewEvidence) GateResult {
+	result := GateResult{Name: name, Passed: true}
+
+	if len(commands) == 0 {
+		result.Skipped = true
+		result.Detail = fmt.Sprintf("no %s command for project type %q", name, ev.projectType)
+		return result
+	}
+
+	var details []string
+	ran := 0
+	for _, command := range commands {
+		if err := tl.restrictions.ValidateCommand(command.Command); err != nil {
+			details = append(details, fmt.Sprintf("%s not run: %v", command, err))
+			continue
+		}
+
+		output, err := runPackCommand(tl.tools, command)
+		ran++
+		ev.commandsRun = append(ev.commandsRun, command.String())
+		ev.commandOutput += fmt.Sprintf("\n=== %s ===\n%s", command, output)
+		if err != nil {
+			result.Passed = false
+			details = append(details, fmt.Sprintf("%s failed: %v", command, err))
+			result.Issues = append(result.Issues, lastLines(output, 10)...)
+			continue
+		}
+		details = append(details, fmt.Sprintf("%s passed", command))
+	}
+
+	result.Skipped = ran == 0
+	result.Detail = strings.Join(details, "; ")
+	return result
+}
+
+// evaluateSignal scores a single soft signal
+func (tl *SeniorTechLead) evaluateSignal(name string, ev *reviewEvidence) SignalResult {
+	signal := SignalResult{Name: name}
+
+	switch name {
+	case "llm_verdict":
+		signal.Value, signal.Detail = parseLLMVerdict(ev.llmResponse)
+
+	case "tests_added":
+		if len(ev.testFiles) > 0 {
+			signal.Value = 1
+			signal.Detail = fmt.Sprintf("%d test file(s) changed", len(ev.testFiles))
+		} else {
+			signal.Detail = "no test files changed"
+		}
+
+	case "no_high_security":
+		high := 0
+		for _, issue := range ev.securityIssues {
+			if issue.Severity == "CRITICAL" || issue.Severity == "HIGH" {
+				high++
+			}
+		}
+		signal.Value = boolScore(high == 0)
+		signal.Detail = fmt.Sprintf("%d high or critical security issue(s)", high)
+
+	case "no_duplication":
+		signal.Value = boolScore(len(ev.duplications) == 0)
+		signal.Detail = fmt.Sprintf("%d significant duplication(s)", len(ev.duplications))
+
+	case "pattern_consistency":
+		deviations := 0
+		if ev.patterns != nil {
+			deviations = len(ev.patterns.Deviations)
+		}
+		// Each deviation costs a quarter of the signal
+		signal.Value = 1 - 0.25*float64(deviations)
+		if signal.Value < 0 {
+			signal.Value = 0
+		}
+		signal.Detail = fmt.Sprintf("%d pattern deviation(s)", deviations)
+
+	case "no_new_lint_findings":
+		signal.Value = boolScore(len(ev.lintIssues) == 0)
+		signal.Detail = fmt.Sprintf("%d new lint finding(s)", len(ev.lintIssues))
+	}
+
+	return signal
+}
+
+// parseLLMVerdict reads the explicit FINAL_DECISION line; anything else is neutral
+func parseLLMVerdict(response string) (float64, string) {
+	lower := strings.ToLower(response)
+	switch {
+	case strings.Contains(lower, "final_decision: approved"):
+		return 1, "LLM approved"
+	case strings.Contains(lower, "final_decision: needs_revision"), strings.Contains(lower, "rejection_reason:"):
+		return 0, "LLM requested revision"
+	default:
+		return 0.5, "LLM gave no explicit verdict"
+	}
+}
+
+// rejectionReasonForGate maps a failed gate onto the structured rejection reasons
+func rejectionReasonForGate(gate string) RejectionReason {
+	switch gate {
+	case "requirements":
+		return RejectionRequirements
+	case "build", "tests":
+		return RejectionBuild
+	case "no_critical_security", "no_high_security":
+		return RejectionSecurity
+	case "no_duplication":
+		return RejectionDuplication
+	case "pattern_consistency":
+		return RejectionPatterns
+	case "no_new_blocking_lint", "lint_clean_changed_lines":
+		return RejectionLint
+	default:
+		return RejectionScore
+	}
+}
+
+// rejectionFromDecision builds the structured feedback for a rejected review
+func (tl *SeniorTechLead) rejectionFromDecision(decision *ReviewDecision, ev *reviewEvidence) string {
+	reason := RejectionScore
+	if len(decision.FailedGates) > 0 {
+		reason = rejectionReasonForGate(decision.FailedGates[0])
+	}
+
+	var issues []string
+	for _, gate := range decision.Gates {
+		if gate.Passed {
+			continue
+		}
+		issues = append(issues, fmt.Sprintf("Gate %s failed: %s", gate.Name, gate.Detail))
+		for _, issue := range gate.Issues {
+			issues = append(issues, "  "+issue)
+		}
+	}
+	if len(decision.FailedGates) == 0 {
+		issues = append(issues, decision.Summary)
+		for _, signal := range decision.Signals {
+			if signal.Value < 1 {
+				issues = append(issues, fmt.Sprintf("Signal %s scored %.2f (weight %.2f): %s", signal.Name, signal.Value, signal.Weight, signal.Detail))
+			}
+		}
+	}
+
+	var examples []string
+	switch reason {
+	case RejectionSecurity:
+		examples = []string{"Use parameterized queries", "Validate all user input", "Use environment variables for secrets"}
+	case RejectionDuplication:
+		for _, issue := range ev.duplications {
+			examples = append(examples, fmt.Sprintf("Existing implementation in %s", issue.ExistingFile))
+		}
+	case RejectionPatterns:
+		if ev.patterns != nil {
+			for _, deviation := range ev.patterns.Deviations {
+				if deviation.Expected != "" {
+					examples = append(examples, fmt.Sprintf("Expected: %s", deviation.Expected))
+				}
+			}
+		}
+	}
+
+	return tl.createRejectionFeedback(reason, issues, examples)
+}
+
+// getBuildCommands returns the policy's build command, or the build command of every touched sub-project
+func (tl *SeniorTechLead) getBuildCommands(ev *reviewEvidence, policy config.ReviewPolicyConfig) []packCommand {
+	if policy.BuildCommand != "" {
+		return []packCommand{{Dir: ".", Command: policy.BuildCommand}}
+	}
+	return packCommands(ev.targets, buildCommandOf)
+}
+
+// getTestCommands returns the policy's test command, or the test command of every touched sub-project
+func (tl *SeniorTechLead) getTestCommands(ev *reviewEvidence, policy config.ReviewPolicyConfig) []packCommand {
+	if policy.TestCommand != "" {
+		return []packCommand{{Dir: ".", Command: policy.TestCommand}}
+	}
+	return packCommands(ev.targets, testCommandOf)
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// parseChangedLines maps each file in a unified diff to the line numbers it adds or modifies
+func parseChangedLines(gitDiff string) map[string]map[int]bool {
+	changed := make(map[string]map[int]bool)
+	var current map[int]bool
+	line := 0
+
+	for _, text := range strings.Split(gitDiff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			path := strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			if path == "/dev/null" {
+				current = nil
+				continue
+			}
+			current = make(map[int]bool)
+			changed[path] = current
+		case strings.HasPrefix(text, "--- "), strings.HasPrefix(text, "diff "):
+			continue
+		case strings.HasPrefix(text, "@@"):
+			if match := hunkHeaderPattern.FindStringSubmatch(text); match != nil {
+				line, _ = strconv.Atoi(match[1])
+			}
+		case current == nil:
+			continue
+		case strings.HasPrefix(text, "+"):
+			current[line] = true
+			line++
+		case strings.HasPrefix(text, "-"):
+			// Removed lines do not exist in the new file
+		default:
+			line++
+		}
+	}
+
+	return changed
+}
+
+// changedLines maps each changed file to the lines the diff touched; untracked
+// files are new, so every line in them counts as changed
+func (ctx *ReviewContext) changedLines() map[string]map[int]bool {
+	changed := parseChangedLines(ctx.GitDiff)
+	for _, file := range ctx.UntrackedFiles {
+		if _, ok := changed[file]; !ok {
+			changed[file] = nil
+		}
+	}
+	return changed
+}
+
+// onChangedLine reports whether a finding falls on a line touched by the diff;
+// file-level findings count when the file was changed at all, and a file without
+// line information was changed in full
+func onChangedLine(changed map[string]map[int]bool, file string, line int) bool {
+	lines, ok := changed[file]
+	if !ok {
+		return false
+	}
+	return line == 0 || lines == nil || lines[line]
+}
+
+func formatLintIssue(issue LintIssue) string {
+	return fmt.Sprintf("%s %s in %s:%d: %s", issue.Linter, issue.Rule, issue.File, issue.Line, issue.Message)
+}
+
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func lastLines(output string, n int) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/config"
+)
+
+func TestDefaultReviewPolicy(t *testing.T) {
+	neutral := "The change looks reasonable."
+	approved := "FINAL_DECISION: APPROVED"
+	rejected := "FINAL_DECISION: NEEDS_REVISION"
+	changed := map[string]map[int]bool{"calc.go": {10: true}}
+	withNewFile := (&ReviewContext{
+		GitDiff:        "--- a/calc.go\n+++ b/calc.go\n@@ -10 +10 @@\n+\treturn a + b\n",
+		UntrackedFiles: []string{"stats.go"},
+	}).changedLines()
+
+	tests := []struct {
+		name        string
+		ev          reviewEvidence
+		approved    bool
+		score       float64
+		failedGates string
+	}{
+		{
+			// Sits exactly on the 0.7 threshold: no duplication, consistent patterns,
+			// a neutral LLM verdict and no test changes
+			name:     "clean change without tests",
+			ev:       reviewEvidence{llmResponse: neutral},
+			approved: true,
+			score:    0.7,
+		},
+		{
+			name:     "clean change with tests and approval",
+			ev:       reviewEvidence{llmResponse: approved, testFiles: []string{"calc_test.go"}},
+			approved: true,
+			score:    1,
+		},
+		{
+			name:     "LLM rejection outweighed by tests",
+			ev:       reviewEvidence{llmResponse: rejected, testFiles: []string{"calc_test.go"}},
+			approved: true,
+			score:    0.8,
+		},
+		{
+			name: "one pattern deviation drops below the threshold",
+			ev: reviewEvidence{
+				llmResponse: neutral,
+				patterns:    &PatternAnalysis{Deviations: []PatternDeviation{{Type: "NAMING", File: "calc.go"}}},
+			},
+			score: 0.6375,
+		},
+		{
+			name: "duplication",
+			ev: reviewEvidence{
+				llmResponse:  approved,
+				testFiles:    []string{"calc_test.go"},
+				duplications: []DuplicationIssue{{Type: "FUNCTION_DUPLICATION"}},
+			},
+			score: 0.65,
+		},
+		{
+			name: "critical security issue",
+			ev: reviewEvidence{
+				llmResponse:    approved,
+				securityIssues: []SecurityIssue{{Type: "SQL_INJECTION", Severity: "CRITICAL"}},
+			},
+			score:       0.8,
+			failedGates: "no_critical_security,no_high_security",
+		},
+		{
+			name: "high security issue",
+			ev: reviewEvidence{
+				llmResponse:    approved,
+				securityIssues: []SecurityIssue{{Type: "PATH_TRAVERSAL", Severity: "HIGH"}},
+			},
+			score:       0.8,
+			failedGates: "no_high_security",
+		},
+		{
+			name:        "requirement gaps",
+			ev:          reviewEvidence{llmResponse: approved, requirementGaps: []string{"Subtract is missing"}},
+			score:       0.8,
+			failedGates: "requirements",
+		},
+		{
+			name: "blocking lint on a changed line",
+			ev: reviewEvidence{
+				llmResponse:  approved,
+				changedLines: changed,
+				lintIssues:   []LintIssue{{Linter: "go vet", File: "calc.go", Line: 10, Severity: "HIGH"}},
+			},
+			score:       0.8,
+			failedGates: "no_new_blocking_lint,lint_clean_changed_lines",
+		},
+		{
+			name: "minor lint outside the changed lines",
+			ev: reviewEvidence{
+				llmResponse:  approved,
+				changedLines: changed,
+				lintIssues:   []LintIssue{{Linter: "go vet", File: "calc.go", Line: 3, Severity: "MEDIUM"}},
+			},
+			approved: true,
+			score:    0.8,
+		},
+		{
+			name: "minor lint in an untracked new file",
+			ev: reviewEvidence{
+				llmResponse:  approved,
+				changedLines: withNewFile,
+				lintIssues:   []LintIssue{{Linter: "go vet", File: "stats.go", Line: 3, Severity: "MEDIUM"}},
+			},
+			score:       0.8,
+			failedGates: "lint_clean_changed_lines",
+		},
+	}
+
+	tl := &SeniorTechLead{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := tt.ev
+			decision := tl.evaluateReviewPolicy(&ev)
+			if decision.Approved != tt.approved {
+				t.Errorf("approved = %v, want %v (%s)", decision.Approved, tt.approved, decision.Summary)
+			}
+			if math.Abs(decision.Score-tt.score) > 1e-9 {
+				t.Errorf("score = %v, want %v", decision.Score, tt.score)
+			}
+			if got := strings.Join(decision.FailedGates, ","); got != tt.failedGates {
+				t.Errorf("failed gates = %q, want %q", got, tt.failedGates)
+			}
+		})
+	}
+}
+
+func TestReviewPolicyThresholdBoundary(t *testing.T) {
+	// The weights total 0.6000000000000001, so a score of exactly half comes out
+	// as 0.4999999999999999 and must still meet the 0.5 threshold
+	policy := config.ReviewPolicyConfig{
+		Signals:           map[string]float64{"tests_added": 0.1, "no_duplication": 0.2, "pattern_consistency": 0.3},
+		ApprovalThreshold: 0.5,
+	}
+	tl := &SeniorTechLead{config: config.WorkflowAgentConfig{Policy: &policy}}
+
+	tests := []struct {
+		name     string
+		ev       reviewEvidence
+		approved bool
+	}{
+		{
+			name:     "at the threshold",
+			ev:       reviewEvidence{duplications: []DuplicationIssue{{}}},
+			approved: true,
+		},
+		{
+			name: "below the threshold",
+			ev: reviewEvidence{
+				duplications: []DuplicationIssue{{}},
+				patterns:     &PatternAnalysis{Deviations: make([]PatternDeviation, 1)},
+			},
+			approved: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := tt.ev
+			decision := tl.evaluateReviewPolicy(&ev)
+			if decision.Approved != tt.approved {
+				t.Errorf("approved = %v with score %v, want %v", decision.Approved, decision.Score, tt.approved)
+			}
+		})
+	}
+}
+
+func TestParseLLMVerdict(t *testing.T) {
+	tests := []struct {
+		response string
+		want     float64
+	}{
+		{"Looks good.\nFINAL_DECISION: APPROVED", 1},
+		{"final_decision: needs_revision", 0},
+		{"REJECTION_REASON: missing tests", 0},
+		{"I am not sure.", 0.5},
+	}
+
+	for _, tt := range tests {
+		if got, _ := parseLLMVerdict(tt.response); got != tt.want {
+			t.Errorf("parseLLMVerdict(%q) = %v, want %v", tt.response, got, tt.want)
+		}
+	}
+}
@@ -14,6 +14,10 @@ const (
 	ProjectTypeGo         ProjectType = "go"
 	ProjectTypeTypeScript ProjectType = "typescript" 
 	ProjectTypePython     ProjectType = "python"
+	ProjectTypeRust       ProjectType = "rust"
+	ProjectTypeMaven      ProjectType = "maven"
+	ProjectTypeGradle     ProjectType = "gradle"
+	ProjectTypeDotnet     ProjectType = "dotnet"
 )
 
 type AgentRole string
@@ -40,17 +44,25 @@ type ImplementFeatureResponse struct {
 	BuildOutput      string   `json:"build_output"`
 	NextSteps        string   `json:"next_steps"`
 	Error            string   `json:"error,omitempty"`
+	Review           *ReviewDecision `json:"review,omitempty"`
+	Findings         []ReviewFinding `json:"findings,omitempty"`
 }
 
 // Workflow Types
 type WorkflowRequest struct {
 	Description      string      `json:"description"`
-	ProjectType      ProjectType `json:"project_type"`
+	ProjectType      ProjectType `json:"project_type,omitempty"` // inferred from the working directory when empty
 	WorkingDirectory string      `json:"working_directory"`
+	SarifPath        string      `json:"sarif_path,omitempty"` // overrides workflow.sarif_path
+	Flow             string      `json:"flow,omitempty"`       // named flow from config, empty selects the default
 }
 
 type WorkflowResult struct {
 	Success          bool                        `json:"success"`
+	Flow             string                      `json:"flow,omitempty"`
+	TraceRun         string                      `json:"trace_run,omitempty"` // run ID in the debug trace, see the trace command
+	ProjectType      ProjectType                 `json:"project_type,omitempty"`
+	ProjectDetection *ProjectDetection           `json:"project_detection,omitempty"`
 	CompletedPhases  []string                    `json:"completed_phases"`
 	FilesModified    []string                    `json:"files_modified"`
 	TestsAdded       []string                    `json:"tests_added"`
@@ -61,6 +73,26 @@ type WorkflowResult struct {
 	NextSteps        string                      `json:"next_steps"`
 	Error            string                      `json:"error,omitempty"`
 	FailureReason    string                      `json:"failure_reason,omitempty"`
+	ReviewDecision   *ReviewDecision             `json:"review_decision,omitempty"`
+	Findings         []ReviewFinding             `json:"findings,omitempty"`
+	SarifFile        string                      `json:"sarif_file,omitempty"`
+	TokenUsage       map[string]TokenUsage       `json:"token_usage,omitempty"` // per agent, plus "total"
+	LLMCalls         []LLMCall                   `json:"llm_calls,omitempty"`
+}
+
+// ProjectDetection records how a workflow's project type was determined
+type ProjectDetection struct {
+	ProjectType ProjectType     `json:"project_type"`
+	Inferred    bool            `json:"inferred"`        // false when the request named the type
+	Mixed       bool            `json:"mixed,omitempty"` // stacks of more than one kind were found
+	Stacks      []DetectedStack `json:"stacks,omitempty"`
+}
+
+// DetectedStack is one language stack found in the working directory
+type DetectedStack struct {
+	ProjectType ProjectType `json:"project_type"`
+	Directory   string      `json:"directory"`
+	Evidence    []string    `json:"evidence"` // manifest files that identified the stack
 }
 
 type AgentSummary struct {
@@ -84,9 +116,23 @@ type Agent interface {
 	DocumentTask(ctx context.Context, result *WorkflowResult) error
 }
 
+// BaselineRecorder is implemented by agents that snapshot the project before the workflow changes it
+type BaselineRecorder interface {
+	RecordBaseline(ctx context.Context, workingDirectory string) error
+}
+
+// PromptRenderer is implemented by agents that can show the prompt they would
+// send for a request without calling the model
+type PromptRenderer interface {
+	RenderPrompt(ctx context.Context, req ImplementFeatureRequest) (*RenderedPrompt, error)
+}
+
 type WorkflowOrchestrator interface {
 	ExecuteWorkflow(ctx context.Context, req WorkflowRequest) (*WorkflowResult, error)
 	RegisterAgent(role AgentRole, agent Agent)
+	FlowNames() []string
+	RoleNames() []string
+	RenderPrompt(ctx context.Context, role AgentRole, req WorkflowRequest) (*RenderedPrompt, error)
 }
 
 type LLMClient interface {
@@ -97,8 +143,10 @@ type ToolSet interface {
 	ReadFile(path string) (string, error)
 	WriteFile(path, content string) error
 	ExecuteCommand(command string) (string, error)
+	ExecuteCommandIn(dir, command string) (string, error)
 	GetGitStatus() (string, error)
 	GetGitDiff() (string, error)
+	GetGitUntrackedFiles() ([]string, error)
 	GetGitLog(limit int) (string, error)
 	SetWorkingDirectory(dir string)
 	GetWorkingDirectory() string
@@ -106,6 +154,10 @@ type ToolSet interface {
 	FindFiles(pattern string, searchPath string) ([]string, error)
 	SearchForSolution(query string) (*tools.SearchResponse, error)
 	SearchForError(errorMessage string) (*tools.SearchResponse, error)
+	IndexCode() (*tools.CodeIndexStats, error)
+	SearchCode(pattern, path string, contextLines int) ([]tools.CodeMatch, error)
+	FindSymbol(name string) (*tools.SymbolLookup, error)
+	SearchSymbols(query string, limit int) ([]tools.Symbol, error)
 }
 
 type CommandRestrictions interface {
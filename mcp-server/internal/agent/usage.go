@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/debug"
+	"mcp-server/internal/metrics"
+	"mcp-server/internal/prompt"
+	"mcp-server/internal/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// LLMCall records the tokens one agent call to a model used
+type LLMCall struct {
+	Agent            string `json:"agent"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Estimated        bool   `json:"estimated,omitempty"` // the client reported no counts, so they were estimated
+	BudgetTokens     int    `json:"budget_tokens"`       // prompt budget the call was fitted to
+}
+
+// TokenUsage sums the calls of one agent, or of a whole workflow
+type TokenUsage struct {
+	Calls            int `json:"calls"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// UsageReporter is implemented by clients that report how many tokens a call used
+type UsageReporter interface {
+	GenerateWithUsage(ctx context.Context, text string) (string, prompt.Usage, error)
+}
+
+// OutputReporter is implemented by clients that switch models when one keeps
+// producing responses the agent cannot parse
+type OutputReporter interface {
+	ReportOutput(usable bool)
+}
+
+// reportOutput tells client whether the response it just gave could be used
+func reportOutput(client LLMClient, usable bool) {
+	if reporter, ok := client.(OutputReporter); ok {
+		reporter.ReportOutput(usable)
+	}
+}
+
+// UsageRecorder collects the LLM calls of a workflow run; agents running in
+// parallel share one recorder
+type UsageRecorder struct {
+	mu    sync.Mutex
+	calls []LLMCall
+}
+
+func NewUsageRecorder() *UsageRecorder {
+	return &UsageRecorder{}
+}
+
+// Record adds a call
+func (r *UsageRecorder) Record(call LLMCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+// Calls returns the recorded calls in the order they finished
+func (r *UsageRecorder) Calls() []LLMCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]LLMCall(nil), r.calls...)
+}
+
+// Totals sums the calls per agent, plus a "total" entry covering every agent
+func (r *UsageRecorder) Totals() map[string]TokenUsage {
+	totals := make(map[string]TokenUsage)
+	for _, call := range r.Calls() {
+		for _, key := range []string{call.Agent, "total"} {
+			usage := totals[key]
+			usage.Calls++
+			usage.PromptTokens += call.PromptTokens
+			usage.CompletionTokens += call.CompletionTokens
+			usage.TotalTokens += call.PromptTokens + call.CompletionTokens
+			totals[key] = usage
+		}
+	}
+	return totals
+}
+
+type usageRecorderKey struct{}
+
+// WithUsageRecorder makes LLM calls made with ctx record their token usage in recorder
+func WithUsageRecorder(ctx context.Context, recorder *UsageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, recorder)
+}
+
+func usageRecorderFrom(ctx context.Context) *UsageRecorder {
+	recorder, _ := ctx.Value(usageRecorderKey{}).(*UsageRecorder)
+	return recorder
+}
+
+// meteredClient records the token usage of an agent's calls and knows the
+// prompt budget of the agent's model
+type meteredClient struct {
+	LLMClient
+	agent  string
+	model  string
+	budget int
+}
+
+// newMeteredClient wraps client for the agent configured by cfg
+func newMeteredClient(client LLMClient, role AgentRole, cfg config.WorkflowAgentConfig) *meteredClient {
+	if metered, ok := client.(*meteredClient); ok {
+		client = metered.LLMClient
+	}
+	return &meteredClient{LLMClient: client, agent: string(role), model: cfg.Model, budget: cfg.PromptTokens()}
+}
+
+func (c *meteredClient) Generate(ctx context.Context, text string) (string, error) {
+	var (
+		response string
+		usage    prompt.Usage
+		err      error
+	)
+	ctx, otelSpan := telemetry.Start(ctx, "llm generate",
+		attribute.String(telemetry.AttrRole, c.agent),
+		attribute.String(telemetry.AttrModel, c.model),
+	)
+	start := time.Now()
+	if reporter, ok := c.LLMClient.(UsageReporter); ok {
+		response, usage, err = reporter.GenerateWithUsage(ctx, text)
+	} else {
+		response, err = c.LLMClient.Generate(ctx, text)
+	}
+	metrics.LLMCall(c.model, time.Since(start), err)
+	span := debug.SpanFromContext(ctx)
+	if err != nil {
+		span.LogLLMCall(debug.LLMExchange{Model: c.model, Prompt: text}, time.Since(start), err)
+		telemetry.End(otelSpan, err)
+		return response, err
+	}
+
+	call := LLMCall{
+		Agent:            c.agent,
+		Model:            c.model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		BudgetTokens:     c.budget,
+	}
+	if call.PromptTokens == 0 && call.CompletionTokens == 0 {
+		call.PromptTokens = prompt.EstimateTokens(c.model, text)
+		call.CompletionTokens = prompt.EstimateTokens(c.model, response)
+		call.Estimated = true
+	}
+	if recorder := usageRecorderFrom(ctx); recorder != nil {
+		recorder.Record(call)
+	}
+	otelSpan.SetAttributes(
+		attribute.Int(telemetry.AttrPromptTokens, call.PromptTokens),
+		attribute.Int(telemetry.AttrCompletionTokens, call.CompletionTokens),
+		attribute.Bool(telemetry.AttrTokensEstimated, call.Estimated),
+	)
+	telemetry.End(otelSpan, nil)
+	span.LogLLMCall(debug.LLMExchange{
+		Model:            c.model,
+		Prompt:           text,
+		Response:         response,
+		PromptTokens:     call.PromptTokens,
+		CompletionTokens: call.CompletionTokens,
+	}, time.Since(start), nil)
+	return response, nil
+}
+
+// ReportOutput passes the agent's verdict on the last response to the wrapped client
+func (c *meteredClient) ReportOutput(usable bool) {
+	reportOutput(c.LLMClient, usable)
+}
+
+// PromptBudget returns the model prompts are sent to and how many tokens they may use
+func (c *meteredClient) PromptBudget() (string, int) {
+	return c.model, c.budget
+}
+
+// promptAssembler creates an assembler sized for client's model; clients that were
+// not created by the factory get the default budget
+func promptAssembler(client LLMClient) *prompt.Assembler {
+	if budgeted, ok := client.(interface{ PromptBudget() (string, int) }); ok {
+		model, budget := budgeted.PromptBudget()
+		return prompt.NewAssembler(model, budget)
+	}
+	return prompt.NewAssembler("", config.WorkflowAgentConfig{}.PromptTokens())
+}
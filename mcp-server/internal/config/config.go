@@ -3,8 +3,14 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 // Legacy single agent config - kept for backward compatibility
@@ -28,11 +34,119 @@ type WorkflowConfig struct {
 	Agents       map[string]WorkflowAgentConfig `toml:"agents"`
 	Commands     CommandsSection                `toml:"commands"`
 	Restrictions RestrictionsSection           `toml:"restrictions"`
+	Routing      RoutingConfig                  `toml:"routing"`
+	Flows        map[string]FlowConfig          `toml:"flows"`
+	LLM          LLMSection                     `toml:"llm"`
 }
 
 type WorkflowSection struct {
 	MaxTotalIterations int `toml:"max_total_iterations"`
 	TimeoutMinutes     int `toml:"timeout_minutes"`
+	SarifPath          string `toml:"sarif_path"` // Tech Lead findings as SARIF, relative to the project
+	RoutingFile        string `toml:"routing_file"` // routing rules file, relative to this config file
+	PromptsDir         string `toml:"prompts_dir"`  // prompt templates overriding the built-in ones, relative to this config file
+	ContextTokens      int    `toml:"context_tokens"`  // default model context window (num_ctx) for agents
+	ResponseTokens     int    `toml:"response_tokens"` // default share of the window kept free for the response
+}
+
+// LLMSection controls retries, model fallback and the circuit breaker around model calls
+type LLMSection struct {
+	MaxRetries              int `toml:"max_retries"`               // retries of a transient failure before falling back
+	InitialBackoffMS        int `toml:"initial_backoff_ms"`        // first retry delay, doubled for every retry
+	MaxBackoffMS            int `toml:"max_backoff_ms"`            // cap on the retry delay
+	UnparseableLimit        int `toml:"unparseable_limit"`         // unusable responses in a row before falling back
+	FallbackCooldownSeconds int `toml:"fallback_cooldown_seconds"` // how long a missing or failing model is skipped
+	BreakerFailures         int `toml:"breaker_failures"`          // failed calls in a row that open a model's breaker
+	BreakerCooldownSeconds  int `toml:"breaker_cooldown_seconds"`  // how long an open breaker rejects calls before a trial
+
+	KeepAlive  string `toml:"keep_alive"`   // how long Ollama keeps a model loaded after a call, agents can override it
+	SkipPull   bool   `toml:"skip_pull"`    // don't pull missing models at startup
+	SkipWarmUp bool   `toml:"skip_warm_up"` // don't load the agents' models into memory at startup
+
+	Cache CacheSection `toml:"cache"`
+}
+
+// CacheSection controls the on-disk cache of model responses, off unless enabled
+type CacheSection struct {
+	Enabled  bool   `toml:"enabled"`
+	Dir      string `toml:"dir"`       // defaults to mcp-server/llm in the user cache directory
+	TTLHours int    `toml:"ttl_hours"` // how long a response is reused
+	MaxMB    int    `toml:"max_mb"`    // the least recently used responses are evicted past this
+}
+
+// LLM call defaults
+const (
+	DefaultMaxRetries              = 3
+	DefaultInitialBackoffMS        = 500
+	DefaultMaxBackoffMS            = 10000
+	DefaultUnparseableLimit        = 2
+	DefaultFallbackCooldownSeconds = 300
+	DefaultBreakerFailures         = 5
+	DefaultBreakerCooldownSeconds  = 30
+	DefaultKeepAlive               = "30m"
+	DefaultCacheTTLHours           = 24
+	DefaultCacheMaxMB              = 256
+)
+
+// DefaultPromptsDir is where prompt template overrides are looked for, next to
+// the workflow config
+const DefaultPromptsDir = "prompts"
+
+// Context window defaults; prompts are fitted into ContextTokens minus ResponseTokens
+const (
+	DefaultContextTokens  = 8192
+	DefaultResponseTokens = 2048
+)
+
+// RoutingConfig declares agent transitions and error classification; empty lists use the built-in defaults
+type RoutingConfig struct {
+	Rules         []RoutingRuleConfig  `toml:"rules" yaml:"rules"`
+	ErrorPatterns []ErrorPatternConfig `toml:"error_patterns" yaml:"error_patterns"`
+}
+
+// RoutingRuleConfig routes from one agent to another when its condition holds
+type RoutingRuleConfig struct {
+	From     string `toml:"from" yaml:"from"`
+	When     string `toml:"when" yaml:"when"` // condition expression, empty means always
+	To       string `toml:"to" yaml:"to"`
+	Priority int    `toml:"priority" yaml:"priority"`
+	Reason   string `toml:"reason" yaml:"reason"`
+}
+
+// FlowConfig is a named workflow graph: where it starts, which roles it visits,
+// how agents hand over to each other and which outcomes end it
+type FlowConfig struct {
+	Description string              `toml:"description"`
+	Start       string              `toml:"start"`
+	Roles       []string            `toml:"roles"`    // defaults to every role named by start, edges and terminals
+	Terminal    []TerminalConfig    `toml:"terminal"` // checked before edges after every agent run
+	Edges       []RoutingRuleConfig `toml:"edges"`    // the default flow falls back to the routing rules
+	Parallel    []ParallelConfig    `toml:"parallel"` // fan-out nodes that edges and terminals address by name
+}
+
+// ParallelConfig is a fan-out/fan-in node: its roles run concurrently, each on
+// its own copy of the working tree, and their changes and results are merged
+// before routing continues
+type ParallelConfig struct {
+	Name  string   `toml:"name"`
+	Roles []string `toml:"roles"`
+	Join  string   `toml:"join"` // all (default): succeeds when every role succeeds; any: when one does
+}
+
+// TerminalConfig ends a flow when role finishes and its condition holds
+type TerminalConfig struct {
+	Role    string `toml:"role"`
+	When    string `toml:"when"`    // condition expression, empty means always
+	Outcome string `toml:"outcome"` // success or failure
+	Reason  string `toml:"reason"`
+}
+
+// ErrorPatternConfig classifies agent errors for routing conditions
+type ErrorPatternConfig struct {
+	Pattern     string   `toml:"pattern" yaml:"pattern"`
+	Category    string   `toml:"category" yaml:"category"`
+	Severity    int      `toml:"severity" yaml:"severity"` // 1=low, 2=medium, 3=high, 4=critical
+	Suggestions []string `toml:"suggestions" yaml:"suggestions"`
 }
 
 type WorkflowAgentConfig struct {
@@ -41,6 +155,76 @@ type WorkflowAgentConfig struct {
 	MaxIterations int      `toml:"max_iterations"`
 	PerAgentTimeoutMinutes int `toml:"per_agent_timeout_minutes"`
 	Tools         []string `toml:"tools"`
+	Linters       []LinterConfig `toml:"linters"`
+	Policy        *ReviewPolicyConfig `toml:"policy"`
+	Prompt        string   `toml:"prompt"`  // instructions for custom roles backed by the prompt-driven agent
+	Context       []string `toml:"context"` // files the prompt-driven agent reads into its prompt
+	ContextTokens  int     `toml:"context_tokens"`  // model context window (num_ctx), defaults to the workflow's
+	ResponseTokens int     `toml:"response_tokens"` // kept free for the response, defaults to the workflow's
+	FallbackModels []string `toml:"fallback_models"` // tried in order when the model is missing, out of memory or unusable
+	KeepAlive      string   `toml:"keep_alive"`      // overrides the [llm] keep_alive for this agent's models
+}
+
+// Models is the agent's model followed by its fallbacks
+func (a WorkflowAgentConfig) Models() []string {
+	return append([]string{a.Model}, a.FallbackModels...)
+}
+
+// PromptTokens is how many tokens of the context window a prompt may use
+func (a WorkflowAgentConfig) PromptTokens() int {
+	contextTokens, responseTokens := a.ContextTokens, a.ResponseTokens
+	if contextTokens <= 0 {
+		contextTokens = DefaultContextTokens
+	}
+	if responseTokens <= 0 {
+		responseTokens = DefaultResponseTokens
+	}
+	return contextTokens - responseTokens
+}
+
+// BuiltinRoles lists the roles with dedicated agent implementations; any other role needs a prompt
+var BuiltinRoles = []string{"engineering_manager", "senior_engineer", "senior_qa", "senior_tech_lead"}
+
+// IsBuiltinRole reports whether role has a dedicated agent implementation
+func IsBuiltinRole(role string) bool {
+	return containsString(BuiltinRoles, role)
+}
+
+// ReviewPolicyConfig decides Tech Lead approval from hard gates and weighted soft signals
+type ReviewPolicyConfig struct {
+	Gates             []string           `toml:"gates"`              // every gate must pass
+	Signals           map[string]float64 `toml:"signals"`            // signal name -> weight
+	ApprovalThreshold float64            `toml:"approval_threshold"` // minimum weighted signal score, 0..1
+	BuildCommand      string             `toml:"build_command"`      // overrides the per-project-type default
+	TestCommand       string             `toml:"test_command"`       // overrides the per-project-type default
+}
+
+// ReviewGates lists the gate names a review policy may use
+var ReviewGates = []string{
+	"requirements", "build", "tests",
+	"no_critical_security", "no_high_security",
+	"no_duplication", "pattern_consistency",
+	"lint_clean_changed_lines", "no_new_blocking_lint",
+}
+
+// ReviewSignals lists the soft signal names a review policy may weight
+var ReviewSignals = []string{
+	"llm_verdict", "tests_added",
+	"no_high_security", "no_duplication", "pattern_consistency",
+	"no_new_lint_findings",
+}
+
+// LinterConfig describes an external linter run during the Tech Lead review
+type LinterConfig struct {
+	Name            string            `toml:"name"`
+	Command         string            `toml:"command"`
+	Format          string            `toml:"format"`           // sarif, checkstyle, json or regex
+	Detect          []string          `toml:"detect"`           // files whose presence enables the linter
+	Pattern         string            `toml:"pattern"`          // regex format: named groups file, line, col, rule, severity, message
+	JSONItems       string            `toml:"json_items"`       // json format: dotted path to the findings array
+	JSONFields      map[string]string `toml:"json_fields"`      // json format: file/line/col/rule/severity/message -> dotted path
+	Severity        map[string]string `toml:"severity"`         // rule or linter level -> CRITICAL/HIGH/MEDIUM/LOW
+	DefaultSeverity string            `toml:"default_severity"` // used when no severity mapping matches
 }
 
 type CommandsSection struct {
@@ -73,6 +257,24 @@ func LoadConfig(path string) (*AgentConfig, error) {
 	return &cfg, nil
 }
 
+// WorkflowConfig returns a workflow configuration holding just the single agent,
+// with the default context and [llm] settings, so it gets the workflow agents' client
+func (c *AgentConfig) WorkflowConfig() *WorkflowConfig {
+	agentCfg := WorkflowAgentConfig{
+		Role:                   c.Agent.Role,
+		Model:                  c.Agent.Model,
+		PerAgentTimeoutMinutes: c.Agent.PerAgentTimeoutMinutes,
+	}
+	cfg := &WorkflowConfig{
+		Agents:       map[string]WorkflowAgentConfig{agentCfg.Role: agentCfg},
+		Commands:     c.Commands,
+		Restrictions: c.Restrictions,
+	}
+	cfg.applyContextDefaults()
+	cfg.applyLLMDefaults()
+	return cfg
+}
+
 // LoadWorkflowConfig loads the multi-agent workflow configuration
 func LoadWorkflowConfig(path string) (*WorkflowConfig, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -84,6 +286,24 @@ func LoadWorkflowConfig(path string) (*WorkflowConfig, error) {
 		return nil, fmt.Errorf("failed to decode workflow config file: %w", err)
 	}
 
+	// Routing may live in its own file next to the workflow config
+	if cfg.Workflow.RoutingFile != "" {
+		routingPath := cfg.Workflow.RoutingFile
+		if !filepath.IsAbs(routingPath) {
+			routingPath = filepath.Join(filepath.Dir(path), routingPath)
+		}
+		if err := loadRoutingFile(routingPath, &cfg.Routing); err != nil {
+			return nil, fmt.Errorf("failed to decode routing file %s: %w", routingPath, err)
+		}
+	}
+
+	if cfg.Workflow.PromptsDir == "" {
+		cfg.Workflow.PromptsDir = DefaultPromptsDir
+	}
+	if !filepath.IsAbs(cfg.Workflow.PromptsDir) {
+		cfg.Workflow.PromptsDir = filepath.Join(filepath.Dir(path), cfg.Workflow.PromptsDir)
+	}
+
 	// Validate configuration
 	if err := cfg.validateWorkflow(); err != nil {
 		return nil, fmt.Errorf("invalid workflow configuration: %w", err)
@@ -92,6 +312,21 @@ func LoadWorkflowConfig(path string) (*WorkflowConfig, error) {
 	return &cfg, nil
 }
 
+// loadRoutingFile decodes a routing file as YAML when its extension says so, TOML otherwise
+func loadRoutingFile(path string, routing *RoutingConfig) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return yaml.Unmarshal(data, routing)
+	default:
+		_, err := toml.DecodeFile(path, routing)
+		return err
+	}
+}
+
 func (cfg *AgentConfig) validate() error {
 	if cfg.Agent.Role == "" {
 		return fmt.Errorf("agent role is required")
@@ -143,7 +378,7 @@ func getDefaultConfig() *AgentConfig {
 }
 
 func getDefaultWorkflowConfig() *WorkflowConfig {
-	return &WorkflowConfig{
+	cfg := &WorkflowConfig{
 		Workflow: WorkflowSection{
 			MaxTotalIterations: 7,
 			TimeoutMinutes:     15,
@@ -203,6 +438,101 @@ func getDefaultWorkflowConfig() *WorkflowConfig {
 			},
 		},
 	}
+	cfg.applyContextDefaults()
+	cfg.applyLLMDefaults()
+	return cfg
+}
+
+// applyContextDefaults fills in context window sizes agents and the workflow leave out
+func (cfg *WorkflowConfig) applyContextDefaults() {
+	if cfg.Workflow.ContextTokens <= 0 {
+		cfg.Workflow.ContextTokens = DefaultContextTokens
+	}
+	if cfg.Workflow.ResponseTokens <= 0 {
+		cfg.Workflow.ResponseTokens = DefaultResponseTokens
+	}
+	for name, agentCfg := range cfg.Agents {
+		if agentCfg.ContextTokens <= 0 {
+			agentCfg.ContextTokens = cfg.Workflow.ContextTokens
+		}
+		if agentCfg.ResponseTokens <= 0 {
+			agentCfg.ResponseTokens = cfg.Workflow.ResponseTokens
+		}
+		cfg.Agents[name] = agentCfg
+	}
+}
+
+// applyLLMDefaults fills in the retry, fallback and breaker settings left out
+func (cfg *WorkflowConfig) applyLLMDefaults() {
+	llm := &cfg.LLM
+	if llm.MaxRetries < 0 {
+		llm.MaxRetries = 0
+	} else if llm.MaxRetries == 0 {
+		llm.MaxRetries = DefaultMaxRetries
+	}
+	if llm.InitialBackoffMS <= 0 {
+		llm.InitialBackoffMS = DefaultInitialBackoffMS
+	}
+	if llm.MaxBackoffMS <= 0 {
+		llm.MaxBackoffMS = DefaultMaxBackoffMS
+	}
+	if llm.UnparseableLimit <= 0 {
+		llm.UnparseableLimit = DefaultUnparseableLimit
+	}
+	if llm.FallbackCooldownSeconds <= 0 {
+		llm.FallbackCooldownSeconds = DefaultFallbackCooldownSeconds
+	}
+	if llm.BreakerFailures <= 0 {
+		llm.BreakerFailures = DefaultBreakerFailures
+	}
+	if llm.BreakerCooldownSeconds <= 0 {
+		llm.BreakerCooldownSeconds = DefaultBreakerCooldownSeconds
+	}
+	if llm.KeepAlive == "" {
+		llm.KeepAlive = DefaultKeepAlive
+	}
+	if llm.Cache.Dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			base = os.TempDir()
+		}
+		llm.Cache.Dir = filepath.Join(base, "mcp-server", "llm")
+	}
+	if llm.Cache.TTLHours <= 0 {
+		llm.Cache.TTLHours = DefaultCacheTTLHours
+	}
+	if llm.Cache.MaxMB <= 0 {
+		llm.Cache.MaxMB = DefaultCacheMaxMB
+	}
+}
+
+// KeepAlive is how long Ollama should keep the agent's models loaded, the
+// workflow's keep_alive unless the agent sets its own
+func (cfg *WorkflowConfig) KeepAlive(agentCfg WorkflowAgentConfig) time.Duration {
+	keepAlive := agentCfg.KeepAlive
+	if keepAlive == "" {
+		keepAlive = cfg.LLM.KeepAlive
+	}
+	d, err := ParseKeepAlive(keepAlive)
+	if err != nil {
+		// Rejected when the config was loaded
+		d, _ = ParseKeepAlive(DefaultKeepAlive)
+	}
+	return d
+}
+
+// ParseKeepAlive parses a keep_alive the way Ollama does: a duration such as
+// "30m" or a number of seconds, where a negative value keeps the model loaded
+// until the server stops
+func ParseKeepAlive(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid keep_alive %q: want a duration such as 30m or a number of seconds", value)
+	}
+	return d, nil
 }
 
 func (cfg *WorkflowConfig) validateWorkflow() error {
@@ -218,6 +548,13 @@ func (cfg *WorkflowConfig) validateWorkflow() error {
 		return fmt.Errorf("at least one agent configuration is required")
 	}
 
+	cfg.applyContextDefaults()
+	cfg.applyLLMDefaults()
+
+	if _, err := ParseKeepAlive(cfg.LLM.KeepAlive); err != nil {
+		return fmt.Errorf("llm: %w", err)
+	}
+
 	// Validate each agent config
 	for name, agentCfg := range cfg.Agents {
 		if agentCfg.Role == "" {
@@ -228,6 +565,18 @@ func (cfg *WorkflowConfig) validateWorkflow() error {
 			return fmt.Errorf("agent %s model is required", name)
 		}
 
+		for _, model := range agentCfg.FallbackModels {
+			if model == "" || model == agentCfg.Model {
+				return fmt.Errorf("agent %s fallback_models must name models other than its model", name)
+			}
+		}
+
+		if agentCfg.KeepAlive != "" {
+			if _, err := ParseKeepAlive(agentCfg.KeepAlive); err != nil {
+				return fmt.Errorf("agent %s: %w", name, err)
+			}
+		}
+
 		if agentCfg.MaxIterations <= 0 {
 			return fmt.Errorf("agent %s max_iterations must be positive", name)
 		}
@@ -236,6 +585,33 @@ func (cfg *WorkflowConfig) validateWorkflow() error {
 			agentCfg.PerAgentTimeoutMinutes = 5 // default
 			cfg.Agents[name] = agentCfg
 		}
+
+		for i := range agentCfg.Linters {
+			if err := agentCfg.Linters[i].validate(); err != nil {
+				return fmt.Errorf("agent %s: %w", name, err)
+			}
+		}
+
+		if agentCfg.Policy != nil {
+			if err := agentCfg.Policy.validate(); err != nil {
+				return fmt.Errorf("agent %s policy: %w", name, err)
+			}
+		}
+
+		if agentCfg.ResponseTokens >= agentCfg.ContextTokens {
+			return fmt.Errorf("agent %s response_tokens (%d) must be less than context_tokens (%d)", name, agentCfg.ResponseTokens, agentCfg.ContextTokens)
+		}
+
+		if !IsBuiltinRole(agentCfg.Role) && strings.TrimSpace(agentCfg.Prompt) == "" {
+			return fmt.Errorf("agent %s has custom role %s and needs a prompt", name, agentCfg.Role)
+		}
+	}
+
+	for name, flowCfg := range cfg.Flows {
+		if err := flowCfg.validate(); err != nil {
+			return fmt.Errorf("flow %s: %w", name, err)
+		}
+		cfg.Flows[name] = flowCfg
 	}
 
 	if len(cfg.Commands.Allowed) == 0 {
@@ -243,4 +619,128 @@ func (cfg *WorkflowConfig) validateWorkflow() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+var linterSeverities = map[string]bool{"CRITICAL": true, "HIGH": true, "MEDIUM": true, "LOW": true}
+
+func (lc *LinterConfig) validate() error {
+	if lc.Name == "" {
+		return fmt.Errorf("linter name is required")
+	}
+
+	if lc.Command == "" {
+		return fmt.Errorf("linter %s command is required", lc.Name)
+	}
+
+	lc.Format = strings.ToLower(lc.Format)
+	switch lc.Format {
+	case "sarif", "checkstyle":
+	case "json":
+		if lc.JSONFields["file"] == "" || lc.JSONFields["message"] == "" {
+			return fmt.Errorf("linter %s json_fields must map at least file and message", lc.Name)
+		}
+	case "regex":
+		re, err := regexp.Compile(lc.Pattern)
+		if err != nil {
+			return fmt.Errorf("linter %s pattern is invalid: %w", lc.Name, err)
+		}
+		if re.SubexpIndex("file") < 0 || re.SubexpIndex("message") < 0 {
+			return fmt.Errorf("linter %s pattern needs named groups file and message", lc.Name)
+		}
+	default:
+		return fmt.Errorf("linter %s format must be sarif, checkstyle, json or regex, got %q", lc.Name, lc.Format)
+	}
+
+	lc.DefaultSeverity = strings.ToUpper(lc.DefaultSeverity)
+	if lc.DefaultSeverity == "" {
+		lc.DefaultSeverity = "MEDIUM" // default
+	}
+	if !linterSeverities[lc.DefaultSeverity] {
+		return fmt.Errorf("linter %s default_severity %q is not one of CRITICAL, HIGH, MEDIUM, LOW", lc.Name, lc.DefaultSeverity)
+	}
+
+	for key, severity := range lc.Severity {
+		severity = strings.ToUpper(severity)
+		if !linterSeverities[severity] {
+			return fmt.Errorf("linter %s severity for %q must be one of CRITICAL, HIGH, MEDIUM, LOW", lc.Name, key)
+		}
+		lc.Severity[key] = severity
+	}
+
+	return nil
+}
+
+func (fc *FlowConfig) validate() error {
+	if fc.Start == "" {
+		return fmt.Errorf("start role is required")
+	}
+
+	if len(fc.Terminal) == 0 {
+		return fmt.Errorf("at least one terminal condition is required")
+	}
+
+	for i := range fc.Terminal {
+		terminal := &fc.Terminal[i]
+		if terminal.Role == "" {
+			return fmt.Errorf("terminal %d role is required", i+1)
+		}
+		terminal.Outcome = strings.ToLower(terminal.Outcome)
+		if terminal.Outcome == "" {
+			terminal.Outcome = "success" // default
+		}
+		if terminal.Outcome != "success" && terminal.Outcome != "failure" {
+			return fmt.Errorf("terminal %d outcome must be success or failure, got %q", i+1, terminal.Outcome)
+		}
+	}
+
+	for i := range fc.Parallel {
+		node := &fc.Parallel[i]
+		if node.Name == "" {
+			return fmt.Errorf("parallel node %d name is required", i+1)
+		}
+		if len(node.Roles) < 2 {
+			return fmt.Errorf("parallel node %s needs at least two roles", node.Name)
+		}
+		node.Join = strings.ToLower(node.Join)
+		if node.Join == "" {
+			node.Join = "all" // default
+		}
+		if node.Join != "all" && node.Join != "any" {
+			return fmt.Errorf("parallel node %s join must be all or any, got %q", node.Name, node.Join)
+		}
+	}
+
+	return nil
+}
+
+func (pc *ReviewPolicyConfig) validate() error {
+	for _, gate := range pc.Gates {
+		if !containsString(ReviewGates, gate) {
+			return fmt.Errorf("unknown gate %q (known: %s)", gate, strings.Join(ReviewGates, ", "))
+		}
+	}
+
+	for signal, weight := range pc.Signals {
+		if !containsString(ReviewSignals, signal) {
+			return fmt.Errorf("unknown signal %q (known: %s)", signal, strings.Join(ReviewSignals, ", "))
+		}
+		if weight < 0 {
+			return fmt.Errorf("signal %s weight must not be negative", signal)
+		}
+	}
+
+	if pc.ApprovalThreshold < 0 || pc.ApprovalThreshold > 1 {
+		return fmt.Errorf("approval_threshold must be between 0 and 1")
+	}
+
+	return nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadRoutingFile(t *testing.T) {
+	const tomlRouting = `
+[[rules]]
+from = "engineering_manager"
+when = 'success'
+to = "senior_engineer"
+priority = 10
+reason = "Plan approved"
+
+[[error_patterns]]
+pattern = '(?i)undefined: \w+'
+category = "undefined_symbol"
+severity = 3
+suggestions = ["Declare the symbol"]
+`
+	const yamlRouting = `
+rules:
+  - from: engineering_manager
+    when: success
+    to: senior_engineer
+    priority: 10
+    reason: Plan approved
+error_patterns:
+  - pattern: '(?i)undefined: \w+'
+    category: undefined_symbol
+    severity: 3
+    suggestions: [Declare the symbol]
+`
+	want := RoutingConfig{
+		Rules: []RoutingRuleConfig{{From: "engineering_manager", When: "success", To: "senior_engineer", Priority: 10, Reason: "Plan approved"}},
+		ErrorPatterns: []ErrorPatternConfig{{
+			Pattern: `(?i)undefined: \w+`, Category: "undefined_symbol", Severity: 3, Suggestions: []string{"Declare the symbol"},
+		}},
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"routing.toml": tomlRouting, "routing.yaml": yamlRouting, "routing.yml": yamlRouting} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var got RoutingConfig
+			if err := loadRoutingFile(path, &got); err != nil {
+				t.Fatalf("loadRoutingFile: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("routing = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestGetTelemetryConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantExporter string
+		wantEndpoint string
+		wantFile     string
+	}{
+		{"disabled by default", nil, "none", "", "/tmp/agent-traces.jsonl"},
+		{"otlp endpoint", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"}, "otlp", "http://collector:4318", "/tmp/agent-traces.jsonl"},
+		{
+			name:         "traces endpoint wins",
+			env:          map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces:4318/v1/traces"},
+			wantExporter: "otlp",
+			wantEndpoint: "http://traces:4318/v1/traces",
+			wantFile:     "/tmp/agent-traces.jsonl",
+		},
+		{"console is stdout", map[string]string{"OTEL_TRACES_EXPORTER": "console"}, "stdout", "", "/tmp/agent-traces.jsonl"},
+		{"file", map[string]string{"OTEL_TRACES_EXPORTER": "file", "OTEL_TRACES_FILE": "/var/log/spans.jsonl"}, "file", "", "/var/log/spans.jsonl"},
+		{
+			name:         "exporter overrides an endpoint",
+			env:          map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "OTEL_TRACES_EXPORTER": "none"},
+			wantExporter: "none",
+			wantEndpoint: "http://collector:4318",
+			wantFile:     "/tmp/agent-traces.jsonl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_TRACES_EXPORTER", "OTEL_TRACES_FILE"} {
+				t.Setenv(key, tt.env[key])
+			}
+			cfg := GetTelemetryConfig()
+			if cfg.Exporter != tt.wantExporter || cfg.Endpoint != tt.wantEndpoint || cfg.File != tt.wantFile {
+				t.Errorf("exporter, endpoint, file = %q, %q, %q, want %q, %q, %q",
+					cfg.Exporter, cfg.Endpoint, cfg.File, tt.wantExporter, tt.wantEndpoint, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestAgentConfigWorkflowConfig(t *testing.T) {
+	cfg := getDefaultConfig()
+	cfg.Agent.PerAgentTimeoutMinutes = 9
+
+	workflow := cfg.WorkflowConfig()
+	agentCfg, ok := workflow.Agents[cfg.Agent.Role]
+	if !ok || len(workflow.Agents) != 1 {
+		t.Fatalf("agents = %v, want just %s", workflow.Agents, cfg.Agent.Role)
+	}
+	if agentCfg.Model != cfg.Agent.Model || agentCfg.PerAgentTimeoutMinutes != 9 {
+		t.Errorf("agent model, timeout = %q, %d, want %q, 9", agentCfg.Model, agentCfg.PerAgentTimeoutMinutes, cfg.Agent.Model)
+	}
+	if agentCfg.ContextTokens != DefaultContextTokens {
+		t.Errorf("agent context tokens = %d, want %d", agentCfg.ContextTokens, DefaultContextTokens)
+	}
+	// The single agent's client needs the same retry and breaker defaults as the workflow's
+	if workflow.LLM.MaxRetries != DefaultMaxRetries || workflow.LLM.BreakerFailures != DefaultBreakerFailures {
+		t.Errorf("llm retries, breaker failures = %d, %d, want defaults", workflow.LLM.MaxRetries, workflow.LLM.BreakerFailures)
+	}
+}
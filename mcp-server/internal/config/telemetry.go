@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// TelemetryConfig selects where OpenTelemetry spans are exported
+type TelemetryConfig struct {
+	Exporter    string  `toml:"exporter"` // otlp, stdout, file or none
+	Endpoint    string  `toml:"endpoint"` // OTLP/HTTP endpoint, e.g. http://localhost:4318
+	File        string  `toml:"file"`     // where the file exporter writes
+	ServiceName string  `toml:"service_name"`
+	SampleRatio float64 `toml:"sample_ratio"` // share of workflows traced, 1 traces all
+}
+
+// GetTelemetryConfig returns telemetry configuration from the standard OTEL_*
+// environment variables and defaults; exporting is off unless an exporter or
+// endpoint is set
+func GetTelemetryConfig() TelemetryConfig {
+	config := TelemetryConfig{
+		Exporter:    "none",
+		File:        "/tmp/agent-traces.jsonl",
+		ServiceName: "mcp-server",
+		SampleRatio: 1,
+	}
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+		config.Exporter = "otlp"
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+		config.Exporter = "otlp"
+	}
+
+	// console is the name the OpenTelemetry specification uses for stdout
+	if exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter != "" {
+		if exporter == "console" {
+			exporter = "stdout"
+		}
+		config.Exporter = exporter
+	}
+
+	if file := os.Getenv("OTEL_TRACES_FILE"); file != "" {
+		config.File = file
+	}
+
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		config.ServiceName = name
+	}
+
+	if ratio := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); ratio != "" {
+		if value, err := strconv.ParseFloat(ratio, 64); err == nil && value >= 0 && value <= 1 {
+			config.SampleRatio = value
+		}
+	}
+
+	return config
+}
@@ -1,294 +1,286 @@
 package debug
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
-// DebugLogger provides comprehensive debugging capabilities for agents
+const (
+	traceFile       = "trace.jsonl"
+	traceFilePrefix = "trace-"
+	// traceFiles is how many files, the current one included, share the size limit
+	traceFiles = 5
+)
+
+// DebugLogger writes one JSON event per line, rotating files so that traces take
+// at most maxBytes of disk
 type DebugLogger struct {
-	enabled     bool
-	baseDir     string
-	currentFile string
+	mu       sync.Mutex // serialises writes and rotation from concurrent agents
+	enabled  bool
+	baseDir  string
+	maxBytes int64
 }
 
 // AgentThought represents what an agent is thinking about
 type AgentThought struct {
-	Timestamp   time.Time `json:"timestamp"`
-	Agent       string    `json:"agent"`
-	Phase       string    `json:"phase"`
-	Task        string    `json:"task"`
-	Thinking    string    `json:"thinking"`
-	Context     string    `json:"context"`
-	PlanOfAction string   `json:"plan_of_action"`
+	Timestamp    time.Time `json:"timestamp"`
+	Agent        string    `json:"agent"`
+	Phase        string    `json:"phase"`
+	Task         string    `json:"task"`
+	Thinking     string    `json:"thinking"`
+	Context      string    `json:"context"`
+	PlanOfAction string    `json:"plan_of_action"`
 }
 
 // AgentAction represents what an agent actually does
 type AgentAction struct {
-	Timestamp   time.Time `json:"timestamp"`
-	Agent       string    `json:"agent"`
-	ActionType  string    `json:"action_type"`
-	Command     string    `json:"command,omitempty"`
-	FilePath    string    `json:"file_path,omitempty"`
-	Content     string    `json:"content,omitempty"`
-	Result      string    `json:"result"`
-	Success     bool      `json:"success"`
-	Error       string    `json:"error,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Agent      string    `json:"agent"`
+	ActionType string    `json:"action_type"`
+	Command    string    `json:"command,omitempty"`
+	FilePath   string    `json:"file_path,omitempty"`
+	Content    string    `json:"content,omitempty"`
+	Result     string    `json:"result"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
 }
 
 // AgentDecision represents why an agent made a choice
 type AgentDecision struct {
-	Timestamp   time.Time `json:"timestamp"`
-	Agent       string    `json:"agent"`
-	Decision    string    `json:"decision"`
-	Reasoning   string    `json:"reasoning"`
+	Timestamp    time.Time `json:"timestamp"`
+	Agent        string    `json:"agent"`
+	Decision     string    `json:"decision"`
+	Reasoning    string    `json:"reasoning"`
 	Alternatives []string  `json:"alternatives"`
-	Confidence  int       `json:"confidence"` // 1-10 scale
+	Confidence   int       `json:"confidence"` // 1-10 scale
+}
+
+// LLMExchange is one prompt sent to a model and the response it gave
+type LLMExchange struct {
+	Model            string `json:"model"`
+	Prompt           string `json:"prompt"`
+	Response         string `json:"response"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+}
+
+// Event types
+const (
+	EventSpanStart  = "span_start"
+	EventSpanEnd    = "span_end"
+	EventLLMCall    = "llm_call"
+	EventToolCall   = "tool_call"
+	EventThought    = "thought"
+	EventAction     = "action"
+	EventDecision   = "decision"
+	EventError      = "error"
+	EventRecovery   = "recovery"
+	EventTransition = "transition"
+	EventState      = "state"
+)
+
+// Event is one line of a trace file
+type Event struct {
+	Time       time.Time         `json:"time"`
+	Run        string            `json:"run,omitempty"`
+	Span       string            `json:"span,omitempty"`
+	Parent     string            `json:"parent,omitempty"`
+	Type       string            `json:"type"`
+	Kind       string            `json:"kind,omitempty"` // span kind: run, agent, parallel
+	Name       string            `json:"name,omitempty"`
+	Agent      string            `json:"agent,omitempty"`
+	DurationMS int64             `json:"duration_ms,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Message    string            `json:"message,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	LLM        *LLMExchange      `json:"llm,omitempty"`
+	Thought    *AgentThought     `json:"thought,omitempty"`
+	Action     *AgentAction      `json:"action,omitempty"`
+	Decision   *AgentDecision    `json:"decision,omitempty"`
+	State      json.RawMessage   `json:"state,omitempty"`
 }
 
-// NewDebugLogger creates a new debug logger
-func NewDebugLogger(enabled bool, baseDir string) *DebugLogger {
+// NewDebugLogger creates a logger writing to baseDir that keeps at most maxLogMB
+// of traces; zero or less means 10MB
+func NewDebugLogger(enabled bool, baseDir string, maxLogMB int) *DebugLogger {
 	if baseDir == "" {
 		baseDir = "/tmp/agent-debug"
 	}
-	
+	if maxLogMB <= 0 {
+		maxLogMB = 10
+	}
+
 	// Create debug directory if it doesn't exist
 	if enabled {
 		os.MkdirAll(baseDir, 0755)
 	}
-	
+
 	return &DebugLogger{
-		enabled: enabled,
-		baseDir: baseDir,
+		enabled:  enabled,
+		baseDir:  baseDir,
+		maxBytes: int64(maxLogMB) << 20,
 	}
 }
 
-// StartNewSession creates a new debug session file
-func (dl *DebugLogger) StartNewSession(sessionID string) error {
-	if !dl.enabled {
-		return nil
+// StartRun opens the root span of a workflow run; the returned context carries it
+// to every span and event of the run. Disabled loggers return ctx unchanged.
+func (dl *DebugLogger) StartRun(ctx context.Context, name string, attributes map[string]string) (context.Context, *Span) {
+	if !dl.IsEnabled() {
+		return ctx, nil
 	}
-	
-	timestamp := time.Now().Format("20060102-150405")
-	filename := fmt.Sprintf("session-%s-%s.log", sessionID, timestamp)
-	dl.currentFile = filepath.Join(dl.baseDir, filename)
-	
-	// Write session header
-	header := fmt.Sprintf(`
-=== AGENT DEBUGGING SESSION ===
-Session ID: %s
-Started: %s
-File: %s
-
-`, sessionID, time.Now().Format(time.RFC3339), dl.currentFile)
-	
-	return dl.writeToFile(header)
+	run := newID()
+	span := &Span{logger: dl, run: run, id: run, kind: "run", name: name, start: time.Now()}
+	span.emit(Event{Type: EventSpanStart, Kind: span.kind, Name: name, Attributes: attributes})
+	return context.WithValue(ctx, spanKey{}, span), span
 }
 
 // LogThought logs what an agent is thinking
-func (dl *DebugLogger) LogThought(thought AgentThought) error {
-	if !dl.enabled {
-		return nil
-	}
-	
-	entry := fmt.Sprintf(`
-[%s] 🧠 AGENT THOUGHT - %s
-Phase: %s
-Task: %s
-Context: %s
-
-💭 Thinking:
-%s
-
-📋 Plan of Action:
-%s
-
----
-
-`, thought.Timestamp.Format("15:04:05"), thought.Agent, thought.Phase, thought.Task, thought.Context, thought.Thinking, thought.PlanOfAction)
-	
-	return dl.writeToFile(entry)
+func (dl *DebugLogger) LogThought(ctx context.Context, thought AgentThought) error {
+	return dl.log(ctx, Event{Type: EventThought, Agent: thought.Agent, Thought: &thought})
 }
 
 // LogAction logs what an agent actually does
-func (dl *DebugLogger) LogAction(action AgentAction) error {
-	if !dl.enabled {
-		return nil
-	}
-	
-	status := "✅ SUCCESS"
-	if !action.Success {
-		status = "❌ FAILED"
-	}
-	
-	entry := fmt.Sprintf(`
-[%s] 🚀 AGENT ACTION - %s %s
-Type: %s
-`, action.Timestamp.Format("15:04:05"), action.Agent, status, action.ActionType)
-	
-	if action.Command != "" {
-		entry += fmt.Sprintf("Command: %s\n", action.Command)
-	}
-	if action.FilePath != "" {
-		entry += fmt.Sprintf("File: %s\n", action.FilePath)
-	}
-	if action.Content != "" {
-		// Truncate very long content
-		content := action.Content
-		if len(content) > 500 {
-			content = content[:500] + "... [truncated]"
-		}
-		entry += fmt.Sprintf("Content:\n%s\n", content)
-	}
-	
-	entry += fmt.Sprintf("Result: %s\n", action.Result)
-	
-	if action.Error != "" {
-		entry += fmt.Sprintf("Error: %s\n", action.Error)
-	}
-	
-	entry += "---\n\n"
-	
-	return dl.writeToFile(entry)
+func (dl *DebugLogger) LogAction(ctx context.Context, action AgentAction) error {
+	return dl.log(ctx, Event{Type: EventAction, Agent: action.Agent, Error: action.Error, Action: &action})
 }
 
 // LogDecision logs why an agent made a decision
-func (dl *DebugLogger) LogDecision(decision AgentDecision) error {
-	if !dl.enabled {
-		return nil
-	}
-	
-	confidence := "🟢"
-	if decision.Confidence < 7 {
-		confidence = "🟡"
-	}
-	if decision.Confidence < 4 {
-		confidence = "🔴"
-	}
-	
-	entry := fmt.Sprintf(`
-[%s] 🤔 AGENT DECISION - %s %s
-Decision: %s
-Confidence: %d/10
-
-🎯 Reasoning:
-%s
-
-`, decision.Timestamp.Format("15:04:05"), decision.Agent, confidence, decision.Decision, decision.Confidence, decision.Reasoning)
-	
-	if len(decision.Alternatives) > 0 {
-		entry += "🔄 Alternatives Considered:\n"
-		for i, alt := range decision.Alternatives {
-			entry += fmt.Sprintf("  %d. %s\n", i+1, alt)
-		}
-	}
-	
-	entry += "---\n\n"
-	
-	return dl.writeToFile(entry)
+func (dl *DebugLogger) LogDecision(ctx context.Context, decision AgentDecision) error {
+	return dl.log(ctx, Event{Type: EventDecision, Agent: decision.Agent, Decision: &decision})
 }
 
 // LogError logs critical errors with context
-func (dl *DebugLogger) LogError(agent string, phase string, err error, context string) error {
-	if !dl.enabled {
-		return nil
-	}
-	
-	entry := fmt.Sprintf(`
-[%s] 💥 CRITICAL ERROR - %s
-Phase: %s
-Context: %s
-Error: %s
-
----
-
-`, time.Now().Format("15:04:05"), agent, phase, context, err.Error())
-	
-	return dl.writeToFile(entry)
+func (dl *DebugLogger) LogError(ctx context.Context, agent string, phase string, err error, context string) error {
+	return dl.log(ctx, Event{Type: EventError, Agent: agent, Name: phase, Error: err.Error(), Message: context})
 }
 
 // LogRecoveryAttempt logs error recovery attempts
-func (dl *DebugLogger) LogRecoveryAttempt(agent string, errorType string, recovery string, success bool) error {
-	if !dl.enabled {
-		return nil
-	}
-	
-	status := "✅ RECOVERED"
-	if !success {
-		status = "❌ RECOVERY FAILED"
-	}
-	
-	entry := fmt.Sprintf(`
-[%s] 🔧 ERROR RECOVERY - %s %s
-Error Type: %s
-Recovery Strategy: %s
-
----
-
-`, time.Now().Format("15:04:05"), agent, status, errorType, recovery)
-	
-	return dl.writeToFile(entry)
+func (dl *DebugLogger) LogRecoveryAttempt(ctx context.Context, agent string, errorType string, recovery string, success bool) error {
+	return dl.log(ctx, Event{
+		Type:       EventRecovery,
+		Agent:      agent,
+		Name:       errorType,
+		Message:    recovery,
+		Attributes: map[string]string{"recovered": fmt.Sprint(success)},
+	})
 }
 
 // LogWorkflowTransition logs when agents transition
-func (dl *DebugLogger) LogWorkflowTransition(fromAgent, toAgent, reason string) error {
-	if !dl.enabled {
+func (dl *DebugLogger) LogWorkflowTransition(ctx context.Context, fromAgent, toAgent, reason string) error {
+	return dl.log(ctx, Event{
+		Type:       EventTransition,
+		Message:    reason,
+		Attributes: map[string]string{"from": fromAgent, "to": toAgent},
+	})
+}
+
+// DumpAgentState logs the complete state of an agent for debugging
+func (dl *DebugLogger) DumpAgentState(ctx context.Context, agent string, state interface{}) error {
+	if !dl.IsEnabled() {
 		return nil
 	}
-	
-	entry := fmt.Sprintf(`
-[%s] 🔄 WORKFLOW TRANSITION
-From: %s → To: %s
-Reason: %s
-
----
-
-`, time.Now().Format("15:04:05"), fromAgent, toAgent, reason)
-	
-	return dl.writeToFile(entry)
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode agent state: %w", err)
+	}
+	return dl.log(ctx, Event{Type: EventState, Agent: agent, State: data})
 }
 
-// GetCurrentLogFile returns the path to the current log file
+// GetCurrentLogFile returns the path to the file events are written to
 func (dl *DebugLogger) GetCurrentLogFile() string {
-	return dl.currentFile
+	return filepath.Join(dl.baseDir, traceFile)
+}
+
+// GetLogDir returns the directory trace files are written to
+func (dl *DebugLogger) GetLogDir() string {
+	return dl.baseDir
 }
 
 // IsEnabled returns whether debugging is enabled
 func (dl *DebugLogger) IsEnabled() bool {
-	return dl.enabled
+	return dl != nil && dl.enabled
 }
 
-// writeToFile appends content to the current debug file
-func (dl *DebugLogger) writeToFile(content string) error {
-	if dl.currentFile == "" {
-		return fmt.Errorf("no debug session started")
+// log writes event under the span carried by ctx, if any
+func (dl *DebugLogger) log(ctx context.Context, event Event) error {
+	if !dl.IsEnabled() {
+		return nil
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		span.fill(&event)
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	return dl.write(event)
+}
+
+// write appends event to the current trace file, rotating it first when it is full
+func (dl *DebugLogger) write(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode trace event: %w", err)
+	}
+	line = append(line, '\n')
+
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	current := filepath.Join(dl.baseDir, traceFile)
+	if info, err := os.Stat(current); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > dl.maxBytes/traceFiles {
+		if err := dl.rotate(current); err != nil {
+			return err
+		}
 	}
-	
-	file, err := os.OpenFile(dl.currentFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+	file, err := os.OpenFile(current, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	
-	_, err = file.WriteString(content)
+
+	_, err = file.Write(line)
 	return err
 }
 
-// DumpAgentState logs the complete state of an agent for debugging
-func (dl *DebugLogger) DumpAgentState(agent string, state interface{}) error {
-	if !dl.enabled {
-		return nil
+// rotate renames the current trace file by time and removes the oldest rotated files
+func (dl *DebugLogger) rotate(current string) error {
+	rotated := filepath.Join(dl.baseDir, traceFilePrefix+time.Now().UTC().Format("20060102-150405.000000")+".jsonl")
+	if err := os.Rename(current, rotated); err != nil {
+		return fmt.Errorf("failed to rotate trace file: %w", err)
 	}
-	
-	entry := fmt.Sprintf(`
-[%s] 📊 AGENT STATE DUMP - %s
-State: %+v
 
----
+	files, err := rotatedTraceFiles(dl.baseDir)
+	if err != nil {
+		return err
+	}
+	for len(files) > traceFiles-1 {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
 
-`, time.Now().Format("15:04:05"), agent, state)
-	
-	return dl.writeToFile(entry)
-}
\ No newline at end of file
+// rotatedTraceFiles lists the rotated trace files in dir, oldest first
+func rotatedTraceFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, traceFilePrefix) && strings.HasSuffix(name, ".jsonl") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,94 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDebugLoggerRotation(t *testing.T) {
+	dir := t.TempDir()
+	const maxBytes = 4096
+	dl := &DebugLogger{enabled: true, baseDir: dir, maxBytes: maxBytes}
+
+	const events = 200
+	for i := 0; i < events; i++ {
+		if err := dl.LogError(context.Background(), "senior_engineer", "build", fmt.Errorf("error %d", i), strings.Repeat("x", 50)); err != nil {
+			t.Fatalf("LogError %d: %v", i, err)
+		}
+	}
+
+	rotated, err := rotatedTraceFiles(dir)
+	if err != nil {
+		t.Fatalf("rotatedTraceFiles: %v", err)
+	}
+	if len(rotated) != traceFiles-1 {
+		t.Errorf("kept %d rotated files, want %d", len(rotated), traceFiles-1)
+	}
+
+	var total int64
+	for _, file := range append(rotated, dl.GetCurrentLogFile()) {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > maxBytes/traceFiles {
+			t.Errorf("%s holds %d bytes, over its share of %d", filepath.Base(file), info.Size(), maxBytes/traceFiles)
+		}
+		total += info.Size()
+	}
+	if total > maxBytes {
+		t.Errorf("traces take %d bytes, want at most %d", total, maxBytes)
+	}
+
+	// The oldest events were dropped; the rest read back in order, newest last
+	read, err := ReadEvents(dir)
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+	if len(read) == 0 || len(read) >= events {
+		t.Fatalf("read %d events, want some but not all of %d", len(read), events)
+	}
+	first := events - len(read)
+	for i, event := range read {
+		if want := fmt.Sprintf("error %d", first+i); event.Error != want {
+			t.Fatalf("event %d error = %q, want %q", i, event.Error, want)
+		}
+	}
+}
+
+func TestRotatedTraceFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"trace.jsonl",
+		"trace-20240102-000000.000000.jsonl",
+		"trace-20240101-000000.000000.jsonl",
+		"trace-20240101-120000.000000.jsonl",
+		"trace-notes.txt",
+		"other.jsonl",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "trace-old.jsonl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := rotatedTraceFiles(dir)
+	if err != nil {
+		t.Fatalf("rotatedTraceFiles: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "trace-20240101-000000.000000.jsonl"),
+		filepath.Join(dir, "trace-20240101-120000.000000.jsonl"),
+		filepath.Join(dir, "trace-20240102-000000.000000.jsonl"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rotatedTraceFiles = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package debug
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimelineRow is one line of a rendered timeline: a span or an event within one
+type TimelineRow struct {
+	Depth    int
+	Offset   time.Duration // since the run started
+	Duration time.Duration
+	Kind     string // span kind, or the event type
+	Label    string
+	Error    string
+	Prompt   string
+	Response string
+	Detail   string // tool output, reasoning and the like
+}
+
+// Rows flattens the trace into rows in start order, children indented under their span
+func (t *Trace) Rows() []TimelineRow {
+	var rows []TimelineRow
+	var walk func(span *TraceSpan, depth int)
+	walk = func(span *TraceSpan, depth int) {
+		rows = append(rows, TimelineRow{
+			Depth:    depth,
+			Offset:   span.Start.Sub(t.Root.Start),
+			Duration: span.Duration(),
+			Kind:     span.Kind,
+			Label:    spanLabel(span),
+			Error:    span.Error,
+		})
+
+		// Interleave the span's own events with its children by time
+		type item struct {
+			at    time.Time
+			event *Event
+			child *TraceSpan
+		}
+		var items []item
+		for i := range span.Events {
+			event := &span.Events[i]
+			// Events are logged when they finish, so place them where they started
+			items = append(items, item{at: event.Time.Add(-time.Duration(event.DurationMS) * time.Millisecond), event: event})
+		}
+		for _, child := range span.Children {
+			items = append(items, item{at: child.Start, child: child})
+		}
+		sort.SliceStable(items, func(i, j int) bool { return items[i].at.Before(items[j].at) })
+
+		for _, it := range items {
+			if it.child != nil {
+				walk(it.child, depth+1)
+				continue
+			}
+			row := eventRow(*it.event)
+			row.Depth = depth + 1
+			row.Offset = it.at.Sub(t.Root.Start)
+			rows = append(rows, row)
+		}
+	}
+	walk(t.Root, 0)
+	return rows
+}
+
+func spanLabel(span *TraceSpan) string {
+	label := span.Name
+	if span.Agent != "" && span.Agent != span.Name {
+		label = span.Agent + ": " + label
+	}
+	if status := span.Attributes["status"]; status != "" {
+		label += " (" + status + ")"
+	}
+	return label
+}
+
+// eventRow describes an event in a line
+func eventRow(event Event) TimelineRow {
+	row := TimelineRow{Kind: event.Type, Duration: time.Duration(event.DurationMS) * time.Millisecond, Error: event.Error, Label: event.Name}
+	switch event.Type {
+	case EventLLMCall:
+		if event.LLM != nil {
+			row.Label = fmt.Sprintf("%s, %d prompt + %d completion tokens", event.LLM.Model, event.LLM.PromptTokens, event.LLM.CompletionTokens)
+			row.Prompt, row.Response = event.LLM.Prompt, event.LLM.Response
+		}
+	case EventToolCall, EventAction:
+		if action := event.Action; action != nil {
+			row.Label = strings.TrimSpace(action.ActionType + " " + firstNonEmpty(action.Command, action.FilePath))
+			row.Detail = action.Result
+		}
+	case EventTransition:
+		row.Label = fmt.Sprintf("%s -> %s", event.Attributes["from"], event.Attributes["to"])
+		row.Detail = event.Message
+	case EventRecovery:
+		row.Label = fmt.Sprintf("%s, recovered: %s", event.Name, event.Attributes["recovered"])
+		row.Detail = event.Message
+	case EventError:
+		row.Detail = event.Message
+	case EventThought:
+		if thought := event.Thought; thought != nil {
+			row.Label, row.Detail = thought.Phase, thought.Thinking
+		}
+	case EventDecision:
+		if decision := event.Decision; decision != nil {
+			row.Label = fmt.Sprintf("%s (confidence %d/10)", decision.Decision, decision.Confidence)
+			row.Detail = decision.Reasoning
+		}
+	case EventState:
+		row.Detail = string(event.State)
+	}
+	return row
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// RenderText writes the trace as an indented timeline for the terminal
+func RenderText(w io.Writer, t *Trace) error {
+	root := t.Root
+	status := "ok"
+	if root.Error != "" {
+		status = "FAILED: " + root.Error
+	} else if !root.Ended {
+		status = "unfinished"
+	}
+	if _, err := fmt.Fprintf(w, "Run %s started %s, took %s, %s\n\n", root.ID, root.Start.Format(time.RFC3339), formatDuration(root.Duration()), status); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows() {
+		line := fmt.Sprintf("%9s %8s  %s%-10s %s", "+"+formatDuration(row.Offset), formatDuration(row.Duration), strings.Repeat("  ", row.Depth), row.Kind, row.Label)
+		if row.Error != "" {
+			line += "  ERROR: " + firstLine(row.Error)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "0s"
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+func firstLine(s string) string {
+	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+		return s[:idx] + " ..."
+	}
+	return s
+}
+
+// RenderHTML writes the trace as a self-contained HTML page with a bar per row and
+// the prompts, responses and tool output folded under each row
+func RenderHTML(w io.Writer, t *Trace) error {
+	total := t.Root.Duration()
+	if total <= 0 {
+		total = time.Millisecond
+	}
+	type htmlRow struct {
+		TimelineRow
+		Indent, Left, Width float64
+		OffsetText          string
+		DurationText        string
+	}
+	var rows []htmlRow
+	for _, row := range t.Rows() {
+		width := float64(row.Duration) / float64(total) * 100
+		if width < 0.3 {
+			width = 0.3
+		}
+		rows = append(rows, htmlRow{
+			TimelineRow:  row,
+			Indent:       float64(row.Depth) * 1.2,
+			Left:         float64(row.Offset) / float64(total) * 100,
+			Width:        width,
+			OffsetText:   "+" + formatDuration(row.Offset),
+			DurationText: formatDuration(row.Duration),
+		})
+	}
+
+	return traceTemplate.Execute(w, map[string]interface{}{
+		"Run":      t.Root.ID,
+		"Name":     t.Root.Name,
+		"Start":    t.Root.Start.Format(time.RFC3339),
+		"Duration": formatDuration(t.Root.Duration()),
+		"Error":    t.Root.Error,
+		"Rows":     rows,
+	})
+}
+
+var traceTemplate = template.Must(template.New("trace").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Run {{.Run}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 1.5em; }
+table { border-collapse: collapse; width: 100%; }
+td { padding: 2px 6px; vertical-align: top; border-bottom: 1px solid #eee; }
+td.time { font-family: monospace; white-space: nowrap; color: #555; text-align: right; }
+td.label { width: 45%; }
+td.bar { width: 40%; position: relative; }
+.bar div { position: absolute; top: 4px; height: 10px; border-radius: 2px; background: #7aa6da; }
+.run div, .parallel div { background: #aaa; }
+.agent div { background: #4a7fc1; }
+.llm_call div { background: #9c6ade; }
+.tool_call div { background: #5fb760; }
+.failed div { background: #d9534f; }
+.kind { color: #888; }
+.error { color: #c9302c; }
+pre { white-space: pre-wrap; max-height: 30em; overflow: auto; background: #f7f7f7; padding: 6px; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>Run <code>{{.Run}}</code> started {{.Start}}, took {{.Duration}}{{if .Error}}, <span class="error">failed: {{.Error}}</span>{{end}}</p>
+<table>
+{{range .Rows}}<tr class="{{.Kind}}{{if .Error}} failed{{end}}">
+<td class="time">{{.OffsetText}}</td>
+<td class="time">{{.DurationText}}</td>
+<td class="label" style="padding-left: {{.Indent}}em"><span class="kind">{{.Kind}}</span> {{.Label}}
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if .Prompt}}<details><summary>prompt</summary><pre>{{.Prompt}}</pre></details>{{end}}
+{{if .Response}}<details><summary>response</summary><pre>{{.Response}}</pre></details>{{end}}
+{{if .Detail}}<details><summary>details</summary><pre>{{.Detail}}</pre></details>{{end}}
+</td>
+<td class="bar {{.Kind}}{{if .Error}} failed{{end}}"><div style="left: {{printf "%.2f" .Left}}%; width: {{printf "%.2f" .Width}}%"></div></td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,127 @@
+package debug
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Span is a timed part of a workflow run, such as one agent invocation
+type Span struct {
+	logger *DebugLogger
+	run    string
+	id     string
+	parent string
+	kind   string
+	name   string
+	agent  string
+	start  time.Time
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the span ctx carries, or nil when the run is not traced
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// StartSpan opens a span under the one ctx carries; without one it returns ctx
+// unchanged and a nil span, whose methods do nothing
+func StartSpan(ctx context.Context, kind, name, agent string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	if agent == "" {
+		agent = parent.agent
+	}
+	span := &Span{
+		logger: parent.logger,
+		run:    parent.run,
+		id:     newID(),
+		parent: parent.id,
+		kind:   kind,
+		name:   name,
+		agent:  agent,
+		start:  time.Now(),
+	}
+	span.emit(Event{Type: EventSpanStart, Kind: kind, Name: name})
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// RunID returns the ID of the run the span belongs to
+func (s *Span) RunID() string {
+	if s == nil {
+		return ""
+	}
+	return s.run
+}
+
+// End closes the span, recording err when it failed
+func (s *Span) End(err error, attributes map[string]string) {
+	if s == nil {
+		return
+	}
+	event := Event{
+		Type:       EventSpanEnd,
+		Kind:       s.kind,
+		Name:       s.name,
+		DurationMS: time.Since(s.start).Milliseconds(),
+		Attributes: attributes,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	s.emit(event)
+}
+
+// LogLLMCall records a prompt and the response to it
+func (s *Span) LogLLMCall(exchange LLMExchange, duration time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	event := Event{Type: EventLLMCall, Name: exchange.Model, DurationMS: duration.Milliseconds(), LLM: &exchange}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	s.emit(event)
+}
+
+// LogToolCall records a call an agent made to one of its tools
+func (s *Span) LogToolCall(action AgentAction, duration time.Duration) {
+	if s == nil {
+		return
+	}
+	if action.Agent == "" {
+		action.Agent = s.agent
+	}
+	s.emit(Event{Type: EventToolCall, Name: action.ActionType, DurationMS: duration.Milliseconds(), Error: action.Error, Action: &action})
+}
+
+// fill sets the run, span and agent of an event logged under s
+func (s *Span) fill(event *Event) {
+	event.Run = s.run
+	event.Span = s.id
+	if event.Type == EventSpanStart || event.Type == EventSpanEnd {
+		event.Parent = s.parent
+	}
+	if event.Agent == "" {
+		event.Agent = s.agent
+	}
+}
+
+func (s *Span) emit(event Event) {
+	s.fill(&event)
+	event.Time = time.Now()
+	// Tracing must never fail a workflow, so write errors are dropped
+	_ = s.logger.write(event)
+}
+
+// newID returns a random 16 character hex ID
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
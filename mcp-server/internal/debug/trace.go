@@ -0,0 +1,200 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RunSummary describes one traced run
+type RunSummary struct {
+	ID       string
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Error    string
+	Finished bool
+}
+
+// TraceSpan is a span of a run with the events logged under it
+type TraceSpan struct {
+	ID         string
+	Parent     string
+	Kind       string
+	Name       string
+	Agent      string
+	Start      time.Time
+	End        time.Time
+	Error      string
+	Ended      bool // the span's end was logged
+	Attributes map[string]string
+	Events     []Event // everything but the span's own start and end
+	Children   []*TraceSpan
+}
+
+// Duration is how long the span took; spans that never ended last until their last event
+func (s *TraceSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Trace is a run rebuilt from its events
+type Trace struct {
+	Root *TraceSpan
+}
+
+// ReadEvents reads every event in the trace files of dir, oldest file first.
+// Lines that are not events, such as a line cut short by a crash, are skipped.
+func ReadEvents(dir string) ([]Event, error) {
+	files, err := rotatedTraceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, traceFile)); err == nil {
+		files = append(files, filepath.Join(dir, traceFile))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no trace files in %s", dir)
+	}
+
+	var events []Event
+	for _, file := range files {
+		fileEvents, err := readEventFile(file)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, fileEvents...)
+	}
+	return events, nil
+}
+
+func readEventFile(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Prompts make for long lines, so read whole lines rather than scanning tokens
+	var events []Event
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var event Event
+			if json.Unmarshal(line, &event) == nil && event.Type != "" {
+				events = append(events, event)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
+
+// ListRuns summarizes the runs found in events, oldest first
+func ListRuns(events []Event) []RunSummary {
+	var runs []RunSummary
+	index := make(map[string]int)
+	for _, event := range events {
+		if event.Run == "" || event.Span != event.Run {
+			continue
+		}
+		switch event.Type {
+		case EventSpanStart:
+			index[event.Run] = len(runs)
+			runs = append(runs, RunSummary{ID: event.Run, Name: event.Name, Start: event.Time})
+		case EventSpanEnd:
+			if i, ok := index[event.Run]; ok {
+				runs[i].Duration = time.Duration(event.DurationMS) * time.Millisecond
+				runs[i].Error = event.Error
+				runs[i].Finished = true
+			}
+		}
+	}
+	return runs
+}
+
+// BuildTrace rebuilds run from events; an empty run selects the latest one
+func BuildTrace(events []Event, run string) (*Trace, error) {
+	if run == "" {
+		runs := ListRuns(events)
+		if len(runs) == 0 {
+			return nil, fmt.Errorf("no runs found")
+		}
+		run = runs[len(runs)-1].ID
+	}
+
+	spans := make(map[string]*TraceSpan)
+	var order []string
+	spanFor := func(id string) *TraceSpan {
+		if span, ok := spans[id]; ok {
+			return span
+		}
+		span := &TraceSpan{ID: id}
+		spans[id] = span
+		order = append(order, id)
+		return span
+	}
+
+	for _, event := range events {
+		if event.Run != run || event.Span == "" {
+			continue
+		}
+		span := spanFor(event.Span)
+		if span.Start.IsZero() || event.Time.Before(span.Start) {
+			span.Start = event.Time
+		}
+		if event.Time.After(span.End) {
+			span.End = event.Time
+		}
+		switch event.Type {
+		case EventSpanStart:
+			span.Parent, span.Kind, span.Name, span.Agent = event.Parent, event.Kind, event.Name, event.Agent
+			span.Start = event.Time
+		case EventSpanEnd:
+			span.Error = event.Error
+			span.Ended = true
+			span.Attributes = event.Attributes
+		default:
+			span.Events = append(span.Events, event)
+		}
+	}
+
+	root, ok := spans[run]
+	if !ok {
+		return nil, fmt.Errorf("run %s not found", run)
+	}
+	for _, id := range order {
+		span := spans[id]
+		if id == run {
+			continue
+		}
+		parent, ok := spans[span.Parent]
+		if !ok {
+			parent = root
+		}
+		parent.Children = append(parent.Children, span)
+	}
+	settle(root)
+	return &Trace{Root: root}, nil
+}
+
+// settle orders the children of span by start time and stretches span to cover them
+func settle(span *TraceSpan) {
+	sort.SliceStable(span.Children, func(i, j int) bool { return span.Children[i].Start.Before(span.Children[j].Start) })
+	for _, child := range span.Children {
+		settle(child)
+		if child.End.After(span.End) {
+			span.End = child.End
+		}
+	}
+}
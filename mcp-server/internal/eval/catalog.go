@@ -0,0 +1,90 @@
+// Package eval runs a catalog of seeded tasks through the workflow under one or
+// more model configurations and scores the outcomes, so models can be compared
+// on this pipeline rather than on general benchmarks.
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Task is one catalog entry: a fixture repository, the feature to ask for and
+// acceptance tests the agents never see. It lives in a directory holding
+// task.toml, repo/ and hidden/.
+type Task struct {
+	Name        string `toml:"-"`
+	Dir         string `toml:"-"`
+	Description string `toml:"description"`
+	Flow        string `toml:"flow"`         // named flow, empty for the default
+	ProjectType string `toml:"project_type"` // inferred when empty
+	// Acceptance runs in the project after the hidden files are copied in; the
+	// task passes when it exits 0
+	Acceptance string `toml:"acceptance"`
+}
+
+// LoadCatalog reads every task under dir, sorted by name
+func LoadCatalog(dir string) ([]Task, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task catalog: %w", err)
+	}
+
+	var tasks []Task
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		taskDir := filepath.Join(dir, entry.Name())
+		task := Task{Name: entry.Name(), Dir: taskDir}
+		if _, err := toml.DecodeFile(filepath.Join(taskDir, "task.toml"), &task); err != nil {
+			return nil, fmt.Errorf("task %s: %w", task.Name, err)
+		}
+		if task.Description == "" {
+			return nil, fmt.Errorf("task %s: description is required", task.Name)
+		}
+		if task.Acceptance == "" {
+			return nil, fmt.Errorf("task %s: acceptance is required", task.Name)
+		}
+		if info, err := os.Stat(task.repoDir()); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("task %s: missing repo directory", task.Name)
+		}
+		tasks = append(tasks, task)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no tasks in %s", dir)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks, nil
+}
+
+// SelectTasks keeps the tasks named in names, or all of them when names is empty
+func SelectTasks(tasks []Task, names []string) ([]Task, error) {
+	if len(names) == 0 {
+		return tasks, nil
+	}
+	byName := make(map[string]Task, len(tasks))
+	for _, task := range tasks {
+		byName[task.Name] = task
+	}
+	var selected []Task
+	for _, name := range names {
+		task, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown task %q", name)
+		}
+		selected = append(selected, task)
+	}
+	return selected, nil
+}
+
+func (t Task) repoDir() string {
+	return filepath.Join(t.Dir, "repo")
+}
+
+func (t Task) hiddenDir() string {
+	return filepath.Join(t.Dir, "hidden")
+}
@@ -0,0 +1,92 @@
+package eval
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/fakeollama"
+	"mcp-server/internal/llm"
+)
+
+func TestRunScoresTask(t *testing.T) {
+	script, err := fakeollama.LoadScript("../../testdata/integration/subtract.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := fakeollama.NewServer(script)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	tasks, err := LoadCatalog("../../eval/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err = SelectTasks(tasks, []string{"calculator-subtract"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := Configurations([]string{"../../config/agents.toml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := Run(context.Background(), tasks, configs, Options{
+		Runs: 1,
+		NewClient: func(_ *config.WorkflowConfig, agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+			return llm.NewOllamaClient(server.URL, agentCfg.Model)
+		},
+	})
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	result := results[0]
+	if !result.Passed {
+		t.Fatalf("run failed (%s): %s\n%s", result.FailureReason, result.Error, result.Acceptance)
+	}
+	if result.Configuration != "agents" || result.Iterations < 4 || result.Tokens == 0 || result.RoutingLoops != 0 {
+		t.Errorf("result = %+v, want configuration agents, at least 4 iterations, tokens and no loops", result)
+	}
+	if !strings.Contains(result.Acceptance, "ok") {
+		t.Errorf("acceptance output = %q, want the hidden tests to run", result.Acceptance)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{Task: "a", Configuration: "m1", Passed: true, Iterations: 4, WallTime: 2 * time.Second, Tokens: 100},
+		{Task: "a", Configuration: "m1", FailureReason: "iteration_limit_exceeded", Iterations: 12, WallTime: 4 * time.Second, Tokens: 300, RoutingLoops: 3},
+		{Task: "b", Configuration: "m1", FailureReason: "acceptance_failed", Iterations: 5, WallTime: 3 * time.Second, Tokens: 200, RoutingLoops: 1},
+		{Task: "a", Configuration: "m2", Passed: true, Iterations: 4, WallTime: time.Second, Tokens: 80},
+	}
+	summaries := Summarize(results)
+
+	var got []string
+	for _, s := range summaries {
+		got = append(got, s.Configuration+"/"+s.Task)
+	}
+	if want := "m1/a m1/b m1/(all) m2/a m2/(all)"; strings.Join(got, " ") != want {
+		t.Fatalf("summaries = %v, want %s", got, want)
+	}
+
+	first := summaries[0]
+	if first.Runs != 2 || first.Passed != 1 || first.MeanIterations != 8 || first.MeanWallTime != 3*time.Second || first.MeanTokens != 200 || first.MeanLoops != 1.5 {
+		t.Errorf("m1/a = %+v", first)
+	}
+	all := summaries[2]
+	if all.Runs != 3 || all.FailureReasons["iteration_limit_exceeded"] != 1 || all.FailureReasons["acceptance_failed"] != 1 {
+		t.Errorf("m1/(all) = %+v", all)
+	}
+
+	var table strings.Builder
+	if err := WriteTable(&table, summaries); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "1/2 (50%)") || !strings.Contains(table.String(), "acceptance_failed x1, iteration_limit_exceeded x1") {
+		t.Errorf("table:\n%s", table.String())
+	}
+}
@@ -0,0 +1,130 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// AllTasks is the Task of a summary over every task of a configuration
+const AllTasks = "(all)"
+
+// Summary aggregates the runs of one task, or of all tasks, under one configuration
+type Summary struct {
+	Configuration  string         `json:"configuration"`
+	Task           string         `json:"task"`
+	Runs           int            `json:"runs"`
+	Passed         int            `json:"passed"`
+	MeanIterations float64        `json:"mean_iterations"`
+	MeanWallTime   time.Duration  `json:"mean_wall_time"`
+	MeanTokens     float64        `json:"mean_tokens"`
+	MeanLoops      float64        `json:"mean_routing_loops"`
+	FailureReasons map[string]int `json:"failure_reasons,omitempty"`
+}
+
+// PassRate is the share of runs that passed
+func (s Summary) PassRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Runs)
+}
+
+// Summarize groups results by configuration and task, followed for each
+// configuration by a summary over all its tasks, in the order results ran
+func Summarize(results []Result) []Summary {
+	type key struct{ configuration, task string }
+	var order []key
+	groups := make(map[key][]Result)
+	var configOrder []string
+	byConfig := make(map[string][]Result)
+
+	for _, result := range results {
+		k := key{result.Configuration, result.Task}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], result)
+		if _, ok := byConfig[result.Configuration]; !ok {
+			configOrder = append(configOrder, result.Configuration)
+		}
+		byConfig[result.Configuration] = append(byConfig[result.Configuration], result)
+	}
+
+	var summaries []Summary
+	for _, configuration := range configOrder {
+		for _, k := range order {
+			if k.configuration == configuration {
+				summaries = append(summaries, summarize(configuration, k.task, groups[k]))
+			}
+		}
+		summaries = append(summaries, summarize(configuration, AllTasks, byConfig[configuration]))
+	}
+	return summaries
+}
+
+func summarize(configuration, task string, results []Result) Summary {
+	s := Summary{Configuration: configuration, Task: task, Runs: len(results)}
+	var iterations, tokens, loops int
+	var wall time.Duration
+	for _, result := range results {
+		if result.Passed {
+			s.Passed++
+		} else {
+			if s.FailureReasons == nil {
+				s.FailureReasons = make(map[string]int)
+			}
+			s.FailureReasons[result.FailureReason]++
+		}
+		iterations += result.Iterations
+		tokens += result.Tokens
+		loops += result.RoutingLoops
+		wall += result.WallTime
+	}
+	if s.Runs > 0 {
+		n := float64(s.Runs)
+		s.MeanIterations = float64(iterations) / n
+		s.MeanTokens = float64(tokens) / n
+		s.MeanLoops = float64(loops) / n
+		s.MeanWallTime = wall / time.Duration(s.Runs)
+	}
+	return s
+}
+
+// WriteTable writes summaries as an aligned comparison table
+func WriteTable(w io.Writer, summaries []Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONFIGURATION\tTASK\tPASS\tITERATIONS\tWALL TIME\tTOKENS\tLOOPS\tFAILURES")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d (%.0f%%)\t%.1f\t%s\t%.0f\t%.1f\t%s\n",
+			s.Configuration, s.Task, s.Passed, s.Runs, 100*s.PassRate(),
+			s.MeanIterations, s.MeanWallTime.Round(time.Second), s.MeanTokens, s.MeanLoops,
+			formatReasons(s.FailureReasons))
+	}
+	return tw.Flush()
+}
+
+// formatReasons lists failure reasons by how often they occurred
+func formatReasons(reasons map[string]int) string {
+	if len(reasons) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(reasons))
+	for name := range reasons {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if reasons[names[i]] != reasons[names[j]] {
+			return reasons[names[i]] > reasons[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s x%d", name, reasons[name])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,223 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/fixture"
+	"mcp-server/internal/llm"
+	"mcp-server/internal/orchestrator"
+	"mcp-server/internal/tools"
+)
+
+// acceptanceTimeout bounds the hidden tests, which only exercise the change
+const acceptanceTimeout = 5 * time.Minute
+
+// Configuration is a workflow configuration under evaluation, usually
+// agents.toml with every agent switched to one model
+type Configuration struct {
+	Name   string
+	Config *config.WorkflowConfig
+}
+
+// Configurations loads each config file, and when models are given pairs every
+// file with every model, overriding the model of all its agents
+func Configurations(configPaths, models []string) ([]Configuration, error) {
+	var configs []Configuration
+	for _, path := range configPaths {
+		base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if len(models) == 0 {
+			cfg, err := config.LoadWorkflowConfig(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			configs = append(configs, Configuration{Name: base, Config: cfg})
+			continue
+		}
+		for _, model := range models {
+			// Load a fresh copy per model; the agent map is shared otherwise
+			cfg, err := config.LoadWorkflowConfig(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			for name, agentCfg := range cfg.Agents {
+				agentCfg.Model = model
+				cfg.Agents[name] = agentCfg
+			}
+			name := model
+			if len(configPaths) > 1 {
+				name = base + ":" + model
+			}
+			configs = append(configs, Configuration{Name: name, Config: cfg})
+		}
+	}
+	return configs, nil
+}
+
+// Options controls a run of the catalog
+type Options struct {
+	Runs int // per task and configuration
+	// NewClient returns the model client for an agent of the configuration cfg
+	NewClient func(cfg *config.WorkflowConfig, agentCfg config.WorkflowAgentConfig) agent.LLMClient
+	// Progress gets a line per finished run when set
+	Progress io.Writer
+}
+
+// Result is the outcome of one task run under one configuration
+type Result struct {
+	Task          string        `json:"task"`
+	Configuration string        `json:"configuration"`
+	Run           int           `json:"run"`
+	Passed        bool          `json:"passed"`    // workflow succeeded and the hidden tests pass
+	Completed     bool          `json:"completed"` // the workflow reported success
+	FailureReason string        `json:"failure_reason,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	Iterations    int           `json:"iterations"` // agent iterations across roles
+	WallTime      time.Duration `json:"wall_time"`
+	Tokens        int           `json:"tokens"`
+	RoutingLoops  int           `json:"routing_loops"` // hand-offs back to an agent that already ran
+	Route         []string      `json:"route"`
+	Acceptance    string        `json:"acceptance_output,omitempty"`
+}
+
+// Run executes every task opts.Runs times under every configuration
+func Run(ctx context.Context, tasks []Task, configs []Configuration, opts Options) []Result {
+	runs := opts.Runs
+	if runs < 1 {
+		runs = 1
+	}
+
+	var results []Result
+	for _, task := range tasks {
+		for _, cfg := range configs {
+			for i := 1; i <= runs; i++ {
+				result := runOnce(ctx, task, cfg, opts, i)
+				results = append(results, result)
+				if opts.Progress != nil {
+					status := "passed"
+					if !result.Passed {
+						status = "failed: " + result.FailureReason
+					}
+					fmt.Fprintf(opts.Progress, "%s  %s  run %d/%d  %s  %s\n", task.Name, cfg.Name, i, runs, status, result.WallTime.Round(time.Second))
+				}
+				if ctx.Err() != nil {
+					return results
+				}
+			}
+		}
+	}
+	return results
+}
+
+func runOnce(ctx context.Context, task Task, cfg Configuration, opts Options, run int) Result {
+	result := Result{Task: task.Name, Configuration: cfg.Name, Run: run}
+	fail := func(reason string, err error) Result {
+		result.FailureReason = reason
+		result.Error = err.Error()
+		return result
+	}
+
+	dir, err := os.MkdirTemp("", "eval-"+task.Name+"-")
+	if err != nil {
+		return fail("setup_failed", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := fixture.Repo(task.repoDir(), dir); err != nil {
+		return fail("setup_failed", err)
+	}
+
+	if _, err := agent.LoadPromptTemplates(cfg.Config.Workflow.PromptsDir); err != nil {
+		return fail("setup_failed", err)
+	}
+	toolSet := tools.NewToolSet(cfg.Config.Commands, cfg.Config.Restrictions, dir)
+	wo, err := orchestrator.NewWorkflowOrchestrator(nil, toolSet, cfg.Config)
+	if err != nil {
+		return fail("setup_failed", err)
+	}
+	builder := orchestrator.NewAgentBuilder(cfg.Config, agent.NewAgentFactory(nil), func(agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+		return opts.NewClient(cfg.Config, agentCfg)
+	})
+	if err := wo.SetAgentBuilder(builder); err != nil {
+		return fail("setup_failed", err)
+	}
+
+	// Repeated runs are meant to sample the model, not the response cache
+	start := time.Now()
+	workflow, err := wo.ExecuteWorkflow(llm.WithoutCache(ctx), agent.WorkflowRequest{
+		Description:      task.Description,
+		ProjectType:      agent.ProjectType(task.ProjectType),
+		WorkingDirectory: dir,
+		Flow:             task.Flow,
+	})
+	result.WallTime = time.Since(start)
+	if err != nil {
+		return fail("error", err)
+	}
+
+	for _, summary := range workflow.AgentSummaries {
+		result.Iterations += summary.Iterations
+	}
+	result.Tokens = workflow.TokenUsage["total"].TotalTokens
+	result.Route, result.RoutingLoops = routeOf(workflow.WorkflowHistory)
+	result.Completed = workflow.Success
+	if !workflow.Success {
+		result.FailureReason = workflow.FailureReason
+		if result.FailureReason == "" {
+			result.FailureReason = "failed"
+		}
+		result.Error = workflow.Error
+		return result
+	}
+
+	output, err := runAcceptance(ctx, task, dir)
+	result.Acceptance = output
+	if err != nil {
+		return fail("acceptance_failed", err)
+	}
+	result.Passed = true
+	return result
+}
+
+// runAcceptance copies the hidden tests into the project and runs the task's
+// acceptance command there
+func runAcceptance(ctx context.Context, task Task, dir string) (string, error) {
+	if _, err := os.Stat(task.hiddenDir()); err == nil {
+		if err := tools.CopyTree(task.hiddenDir(), dir); err != nil {
+			return "", fmt.Errorf("copying hidden tests: %w", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, acceptanceTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", task.Acceptance)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// routeOf lists the agents a run went through and counts the hand-offs back to
+// an agent that had already run
+func routeOf(history []agent.AgentTransition) ([]string, int) {
+	var route []string
+	loops := 0
+	seen := make(map[agent.AgentRole]bool)
+	for i, transition := range history {
+		if i == 0 {
+			route = append(route, string(transition.FromAgent))
+			seen[transition.FromAgent] = true
+		}
+		if seen[transition.ToAgent] {
+			loops++
+		}
+		seen[transition.ToAgent] = true
+		route = append(route, string(transition.ToAgent))
+	}
+	return route, loops
+}
@@ -0,0 +1,468 @@
+// Package fakeollama is a scriptable stand-in for the Ollama HTTP API, for
+// running the servers end to end without a model.
+package fakeollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Script maps prompts to canned responses. Rules are tried in order and the
+// first whose substrings all appear in the prompt answers it.
+type Script struct {
+	// Models listed by /api/tags; requests for other models get a 404 like a
+	// real server. Empty accepts any model.
+	Models []string `yaml:"models"`
+	// Pullable lists the models /api/pull can fetch, which are served like
+	// Models from then on. Empty lets any model be pulled.
+	Pullable []string `yaml:"pullable"`
+	Rules    []*Rule  `yaml:"rules"`
+	// Default answers prompts no rule matches; without one they fail with a 500
+	Default *string `yaml:"default"`
+}
+
+// Rule is one scripted response
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Contains []string `yaml:"contains"`
+	Model    string   `yaml:"model"`    // only requests for this model, when set
+	Times    int      `yaml:"times"`    // answers at most this many prompts, when set
+	Response string   `yaml:"response"` // returned as the generation
+	Error    string   `yaml:"error"`    // returned as an Ollama error instead, with Status
+	Status   int      `yaml:"status"`   // HTTP status for Error (default 500)
+}
+
+// Call is one request the server answered
+type Call struct {
+	Endpoint  string
+	Model     string
+	Prompt    string
+	Rule      string // name of the rule that answered, empty for the default
+	KeepAlive string // keep_alive the request asked for, empty when unset
+	NumCtx    int    // context window the request asked for, 0 when unset
+}
+
+// Rule names of the calls no script rule answers
+const (
+	LoadRule = "(load)" // generate requests without a prompt, which only load the model
+	PullRule = "(pull)"
+)
+
+// LoadScript reads a YAML script from path
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	script, err := ParseScript(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return script, nil
+}
+
+// ParseScript parses a YAML script
+func ParseScript(data []byte) (*Script, error) {
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+	for i, rule := range script.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if len(rule.Contains) == 0 {
+			return nil, fmt.Errorf("%s: contains is required", rule.Name)
+		}
+		if rule.Error != "" && rule.Status == 0 {
+			rule.Status = http.StatusInternalServerError
+		}
+	}
+	return &script, nil
+}
+
+// Server serves a script over the Ollama API
+type Server struct {
+	// OnCall, when set, is called with every request as it is answered
+	OnCall func(Call)
+
+	script    *Script
+	mux       *http.ServeMux
+	mu        sync.Mutex
+	used      map[*Rule]int
+	pulled    map[string]bool
+	calls     []Call
+	unmatched []string
+}
+
+// NewServer returns a handler for /api/generate, /api/chat, /api/tags and /api/pull
+func NewServer(script *Script) *Server {
+	s := &Server{
+		script: script,
+		mux:    http.NewServeMux(),
+		used:   make(map[*Rule]int),
+		pulled: make(map[string]bool),
+	}
+	s.mux.HandleFunc("/api/generate", s.handleGenerate)
+	s.mux.HandleFunc("/api/chat", s.handleChat)
+	s.mux.HandleFunc("/api/tags", s.handleTags)
+	s.mux.HandleFunc("/api/pull", s.handlePull)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Calls lists the requests answered so far, in order
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Unmatched lists the prompts no rule or default answered
+func (s *Server) Unmatched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.unmatched...)
+}
+
+type options struct {
+	NumCtx int `json:"num_ctx,omitempty"`
+}
+
+type generateRequest struct {
+	Model     string      `json:"model"`
+	Prompt    string      `json:"prompt"`
+	Stream    *bool       `json:"stream"`
+	KeepAlive interface{} `json:"keep_alive"` // a duration string or seconds
+	Options   *options    `json:"options"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	Stream    *bool         `json:"stream"`
+	KeepAlive interface{}   `json:"keep_alive"`
+	Options   *options      `json:"options"`
+}
+
+type pullRequest struct {
+	Model  string `json:"model"`
+	Name   string `json:"name"` // older clients
+	Stream *bool  `json:"stream"`
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req generateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	call := Call{Endpoint: "/api/generate", Model: req.Model, Prompt: req.Prompt, KeepAlive: keepAlive(req.KeepAlive), NumCtx: numCtx(req.Options)}
+	if req.Prompt == "" {
+		// Ollama only loads the model and answers at once
+		s.load(w, call)
+		return
+	}
+	response, ok := s.answer(w, call)
+	if !ok {
+		return
+	}
+
+	stream := req.Stream == nil || *req.Stream
+	chunk := func(text string, done bool) map[string]interface{} {
+		body := map[string]interface{}{
+			"model":      req.Model,
+			"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"response":   text,
+			"done":       done,
+		}
+		if done {
+			body["done_reason"] = "stop"
+			body["prompt_eval_count"] = countTokens(req.Prompt)
+			body["eval_count"] = countTokens(response)
+		}
+		return body
+	}
+	writeGeneration(w, stream, response, chunk)
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	var parts []string
+	for _, message := range req.Messages {
+		parts = append(parts, message.Content)
+	}
+	text := strings.Join(parts, "\n")
+	call := Call{Endpoint: "/api/chat", Model: req.Model, Prompt: text, KeepAlive: keepAlive(req.KeepAlive), NumCtx: numCtx(req.Options)}
+	response, ok := s.answer(w, call)
+	if !ok {
+		return
+	}
+
+	stream := req.Stream == nil || *req.Stream
+	chunk := func(content string, done bool) map[string]interface{} {
+		body := map[string]interface{}{
+			"model":      req.Model,
+			"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"message":    chatMessage{Role: "assistant", Content: content},
+			"done":       done,
+		}
+		if done {
+			body["done_reason"] = "stop"
+			body["prompt_eval_count"] = countTokens(text)
+			body["eval_count"] = countTokens(response)
+		}
+		return body
+	}
+	writeGeneration(w, stream, response, chunk)
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	names := append([]string(nil), s.script.Models...)
+	for name := range s.pulled {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	models := []map[string]interface{}{}
+	for _, name := range names {
+		models = append(models, map[string]interface{}{
+			"name":        name,
+			"model":       name,
+			"modified_at": time.Time{}.Format(time.RFC3339),
+			"size":        0,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"models": models})
+}
+
+// handlePull pretends to download a model, streaming the status lines Ollama
+// sends, and serves it from then on
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	var req pullRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	model := req.Model
+	if model == "" {
+		model = req.Name
+	}
+	if len(s.script.Pullable) > 0 && !containsString(s.script.Pullable, model) {
+		writeError(w, http.StatusInternalServerError, "pull model manifest: file does not exist")
+		return
+	}
+	s.mu.Lock()
+	s.pulled[model] = true
+	s.record(Call{Endpoint: "/api/pull", Model: model, Rule: PullRule})
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	if req.Stream != nil && !*req.Stream {
+		encoder.Encode(map[string]interface{}{"status": "success"})
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	send := func(line map[string]interface{}) {
+		encoder.Encode(line)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	const digest, total = "sha256:0123456789abcdef0123456789abcdef", 4 << 20
+	send(map[string]interface{}{"status": "pulling manifest"})
+	for completed := 0; completed <= total; completed += total / 4 {
+		send(map[string]interface{}{"status": "pulling " + digest[7:19], "digest": digest, "total": total, "completed": completed})
+	}
+	for _, status := range []string{"verifying sha256 digest", "writing manifest", "success"} {
+		send(map[string]interface{}{"status": status})
+	}
+}
+
+// load answers a generate request without a prompt
+func (s *Server) load(w http.ResponseWriter, call Call) {
+	if !s.knowsModel(call.Model) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", call.Model))
+		return
+	}
+	s.mu.Lock()
+	call.Rule = LoadRule
+	s.record(call)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"model":       call.Model,
+		"created_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"response":    "",
+		"done":        true,
+		"done_reason": "load",
+	})
+}
+
+// answer picks the response for call's prompt, writing the error reply itself
+// when there is none
+func (s *Server) answer(w http.ResponseWriter, call Call) (string, bool) {
+	model, prompt := call.Model, call.Prompt
+	if !s.knowsModel(model) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", model))
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rule := range s.script.Rules {
+		if !rule.matches(model, prompt) || (rule.Times > 0 && s.used[rule] >= rule.Times) {
+			continue
+		}
+		s.used[rule]++
+		call.Rule = rule.Name
+		s.record(call)
+		if rule.Error != "" {
+			writeError(w, rule.Status, rule.Error)
+			return "", false
+		}
+		return rule.Response, true
+	}
+
+	s.record(call)
+	if s.script.Default != nil {
+		return *s.script.Default, true
+	}
+	s.unmatched = append(s.unmatched, prompt)
+	writeError(w, http.StatusInternalServerError, "fake ollama: no scripted response for prompt: "+firstLine(prompt))
+	return "", false
+}
+
+// record keeps call; s.mu is held
+func (s *Server) record(call Call) {
+	s.calls = append(s.calls, call)
+	if s.OnCall != nil {
+		s.OnCall(call)
+	}
+}
+
+func (s *Server) knowsModel(model string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.script.Models) == 0 || containsString(s.script.Models, model) || s.pulled[model]
+}
+
+func (r *Rule) matches(model, prompt string) bool {
+	if r.Model != "" && r.Model != model {
+		return false
+	}
+	for _, part := range r.Contains {
+		if !strings.Contains(prompt, part) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeGeneration sends response as a single JSON object, or streamed as
+// newline-delimited chunks ending with a done chunk, as Ollama does
+func writeGeneration(w http.ResponseWriter, stream bool, response string, chunk func(text string, done bool) map[string]interface{}) {
+	encoder := json.NewEncoder(w)
+	if !stream {
+		w.Header().Set("Content-Type", "application/json")
+		encoder.Encode(chunk(response, true))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	for _, word := range strings.SplitAfter(response, " ") {
+		if word == "" {
+			continue
+		}
+		encoder.Encode(chunk(word, false))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	encoder.Encode(chunk("", true))
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// countTokens approximates token counts by words, which is enough for usage
+// reporting to see non-zero numbers
+func countTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+// keepAlive renders a request's keep_alive, which Ollama takes as a duration
+// string or a number of seconds
+func keepAlive(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func numCtx(opts *options) int {
+	if opts == nil {
+		return 0
+	}
+	return opts.NumCtx
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
@@ -0,0 +1,189 @@
+package fakeollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/llm"
+)
+
+const testScript = `
+models: [coder]
+rules:
+  - name: overloaded
+    contains: [retry me]
+    times: 1
+    error: model is overloaded
+    status: 503
+  - name: greeting
+    contains: [hello, world]
+    response: hi there
+  - name: fallback
+    contains: [retry me]
+    response: second time lucky
+`
+
+func startServer(t *testing.T, script string) (*Server, *httptest.Server) {
+	t.Helper()
+	parsed, err := ParseScript([]byte(script))
+	if err != nil {
+		t.Fatalf("ParseScript: %v", err)
+	}
+	server := NewServer(parsed)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+func TestGenerateWithOllamaClient(t *testing.T) {
+	server, httpServer := startServer(t, testScript)
+	client := llm.NewOllamaClient(httpServer.URL, "coder")
+	ctx := context.Background()
+
+	response, usage, err := client.GenerateWithUsage(ctx, "say hello to the world")
+	if err != nil {
+		t.Fatalf("GenerateWithUsage: %v", err)
+	}
+	if response != "hi there" {
+		t.Errorf("response = %q, want %q", response, "hi there")
+	}
+	if usage.PromptTokens != 5 || usage.CompletionTokens != 2 {
+		t.Errorf("usage = %+v, want 5 prompt and 2 completion tokens", usage)
+	}
+
+	// The first retry hits the one-shot error rule, the next falls through to
+	// the following rule
+	if _, err := client.Generate(ctx, "please retry me"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("first retry error = %v, want status 503", err)
+	}
+	if response, err := client.Generate(ctx, "please retry me"); err != nil || response != "second time lucky" {
+		t.Errorf("second retry = %q, %v, want %q", response, err, "second time lucky")
+	}
+
+	if _, err := client.Generate(ctx, "hello there"); err == nil {
+		t.Error("prompt missing a substring was answered, want an error")
+	}
+	if unmatched := server.Unmatched(); len(unmatched) != 1 || unmatched[0] != "hello there" {
+		t.Errorf("Unmatched = %q, want [hello there]", unmatched)
+	}
+
+	var rules []string
+	for _, call := range server.Calls() {
+		rules = append(rules, call.Rule)
+	}
+	if got, want := strings.Join(rules, ","), "greeting,overloaded,fallback,"; got != want {
+		t.Errorf("rules used = %s, want %s", got, want)
+	}
+}
+
+func TestUnknownModel(t *testing.T) {
+	_, httpServer := startServer(t, testScript)
+	_, err := llm.NewOllamaClient(httpServer.URL, "other").Generate(context.Background(), "hello world")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("error = %v, want status 404", err)
+	}
+}
+
+func TestDefaultResponse(t *testing.T) {
+	server, httpServer := startServer(t, "default: anything\n")
+	response, err := llm.NewOllamaClient(httpServer.URL, "any").Generate(context.Background(), "unscripted")
+	if err != nil || response != "anything" {
+		t.Errorf("Generate = %q, %v, want %q", response, err, "anything")
+	}
+	if unmatched := server.Unmatched(); len(unmatched) != 0 {
+		t.Errorf("Unmatched = %q, want none", unmatched)
+	}
+}
+
+func TestChatStreams(t *testing.T) {
+	_, httpServer := startServer(t, testScript)
+	body := `{"model":"coder","messages":[{"role":"system","content":"hello"},{"role":"user","content":"world"}]}`
+	resp, err := http.Post(httpServer.URL+"/api/chat", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /api/chat: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var chunks int
+	var done bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var chunk struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+			Done bool `json:"done"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", scanner.Text(), err)
+		}
+		content.WriteString(chunk.Message.Content)
+		chunks++
+		done = chunk.Done
+	}
+	if content.String() != "hi there" || !done || chunks < 2 {
+		t.Errorf("streamed %q in %d chunks (done %v), want %q over several chunks ending done", content.String(), chunks, done, "hi there")
+	}
+}
+
+func TestTags(t *testing.T) {
+	_, httpServer := startServer(t, testScript)
+	resp, err := http.Get(httpServer.URL + "/api/tags")
+	if err != nil {
+		t.Fatalf("GET /api/tags: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		t.Fatalf("decoding tags: %v", err)
+	}
+	if len(tags.Models) != 1 || tags.Models[0].Name != "coder" {
+		t.Errorf("models = %+v, want [coder]", tags.Models)
+	}
+}
+
+func TestPullServesModel(t *testing.T) {
+	_, httpServer := startServer(t, testScript+"pullable: [small]\n")
+	ctx := context.Background()
+	client := llm.NewOllamaClient(httpServer.URL, "small")
+
+	if err := client.Load(ctx); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("loading before the pull = %v, want status 404", err)
+	}
+	var statuses []string
+	if err := client.Pull(ctx, func(p llm.PullProgress) { statuses = append(statuses, p.Status) }); err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if len(statuses) < 3 || statuses[0] != "pulling manifest" || statuses[len(statuses)-1] != "success" {
+		t.Errorf("statuses = %q, want pulling manifest ... success", statuses)
+	}
+	if err := client.Load(ctx); err != nil {
+		t.Errorf("loading after the pull: %v", err)
+	}
+
+	models, err := client.ListModels(ctx)
+	if err != nil || strings.Join(models, " ") != "coder small" {
+		t.Errorf("ListModels = %q, %v, want coder and small", models, err)
+	}
+	if err := llm.NewOllamaClient(httpServer.URL, "other").Pull(ctx, nil); err == nil {
+		t.Error("pulled a model that is not pullable, want an error")
+	}
+}
+
+func TestParseScriptRequiresContains(t *testing.T) {
+	if _, err := ParseScript([]byte("rules:\n  - response: hi\n")); err == nil {
+		t.Error("rule without contains parsed, want an error")
+	}
+}
@@ -0,0 +1,34 @@
+// Package fixture turns fixture directories into the git repositories agents work
+// in, for the tests and the eval runner alike.
+package fixture
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"mcp-server/internal/tools"
+)
+
+// Repo copies the files under src into dst and commits them to a fresh repository
+// on main, as agents read git status and diffs. The author and dates are fixed so
+// the same fixture always gets the same commit hash, and so the same prompts.
+func Repo(src, dst string) error {
+	if err := tools.CopyTree(src, dst); err != nil {
+		return fmt.Errorf("copying fixture: %w", err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Fixture", "-c", "user.email=fixture@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+		cmd.Dir = dst
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2024-01-01T00:00:00Z", "GIT_COMMITTER_DATE=2024-01-01T00:00:00Z")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, output)
+		}
+	}
+	return nil
+}
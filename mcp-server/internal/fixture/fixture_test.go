@@ -0,0 +1,56 @@
+package fixture
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestRepo(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "calc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{"go.mod": "module calc\n", "calc/calc.go": "package calc\n"} {
+		if err := os.WriteFile(filepath.Join(src, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, second := filepath.Join(t.TempDir(), "calc"), filepath.Join(t.TempDir(), "calc")
+	for _, dst := range []string{first, second} {
+		if err := Repo(src, dst); err != nil {
+			t.Fatalf("Repo: %v", err)
+		}
+	}
+
+	if status := git(t, first, "status", "--porcelain"); status != "" {
+		t.Errorf("fixture has uncommitted changes:\n%s", status)
+	}
+	if branch := git(t, first, "rev-parse", "--abbrev-ref", "HEAD"); branch != "main" {
+		t.Errorf("branch = %s, want main", branch)
+	}
+	if files := git(t, first, "ls-files"); files != "calc/calc.go\ngo.mod" {
+		t.Errorf("committed files = %q", files)
+	}
+	if a, b := git(t, first, "rev-parse", "HEAD"), git(t, second, "rev-parse", "HEAD"); a != b {
+		t.Errorf("the same fixture committed as %s and %s", a, b)
+	}
+
+	if err := Repo(filepath.Join(src, "missing"), t.TempDir()); err == nil {
+		t.Error("Repo copied a fixture that does not exist")
+	}
+}
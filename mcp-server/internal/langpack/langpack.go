@@ -0,0 +1,353 @@
+package langpack
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"mcp-server/internal/config"
+)
+
+// Pack describes how to build, test and lint one language stack
+type Pack struct {
+	Name          string
+	Aliases       []string                    // other project_type values that select this pack
+	Detect        []string                    // globs matched against the entries of a directory
+	Root          []string                    // globs marking the directory a multi-module build runs from
+	RootOutermost bool                        // the outermost directory with a Root match wins rather than the nearest
+	Build         string                      // run from the directory the pack was detected in, or its Root
+	Test          string                      // run from the same directory as Build
+	AutoFix       []string                    // formatting commands the tech lead applies
+	Linters       []config.LinterConfig       // used when the tech lead config declares none
+	TestFiles     []string                    // globs matched against a file's base name
+	TestDirs      []string                    // globs matched against each directory of a file's path
+	ErrorPatterns []config.ErrorPatternConfig // appended to the routing engine's patterns
+}
+
+// Target is a pack applied to one directory of the project
+type Target struct {
+	Pack *Pack
+	Dir  string // relative to the project root, "." for the root
+}
+
+// Files lists directory entries relative to the project root; directories end in "/"
+type Files interface {
+	ListFiles(path string) ([]string, error)
+}
+
+var (
+	mu    sync.RWMutex
+	packs []*Pack
+)
+
+func init() {
+	for _, pack := range builtinPacks() {
+		Register(pack)
+	}
+}
+
+// Register adds pack to the registry, replacing any pack with the same name
+func Register(pack *Pack) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, existing := range packs {
+		if existing.Name == pack.Name {
+			packs[i] = pack
+			return
+		}
+	}
+	packs = append(packs, pack)
+}
+
+// All returns the registered packs in registration order
+func All() []*Pack {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]*Pack(nil), packs...)
+}
+
+// Get returns the pack selected by a project type name or alias
+func Get(name string) *Pack {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	for _, pack := range All() {
+		if pack.Name == name {
+			return pack
+		}
+		for _, alias := range pack.Aliases {
+			if alias == name {
+				return pack
+			}
+		}
+	}
+	return nil
+}
+
+// Names lists every project type a request may name, packs first then aliases
+func Names() []string {
+	var names, aliases []string
+	for _, pack := range All() {
+		names = append(names, pack.Name)
+		aliases = append(aliases, pack.Aliases...)
+	}
+	return append(names, aliases...)
+}
+
+// ErrorPatterns returns the routing error patterns of every pack
+func ErrorPatterns() []config.ErrorPatternConfig {
+	var patterns []config.ErrorPatternConfig
+	for _, pack := range All() {
+		patterns = append(patterns, pack.ErrorPatterns...)
+	}
+	return patterns
+}
+
+// Linters returns the default linters of every pack
+func Linters() []config.LinterConfig {
+	var linters []config.LinterConfig
+	for _, pack := range All() {
+		linters = append(linters, pack.Linters...)
+	}
+	return linters
+}
+
+// IsTestFile reports whether any pack considers file a test
+func IsTestFile(file string) bool {
+	for _, pack := range All() {
+		if pack.IsTestFile(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTestFile reports whether file follows the pack's test naming or lives in a test directory
+func (p *Pack) IsTestFile(file string) bool {
+	file = filepath.ToSlash(file)
+	base := path.Base(file)
+	for _, pattern := range p.TestFiles {
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	dirs := strings.Split(path.Dir(file), "/")
+	for _, pattern := range p.TestDirs {
+		for _, dir := range dirs {
+			if ok, _ := path.Match(pattern, dir); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches returns the directory entries that are detection files of the pack
+func (p *Pack) matches(entries []string) []string {
+	var matched []string
+	for _, entry := range entries {
+		entry = strings.TrimSuffix(entry, "/")
+		for _, pattern := range p.Detect {
+			if ok, _ := path.Match(pattern, entry); ok {
+				matched = append(matched, entry)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Detect returns the packs whose detection files exist in dir
+func Detect(files Files, dir string) []*Pack {
+	entries, err := files.ListFiles(dir)
+	if err != nil {
+		return nil
+	}
+	return detectEntries(entries)
+}
+
+func detectEntries(entries []string) []*Pack {
+	var found []*Pack
+	for _, pack := range All() {
+		if len(pack.matches(entries)) > 0 {
+			found = append(found, pack)
+		}
+	}
+	return found
+}
+
+// hasRoot reports whether entries include one of the pack's build root markers
+func (p *Pack) hasRoot(entries []string) bool {
+	for _, entry := range entries {
+		entry = strings.TrimSuffix(entry, "/")
+		for _, pattern := range p.Root {
+			if ok, _ := path.Match(pattern, entry); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Detection is a pack found in one directory together with the files that identified it
+type Detection struct {
+	Pack     *Pack
+	Dir      string
+	Evidence []string // detection files, relative to the project root
+}
+
+// skipDirs hold dependencies and build output rather than sub-projects
+var skipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "target": true, "build": true,
+	"dist": true, "bin": true, "obj": true, "venv": true, "__pycache__": true,
+}
+
+// SkipDir reports whether a directory is hidden or holds dependencies or build output
+// rather than source
+func SkipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || skipDirs[name]
+}
+
+// Survey detects packs in the project root and in subdirectories up to depth levels
+// below it, shallowest first, so mixed projects such as a Go backend next to a
+// TypeScript frontend report every stack
+func Survey(files Files, depth int) []Detection {
+	var detections []Detection
+	level := []string{"."}
+	for current := 0; current <= depth && len(level) > 0; current++ {
+		var next []string
+		for _, dir := range level {
+			entries, err := files.ListFiles(dir)
+			if err != nil {
+				continue
+			}
+			for _, pack := range All() {
+				matched := pack.matches(entries)
+				if len(matched) == 0 {
+					continue
+				}
+				detection := Detection{Pack: pack, Dir: dir}
+				for _, name := range matched {
+					detection.Evidence = append(detection.Evidence, path.Join(dir, name))
+				}
+				detections = append(detections, detection)
+			}
+			for _, entry := range entries {
+				name := strings.TrimSuffix(entry, "/")
+				if name == entry || SkipDir(name) {
+					continue
+				}
+				next = append(next, path.Join(dir, name))
+			}
+		}
+		level = next
+	}
+	return detections
+}
+
+// Resolve picks the pack for every directory a change touched: each file belongs to
+// the nearest enclosing directory with detection files, so a monorepo gets one target
+// per sub-project. Without touched files, or when none resolve, the pack named by
+// projectType applies to the root, falling back to whatever the root detects.
+func Resolve(files Files, projectType string, touched []string) []Target {
+	preferred := Get(projectType)
+	listed := make(map[string][]string)
+	list := func(dir string) []string {
+		if entries, ok := listed[dir]; ok {
+			return entries
+		}
+		entries, _ := files.ListFiles(dir)
+		listed[dir] = entries
+		return entries
+	}
+	detectIn := func(dir string) []*Pack {
+		return prefer(detectEntries(list(dir)), preferred)
+	}
+
+	seen := make(map[Target]bool)
+	var targets []Target
+	add := func(pack *Pack, dir string) {
+		target := Target{Pack: pack, Dir: buildRoot(pack, dir, list)}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+
+	for _, file := range touched {
+		if file == "" || filepath.IsAbs(file) {
+			continue
+		}
+		dir := path.Dir(path.Clean(filepath.ToSlash(file)))
+		if strings.HasPrefix(dir, "..") {
+			continue
+		}
+		for {
+			if found := detectIn(dir); len(found) > 0 {
+				for _, pack := range found {
+					add(pack, dir)
+				}
+				break
+			}
+			if dir == "." {
+				break
+			}
+			dir = path.Dir(dir)
+		}
+	}
+
+	if len(targets) == 0 {
+		if preferred != nil {
+			add(preferred, ".")
+		} else {
+			for _, pack := range detectIn(".") {
+				add(pack, ".")
+			}
+		}
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool { return targets[i].Dir < targets[j].Dir })
+	return targets
+}
+
+// buildRoot moves a module detected in dir up to the directory its build runs
+// from: the nearest ancestor with one of the pack's Root markers, such as a
+// Gradle settings file or wrapper, or the outermost one for a Maven reactor.
+// Packs without markers, and modules no marker encloses, stay where they are.
+func buildRoot(pack *Pack, dir string, list func(dir string) []string) string {
+	if len(pack.Root) == 0 {
+		return dir
+	}
+	root := ""
+	for current := dir; ; current = path.Dir(current) {
+		if pack.hasRoot(list(current)) {
+			root = current
+			if !pack.RootOutermost {
+				break
+			}
+		}
+		if current == "." {
+			break
+		}
+	}
+	if root == "" {
+		return dir
+	}
+	return root
+}
+
+// prefer narrows a directory that several packs detect to the requested one
+func prefer(found []*Pack, preferred *Pack) []*Pack {
+	if preferred == nil || len(found) < 2 {
+		return found
+	}
+	for _, pack := range found {
+		if pack == preferred {
+			return []*Pack{pack}
+		}
+	}
+	return found
+}
@@ -0,0 +1,227 @@
+package langpack
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeFiles is a project tree given as the paths of its files
+type fakeFiles []string
+
+func (f fakeFiles) ListFiles(dir string) ([]string, error) {
+	seen := make(map[string]bool)
+	var entries []string
+	for _, file := range f {
+		rel := file
+		if dir != "." {
+			if !strings.HasPrefix(file, dir+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(file, dir+"/")
+		}
+		entry := rel
+		if i := strings.Index(rel, "/"); i >= 0 {
+			entry = rel[:i+1]
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	if entries == nil && dir != "." {
+		return nil, fmt.Errorf("%s: no such directory", dir)
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+func packNames(packs []*Pack) []string {
+	var names []string
+	for _, pack := range packs {
+		names = append(names, pack.Name)
+	}
+	return names
+}
+
+func targetStrings(targets []Target) []string {
+	var out []string
+	for _, target := range targets {
+		out = append(out, target.Pack.Name+"@"+target.Dir)
+	}
+	return out
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"go", "go"},
+		{" Java ", "maven"},
+		{"kotlin", "gradle"},
+		{"csharp", "dotnet"},
+		{"cobol", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if pack := Get(tt.name); pack != nil {
+			got = pack.Name
+		}
+		if got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fakeFiles
+		dir   string
+		want  []string
+	}{
+		{"go module", fakeFiles{"go.mod", "main.go"}, ".", []string{"go"}},
+		{"maven", fakeFiles{"pom.xml", "src/main/java/App.java"}, ".", []string{"maven"}},
+		{"gradle kotlin dsl", fakeFiles{"build.gradle.kts"}, ".", []string{"gradle"}},
+		{"dotnet solution glob", fakeFiles{"Shop.sln", "Shop/Shop.csproj"}, ".", []string{"dotnet"}},
+		{"detection files in a subdirectory", fakeFiles{"web/package.json"}, "web", []string{"typescript"}},
+		{"nothing", fakeFiles{"README.md"}, ".", nil},
+		{"missing directory", fakeFiles{"go.mod"}, "api", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := packNames(Detect(tt.files, tt.dir)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"calc_test.go", true},
+		{"internal/calc.go", false},
+		{"src/test/java/com/acme/AppTest.java", true},
+		{"src/main/java/com/acme/App.java", false},
+		{"Shop.Tests/CartTests.cs", true},
+		{"tests/test_cart.py", true},
+		{"cart.py", false},
+	}
+	for _, tt := range tests {
+		if got := IsTestFile(tt.file); got != tt.want {
+			t.Errorf("IsTestFile(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	monorepo := fakeFiles{
+		"go.mod", "main.go",
+		"web/package.json", "web/src/app.ts",
+		"tools/script.sh",
+	}
+	gradleMultiProject := fakeFiles{
+		"settings.gradle.kts", "gradlew", "build.gradle.kts",
+		"app/build.gradle.kts", "app/src/main/kotlin/App.kt",
+		"lib/build.gradle.kts", "lib/src/main/kotlin/Lib.kt",
+	}
+	gradleNested := fakeFiles{
+		"README.md",
+		"backend/settings.gradle", "backend/gradlew",
+		"backend/core/build.gradle", "backend/core/src/main/java/Core.java",
+	}
+	gradleStandalone := fakeFiles{"service/build.gradle", "service/src/main/java/Service.java"}
+	mavenReactor := fakeFiles{
+		"pom.xml",
+		"services/api/pom.xml", "services/api/src/main/java/Api.java",
+		"services/worker/pom.xml", "services/worker/src/main/java/Worker.java",
+	}
+
+	tests := []struct {
+		name        string
+		files       fakeFiles
+		projectType string
+		touched     []string
+		want        []string
+	}{
+		{"file in the root", monorepo, "", []string{"main.go"}, []string{"go@."}},
+		{"one target per sub-project", monorepo, "", []string{"web/src/app.ts", "main.go"}, []string{"go@.", "typescript@web"}},
+		{"file outside any sub-project falls back to the root", monorepo, "", []string{"tools/script.sh"}, []string{"go@."}},
+		{"paths outside the project are ignored", monorepo, "", []string{"../other/main.go", "/abs/main.go"}, []string{"go@."}},
+		{"no touched files uses the requested pack", monorepo, "java", nil, []string{"maven@."}},
+		{"no touched files detects the root", monorepo, "", nil, []string{"go@."}},
+		{"gradle subprojects build from the settings directory", gradleMultiProject, "", []string{"app/src/main/kotlin/App.kt", "lib/src/main/kotlin/Lib.kt"}, []string{"gradle@."}},
+		{"gradle build nested below the project root", gradleNested, "", []string{"backend/core/src/main/java/Core.java"}, []string{"gradle@backend"}},
+		{"gradle module without settings or wrapper stays put", gradleStandalone, "", []string{"service/src/main/java/Service.java"}, []string{"gradle@service"}},
+		{"maven modules build from the outermost pom", mavenReactor, "", []string{"services/api/src/main/java/Api.java", "services/worker/src/main/java/Worker.java"}, []string{"maven@."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetStrings(Resolve(tt.files, tt.projectType, tt.touched)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePrefersRequestedPack(t *testing.T) {
+	// A directory with both build files resolves to the requested one
+	files := fakeFiles{"pom.xml", "build.gradle", "src/main/java/App.java"}
+	tests := []struct {
+		projectType string
+		want        []string
+	}{
+		{"maven", []string{"maven@."}},
+		{"gradle", []string{"gradle@."}},
+		{"", []string{"maven@.", "gradle@."}},
+	}
+	for _, tt := range tests {
+		if got := targetStrings(Resolve(files, tt.projectType, []string{"src/main/java/App.java"})); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Resolve(%q) = %v, want %v", tt.projectType, got, tt.want)
+		}
+	}
+}
+
+func TestSurvey(t *testing.T) {
+	files := fakeFiles{
+		"go.mod",
+		"web/package.json",
+		"web/node_modules/left-pad/package.json",
+		".cache/go.mod",
+		"services/billing/pom.xml",
+		"services/billing/core/deep/Cargo.toml",
+	}
+
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{0, []string{"go@. [go.mod]"}},
+		{1, []string{"go@. [go.mod]", "typescript@web [web/package.json]"}},
+		{2, []string{"go@. [go.mod]", "typescript@web [web/package.json]", "maven@services/billing [services/billing/pom.xml]"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, detection := range Survey(files, tt.depth) {
+			got = append(got, fmt.Sprintf("%s@%s %v", detection.Pack.Name, detection.Dir, detection.Evidence))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Survey(depth %d) = %v, want %v", tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestSkipDir(t *testing.T) {
+	for name, want := range map[string]bool{".git": true, "node_modules": true, "target": true, "src": false, "web": false} {
+		if got := SkipDir(name); got != want {
+			t.Errorf("SkipDir(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package langpack
+
+import "mcp-server/internal/config"
+
+var (
+	javaTestFiles = []string{"*Test.java", "*Tests.java", "*IT.java", "*Test.kt", "*Tests.kt"}
+
+	jvmLinkSuggestions = []string{
+		"Check imports and package names",
+		"Verify the symbol is declared in a module on the classpath",
+	}
+)
+
+// builtinPacks are the stacks the server supports out of the box
+func builtinPacks() []*Pack {
+	return []*Pack{
+		{
+			Name:    "go",
+			Aliases: []string{"golang"},
+			Detect:  []string{"go.mod", "go.work"},
+			Build:   "go build ./...",
+			Test:    "go test ./...",
+			AutoFix: []string{"go fmt", "go mod tidy"},
+			Linters: []config.LinterConfig{
+				{
+					Name:            "go vet",
+					Command:         "go vet ./...",
+					Format:          "regex",
+					Detect:          []string{"go.mod"},
+					Pattern:         `^(?:vet: )?(?P<file>[^\s:]+\.go):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?P<message>.+)$`,
+					DefaultSeverity: "HIGH",
+				},
+			},
+			TestFiles: []string{"*_test.go"},
+			ErrorPatterns: []config.ErrorPatternConfig{
+				{
+					Pattern:     `(?i)(missing go\.sum entry|no required module provides package)`,
+					Category:    "missing_dependency",
+					Severity:    3,
+					Suggestions: []string{"Run 'go mod tidy'", "Add the module with 'go get'"},
+				},
+			},
+		},
+		{
+			Name:      "typescript",
+			Aliases:   []string{"javascript", "node"},
+			Detect:    []string{"package.json", "tsconfig.json"},
+			Build:     "npm run build",
+			Test:      "npm test",
+			AutoFix:   []string{"npm run lint --fix"},
+			TestFiles: []string{"*.test.ts", "*.test.tsx", "*.test.js", "*.test.jsx", "*.spec.ts", "*.spec.tsx", "*.spec.js", "*.spec.jsx"},
+			TestDirs:  []string{"__tests__", "test", "tests"},
+			ErrorPatterns: []config.ErrorPatternConfig{
+				{
+					Pattern:     `(?i)(error TS2304|cannot find name '\w+')`,
+					Category:    "undefined_symbol",
+					Severity:    4,
+					Suggestions: []string{"Import or declare the missing name", "Check for typos in identifiers"},
+				},
+				{
+					Pattern:     `(?i)(error TS2322|error TS2345|is not assignable to (type|parameter of type))`,
+					Category:    "type_error",
+					Severity:    3,
+					Suggestions: []string{"Check the declared types", "Narrow or convert the value before use"},
+				},
+				{
+					Pattern:     `(?i)(cannot find module '[^']+'|error TS2307)`,
+					Category:    "missing_dependency",
+					Severity:    3,
+					Suggestions: []string{"Run 'npm install'", "Add the package to package.json", "Check the import path"},
+				},
+			},
+		},
+		{
+			Name:    "python",
+			Aliases: []string{"py"},
+			Detect:  []string{"pyproject.toml", "requirements.txt", "setup.py", "Pipfile"},
+			Build:   "python -m py_compile *.py",
+			Test:    "python -m pytest",
+			AutoFix: []string{"python -m black ."},
+			Linters: []config.LinterConfig{
+				{
+					Name:            "flake8",
+					Command:         "python -m flake8",
+					Format:          "regex",
+					Detect:          []string{"requirements.txt", "pyproject.toml", "setup.py"},
+					Pattern:         `^(?P<file>[^\s:]+\.py):(?P<line>\d+):(?P<col>\d+): (?P<rule>[A-Z]+\d+) (?P<message>.+)$`,
+					Severity:        map[string]string{"E999": "CRITICAL", "F821": "HIGH", "F811": "HIGH"},
+					DefaultSeverity: "LOW",
+				},
+			},
+			TestFiles: []string{"test_*.py", "*_test.py", "conftest.py"},
+			TestDirs:  []string{"tests", "test"},
+			ErrorPatterns: []config.ErrorPatternConfig{
+				{
+					Pattern:     `(?i)(modulenotfounderror|no module named '[^']+')`,
+					Category:    "missing_dependency",
+					Severity:    3,
+					Suggestions: []string{"Add the package to requirements.txt or pyproject.toml", "Install the dependencies", "Check the import path"},
+				},
+				{
+					Pattern:     `(?i)nameerror: name '\w+' is not defined`,
+					Category:    "undefined_symbol",
+					Severity:    4,
+					Suggestions: []string{"Import or define the missing name", "Check for typos in identifiers"},
+				},
+				{
+					Pattern:     `(?i)(indentationerror|taberror)`,
+					Category:    "syntax_error",
+					Severity:    4,
+					Suggestions: []string{"Fix inconsistent indentation", "Do not mix tabs and spaces"},
+				},
+			},
+		},
+		{
+			Name:    "rust",
+			Aliases: []string{"cargo"},
+			Detect:  []string{"Cargo.toml"},
+			Build:   "cargo build",
+			Test:    "cargo test",
+			AutoFix: []string{"cargo fmt"},
+			Linters: []config.LinterConfig{
+				{
+					Name:            "clippy",
+					Command:         "cargo clippy --quiet --message-format=short",
+					Format:          "regex",
+					Detect:          []string{"Cargo.toml"},
+					Pattern:         `^(?P<file>[^\s:]+\.rs):(?P<line>\d+):(?P<col>\d+): (?P<severity>warning|error)(?:\[(?P<rule>[\w:]+)\])?: (?P<message>.+)$`,
+					Severity:        map[string]string{"error": "HIGH", "warning": "LOW"},
+					DefaultSeverity: "LOW",
+				},
+			},
+			TestDirs: []string{"tests", "benches"},
+			ErrorPatterns: []config.ErrorPatternConfig{
+				{
+					Pattern:     `(?i)error\[E0(425|412|433)\]`,
+					Category:    "undefined_symbol",
+					Severity:    4,
+					Suggestions: []string{"Bring the item into scope with 'use'", "Check the module path and visibility"},
+				},
+				{
+					Pattern:     `(?i)error\[E0(308|277|599)\]`,
+					Category:    "type_error",
+					Severity:    3,
+					Suggestions: []string{"Check the expected and found types", "Implement or derive the missing trait"},
+				},
+				{
+					Pattern:     `(?i)(error\[E0432\]|no matching package named|failed to select a version)`,
+					Category:    "missing_dependency",
+					Severity:    3,
+					Suggestions: []string{"Add the crate to Cargo.toml", "Check the crate name and version"},
+				},
+				{
+					Pattern:     `(?i)test result: failed`,
+					Category:    "test_failure",
+					Severity:    2,
+					Suggestions: []string{"Review the failing assertions", "Run 'cargo test' with the failing test name"},
+				},
+			},
+		},
+		{
+			Name:          "maven",
+			Aliases:       []string{"java"},
+			Detect:        []string{"pom.xml"},
+			Root:          []string{"pom.xml"},
+			RootOutermost: true,
+			Build:         "mvn -q compile",
+			Test:          "mvn -q test",
+			TestFiles:     javaTestFiles,
+			TestDirs:      []string{"test"},
+			ErrorPatterns: []config.ErrorPatternConfig{
+				{
+					Pattern:     `(?i)cannot find symbol`,
+					Category:    "undefined_symbol",
+					Severity:    4,
+					Suggestions: jvmLinkSuggestions,
+				},
+				{
+					Pattern:     `(?i)incompatible types`,
+					Category:    "type_error",
+					Severity:    3,
+					Suggestions: []string{"Check the declared and assigned types", "Add an explicit conversion"},
+				},
+				{
+					Pattern:     `(?i)(could not resolve dependencies|package [\w.]+ does not exist)`,
+					Category:    "missing_dependency",
+					Severity:    3,
+					Suggestions: []string{"Add the dependency to pom.xml", "Check the groupId, artifactId and version"},
+				},
+				{
+					Pattern:     `(?i)(tests run:.*failures: [1-9]|there are test failures)`,
+					Category:    "test_failure",
+					Severity:    2,
+					Suggestions: []string{"Review the surefire reports", "Check test data and setup"},
+				},
+			},
+		},
+		{
+			Name:      "gradle",
+			Aliases:   []string{"kotlin"},
+			Detect:    []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+			Root:      []string{"settings.gradle", "settings.gradle.kts", "gradlew"},
+			Build:     "./gradlew build -x test",
+			Test:      "./gradlew test",
+			TestFiles: javaTestFiles,
+			TestDirs:  []string{"test"},
+			ErrorPatterns: []config.ErrorPatternConfig{
+				{
+					Pattern:     `(?i)unresolved reference`,
+					Category:    "undefined_symbol",
+					Severity:    4,
+					Suggestions: jvmLinkSuggestions,
+				},
+				{
+					Pattern:     `(?i)type mismatch`,
+					Category:    "type_error",
+					Severity:    3,
+					Suggestions: []string{"Check the inferred and expected types", "Add an explicit conversion"},
+				},
+				{
+					Pattern:     `(?i)could not resolve all (files|dependencies)`,
+					Category:    "missing_dependency",
+					Severity:    3,
+					Suggestions: []string{"Add the dependency to build.gradle", "Check the repositories block"},
+				},
+				{
+					Pattern:     `(?i)(there were failing tests|\d+ tests completed, \d+ failed)`,
+					Category:    "test_failure",
+					Severity:    2,
+					Suggestions: []string{"Review the test report under build/reports/tests", "Check test data and setup"},
+				},
+			},
+		},
+		{
+			Name:      "dotnet",
+			Aliases:   []string{"csharp", "fsharp"},
+			Detect:    []string{"*.sln", "*.csproj", "*.fsproj"},
+			Build:     "dotnet build",
+			Test:      "dotnet test",
+			AutoFix:   []string{"dotnet format"},
+			TestFiles: []string{"*Tests.cs", "*Test.cs", "*Tests.fs"},
+			TestDirs:  []string{"*.Tests", "*.UnitTests", "*.IntegrationTests"},
+			ErrorPatterns: []config.ErrorPatternConfig{
+				{
+					Pattern:     `(?i)error CS0(103|117|246)`,
+					Category:    "undefined_symbol",
+					Severity:    4,
+					Suggestions: []string{"Add the missing using directive", "Check the project references"},
+				},
+				{
+					Pattern:     `(?i)error CS(1002|1513|1026)`,
+					Category:    "syntax_error",
+					Severity:    4,
+					Suggestions: []string{"Check semicolons, braces and parentheses"},
+				},
+				{
+					Pattern:     `(?i)error CS(0029|1503|0266)`,
+					Category:    "type_error",
+					Severity:    3,
+					Suggestions: []string{"Check the declared and assigned types", "Add an explicit cast or conversion"},
+				},
+				{
+					Pattern:     `(?i)(error NU110[12]|unable to find package)`,
+					Category:    "missing_dependency",
+					Severity:    3,
+					Suggestions: []string{"Add the package with 'dotnet add package'", "Check the package source and version"},
+				},
+				{
+					Pattern:     `(?i)(test run failed|failed!\s+-\s+failed:\s+[1-9])`,
+					Category:    "test_failure",
+					Severity:    2,
+					Suggestions: []string{"Review the failing test output", "Check test data and setup"},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,159 @@
+package llm
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Breaker states
+const (
+	BreakerClosed   = "closed"    // calls go through
+	BreakerOpen     = "open"      // calls are rejected until the cooldown ends
+	BreakerHalfOpen = "half_open" // one trial call decides whether to close again
+)
+
+// BreakerState is a snapshot of one model's breaker, as reported on /health
+type BreakerState struct {
+	Backend    string     `json:"backend"`
+	Model      string     `json:"model"`
+	State      string     `json:"state"`
+	Failures   int        `json:"consecutive_failures"`
+	OpenedAt   *time.Time `json:"opened_at,omitempty"`
+	RetryAfter *time.Time `json:"retry_after,omitempty"`
+}
+
+// breaker opens after a run of failed calls to a model and lets a single trial
+// call through once its cooldown has passed
+type breaker struct {
+	backend   string
+	model     string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool // a half-open trial call is in flight
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker)
+)
+
+// breakerFor returns the breaker of model on backend, shared by every client
+func breakerFor(backend, model string, policy Policy) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	key := backend + "|" + model
+	b, ok := breakers[key]
+	if !ok {
+		b = &breaker{
+			backend:   backend,
+			model:     model,
+			threshold: policy.BreakerFailures,
+			cooldown:  policy.BreakerCooldown,
+			state:     BreakerClosed,
+		}
+		breakers[key] = b
+	}
+	return b
+}
+
+// BreakerStates reports the breaker of every model called so far
+func BreakerStates() []BreakerState {
+	breakersMu.Lock()
+	list := make([]*breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	states := make([]BreakerState, 0, len(list))
+	for _, b := range list {
+		states = append(states, b.snapshot())
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Backend != states[j].Backend {
+			return states[i].Backend < states[j].Backend
+		}
+		return states[i].Model < states[j].Model
+	})
+	return states
+}
+
+// AnyBreakerOpen reports whether some model is currently rejecting calls
+func AnyBreakerOpen() bool {
+	for _, state := range BreakerStates() {
+		if state.State == BreakerOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// allow reports whether a call may go through, moving an open breaker whose
+// cooldown has passed to half-open for a single trial
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return true
+	case BreakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record notes the outcome of an allowed call
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// abandon forgets an allowed call that ended without an outcome
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+func (b *breaker) snapshot() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := BreakerState{Backend: b.backend, Model: b.model, State: b.state, Failures: b.failures}
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		// The next call will be the trial
+		state.State = BreakerHalfOpen
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAfter := openedAt.Add(b.cooldown)
+		state.OpenedAt, state.RetryAfter = &openedAt, &retryAfter
+	}
+	return state
+}
@@ -0,0 +1,285 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/metrics"
+	"mcp-server/internal/prompt"
+)
+
+// Cache keeps model responses on disk, one file per request hash, for a TTL
+// and up to a size cap past which the least recently used are evicted. Several
+// processes may share a directory.
+type Cache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+
+	mu   sync.Mutex
+	size int64 // bytes in dir, -1 until first counted
+}
+
+type cacheEntry struct {
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	Response  string    `json:"response"`
+}
+
+// NewCache returns a cache in dir, which is created on the first write
+func NewCache(dir string, ttl time.Duration, maxBytes int64) *Cache {
+	return &Cache{dir: dir, ttl: ttl, maxBytes: maxBytes, size: -1}
+}
+
+var (
+	cachesMu sync.Mutex
+	caches   = make(map[string]*Cache)
+)
+
+// cacheFor returns the cache section configures, shared by every client using
+// its directory, or nil when caching is off
+func cacheFor(section config.CacheSection) *Cache {
+	if !section.Enabled {
+		return nil
+	}
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	cache, ok := caches[section.Dir]
+	if !ok {
+		cache = NewCache(section.Dir, time.Duration(section.TTLHours)*time.Hour, int64(section.MaxMB)<<20)
+		caches[section.Dir] = cache
+	}
+	return cache
+}
+
+// Get returns the response stored under key unless it has expired
+func (c *Cache) Get(key string) (string, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || time.Since(entry.CreatedAt) > c.ttl {
+		c.remove(path)
+		return "", false
+	}
+	// The modification time orders eviction, so recently used entries go last
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return entry.Response, true
+}
+
+// Put stores the response of model under key
+func (c *Cache) Put(key, model, response string) error {
+	data, err := json.Marshal(cacheEntry{Model: model, CreatedAt: time.Now(), Response: response})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write and rename so readers never see half an entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var replaced int64
+	if info, err := os.Stat(path); err == nil {
+		replaced = info.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	if c.size < 0 {
+		c.size = c.countLocked()
+	} else {
+		c.size += int64(len(data)) - replaced
+	}
+	if c.size > c.maxBytes {
+		c.evictLocked()
+	}
+	return nil
+}
+
+// Delete removes the response stored under key
+func (c *Cache) Delete(key string) {
+	c.remove(c.path(key))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+func (c *Cache) remove(path string) {
+	info, err := os.Stat(path)
+	if err != nil || os.Remove(path) != nil {
+		return
+	}
+	c.mu.Lock()
+	if c.size >= 0 {
+		c.size -= info.Size()
+	}
+	c.mu.Unlock()
+}
+
+type cacheFile struct {
+	path string
+	size int64
+	used time.Time
+}
+
+func (c *Cache) files() []cacheFile {
+	var files []cacheFile
+	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, cacheFile{path: path, size: info.Size(), used: info.ModTime()})
+		}
+		return nil
+	})
+	return files
+}
+
+// countLocked adds up the size of the entries on disk; c.mu is held
+func (c *Cache) countLocked() int64 {
+	var total int64
+	for _, file := range c.files() {
+		total += file.size
+	}
+	return total
+}
+
+// evictLocked removes the least recently used entries until the cache is a
+// tenth under its cap, so that eviction doesn't run on every write; c.mu is held
+func (c *Cache) evictLocked() {
+	files := c.files()
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	for _, file := range files {
+		if total <= c.maxBytes*9/10 {
+			break
+		}
+		if os.Remove(file.path) == nil {
+			total -= file.size
+		}
+	}
+	c.size = total
+}
+
+// cacheKey identifies a generation of text: the model, its options and the
+// prompt. keep_alive doesn't change the answer and is left out.
+func (c *OllamaClient) cacheKey(text string) string {
+	req := c.request(text)
+	req.KeepAlive = ""
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type noCacheKey struct{}
+
+// WithoutCache makes model calls made with ctx skip the response cache, for
+// retries that need a different answer to the same prompt
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// CacheAllowed reports whether a model call made with ctx may be answered from
+// the response cache
+func CacheAllowed(ctx context.Context) bool {
+	skip, _ := ctx.Value(noCacheKey{}).(bool)
+	return !skip
+}
+
+// cachedClient answers requests to an Ollama model that were made before from
+// a Cache
+type cachedClient struct {
+	client *OllamaClient
+	cache  *Cache
+
+	mu      sync.Mutex
+	lastKey string
+}
+
+func newCachedClient(client *OllamaClient, cache *Cache) *cachedClient {
+	return &cachedClient{client: client, cache: cache}
+}
+
+func (c *cachedClient) Generate(ctx context.Context, text string) (string, error) {
+	response, _, err := c.GenerateWithUsage(ctx, text)
+	return response, err
+}
+
+// GenerateWithUsage returns the cached response to text unless ctx bypasses the
+// cache, and stores every new response. A cached response used no tokens.
+func (c *cachedClient) GenerateWithUsage(ctx context.Context, text string) (string, prompt.Usage, error) {
+	key := c.client.cacheKey(text)
+	if CacheAllowed(ctx) {
+		response, ok := c.cache.Get(key)
+		metrics.LLMCacheLookup(c.client.model, ok)
+		if ok {
+			c.setLastKey(key)
+			return response, prompt.Usage{}, nil
+		}
+	}
+
+	response, usage, err := c.client.GenerateWithUsage(ctx, text)
+	if err != nil {
+		return "", usage, err
+	}
+	if err := c.cache.Put(key, c.client.model, response); err != nil {
+		log.Printf("LLM cache: %v", err)
+	}
+	c.setLastKey(key)
+	return response, usage, nil
+}
+
+// ReportOutput drops the last response from the cache when the agent could not
+// use it, so that asking again reaches the model
+func (c *cachedClient) ReportOutput(usable bool) {
+	c.mu.Lock()
+	key := c.lastKey
+	c.mu.Unlock()
+	if !usable && key != "" {
+		c.cache.Delete(key)
+	}
+}
+
+func (c *cachedClient) setLastKey(key string) {
+	c.mu.Lock()
+	c.lastKey = key
+	c.mu.Unlock()
+}
@@ -0,0 +1,126 @@
+package llm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server/internal/llm"
+)
+
+func cacheFiles(t *testing.T, dir string) int {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestCacheExpiresEntries(t *testing.T) {
+	dir := t.TempDir()
+	key := strings.Repeat("ab", 32)
+	if err := llm.NewCache(dir, time.Hour, 1<<20).Put(key, "coder", "hi"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if response, ok := llm.NewCache(dir, time.Hour, 1<<20).Get(key); !ok || response != "hi" {
+		t.Fatalf("Get = %q, %v; want the stored response from another cache on the directory", response, ok)
+	}
+	if _, ok := llm.NewCache(dir, time.Nanosecond, 1<<20).Get(key); ok {
+		t.Fatal("Get returned an expired entry")
+	}
+	if n := cacheFiles(t, dir); n != 0 {
+		t.Errorf("%d files left, want the expired entry removed", n)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	// Room for three and a half entries of a little over 10 KB
+	cache := llm.NewCache(dir, time.Hour, 36000)
+	response := strings.Repeat("x", 10240)
+	keys := []string{strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64), strings.Repeat("d", 64)}
+
+	for i, key := range keys[:3] {
+		if err := cache.Put(key, "coder", response); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		// Spread the modification times the eviction order depends on
+		past := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(filepath.Join(dir, key[:2], key+".json"), past, past)
+	}
+	// Using the oldest entry makes the second the least recently used
+	if _, ok := cache.Get(keys[0]); !ok {
+		t.Fatal("Get missed a stored entry")
+	}
+	if err := cache.Put(keys[3], "coder", response); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	for i, want := range []bool{true, false, true, true} {
+		if _, ok := cache.Get(keys[i]); ok != want {
+			t.Errorf("entry %d cached = %v, want %v", i, ok, want)
+		}
+	}
+}
+
+func TestAgentClientCachesResponses(t *testing.T) {
+	fake, url := startFake(t, `
+rules:
+  - contains: [hello]
+    times: 1
+    response: first
+  - contains: [hello]
+    times: 1
+    response: second
+  - contains: [hello]
+    response: third
+`)
+	cfg := loadConfig(t, modelsConfig+`
+[llm.cache]
+enabled = true
+dir = "`+t.TempDir()+`"
+`)
+	client := llm.NewOllamaAgentClient(url, cfg.Agents["senior_qa"], cfg)
+	ctx := context.Background()
+	generate := func(ctx context.Context) string {
+		t.Helper()
+		response, err := client.Generate(ctx, "hello")
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		return response
+	}
+
+	if first, again := generate(ctx), generate(ctx); first != "first" || again != "first" {
+		t.Fatalf("responses = %q, %q; want the first answer twice", first, again)
+	}
+	if len(fake.Calls()) != 1 {
+		t.Fatalf("got %d calls, want the second answered from the cache", len(fake.Calls()))
+	}
+
+	// A bypassing call asks the model and refreshes the entry
+	if response := generate(llm.WithoutCache(ctx)); response != "second" {
+		t.Fatalf("bypassing response = %q, want second", response)
+	}
+	if response := generate(ctx); response != "second" {
+		t.Fatalf("response = %q, want the refreshed entry", response)
+	}
+
+	// An unusable response is dropped, so the next call reaches the model
+	client.ReportOutput(false)
+	if response := generate(ctx); response != "third" {
+		t.Fatalf("response after an unusable one = %q, want third", response)
+	}
+
+	// Another context window is another request
+	if _, err := llm.NewOllamaAgentClient(url, cfg.Agents["senior_tech_lead"], cfg).Generate(ctx, "hello"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(fake.Calls()) != 4 {
+		t.Errorf("got %d calls, want 4", len(fake.Calls()))
+	}
+}
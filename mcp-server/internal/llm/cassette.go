@@ -0,0 +1,286 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"mcp-server/internal/prompt"
+)
+
+// CassetteMode selects whether a cassette calls the model or serves recorded responses
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record"
+	CassetteReplay CassetteMode = "replay"
+)
+
+// Generator is the client a cassette records
+type Generator interface {
+	Generate(ctx context.Context, text string) (string, error)
+}
+
+type usageGenerator interface {
+	GenerateWithUsage(ctx context.Context, text string) (string, prompt.Usage, error)
+}
+
+// Interaction is a prompt and the responses the model gave to it, in call order
+type Interaction struct {
+	Hash      string             `json:"hash"`
+	Prompt    string             `json:"prompt"`
+	Responses []RecordedResponse `json:"responses"`
+}
+
+// RecordedResponse is one response with the token counts the model reported
+type RecordedResponse struct {
+	Response         string `json:"response"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+}
+
+type cassetteFile struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Cassette stores model responses keyed by a hash of the prompt, recording them
+// from live clients or replaying them without a model. Values that differ between
+// runs, such as a temporary working directory, are scrubbed from prompts before
+// hashing and restored in replayed responses.
+type Cassette struct {
+	path   string
+	mode   CassetteMode
+	scrubs []scrub
+	mu     sync.Mutex
+	byHash map[string]*Interaction
+	order  []*Interaction
+	served map[string]int
+	misses []string
+}
+
+type scrub struct {
+	value       string
+	placeholder string
+}
+
+// NewCassette opens the cassette at path. Replaying requires the file to exist;
+// recording starts from an empty cassette that Save writes.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{
+		path:   path,
+		mode:   mode,
+		byHash: make(map[string]*Interaction),
+		served: make(map[string]int),
+	}
+
+	switch mode {
+	case CassetteRecord:
+	case CassetteReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		var file cassetteFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		for _, interaction := range file.Interactions {
+			c.byHash[interaction.Hash] = interaction
+			c.order = append(c.order, interaction)
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q (want record or replay)", mode)
+	}
+	return c, nil
+}
+
+// Scrub replaces value with placeholder in prompts, and placeholder with value in
+// replayed responses
+func (c *Cassette) Scrub(value, placeholder string) *Cassette {
+	if value != "" {
+		c.scrubs = append(c.scrubs, scrub{value: value, placeholder: placeholder})
+	}
+	return c
+}
+
+// Mode reports whether the cassette records or replays
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Wrap returns a client that records the responses of client in the cassette, or
+// replays them; client may be nil when replaying
+func (c *Cassette) Wrap(client Generator) *CassetteClient {
+	return &CassetteClient{cassette: c, client: client}
+}
+
+// CassetteClient is one model's client bound to a cassette
+type CassetteClient struct {
+	cassette *Cassette
+	client   Generator
+}
+
+func (cc *CassetteClient) Generate(ctx context.Context, text string) (string, error) {
+	response, _, err := cc.GenerateWithUsage(ctx, text)
+	return response, err
+}
+
+// GenerateWithUsage serves the next recorded response to text, or records a new one
+func (cc *CassetteClient) GenerateWithUsage(ctx context.Context, text string) (string, prompt.Usage, error) {
+	c := cc.cassette
+	scrubbed := c.scrubPrompt(text)
+	hash := promptHash(scrubbed)
+
+	if c.mode == CassetteRecord {
+		if cc.client == nil {
+			return "", prompt.Usage{}, fmt.Errorf("llm cassette %s: recording needs a client", filepath.Base(c.path))
+		}
+		return c.record(ctx, cc.client, text, scrubbed, hash)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interaction, ok := c.byHash[hash]
+	if !ok || c.served[hash] >= len(interaction.Responses) {
+		miss := fmt.Sprintf("no recorded response for prompt %s (call %d)%s", hash, c.served[hash]+1, c.closestDifference(scrubbed))
+		c.misses = append(c.misses, miss)
+		return "", prompt.Usage{}, fmt.Errorf("llm cassette %s: %s", filepath.Base(c.path), miss)
+	}
+	recorded := interaction.Responses[c.served[hash]]
+	c.served[hash]++
+	usage := prompt.Usage{PromptTokens: recorded.PromptTokens, CompletionTokens: recorded.CompletionTokens}
+	return c.unscrub(recorded.Response), usage, nil
+}
+
+func (c *Cassette) record(ctx context.Context, client Generator, text, scrubbed, hash string) (string, prompt.Usage, error) {
+	var (
+		response string
+		usage    prompt.Usage
+		err      error
+	)
+	if reporter, ok := client.(usageGenerator); ok {
+		response, usage, err = reporter.GenerateWithUsage(ctx, text)
+	} else {
+		response, err = client.Generate(ctx, text)
+	}
+	if err != nil {
+		// Failed calls are not recorded, so replays cannot reproduce them
+		return response, usage, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interaction, ok := c.byHash[hash]
+	if !ok {
+		interaction = &Interaction{Hash: hash, Prompt: scrubbed}
+		c.byHash[hash] = interaction
+		c.order = append(c.order, interaction)
+	}
+	interaction.Responses = append(interaction.Responses, RecordedResponse{
+		Response:         c.scrubPrompt(response),
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+	return response, usage, nil
+}
+
+// Save writes what was recorded; replay cassettes are left alone
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.order}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0644)
+}
+
+// Misses describes every prompt a replay had no response for
+func (c *Cassette) Misses() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.misses...)
+}
+
+// Unplayed lists the hashes of recorded responses a replay never served, which
+// means the workflow made fewer calls than when it was recorded
+func (c *Cassette) Unplayed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var unplayed []string
+	for _, interaction := range c.order {
+		if c.served[interaction.Hash] < len(interaction.Responses) {
+			unplayed = append(unplayed, interaction.Hash)
+		}
+	}
+	return unplayed
+}
+
+func (c *Cassette) scrubPrompt(text string) string {
+	for _, s := range c.scrubs {
+		text = strings.ReplaceAll(text, s.value, s.placeholder)
+	}
+	return text
+}
+
+func (c *Cassette) unscrub(text string) string {
+	for _, s := range c.scrubs {
+		text = strings.ReplaceAll(text, s.placeholder, s.value)
+	}
+	return text
+}
+
+// closestDifference points at the first line where text departs from the recorded
+// prompt it shares the longest prefix with, to show what changed
+func (c *Cassette) closestDifference(text string) string {
+	var closest *Interaction
+	best := -1
+	for _, interaction := range c.order {
+		if n := commonPrefix(text, interaction.Prompt); n > best {
+			closest, best = interaction, n
+		}
+	}
+	if closest == nil {
+		return ""
+	}
+
+	got, want := strings.Split(text, "\n"), strings.Split(closest.Prompt, "\n")
+	for i := 0; i < len(got) || i < len(want); i++ {
+		var gotLine, wantLine string
+		if i < len(got) {
+			gotLine = got[i]
+		}
+		if i < len(want) {
+			wantLine = want[i]
+		}
+		if gotLine != wantLine {
+			return fmt.Sprintf("; closest recorded prompt %s differs at line %d:\n  recorded: %q\n  got:      %q", closest.Hash, i+1, wantLine, gotLine)
+		}
+	}
+	return fmt.Sprintf("; prompt matches recorded prompt %s, whose responses ran out", closest.Hash)
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func promptHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,297 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"mcp-server/internal/config"
+)
+
+// ollamaStartupWait bounds how long PrepareModels waits for Ollama to answer,
+// as the servers usually start alongside it
+const ollamaStartupWait = 2 * time.Minute
+
+// PullProgress is one status line Ollama streams while pulling a model
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ListModels returns the models the Ollama server has pulled
+func (c *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
+
+// Pull downloads the client's model, passing every status line Ollama streams
+// to progress
+func (c *OllamaClient) Pull(ctx context.Context, progress func(PullProgress)) error {
+	// Older servers only read name, newer ones prefer model
+	body, err := json.Marshal(map[string]interface{}{"model": c.model, "name": c.model, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// A pull can take far longer than a generation, so only ctx bounds it
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newStatusError(resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	last := ""
+	for {
+		var line PullProgress
+		if err := decoder.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to decode progress: %w", err)
+		}
+		if line.Error != "" {
+			return fmt.Errorf("ollama error: %s", line.Error)
+		}
+		last = line.Status
+		if progress != nil {
+			progress(line)
+		}
+	}
+	if last != "success" {
+		return fmt.Errorf("pull ended without success (last status %q)", last)
+	}
+	return nil
+}
+
+// Load loads the client's model into memory with its context window and keep
+// alive, which Ollama does for a generate request without a prompt
+func (c *OllamaClient) Load(ctx context.Context) error {
+	_, err := c.Generate(ctx, "")
+	return err
+}
+
+// modelSpec is a model the workflow uses and how the agents use it
+type modelSpec struct {
+	name          string
+	keepAlive     time.Duration // longest keep_alive of the agents using it
+	contextTokens []int         // distinct context windows it is used with, ascending
+	fallbackOnly  bool          // no agent uses it as its main model
+}
+
+// workflowModels lists the models of every agent in cfg, fallbacks included,
+// sorted by name
+func workflowModels(cfg *config.WorkflowConfig) []*modelSpec {
+	byName := make(map[string]*modelSpec)
+	for _, agentCfg := range cfg.Agents {
+		for i, model := range agentCfg.Models() {
+			spec, ok := byName[model]
+			if !ok {
+				spec = &modelSpec{name: model, keepAlive: cfg.KeepAlive(agentCfg), fallbackOnly: true}
+				byName[model] = spec
+			}
+			spec.keepAlive = longerKeepAlive(spec.keepAlive, cfg.KeepAlive(agentCfg))
+			if !containsInt(spec.contextTokens, agentCfg.ContextTokens) {
+				spec.contextTokens = append(spec.contextTokens, agentCfg.ContextTokens)
+				sort.Ints(spec.contextTokens)
+			}
+			if i == 0 {
+				spec.fallbackOnly = false
+			}
+		}
+	}
+
+	specs := make([]*modelSpec, 0, len(byName))
+	for _, spec := range byName {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].name < specs[j].name })
+	return specs
+}
+
+// modelKeepAlive is the keep alive every client of model sends, so that a model
+// shared by several agents stays loaded as long as the longest of them asks
+func modelKeepAlive(cfg *config.WorkflowConfig, model string) time.Duration {
+	for _, spec := range workflowModels(cfg) {
+		if spec.name == model {
+			return spec.keepAlive
+		}
+	}
+	return 0
+}
+
+// longerKeepAlive returns whichever keeps a model loaded longer; negative means
+// forever
+func longerKeepAlive(a, b time.Duration) time.Duration {
+	switch {
+	case a < 0:
+		return a
+	case b < 0, b > a:
+		return b
+	default:
+		return a
+	}
+}
+
+// PrepareModels makes sure Ollama at baseURL can serve the workflow: it waits
+// for the server, pulls the models of cfg it is missing unless skip_pull is
+// set, and loads the agents' main models unless skip_warm_up is set, so the
+// first workflow doesn't pay for either. Progress goes to logf.
+func PrepareModels(ctx context.Context, baseURL string, cfg *config.WorkflowConfig, logf func(format string, args ...interface{})) error {
+	installed, err := waitForOllama(ctx, NewOllamaClient(baseURL, ""), logf)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	unavailable := make(map[string]bool)
+	for _, spec := range workflowModels(cfg) {
+		if installed[canonicalModel(spec.name)] {
+			continue
+		}
+		if cfg.LLM.SkipPull {
+			errs = append(errs, fmt.Errorf("model %s is not pulled and skip_pull is set", spec.name))
+			unavailable[spec.name] = true
+			continue
+		}
+		logf("Pulling model %s", spec.name)
+		if err := NewOllamaClient(baseURL, spec.name).Pull(ctx, pullLogger(spec.name, logf)); err != nil {
+			errs = append(errs, fmt.Errorf("pulling %s: %w", spec.name, err))
+			unavailable[spec.name] = true
+			continue
+		}
+		logf("Pulled model %s", spec.name)
+	}
+
+	if !cfg.LLM.SkipWarmUp {
+		for _, spec := range workflowModels(cfg) {
+			// Fallbacks are only loaded when needed, often because memory is short
+			if spec.fallbackOnly || unavailable[spec.name] {
+				continue
+			}
+			if len(spec.contextTokens) > 1 {
+				logf("Agents use model %s with context windows %v; Ollama reloads it whenever the window changes", spec.name, spec.contextTokens)
+			}
+			contextTokens := spec.contextTokens[len(spec.contextTokens)-1]
+			start := time.Now()
+			client := NewOllamaClient(baseURL, spec.name).WithContextWindow(contextTokens).WithKeepAlive(spec.keepAlive)
+			if err := client.Load(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("loading %s: %w", spec.name, err))
+				continue
+			}
+			logf("Loaded model %s in %s (keep_alive %s)", spec.name, time.Since(start).Round(time.Millisecond), spec.keepAlive)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// waitForOllama returns the models Ollama has once it answers
+func waitForOllama(ctx context.Context, client *OllamaClient, logf func(format string, args ...interface{})) (map[string]bool, error) {
+	deadline := time.Now().Add(ollamaStartupWait)
+	for attempt := 0; ; attempt++ {
+		models, err := client.ListModels(ctx)
+		if err == nil {
+			installed := make(map[string]bool, len(models))
+			for _, model := range models {
+				installed[canonicalModel(model)] = true
+			}
+			return installed, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("ollama at %s is not answering: %w", client.baseURL, err)
+		}
+		if attempt == 0 {
+			logf("Waiting for Ollama at %s: %v", client.baseURL, err)
+		}
+		if err := sleepContext(ctx, 2*time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pullLogger logs status changes and every tenth of each layer downloaded
+func pullLogger(model string, logf func(format string, args ...interface{})) func(PullProgress) {
+	lastStatus := ""
+	lastTenth := make(map[string]int64)
+	return func(p PullProgress) {
+		if p.Total > 0 {
+			tenth := p.Completed * 10 / p.Total
+			if seen, ok := lastTenth[p.Digest]; ok && tenth == seen {
+				return
+			}
+			lastTenth[p.Digest] = tenth
+			logf("Pulling %s: %s %d%% of %d MB", model, shortDigest(p.Digest), tenth*10, p.Total>>20)
+			return
+		}
+		if p.Status != lastStatus {
+			lastStatus = p.Status
+			logf("Pulling %s: %s", model, p.Status)
+		}
+	}
+}
+
+// canonicalModel adds the tag Ollama assumes when a name has none
+func canonicalModel(name string) string {
+	if !strings.Contains(name, ":") {
+		return name + ":latest"
+	}
+	return name
+}
+
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+func containsInt(values []int, target int) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package llm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/fakeollama"
+	"mcp-server/internal/llm"
+)
+
+// The engineer and QA share a model with different keep alives, the engineer
+// falls back to a model no agent uses first, and the Tech Lead's model is
+// already pulled
+const modelsConfig = `
+[workflow]
+context_tokens = 4096
+response_tokens = 1024
+
+[agents.senior_engineer]
+role = "senior_engineer"
+model = "coder"
+fallback_models = ["small"]
+keep_alive = "-1"
+max_iterations = 1
+
+[agents.senior_qa]
+role = "senior_qa"
+model = "coder"
+keep_alive = "10m"
+max_iterations = 1
+
+[agents.senior_tech_lead]
+role = "senior_tech_lead"
+model = "reviewer"
+max_iterations = 1
+context_tokens = 8192
+
+[commands]
+allowed = ["go test"]
+`
+
+func loadConfig(t *testing.T, text string) *config.WorkflowConfig {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agents.toml")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadWorkflowConfig(path)
+	if err != nil {
+		t.Fatalf("LoadWorkflowConfig: %v", err)
+	}
+	return cfg
+}
+
+func callsTo(fake *fakeollama.Server, rule string) map[string]fakeollama.Call {
+	calls := make(map[string]fakeollama.Call)
+	for _, call := range fake.Calls() {
+		if call.Rule == rule {
+			calls[call.Model] = call
+		}
+	}
+	return calls
+}
+
+func keys(calls map[string]fakeollama.Call) string {
+	var models []string
+	for model := range calls {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return strings.Join(models, " ")
+}
+
+func TestPrepareModelsPullsAndLoads(t *testing.T) {
+	fake, url := startFake(t, `
+models: [reviewer]
+rules:
+  - contains: [hello]
+    response: hi
+`)
+	cfg := loadConfig(t, modelsConfig)
+
+	var logged []string
+	err := llm.PrepareModels(context.Background(), url, cfg, func(format string, args ...interface{}) {
+		logged = append(logged, format)
+	})
+	if err != nil {
+		t.Fatalf("PrepareModels: %v", err)
+	}
+
+	if got := keys(callsTo(fake, fakeollama.PullRule)); got != "coder small" {
+		t.Errorf("pulled %q, want coder and small", got)
+	}
+	// Fallbacks stay unloaded until an agent needs them
+	loads := callsTo(fake, fakeollama.LoadRule)
+	if got := keys(loads); got != "coder reviewer" {
+		t.Fatalf("loaded %q, want coder and reviewer", got)
+	}
+	// A shared model is kept loaded as long as its longest-lived agent asks
+	if coder := loads["coder"]; coder.KeepAlive != "-1s" || coder.NumCtx != 4096 {
+		t.Errorf("coder loaded with keep_alive %q and num_ctx %d, want -1s and 4096", coder.KeepAlive, coder.NumCtx)
+	}
+	if reviewer := loads["reviewer"]; reviewer.KeepAlive != "30m0s" || reviewer.NumCtx != 8192 {
+		t.Errorf("reviewer loaded with keep_alive %q and num_ctx %d, want the 30m default and 8192", reviewer.KeepAlive, reviewer.NumCtx)
+	}
+	if !strings.Contains(strings.Join(logged, "\n"), "Pulling %s: %s %d%% of %d MB") {
+		t.Errorf("no pull progress logged:\n%s", strings.Join(logged, "\n"))
+	}
+
+	// Agents send the same keep alive with every call
+	client := llm.NewOllamaAgentClient(url, cfg.Agents["senior_qa"], cfg)
+	if _, err := client.Generate(context.Background(), "hello"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if call := callsTo(fake, "rule 1")["coder"]; call.KeepAlive != "-1s" {
+		t.Errorf("QA call keep_alive = %q, want -1s", call.KeepAlive)
+	}
+}
+
+func TestPrepareModelsReportsFailedPulls(t *testing.T) {
+	fake, url := startFake(t, `
+models: [reviewer]
+pullable: [coder]
+`)
+	cfg := loadConfig(t, modelsConfig)
+
+	err := llm.PrepareModels(context.Background(), url, cfg, t.Logf)
+	if err == nil || !strings.Contains(err.Error(), "pulling small") {
+		t.Fatalf("PrepareModels = %v, want the failed pull of small", err)
+	}
+	if got := keys(callsTo(fake, fakeollama.LoadRule)); got != "coder reviewer" {
+		t.Errorf("loaded %q, want the other models loaded regardless", got)
+	}
+}
+
+func TestPrepareModelsSkipsPullWhenConfigured(t *testing.T) {
+	fake, url := startFake(t, `
+models: [reviewer]
+`)
+	cfg := loadConfig(t, modelsConfig+`
+[llm]
+skip_pull = true
+skip_warm_up = true
+`)
+
+	err := llm.PrepareModels(context.Background(), url, cfg, t.Logf)
+	if err == nil || !strings.Contains(err.Error(), "model coder is not pulled") {
+		t.Fatalf("PrepareModels = %v, want coder reported missing", err)
+	}
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("got calls %+v, want none", calls)
+	}
+}
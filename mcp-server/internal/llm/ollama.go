@@ -5,26 +5,62 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
+
+	"mcp-server/internal/prompt"
 )
 
 type OllamaClient struct {
 	baseURL    string
 	model      string
+	numCtx     int
+	keepAlive  string
 	httpClient *http.Client
 }
 
 type OllamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model     string         `json:"model"`
+	Prompt    string         `json:"prompt"`
+	Stream    bool           `json:"stream"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Options   *OllamaOptions `json:"options,omitempty"`
+}
+
+// OllamaOptions are the model parameters sent with a request
+type OllamaOptions struct {
+	NumCtx int `json:"num_ctx,omitempty"`
 }
 
 type OllamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
-	Error    string `json:"error,omitempty"`
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	Error           string `json:"error,omitempty"`
+	PromptEvalCount int    `json:"prompt_eval_count,omitempty"`
+	EvalCount       int    `json:"eval_count,omitempty"`
+}
+
+// StatusError is a non-200 reply from the Ollama API, with the error it reported
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ollama API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("ollama API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// newStatusError reads the error Ollama put in the body of a failed reply
+func newStatusError(resp *http.Response) *StatusError {
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+	return &StatusError{StatusCode: resp.StatusCode, Message: body.Error}
 }
 
 func NewOllamaClient(baseURL, model string) *OllamaClient {
@@ -37,45 +73,76 @@ func NewOllamaClient(baseURL, model string) *OllamaClient {
 	}
 }
 
+// WithContextWindow sets the context window (num_ctx) requested for every generation
+func (c *OllamaClient) WithContextWindow(tokens int) *OllamaClient {
+	c.numCtx = tokens
+	return c
+}
+
+// WithKeepAlive sets how long Ollama keeps the model loaded after every
+// generation; negative keeps it loaded until the server stops
+func (c *OllamaClient) WithKeepAlive(d time.Duration) *OllamaClient {
+	c.keepAlive = d.String()
+	return c
+}
+
 func (c *OllamaClient) Generate(ctx context.Context, prompt string) (string, error) {
-	reqBody := OllamaRequest{
-		Model:  c.model,
-		Prompt: prompt,
-		Stream: false,
-	}
+	response, _, err := c.GenerateWithUsage(ctx, prompt)
+	return response, err
+}
+
+// GenerateWithUsage generates a response and reports the tokens Ollama counted for it
+func (c *OllamaClient) GenerateWithUsage(ctx context.Context, text string) (string, prompt.Usage, error) {
+	reqBody := c.request(text)
 
+	var usage prompt.Usage
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", usage, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", usage, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return "", usage, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ollama API returned status %d", resp.StatusCode)
+		return "", usage, newStatusError(resp)
 	}
 
 	var ollamaResp OllamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return "", usage, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if ollamaResp.Error != "" {
-		return "", fmt.Errorf("ollama error: %s", ollamaResp.Error)
+		return "", usage, fmt.Errorf("ollama error: %s", ollamaResp.Error)
 	}
 
-	return ollamaResp.Response, nil
+	usage = prompt.Usage{PromptTokens: ollamaResp.PromptEvalCount, CompletionTokens: ollamaResp.EvalCount}
+	return ollamaResp.Response, usage, nil
+}
+
+// request is a non-streaming generate request for text with the client's options
+func (c *OllamaClient) request(text string) OllamaRequest {
+	reqBody := OllamaRequest{
+		Model:     c.model,
+		Prompt:    text,
+		Stream:    false,
+		KeepAlive: c.keepAlive,
+	}
+	if c.numCtx > 0 {
+		reqBody.Options = &OllamaOptions{NumCtx: c.numCtx}
+	}
+	return reqBody
 }
 
 func (c *OllamaClient) Health(ctx context.Context) error {
@@ -95,4 +162,4 @@ func (c *OllamaClient) Health(ctx context.Context) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
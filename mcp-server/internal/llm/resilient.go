@@ -0,0 +1,310 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/metrics"
+	"mcp-server/internal/prompt"
+)
+
+// ErrCircuitOpen is returned while a model's breaker rejects calls
+var ErrCircuitOpen = errors.New("llm circuit breaker open")
+
+// outputReporter is a model client that wants to know whether its last
+// response could be used
+type outputReporter interface {
+	ReportOutput(usable bool)
+}
+
+// Policy controls how a ResilientClient retries, falls back and trips breakers
+type Policy struct {
+	MaxRetries       int           // retries of a transient failure per model
+	InitialBackoff   time.Duration // first retry delay, doubled for every retry
+	MaxBackoff       time.Duration
+	UnparseableLimit int           // unusable responses in a row before a model is skipped
+	FallbackCooldown time.Duration // how long a skipped model stays skipped
+	BreakerFailures  int           // failed calls in a row that open a breaker
+	BreakerCooldown  time.Duration // how long an open breaker rejects calls
+}
+
+// ResilientClient calls a model with retries on transient failures, and falls
+// back to the next model in its list when one is missing, out of memory,
+// failing or keeps producing output the agent cannot use. Every model's calls go
+// through a circuit breaker shared by all clients of the same backend, and a
+// model one client skips is skipped by all of them. ReportOutput refers to the
+// client's own last response, so each agent needs a client of its own.
+type ResilientClient struct {
+	backend string
+	models  []string
+	clients map[string]Generator
+	policy  Policy
+	sleep   func(ctx context.Context, d time.Duration) error
+
+	mu          sync.Mutex
+	unparseable map[string]int
+	lastModel   string
+}
+
+var (
+	skipsMu sync.Mutex
+	skips   = make(map[string]time.Time) // backend|model to the end of its skip
+)
+
+// NewResilientClient returns a client for models in order of preference on
+// backend, creating each model's client with newClient
+func NewResilientClient(backend string, models []string, newClient func(model string) Generator, policy Policy) *ResilientClient {
+	clients := make(map[string]Generator, len(models))
+	for _, model := range models {
+		clients[model] = newClient(model)
+	}
+	return &ResilientClient{
+		backend:     backend,
+		models:      models,
+		clients:     clients,
+		policy:      policy,
+		sleep:       sleepContext,
+		unparseable: make(map[string]int),
+	}
+}
+
+// PolicyFrom converts the [llm] section of the workflow config
+func PolicyFrom(section config.LLMSection) Policy {
+	return Policy{
+		MaxRetries:       section.MaxRetries,
+		InitialBackoff:   time.Duration(section.InitialBackoffMS) * time.Millisecond,
+		MaxBackoff:       time.Duration(section.MaxBackoffMS) * time.Millisecond,
+		UnparseableLimit: section.UnparseableLimit,
+		FallbackCooldown: time.Duration(section.FallbackCooldownSeconds) * time.Second,
+		BreakerFailures:  section.BreakerFailures,
+		BreakerCooldown:  time.Duration(section.BreakerCooldownSeconds) * time.Second,
+	}
+}
+
+// NewOllamaAgentClient returns the client an agent of cfg talks to Ollama
+// through: its model followed by its fallback models, each with the agent's
+// context window and the model's keep alive, and answered from the response
+// cache when it is enabled
+func NewOllamaAgentClient(baseURL string, agentCfg config.WorkflowAgentConfig, cfg *config.WorkflowConfig) *ResilientClient {
+	cache := cacheFor(cfg.LLM.Cache)
+	return NewResilientClient(baseURL, agentCfg.Models(), func(model string) Generator {
+		client := NewOllamaClient(baseURL, model).WithContextWindow(agentCfg.ContextTokens).WithKeepAlive(modelKeepAlive(cfg, model))
+		if cache == nil {
+			return client
+		}
+		return newCachedClient(client, cache)
+	}, PolicyFrom(cfg.LLM))
+}
+
+func (c *ResilientClient) Generate(ctx context.Context, text string) (string, error) {
+	response, _, err := c.GenerateWithUsage(ctx, text)
+	return response, err
+}
+
+// GenerateWithUsage asks the first available model, moving down the list when
+// a model cannot answer
+func (c *ResilientClient) GenerateWithUsage(ctx context.Context, text string) (string, prompt.Usage, error) {
+	var lastErr error
+	skipped := ""
+	for _, model := range c.candidates() {
+		breaker := breakerFor(c.backend, model, c.policy)
+		if !breaker.allow() {
+			lastErr = fmt.Errorf("%s: %w", model, ErrCircuitOpen)
+			skipped = firstNonEmpty(skipped, model)
+			continue
+		}
+
+		response, usage, err := c.callWithRetries(ctx, model, text)
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up; that says nothing about the model
+			breaker.abandon()
+			return "", usage, err
+		}
+		kind := classify(err)
+		breaker.record(kind == failNone || kind == failPermanent || kind == failModelMissing)
+		if err == nil {
+			c.mu.Lock()
+			c.lastModel = model
+			c.mu.Unlock()
+			if skipped != "" {
+				metrics.LLMFallback(skipped, model)
+			}
+			return response, usage, nil
+		}
+
+		lastErr = fmt.Errorf("%s: %w", model, err)
+		switch kind {
+		case failModelMissing, failModelOOM:
+			log.Printf("LLM: skipping model %s for %s: %v", model, c.policy.FallbackCooldown, err)
+			c.skip(model)
+		case failTransient:
+			log.Printf("LLM: model %s still failing after %d retries: %v", model, c.policy.MaxRetries, err)
+		default:
+			// The request itself is wrong; another model will not do better
+			return "", usage, err
+		}
+		skipped = firstNonEmpty(skipped, model)
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no models configured")
+	}
+	return "", prompt.Usage{}, fmt.Errorf("all models failed, last error: %w", lastErr)
+}
+
+// ReportOutput tells the client whether the last response could be used; a
+// model whose output is unusable UnparseableLimit times in a row is skipped
+func (c *ResilientClient) ReportOutput(usable bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	model := c.lastModel
+	if model == "" {
+		return
+	}
+	if reporter, ok := c.clients[model].(outputReporter); ok {
+		reporter.ReportOutput(usable)
+	}
+	if usable {
+		c.unparseable[model] = 0
+		return
+	}
+	c.unparseable[model]++
+	if c.unparseable[model] >= c.policy.UnparseableLimit && len(c.models) > 1 {
+		log.Printf("LLM: skipping model %s for %s after %d unusable responses", model, c.policy.FallbackCooldown, c.unparseable[model])
+		c.unparseable[model] = 0
+		c.skip(model)
+	}
+}
+
+// candidates lists the models not being skipped, or every model when all are
+func (c *ResilientClient) candidates() []string {
+	skipsMu.Lock()
+	defer skipsMu.Unlock()
+	now := time.Now()
+	var models []string
+	for _, model := range c.models {
+		if now.After(skips[c.backend+"|"+model]) {
+			models = append(models, model)
+		}
+	}
+	if len(models) == 0 {
+		return c.models
+	}
+	return models
+}
+
+// skip passes over model on the client's backend for the fallback cooldown
+func (c *ResilientClient) skip(model string) {
+	skipsMu.Lock()
+	skips[c.backend+"|"+model] = time.Now().Add(c.policy.FallbackCooldown)
+	skipsMu.Unlock()
+}
+
+// callWithRetries retries transient failures of model with exponential backoff
+// and jitter
+func (c *ResilientClient) callWithRetries(ctx context.Context, model, text string) (string, prompt.Usage, error) {
+	client := c.clients[model]
+	backoff := c.policy.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		var (
+			response string
+			usage    prompt.Usage
+			err      error
+		)
+		if reporter, ok := client.(usageGenerator); ok {
+			response, usage, err = reporter.GenerateWithUsage(ctx, text)
+		} else {
+			response, err = client.Generate(ctx, text)
+		}
+		if err == nil || ctx.Err() != nil || classify(err) != failTransient || attempt >= c.policy.MaxRetries {
+			return response, usage, err
+		}
+
+		metrics.LLMRetry(model)
+		// Full jitter between half and all of the backoff keeps clients that
+		// failed together from retrying together
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		log.Printf("LLM: %s failed (%v), retrying in %s", model, err, delay.Round(time.Millisecond))
+		if err := c.sleep(ctx, delay); err != nil {
+			return "", usage, err
+		}
+		backoff *= 2
+		if backoff > c.policy.MaxBackoff {
+			backoff = c.policy.MaxBackoff
+		}
+	}
+}
+
+type failureKind int
+
+const (
+	failNone failureKind = iota
+	failTransient
+	failModelMissing
+	failModelOOM
+	failPermanent
+)
+
+// classify decides what a failed call means for retrying and falling back
+func classify(err error) failureKind {
+	if err == nil {
+		return failNone
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return failTransient
+	}
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch {
+		case status.StatusCode == 404:
+			return failModelMissing
+		case isOutOfMemory(status.Message):
+			return failModelOOM
+		case status.StatusCode >= 500, status.StatusCode == 429:
+			return failTransient
+		default:
+			return failPermanent
+		}
+	}
+	if isOutOfMemory(err.Error()) {
+		return failModelOOM
+	}
+
+	// Connection refused or reset, timeouts and truncated bodies
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || strings.Contains(err.Error(), "failed to decode response") {
+		return failTransient
+	}
+	return failPermanent
+}
+
+func isOutOfMemory(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "out of memory") || strings.Contains(message, "requires more system memory")
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
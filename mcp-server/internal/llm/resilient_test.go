@@ -0,0 +1,210 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server/internal/fakeollama"
+	"mcp-server/internal/llm"
+)
+
+// testPolicy retries quickly so the tests don't wait on backoff
+var testPolicy = llm.Policy{
+	MaxRetries:       2,
+	InitialBackoff:   time.Millisecond,
+	MaxBackoff:       2 * time.Millisecond,
+	UnparseableLimit: 2,
+	FallbackCooldown: time.Minute,
+	BreakerFailures:  3,
+	BreakerCooldown:  time.Minute,
+}
+
+// startFake serves script on a fresh address, so every test gets its own breakers
+func startFake(t *testing.T, script string) (*fakeollama.Server, string) {
+	t.Helper()
+	parsed, err := fakeollama.ParseScript([]byte(script))
+	if err != nil {
+		t.Fatalf("ParseScript: %v", err)
+	}
+	fake := fakeollama.NewServer(parsed)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server.URL
+}
+
+func newClient(url string, models []string, policy llm.Policy) *llm.ResilientClient {
+	return llm.NewResilientClient(url, models, func(model string) llm.Generator {
+		return llm.NewOllamaClient(url, model)
+	}, policy)
+}
+
+func modelsCalled(fake *fakeollama.Server) []string {
+	var models []string
+	for _, call := range fake.Calls() {
+		models = append(models, call.Model)
+	}
+	return models
+}
+
+func TestResilientClientRetriesTransientFailures(t *testing.T) {
+	fake, url := startFake(t, `
+rules:
+  - contains: [hello]
+    times: 2
+    error: server busy
+    status: 503
+  - contains: [hello]
+    response: hi
+`)
+	client := newClient(url, []string{"primary", "backup"}, testPolicy)
+
+	response, err := client.Generate(context.Background(), "hello")
+	if err != nil || response != "hi" {
+		t.Fatalf("Generate = %q, %v; want hi", response, err)
+	}
+	if got := strings.Join(modelsCalled(fake), " "); got != "primary primary primary" {
+		t.Errorf("calls = %s, want three to the primary model", got)
+	}
+}
+
+func TestResilientClientFallsBackWhenModelMissing(t *testing.T) {
+	fake, url := startFake(t, `
+models: [backup]
+rules:
+  - contains: [hello]
+    response: hi from backup
+`)
+	client := newClient(url, []string{"primary", "backup"}, testPolicy)
+
+	for i := 0; i < 2; i++ {
+		response, err := client.Generate(context.Background(), "hello")
+		if err != nil || response != "hi from backup" {
+			t.Fatalf("Generate %d = %q, %v; want the backup's answer", i, response, err)
+		}
+	}
+	if got := strings.Join(modelsCalled(fake), " "); got != "backup backup" {
+		t.Errorf("answered calls = %s, want both by the backup", got)
+	}
+}
+
+func TestResilientClientFallsBackWhenOutOfMemory(t *testing.T) {
+	fake, url := startFake(t, `
+rules:
+  - contains: [hello]
+    model: primary
+    error: model requires more system memory (12.1 GiB) than is available (8.0 GiB)
+  - contains: [hello]
+    response: hi
+`)
+	client := newClient(url, []string{"primary", "backup"}, testPolicy)
+
+	if _, err := client.Generate(context.Background(), "hello"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	// Running out of memory is not retried
+	if got := strings.Join(modelsCalled(fake), " "); got != "primary backup" {
+		t.Errorf("calls = %s, want primary then backup", got)
+	}
+}
+
+func TestResilientClientFallsBackOnUnusableOutput(t *testing.T) {
+	fake, url := startFake(t, `
+default: whatever
+`)
+	client := newClient(url, []string{"primary", "backup"}, testPolicy)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Generate(context.Background(), "hello"); err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		client.ReportOutput(false)
+	}
+	if got := strings.Join(modelsCalled(fake), " "); got != "primary primary backup" {
+		t.Errorf("calls = %s, want the backup after two unusable responses", got)
+	}
+}
+
+func TestResilientClientsShareSkippedModels(t *testing.T) {
+	fake, url := startFake(t, `
+rules:
+  - contains: [hello]
+    model: primary
+    error: model requires more system memory (12.1 GiB) than is available (8.0 GiB)
+  - contains: [hello]
+    response: hi
+`)
+	first := newClient(url, []string{"primary", "backup"}, testPolicy)
+	second := newClient(url, []string{"primary", "backup"}, testPolicy)
+
+	for _, client := range []*llm.ResilientClient{first, second} {
+		if _, err := client.Generate(context.Background(), "hello"); err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+	}
+	// Only the first client tried the primary
+	if got := strings.Join(modelsCalled(fake), " "); got != "primary backup backup" {
+		t.Errorf("calls = %s, want the second client to go straight to the backup", got)
+	}
+}
+
+func TestResilientClientStopsOnPermanentFailure(t *testing.T) {
+	fake, url := startFake(t, `
+rules:
+  - contains: [hello]
+    error: invalid options
+    status: 400
+`)
+	client := newClient(url, []string{"primary", "backup"}, testPolicy)
+
+	if _, err := client.Generate(context.Background(), "hello"); err == nil {
+		t.Fatal("Generate succeeded, want the 400")
+	}
+	if got := len(fake.Calls()); got != 1 {
+		t.Errorf("got %d calls, want 1 without retries or fallback", got)
+	}
+}
+
+func TestResilientClientOpensBreaker(t *testing.T) {
+	fake, url := startFake(t, `
+rules:
+  - contains: [hello]
+    error: server busy
+    status: 503
+`)
+	policy := testPolicy
+	policy.MaxRetries = 0
+	client := newClient(url, []string{"primary"}, policy)
+
+	for i := 0; i < policy.BreakerFailures; i++ {
+		_, err := client.Generate(context.Background(), "hello")
+		var status *llm.StatusError
+		if !errors.As(err, &status) || status.StatusCode != 503 {
+			t.Fatalf("Generate %d = %v, want the 503", i, err)
+		}
+	}
+	_, err := client.Generate(context.Background(), "hello")
+	if !errors.Is(err, llm.ErrCircuitOpen) {
+		t.Fatalf("Generate = %v, want ErrCircuitOpen", err)
+	}
+	if got := len(fake.Calls()); got != policy.BreakerFailures {
+		t.Errorf("got %d calls, want none once the breaker opened", got)
+	}
+
+	var state *llm.BreakerState
+	for _, s := range llm.BreakerStates() {
+		if s.Backend == url && s.Model == "primary" {
+			s := s
+			state = &s
+		}
+	}
+	if state == nil || state.State != llm.BreakerOpen || state.Failures != policy.BreakerFailures || state.RetryAfter == nil {
+		t.Errorf("breaker state = %+v, want open after %d failures", state, policy.BreakerFailures)
+	}
+	if !llm.AnyBreakerOpen() {
+		t.Error("AnyBreakerOpen = false")
+	}
+}
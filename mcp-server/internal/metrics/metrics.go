@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "agent"
+
+var (
+	workflowsStarted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "workflows_started_total",
+		Help:      "Workflow runs started, by flow.",
+	}, []string{"flow"})
+
+	workflowsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "workflows_finished_total",
+		Help:      "Workflow runs finished, by flow, status (succeeded or failed) and failure reason.",
+	}, []string{"flow", "status", "failure_reason"})
+
+	agentIterations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "iterations",
+		Help:      "Iterations each agent took in a workflow run.",
+		Buckets:   prometheus.LinearBuckets(1, 1, 10),
+	}, []string{"role"})
+
+	agentInvocations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invocations_total",
+		Help:      "Agent invocations, by role, model and status (succeeded, failed or error).",
+	}, []string{"role", "model", "status"})
+
+	llmDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of LLM calls, by model.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600},
+	}, []string{"model"})
+
+	llmErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_errors_total",
+		Help:      "LLM calls that failed, by model.",
+	}, []string{"model"})
+
+	llmRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_retries_total",
+		Help:      "LLM calls retried after a transient failure, by model.",
+	}, []string{"model"})
+
+	llmFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_fallbacks_total",
+		Help:      "Calls answered by a fallback model, by the model skipped and the model that answered.",
+	}, []string{"from", "to"})
+
+	llmCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_cache_lookups_total",
+		Help:      "LLM response cache lookups, by model and result (hit or miss).",
+	}, []string{"model", "result"})
+
+	commandExecutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_executions_total",
+		Help:      "Commands run by agents, by the allowlist entry they matched and exit status.",
+	}, []string{"command", "status"})
+
+	routingTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "routing_transitions_total",
+		Help:      "Hand-offs between agents, by source, destination and routing reason.",
+	}, []string{"from", "to", "reason"})
+)
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// WorkflowStarted counts a run of flow
+func WorkflowStarted(flow string) {
+	workflowsStarted.WithLabelValues(flow).Inc()
+}
+
+// WorkflowFinished counts a finished run of flow; failureReason is empty on success
+func WorkflowFinished(flow string, success bool, failureReason string) {
+	status := "succeeded"
+	if !success {
+		status = "failed"
+	}
+	workflowsFinished.WithLabelValues(flow, status, failureReason).Inc()
+}
+
+// AgentIterations records how many iterations role took in one run
+func AgentIterations(role string, iterations int) {
+	agentIterations.WithLabelValues(role).Observe(float64(iterations))
+}
+
+// AgentInvoked counts one invocation of role running model
+func AgentInvoked(role, model, status string) {
+	agentInvocations.WithLabelValues(role, model, status).Inc()
+}
+
+// LLMCall records the latency of a call to model and whether it failed
+func LLMCall(model string, duration time.Duration, err error) {
+	llmDuration.WithLabelValues(model).Observe(duration.Seconds())
+	if err != nil {
+		llmErrors.WithLabelValues(model).Inc()
+	}
+}
+
+// LLMRetry counts a retry of a call to model
+func LLMRetry(model string) {
+	llmRetries.WithLabelValues(model).Inc()
+}
+
+// LLMFallback counts a call that model to answered in place of from
+func LLMFallback(from, to string) {
+	llmFallbacks.WithLabelValues(from, to).Inc()
+}
+
+// LLMCacheLookup counts a lookup of a response of model in the cache
+func LLMCacheLookup(model string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	llmCacheLookups.WithLabelValues(model, result).Inc()
+}
+
+// CommandExecuted counts a command matching the allowlist entry command; status is
+// the exit code, "blocked" or "error" when it could not be started
+func CommandExecuted(command, status string) {
+	commandExecutions.WithLabelValues(command, status).Inc()
+}
+
+// RoutingTransition counts a hand-off from one agent to another
+func RoutingTransition(from, to, reason string) {
+	routingTransitions.WithLabelValues(from, to, reason).Inc()
+}
+
+// RegisterActiveSessions exposes the number of open WebSocket sessions, read from count
+func RegisterActiveSessions(count func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_sessions_active",
+		Help:      "WebSocket sessions currently open.",
+	}, func() float64 { return float64(count()) })
+}
@@ -0,0 +1,185 @@
+package orchestrator
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/fixture"
+	"mcp-server/internal/llm"
+	"mcp-server/internal/tools"
+)
+
+// Run with -record to re-record the cassettes against the Ollama server at
+// OLLAMA_URL after changing a prompt; replaying needs no model.
+var recordCassettes = flag.Bool("record", false, "record LLM cassettes against the Ollama server at OLLAMA_URL")
+
+// e2eScenario is a workflow run against a fixture project with recorded model responses
+type e2eScenario struct {
+	name        string // also names the cassette in testdata/cassettes
+	fixture     string // project in the module's testdata/fixtures, copied to a temporary directory
+	description string
+	wantSuccess bool
+	wantFailure string            // FailureReason when the run fails
+	wantRoute   []AgentRole       // agents in the order they ran
+	wantFiles   map[string]string // files that must exist afterwards, with text they contain
+}
+
+func TestWorkflowEndToEnd(t *testing.T) {
+	scenarios := []e2eScenario{
+		{
+			name:        "add_function",
+			fixture:     "calculator",
+			description: "Add a Subtract function to the calculator package that returns a minus b",
+			wantSuccess: true,
+			wantRoute:   []AgentRole{AgentRoleEM, AgentRoleEngineer, AgentRoleQA, AgentRoleTechLead},
+			wantFiles: map[string]string{
+				"calc.go":          "func Subtract(a, b int) int",
+				"subtract_test.go": "func TestSubtract",
+				"agents/AGENTS.md": "Subtract",
+			},
+		},
+		{
+			name:        "build_error_retry",
+			fixture:     "calculator",
+			description: "Add a Divide function to the calculator package that rejects a zero divisor",
+			wantSuccess: true,
+			wantRoute:   []AgentRole{AgentRoleEM, AgentRoleEngineer, AgentRoleQA, AgentRoleTechLead},
+			wantFiles: map[string]string{
+				"calc.go":        "func Divide(a, b int) (int, error)",
+				"divide_test.go": "func TestDivide",
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario := scenario
+		t.Run(scenario.name, func(t *testing.T) {
+			dir, result := runScenario(t, scenario)
+
+			if result.Success != scenario.wantSuccess {
+				t.Fatalf("Success = %v, want %v (failure %q: %s)", result.Success, scenario.wantSuccess, result.FailureReason, result.Error)
+			}
+			if result.FailureReason != scenario.wantFailure {
+				t.Errorf("FailureReason = %q, want %q", result.FailureReason, scenario.wantFailure)
+			}
+			if route := routeOf(result); !equalRoles(route, scenario.wantRoute) {
+				t.Errorf("route = %v, want %v", route, scenario.wantRoute)
+			}
+			for file, want := range scenario.wantFiles {
+				content, err := os.ReadFile(filepath.Join(dir, file))
+				if err != nil {
+					t.Errorf("%s: %v", file, err)
+					continue
+				}
+				if !strings.Contains(string(content), want) {
+					t.Errorf("%s does not contain %q:\n%s", file, want, content)
+				}
+			}
+		})
+	}
+}
+
+// runScenario executes the workflow the way main does, with every agent's model
+// replaced by the scenario's cassette, and returns the project directory and result
+func runScenario(t *testing.T, scenario e2eScenario) (string, *WorkflowResult) {
+	t.Helper()
+	dir := copyFixture(t, scenario.fixture)
+
+	cfg, err := config.LoadWorkflowConfig("../../config/agents.toml")
+	if err != nil {
+		t.Fatalf("LoadWorkflowConfig: %v", err)
+	}
+
+	mode := llm.CassetteReplay
+	if *recordCassettes {
+		mode = llm.CassetteRecord
+	}
+	cassette, err := llm.NewCassette(filepath.Join("testdata", "cassettes", scenario.name+".json"), mode)
+	if err != nil {
+		t.Fatalf("%v (run with -record to create it)", err)
+	}
+	cassette.Scrub(dir, "$WORKDIR")
+
+	ollamaURL := os.Getenv("OLLAMA_URL")
+	if ollamaURL == "" {
+		ollamaURL = "http://localhost:11434"
+	}
+	shared := tools.NewToolSet(cfg.Commands, cfg.Restrictions, dir)
+	wo, err := NewWorkflowOrchestrator(nil, shared, cfg)
+	if err != nil {
+		t.Fatalf("NewWorkflowOrchestrator: %v", err)
+	}
+	builder := NewAgentBuilder(cfg, agent.NewAgentFactory(nil), func(agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+		if mode == llm.CassetteReplay {
+			return cassette.Wrap(nil)
+		}
+		return cassette.Wrap(llm.NewOllamaClient(ollamaURL, agentCfg.Model).WithContextWindow(agentCfg.ContextTokens))
+	})
+	if err := wo.SetAgentBuilder(builder); err != nil {
+		t.Fatalf("SetAgentBuilder: %v", err)
+	}
+
+	result, err := wo.ExecuteWorkflow(context.Background(), agent.WorkflowRequest{
+		Description:      scenario.description,
+		WorkingDirectory: dir,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteWorkflow: %v", err)
+	}
+
+	if mode == llm.CassetteRecord {
+		if err := cassette.Save(); err != nil {
+			t.Fatalf("saving cassette: %v", err)
+		}
+		return dir, result
+	}
+	// A prompt without a recorded response means a prompt changed; re-record
+	// deliberately rather than letting the run drift
+	if misses := cassette.Misses(); len(misses) > 0 {
+		t.Fatalf("replay asked for %d unrecorded prompt(s):\n%s", len(misses), strings.Join(misses, "\n"))
+	}
+	if unplayed := cassette.Unplayed(); len(unplayed) > 0 {
+		t.Errorf("replay made fewer model calls than were recorded; unplayed prompts %v", unplayed)
+	}
+	return dir, result
+}
+
+// copyFixture copies the module's testdata/fixtures/name into a fresh git repository
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), name)
+	if err := fixture.Repo(filepath.Join("..", "..", "testdata", "fixtures", name), dir); err != nil {
+		t.Fatalf("fixture %s: %v", name, err)
+	}
+	return dir
+}
+
+// routeOf lists the agents a run went through, from its transitions
+func routeOf(result *WorkflowResult) []AgentRole {
+	var route []AgentRole
+	for i, transition := range result.WorkflowHistory {
+		if i == 0 {
+			route = append(route, transition.FromAgent)
+		}
+		route = append(route, transition.ToAgent)
+	}
+	return route
+}
+
+func equalRoles(a, b []AgentRole) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
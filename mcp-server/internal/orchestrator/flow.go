@@ -0,0 +1,206 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcp-server/internal/config"
+)
+
+// DefaultFlowName is the flow used when a request does not select one
+const DefaultFlowName = "default"
+
+// Flow is a compiled workflow graph that requests select by name
+type Flow struct {
+	Name        string
+	Description string
+	Start       AgentRole
+	Roles       []AgentRole // routed nodes: agent roles and parallel node names
+	Parallel    map[AgentRole]*ParallelNode
+	routing     *RoutingEngine
+}
+
+// hasRole reports whether the flow may hand work to role
+func (f *Flow) hasRole(role AgentRole) bool {
+	for _, candidate := range f.Roles {
+		if candidate == role {
+			return true
+		}
+	}
+	return false
+}
+
+// members returns the agent roles a node runs: the parallel node's roles, or the role itself
+func (f *Flow) members(node AgentRole) []AgentRole {
+	if parallel, ok := f.Parallel[node]; ok {
+		return parallel.Roles
+	}
+	return []AgentRole{node}
+}
+
+// involves reports whether any node of the flow runs the agent for role
+func (f *Flow) involves(role AgentRole) bool {
+	for _, node := range f.Roles {
+		for _, member := range f.members(node) {
+			if member == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// defaultFlow is the EM -> Engineer -> QA -> Tech Lead pipeline over the routing rules,
+// finishing when the Tech Lead approves
+func defaultFlow(cfg *config.WorkflowConfig) config.FlowConfig {
+	var roles []string
+	for _, role := range configuredRoles(cfg) {
+		if config.IsBuiltinRole(string(role)) {
+			roles = append(roles, string(role))
+		}
+	}
+	return config.FlowConfig{
+		Description: "Plan, implement, test and review (EM -> Engineer -> QA -> Tech Lead)",
+		Start:       string(AgentRoleEM),
+		Roles:       roles,
+		Terminal: []config.TerminalConfig{
+			{Role: string(AgentRoleTechLead), When: "success", Outcome: "success",
+				Reason: "Quality review passed, workflow complete"},
+		},
+	}
+}
+
+// compileFlows builds the default flow and every flow declared in config
+func compileFlows(cfg *config.WorkflowConfig) (map[string]*Flow, error) {
+	flowConfigs := map[string]config.FlowConfig{DefaultFlowName: defaultFlow(cfg)}
+	for name, flowCfg := range cfg.Flows {
+		flowConfigs[name] = flowCfg
+	}
+
+	configured := make(map[AgentRole]bool)
+	for _, role := range configuredRoles(cfg) {
+		configured[role] = true
+	}
+
+	flows := make(map[string]*Flow, len(flowConfigs))
+	for name, flowCfg := range flowConfigs {
+		edges := flowCfg.Edges
+		if len(edges) == 0 && name == DefaultFlowName {
+			edges = cfg.Routing.Rules
+			if len(edges) == 0 {
+				edges = defaultRoutingRules()
+			}
+		}
+
+		flow, err := compileFlow(name, flowCfg, edges, cfg.Routing.ErrorPatterns, configured)
+		if err != nil {
+			return nil, fmt.Errorf("flow %s: %w", name, err)
+		}
+		flows[name] = flow
+	}
+	return flows, nil
+}
+
+func compileFlow(name string, cfg config.FlowConfig, edges []config.RoutingRuleConfig, patterns []config.ErrorPatternConfig, configured map[AgentRole]bool) (*Flow, error) {
+	parallel := make(map[AgentRole]*ParallelNode, len(cfg.Parallel))
+	for _, nodeCfg := range cfg.Parallel {
+		nodeName := AgentRole(nodeCfg.Name)
+		if configured[nodeName] || parallel[nodeName] != nil {
+			return nil, fmt.Errorf("parallel node %s clashes with another role or node", nodeName)
+		}
+		node := &ParallelNode{Name: nodeName, RequireAll: nodeCfg.Join != "any"}
+		members := make(map[AgentRole]bool, len(nodeCfg.Roles))
+		for _, member := range nodeCfg.Roles {
+			if !configured[AgentRole(member)] {
+				return nil, fmt.Errorf("parallel node %s role %s has no agent configured", nodeName, member)
+			}
+			// A node runs one agent per role, which cannot work in two snapshots at once
+			if members[AgentRole(member)] {
+				return nil, fmt.Errorf("parallel node %s lists role %s more than once", nodeName, member)
+			}
+			members[AgentRole(member)] = true
+			node.Roles = append(node.Roles, AgentRole(member))
+		}
+		parallel[nodeName] = node
+	}
+
+	roles := flowRoles(cfg, edges)
+	for _, role := range roles {
+		if !configured[role] && parallel[role] == nil {
+			return nil, fmt.Errorf("role %s has no agent configured", role)
+		}
+	}
+
+	routing, err := buildRoutingEngine(patterns, edges, cfg.Terminal, roles, AgentRole(cfg.Start))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Flow{
+		Name:        name,
+		Description: cfg.Description,
+		Start:       AgentRole(cfg.Start),
+		Roles:       roles,
+		Parallel:    parallel,
+		routing:     routing,
+	}, nil
+}
+
+// flowRoles returns the declared roles, or every role the start, edges and terminals mention
+func flowRoles(cfg config.FlowConfig, edges []config.RoutingRuleConfig) []AgentRole {
+	names := cfg.Roles
+	if len(names) == 0 {
+		names = append(names, cfg.Start)
+		for _, edge := range edges {
+			names = append(names, edge.From, edge.To)
+		}
+		for _, terminal := range cfg.Terminal {
+			names = append(names, terminal.Role)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var roles []AgentRole
+	for _, name := range names {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			roles = append(roles, AgentRole(name))
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// FlowNames lists the flows requests may select, default first
+func (wo *WorkflowOrchestrator) FlowNames() []string {
+	names := make([]string, 0, len(wo.flows))
+	for name := range wo.flows {
+		if name != DefaultFlowName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultFlowName}, names...)
+}
+
+// RoleNames lists the configured agent roles in name order
+func (wo *WorkflowOrchestrator) RoleNames() []string {
+	var names []string
+	for _, role := range configuredRoles(wo.config) {
+		names = append(names, string(role))
+	}
+	return names
+}
+
+// selectFlow resolves the flow a request asked for
+func (wo *WorkflowOrchestrator) selectFlow(name string) (*Flow, error) {
+	if name == "" {
+		name = DefaultFlowName
+	}
+	flow, ok := wo.flows[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown flow %q (available: %s)", name, strings.Join(wo.FlowNames(), ", "))
+	}
+	return flow, nil
+}
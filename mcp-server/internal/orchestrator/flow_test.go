@@ -0,0 +1,274 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/tools"
+)
+
+func TestCompileShippedFlows(t *testing.T) {
+	cfg, err := config.LoadWorkflowConfig("../../config/agents.toml")
+	if err != nil {
+		t.Fatalf("LoadWorkflowConfig: %v", err)
+	}
+	flows, err := compileFlows(cfg)
+	if err != nil {
+		t.Fatalf("compileFlows: %v", err)
+	}
+
+	em, engineer, qa, techLead := AgentRoleEM, AgentRoleEngineer, AgentRoleQA, AgentRoleTechLead
+	failed := &agent.ImplementFeatureResponse{Success: false, Error: "rejected"}
+	succeeded := &agent.ImplementFeatureResponse{Success: true, FilesModified: []string{"README.md"}}
+
+	tests := []struct {
+		flow  string
+		start AgentRole
+		roles []AgentRole
+		// Terminal outcome for the given role's result: "success", "failure" or "" for none
+		terminalRole AgentRole
+		result       *agent.ImplementFeatureResponse
+		outcome      string
+	}{
+		{DefaultFlowName, em, []AgentRole{em, engineer, qa, techLead}, techLead, succeeded, "success"},
+		{DefaultFlowName, em, []AgentRole{em, engineer, qa, techLead}, techLead, failed, ""},
+		{DefaultFlowName, em, []AgentRole{em, engineer, qa, techLead}, engineer, succeeded, ""},
+		{"bugfix", engineer, []AgentRole{engineer, qa}, qa, succeeded, "success"},
+		{"bugfix", engineer, []AgentRole{engineer, qa}, engineer, succeeded, ""},
+		{"review-only", techLead, []AgentRole{techLead}, techLead, succeeded, "success"},
+		{"review-only", techLead, []AgentRole{techLead}, techLead, failed, "failure"},
+		{"docs", "technical_writer", []AgentRole{"technical_writer"}, "technical_writer", succeeded, "success"},
+		{"docs", "technical_writer", []AgentRole{"technical_writer"}, "technical_writer", failed, ""},
+		{"parallel-review", em, []AgentRole{em, "review", engineer}, "review", succeeded, "success"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flow+"/"+string(tt.terminalRole), func(t *testing.T) {
+			flow, ok := flows[tt.flow]
+			if !ok {
+				t.Fatalf("flow %s was not compiled", tt.flow)
+			}
+			if flow.Start != tt.start {
+				t.Errorf("start = %s, want %s", flow.Start, tt.start)
+			}
+			if !reflect.DeepEqual(flow.Roles, tt.roles) {
+				t.Errorf("roles = %v, want %v", flow.Roles, tt.roles)
+			}
+
+			outcome := ""
+			if terminal := flow.routing.Terminal(tt.terminalRole, tt.result); terminal != nil {
+				outcome = "failure"
+				if terminal.Success {
+					outcome = "success"
+				}
+			}
+			if outcome != tt.outcome {
+				t.Errorf("terminal outcome = %q, want %q", outcome, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestCompileFlowRejects(t *testing.T) {
+	em, engineer, qa := string(AgentRoleEM), string(AgentRoleEngineer), string(AgentRoleQA)
+	configured := map[AgentRole]bool{AgentRoleEM: true, AgentRoleEngineer: true, AgentRoleQA: true}
+
+	tests := []struct {
+		name    string
+		cfg     config.FlowConfig
+		wantErr string
+	}{
+		{
+			name: "role without an agent",
+			cfg: config.FlowConfig{
+				Start:    em,
+				Edges:    []config.RoutingRuleConfig{{From: em, To: "architect"}, {From: "architect", To: em}},
+				Terminal: []config.TerminalConfig{{Role: em, When: "success"}},
+			},
+			wantErr: "role architect has no agent configured",
+		},
+		{
+			name: "parallel node named after a role",
+			cfg: config.FlowConfig{
+				Start:    em,
+				Parallel: []config.ParallelConfig{{Name: qa, Roles: []string{engineer}}},
+			},
+			wantErr: "parallel node senior_qa clashes with another role or node",
+		},
+		{
+			name: "parallel member without an agent",
+			cfg: config.FlowConfig{
+				Start:    em,
+				Parallel: []config.ParallelConfig{{Name: "review", Roles: []string{qa, "architect"}}},
+			},
+			wantErr: "parallel node review role architect has no agent configured",
+		},
+		{
+			name: "parallel node repeating a role",
+			cfg: config.FlowConfig{
+				Start:    em,
+				Parallel: []config.ParallelConfig{{Name: "review", Roles: []string{qa, engineer, qa}}},
+			},
+			wantErr: "parallel node review lists role senior_qa more than once",
+		},
+		{
+			name: "outcome without an edge",
+			cfg: config.FlowConfig{
+				Start:    em,
+				Edges:    []config.RoutingRuleConfig{{From: em, When: "!success", To: engineer}, {From: engineer, To: em}},
+				Terminal: []config.TerminalConfig{{Role: engineer, When: "success"}},
+			},
+			wantErr: "role engineering_manager has no fallback rule for success=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileFlow("test", tt.cfg, tt.cfg.Edges, nil, configured)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("compileFlow error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectFlow(t *testing.T) {
+	cfg := testWorkflowConfig()
+	cfg.Flows = map[string]config.FlowConfig{
+		"bugfix": {
+			Start:    string(AgentRoleEngineer),
+			Edges:    []config.RoutingRuleConfig{{From: string(AgentRoleEngineer), To: string(AgentRoleEngineer)}},
+			Terminal: []config.TerminalConfig{{Role: string(AgentRoleEngineer), When: "success"}},
+		},
+	}
+	wo, err := NewWorkflowOrchestrator(nil, tools.NewToolSet(cfg.Commands, cfg.Restrictions, t.TempDir()), cfg)
+	if err != nil {
+		t.Fatalf("NewWorkflowOrchestrator: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{"", DefaultFlowName, ""},
+		{DefaultFlowName, DefaultFlowName, ""},
+		{"bugfix", "bugfix", ""},
+		{"hotfix", "", `unknown flow "hotfix" (available: default, bugfix)`},
+	}
+	for _, tt := range tests {
+		flow, err := wo.selectFlow(tt.name)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("selectFlow(%q) error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("selectFlow(%q): %v", tt.name, err)
+			continue
+		}
+		if flow.Name != tt.want {
+			t.Errorf("selectFlow(%q) = %s, want %s", tt.name, flow.Name, tt.want)
+		}
+	}
+}
+
+// scriptedClient answers each call with the next scripted response, repeating the last
+type scriptedClient struct {
+	mu        sync.Mutex
+	responses []string
+	prompts   []string
+}
+
+func (c *scriptedClient) Generate(ctx context.Context, prompt string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prompts = append(c.prompts, prompt)
+	response := c.responses[0]
+	if len(c.responses) > 1 {
+		c.responses = c.responses[1:]
+	}
+	return response, nil
+}
+
+func TestCustomRoleFlow(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "README.md"), []byte("# Calc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testWorkflowConfig()
+	cfg.Agents["technical_writer"] = config.WorkflowAgentConfig{
+		Role:          "technical_writer",
+		Model:         "test",
+		MaxIterations: 2,
+		Context:       []string{"README.md"},
+		Prompt:        "Keep the README accurate.",
+	}
+	cfg.Flows = map[string]config.FlowConfig{
+		"docs": {
+			Start:    "technical_writer",
+			Edges:    []config.RoutingRuleConfig{{From: "technical_writer", When: "!success", To: "technical_writer"}},
+			Terminal: []config.TerminalConfig{{Role: "technical_writer", When: "success", Outcome: "success"}},
+		},
+	}
+	wo, err := NewWorkflowOrchestrator(nil, tools.NewToolSet(cfg.Commands, cfg.Restrictions, projectDir), cfg)
+	if err != nil {
+		t.Fatalf("NewWorkflowOrchestrator: %v", err)
+	}
+
+	client := &scriptedClient{responses: []string{
+		"RESULT: FAILED\nREASON: the README has no usage section to extend",
+		"Added a usage section.\n\nACTION: WRITE_FILE\nPATH: README.md\nCONTENT:\n```\n# Calc\n\n## Usage\n\nAdd(a, b)\n```\n",
+	}}
+	builder := NewAgentBuilder(cfg, agent.NewAgentFactory(nil), func(agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+		if agentCfg.Role == "technical_writer" {
+			return client
+		}
+		// The EM only documents the finished run
+		return &scriptedClient{responses: []string{"No new knowledge."}}
+	})
+	if err := wo.SetAgentBuilder(builder); err != nil {
+		t.Fatalf("SetAgentBuilder: %v", err)
+	}
+
+	result, err := wo.ExecuteWorkflow(context.Background(), agent.WorkflowRequest{
+		Description:      "Document Add",
+		ProjectType:      agent.ProjectTypeGo,
+		WorkingDirectory: projectDir,
+		Flow:             "docs",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteWorkflow: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("workflow failed: %s (%s)", result.Error, result.FailureReason)
+	}
+	if result.Flow != "docs" {
+		t.Errorf("flow = %q, want docs", result.Flow)
+	}
+	if want := []AgentRole{"technical_writer", "technical_writer"}; !equalRoles(routeOf(result), want) {
+		t.Errorf("route = %v, want %v", routeOf(result), want)
+	}
+
+	readme, err := os.ReadFile(filepath.Join(projectDir, "README.md"))
+	if err != nil || string(readme) != "# Calc\n\n## Usage\n\nAdd(a, b)" {
+		t.Errorf("README.md = %q (%v)", readme, err)
+	}
+	if len(client.prompts) != 2 {
+		t.Fatalf("model was called %d times, want 2", len(client.prompts))
+	}
+	for _, want := range []string{"Keep the README accurate.", "Document Add", "# Calc"} {
+		if !strings.Contains(client.prompts[0], want) {
+			t.Errorf("prompt does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/metrics"
+	"mcp-server/internal/tools"
+)
+
+// commandAgent runs one command and reports success when it exits cleanly
+type commandAgent struct {
+	tools   agent.ToolSet
+	command string
+}
+
+func (ca *commandAgent) ImplementFeature(ctx context.Context, req agent.ImplementFeatureRequest) (*agent.ImplementFeatureResponse, error) {
+	ca.tools.SetWorkingDirectory(req.WorkingDirectory)
+	output, err := ca.tools.ExecuteCommand(ca.command)
+	result := &agent.ImplementFeatureResponse{Success: err == nil, BuildOutput: output, CommandsExecuted: []string{ca.command}}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+func (ca *commandAgent) DocumentTask(ctx context.Context, result *agent.WorkflowResult) error {
+	return nil
+}
+
+func TestMetricsAfterWorkflow(t *testing.T) {
+	engineer, qa := string(AgentRoleEngineer), string(AgentRoleQA)
+	cfg := testWorkflowConfig()
+	cfg.Flows = map[string]config.FlowConfig{
+		"metrics-check": {
+			Start: engineer,
+			Edges: []config.RoutingRuleConfig{
+				{From: engineer, When: "success", To: qa, Reason: "Listed the project, checking it"},
+				{From: engineer, To: engineer},
+			},
+			Terminal: []config.TerminalConfig{
+				{Role: qa, When: "success", Outcome: "success"},
+				{Role: qa, When: "!success", Outcome: "failure"},
+			},
+		},
+	}
+	projectDir := t.TempDir()
+	wo, err := NewWorkflowOrchestrator(nil, tools.NewToolSet(cfg.Commands, cfg.Restrictions, projectDir), cfg)
+	if err != nil {
+		t.Fatalf("NewWorkflowOrchestrator: %v", err)
+	}
+
+	// The engineer's listing succeeds, QA's fails with ls's exit code 2
+	commands := map[agent.AgentRole]string{
+		agent.AgentRoleEngineer: "ls",
+		agent.AgentRoleQA:       "ls missing-dir-7f3a",
+	}
+	factory := agentFactoryFunc(func(role agent.AgentRole, toolSet agent.ToolSet) agent.Agent {
+		return &commandAgent{tools: toolSet, command: commands[role]}
+	})
+	if err := wo.SetAgentBuilder(NewAgentBuilder(cfg, factory, func(config.WorkflowAgentConfig) agent.LLMClient { return nil })); err != nil {
+		t.Fatalf("SetAgentBuilder: %v", err)
+	}
+
+	result, err := wo.ExecuteWorkflow(context.Background(), agent.WorkflowRequest{
+		Description:      "list the project",
+		ProjectType:      agent.ProjectTypeGo,
+		WorkingDirectory: projectDir,
+		Flow:             "metrics-check",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteWorkflow: %v", err)
+	}
+	if result.Success || result.FailureReason != "flow_terminated" {
+		t.Fatalf("workflow success %v, failure reason %q, want QA's failure to end it", result.Success, result.FailureReason)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	scrape := recorder.Body.String()
+
+	for _, want := range []string{
+		`agent_workflows_started_total{flow="metrics-check"}`,
+		`agent_workflows_finished_total{failure_reason="flow_terminated",flow="metrics-check",status="failed"}`,
+		// Commands are labelled by the allowlist entry they matched, not the command line
+		`agent_command_executions_total{command="ls",status="0"}`,
+		`agent_command_executions_total{command="ls",status="2"}`,
+		`agent_routing_transitions_total{from="senior_engineer",reason="Listed the project, checking it",to="senior_qa"}`,
+		`agent_invocations_total{model="test",role="senior_qa",status="failed"}`,
+	} {
+		if !strings.Contains(scrape, want+" ") {
+			t.Errorf("scrape has no %s", want)
+		}
+	}
+	if strings.Contains(scrape, "missing-dir-7f3a") {
+		t.Error("scrape labels a command with its arguments")
+	}
+}
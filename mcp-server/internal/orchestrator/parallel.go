@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"mcp-server/internal/agent"
+)
+
+// ParallelNode fans the current task out to several roles and joins their results
+type ParallelNode struct {
+	Name       AgentRole
+	Roles      []AgentRole
+	RequireAll bool // join=all: every role must succeed; join=any: one is enough
+}
+
+// memberResult is the outcome of one role inside a parallel node
+type memberResult struct {
+	role   AgentRole
+	result *agent.ImplementFeatureResponse
+	err    error
+}
+
+// executeParallel runs every role of node on the current task and returns their
+// results in the node's role order. Each member works concurrently in its own
+// snapshot of the working tree; once all are done their changes are merged back,
+// failing the node when two members changed a file differently. Without isolated
+// member agents the roles share one tree, so they run one after another.
+func (wo *WorkflowOrchestrator) executeParallel(ctx context.Context, node *ParallelNode, state *WorkflowState, req WorkflowRequest) ([]memberResult, error) {
+	results := make([]memberResult, len(node.Roles))
+	if state.MemberAgents == nil {
+		for i, role := range node.Roles {
+			result, err := wo.executeAgent(ctx, role, state, req)
+			results[i] = memberResult{role: role, result: result, err: err}
+		}
+		return results, nil
+	}
+
+	workingDir := state.ToolSet.GetWorkingDirectory()
+	snapshots := make([]*snapshot, 0, len(node.Roles))
+	defer func() {
+		for _, s := range snapshots {
+			s.remove()
+		}
+	}()
+	for _, role := range node.Roles {
+		s, err := newSnapshot(role, workingDir)
+		if err != nil {
+			return nil, fmt.Errorf("parallel node %s: %w", node.Name, err)
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	var wg sync.WaitGroup
+	for i, role := range node.Roles {
+		wg.Add(1)
+		go func(i int, role AgentRole) {
+			defer wg.Done()
+			memberReq := req
+			memberReq.WorkingDirectory = snapshots[i].dir
+			result, err := wo.runAgent(ctx, role, state.MemberAgents[role], state, memberReq)
+			results[i] = memberResult{role: role, result: result, err: err}
+		}(i, role)
+	}
+	wg.Wait()
+
+	if err := mergeSnapshots(workingDir, snapshots); err != nil {
+		return nil, fmt.Errorf("parallel node %s: %w", node.Name, err)
+	}
+	return results, nil
+}
+
+// mergeParallelResults joins member results into the single response the flow
+// routes on; it only returns an error when every member failed to run at all
+func mergeParallelResults(node *ParallelNode, members []memberResult) (*agent.ImplementFeatureResponse, error) {
+	merged := &agent.ImplementFeatureResponse{
+		FilesModified:    []string{},
+		CommandsExecuted: []string{},
+	}
+
+	var runErrors []error
+	var messages, nextSteps, failures []string
+	succeeded := 0
+	for _, member := range members {
+		if member.err != nil {
+			runErrors = append(runErrors, fmt.Errorf("%s: %w", member.role, member.err))
+			failures = append(failures, fmt.Sprintf("%s: %v", member.role, member.err))
+			continue
+		}
+
+		result := member.result
+		if result.Success {
+			succeeded++
+		} else if result.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", member.role, result.Error))
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: failed", member.role))
+		}
+
+		if result.Message != "" {
+			messages = append(messages, fmt.Sprintf("%s: %s", member.role, result.Message))
+		}
+		if result.NextSteps != "" {
+			nextSteps = append(nextSteps, fmt.Sprintf("%s: %s", member.role, result.NextSteps))
+		}
+		merged.FilesModified = append(merged.FilesModified, result.FilesModified...)
+		merged.CommandsExecuted = append(merged.CommandsExecuted, result.CommandsExecuted...)
+		if result.BuildOutput != "" {
+			merged.BuildOutput += fmt.Sprintf("\n=== %s Output ===\n%s", member.role, result.BuildOutput)
+		}
+		if result.Review != nil {
+			merged.Review = result.Review
+			merged.Findings = result.Findings
+		}
+	}
+
+	if len(runErrors) == len(members) {
+		return nil, errors.Join(runErrors...)
+	}
+
+	if node.RequireAll {
+		merged.Success = succeeded == len(members)
+	} else {
+		merged.Success = succeeded > 0
+	}
+	merged.NextSteps = strings.Join(nextSteps, "\n")
+	if merged.Success {
+		// join=any tolerates failed members; keep them visible without failing the node
+		for _, failure := range failures {
+			messages = append(messages, "failed "+failure)
+		}
+	} else {
+		merged.Error = strings.Join(failures, "; ")
+	}
+	merged.Message = strings.Join(messages, "\n")
+
+	return merged, nil
+}
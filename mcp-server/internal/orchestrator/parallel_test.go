@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/tools"
+)
+
+// snapshotAgent writes the files in its role's entry of edits and deletes the
+// ones mapped to an empty string, recording the directory it ran in
+type snapshotAgent struct {
+	role  agent.AgentRole
+	tools agent.ToolSet
+	edits map[agent.AgentRole]map[string]string
+
+	mu   *sync.Mutex
+	dirs map[agent.AgentRole]string
+}
+
+func (sa *snapshotAgent) ImplementFeature(ctx context.Context, req agent.ImplementFeatureRequest) (*agent.ImplementFeatureResponse, error) {
+	sa.tools.SetWorkingDirectory(req.WorkingDirectory)
+	sa.mu.Lock()
+	sa.dirs[sa.role] = sa.tools.GetWorkingDirectory()
+	sa.mu.Unlock()
+
+	var files []string
+	for file, content := range sa.edits[sa.role] {
+		if content == "" {
+			if err := os.Remove(filepath.Join(sa.tools.GetWorkingDirectory(), file)); err != nil {
+				return nil, err
+			}
+		} else if err := sa.tools.WriteFile(file, content); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return &agent.ImplementFeatureResponse{Success: true, Message: "done", FilesModified: files}, nil
+}
+
+func (sa *snapshotAgent) DocumentTask(ctx context.Context, result *agent.WorkflowResult) error {
+	return nil
+}
+
+func TestParallelMembersWorkInSnapshots(t *testing.T) {
+	projectDir := t.TempDir()
+	for file, content := range map[string]string{"main.txt": "v1", "obsolete.txt": "old"} {
+		if err := os.WriteFile(filepath.Join(projectDir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := testWorkflowConfig()
+	cfg.Flows = map[string]config.FlowConfig{
+		"parallel-review": {
+			Start:    string(agent.AgentRoleEngineer),
+			Parallel: []config.ParallelConfig{{Name: "review", Roles: []string{string(agent.AgentRoleQA), string(agent.AgentRoleTechLead)}}},
+			Edges: []config.RoutingRuleConfig{
+				{From: string(agent.AgentRoleEngineer), When: "success", To: "review"},
+				{From: string(agent.AgentRoleEngineer), To: string(agent.AgentRoleEngineer)},
+				{From: "review", To: string(agent.AgentRoleEngineer)},
+			},
+			Terminal: []config.TerminalConfig{{Role: "review", When: "success", Outcome: "success"}},
+		},
+	}
+	wo, err := NewWorkflowOrchestrator(nil, tools.NewToolSet(cfg.Commands, cfg.Restrictions, projectDir), cfg)
+	if err != nil {
+		t.Fatalf("NewWorkflowOrchestrator: %v", err)
+	}
+
+	edits := map[agent.AgentRole]map[string]string{
+		agent.AgentRoleEngineer: {"main.txt": "v2"},
+		agent.AgentRoleQA:       {"main_test.txt": "tests", "shared.txt": "agreed"},
+		agent.AgentRoleTechLead: {"shared.txt": "agreed", "obsolete.txt": ""},
+	}
+	var mu sync.Mutex
+	dirs := make(map[agent.AgentRole]string)
+	factory := agentFactoryFunc(func(role agent.AgentRole, toolSet agent.ToolSet) agent.Agent {
+		return &snapshotAgent{role: role, tools: toolSet, edits: edits, mu: &mu, dirs: dirs}
+	})
+	if err := wo.SetAgentBuilder(NewAgentBuilder(cfg, factory, func(config.WorkflowAgentConfig) agent.LLMClient { return nil })); err != nil {
+		t.Fatalf("SetAgentBuilder: %v", err)
+	}
+
+	result, err := wo.ExecuteWorkflow(context.Background(), agent.WorkflowRequest{
+		Description:      "change main",
+		ProjectType:      agent.ProjectTypeGo,
+		WorkingDirectory: projectDir,
+		Flow:             "parallel-review",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteWorkflow: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("workflow failed: %s (%s)", result.Error, result.FailureReason)
+	}
+
+	for _, role := range []agent.AgentRole{agent.AgentRoleQA, agent.AgentRoleTechLead} {
+		if dirs[role] == projectDir || dirs[role] == "" {
+			t.Errorf("%s ran in %q, want its own snapshot", role, dirs[role])
+		}
+		if _, err := os.Stat(dirs[role]); !os.IsNotExist(err) {
+			t.Errorf("snapshot of %s was not removed: %v", role, err)
+		}
+	}
+	if dirs[agent.AgentRoleQA] == dirs[agent.AgentRoleTechLead] {
+		t.Error("parallel members shared a snapshot")
+	}
+
+	want := map[string]string{"main.txt": "v2", "main_test.txt": "tests", "shared.txt": "agreed"}
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Errorf("project has %d files after the merge, want %d", len(entries), len(want))
+	}
+	for file, content := range want {
+		got, err := os.ReadFile(filepath.Join(projectDir, file))
+		if err != nil || string(got) != content {
+			t.Errorf("%s = %q (%v), want %q", file, got, err, content)
+		}
+	}
+}
+
+func TestMergeSnapshotsRejectsConflicts(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "calc.go"), []byte("package calc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshots []*snapshot
+	for role, edit := range map[AgentRole]string{agent.AgentRoleQA: "package calc // qa\n", agent.AgentRoleTechLead: ""} {
+		s, err := newSnapshot(role, projectDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.remove()
+		target := filepath.Join(s.dir, "calc.go")
+		if edit == "" {
+			err = os.Remove(target)
+		} else {
+			err = os.WriteFile(target, []byte(edit), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	err := mergeSnapshots(projectDir, snapshots)
+	if err == nil || !strings.Contains(err.Error(), "calc.go") {
+		t.Fatalf("mergeSnapshots error = %v, want a conflict on calc.go", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(projectDir, "calc.go")); string(got) != "package calc\n" {
+		t.Errorf("calc.go = %q after a conflict, want it untouched", got)
+	}
+}
+
+type agentFactoryFunc func(role agent.AgentRole, toolSet agent.ToolSet) agent.Agent
+
+func (f agentFactoryFunc) CreateAgent(role agent.AgentRole, llmClient agent.LLMClient, toolSet agent.ToolSet, restrictions agent.CommandRestrictions, cfg config.WorkflowAgentConfig) (agent.Agent, error) {
+	return f(role, toolSet), nil
+}
@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/tools"
+)
+
+// RenderPrompt returns the prompt the agent playing role would send when a
+// workflow hands it req, without calling its model. The agent sees req's
+// description with the project's instructions added, as the first agent of a
+// run does; later agents get the task the previous one handed over instead.
+func (wo *WorkflowOrchestrator) RenderPrompt(ctx context.Context, role AgentRole, req WorkflowRequest) (*agent.RenderedPrompt, error) {
+	workingDir := req.WorkingDirectory
+	if workingDir == "" {
+		workingDir = wo.toolSet.GetWorkingDirectory()
+	}
+	if err := agent.LoadProjectPrompts(workingDir); err != nil {
+		return nil, err
+	}
+
+	var toolSet agent.ToolSet
+	var agentInstance agent.Agent
+	if wo.agentBuilder != nil {
+		agentTools := tools.NewToolSet(wo.config.Commands, wo.config.Restrictions, workingDir)
+		built, err := wo.agentBuilder(role, agentTools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create agent %s: %w", role, err)
+		}
+		toolSet, agentInstance = agentTools, built
+	} else {
+		registered, ok := wo.agents[role]
+		if !ok {
+			return nil, fmt.Errorf("agent %s not registered", role)
+		}
+		wo.toolSet.SetWorkingDirectory(workingDir)
+		toolSet, agentInstance = wo.toolSet, registered
+	}
+	renderer, ok := agentInstance.(agent.PromptRenderer)
+	if !ok {
+		return nil, fmt.Errorf("agent %s cannot render its prompt", role)
+	}
+
+	if req.ProjectType == "" {
+		req.ProjectType = agent.DetectProject(toolSet, "").ProjectType
+	}
+	// The EM looks the request up in the code index
+	if _, err := toolSet.IndexCode(); err != nil {
+		log.Printf("Code index unavailable: %v", err)
+	}
+	projectContext, err := wo.gatherProjectContext(toolSet, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather project context: %w", err)
+	}
+	state := &WorkflowState{TaskDescription: req.Description, ProjectContext: projectContext}
+
+	return renderer.RenderPrompt(ctx, agent.ImplementFeatureRequest{
+		Description:      wo.buildAgentPrompt(role, state, req),
+		ProjectType:      req.ProjectType,
+		WorkingDirectory: workingDir,
+	})
+}
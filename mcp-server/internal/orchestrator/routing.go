@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/langpack"
 )
 
 type RoutingDecision struct {
@@ -31,30 +33,44 @@ type ErrorContext struct {
 
 type RoutingRule struct {
 	FromAgent    AgentRole
-	Condition    func(*agent.ImplementFeatureResponse) bool
+	When         string // condition expression, see routing_expr.go
 	NextAgent    AgentRole
 	Reason       string
 	Priority     int
+	condition    exprNode
+}
+
+// TerminalCondition ends a flow when its role finishes and the condition holds
+type TerminalCondition struct {
+	Role      AgentRole
+	When      string
+	Success   bool
+	Reason    string
+	condition exprNode
 }
 
 // RoutingEngine handles complex agent routing logic
 type RoutingEngine struct {
 	rules        []RoutingRule
 	errorPatterns []ErrorPattern
+	terminals    []TerminalCondition
 }
 
+// NewRoutingEngine creates an engine with the built-in rules and error patterns
 func NewRoutingEngine() *RoutingEngine {
-	re := &RoutingEngine{}
-	re.initializeErrorPatterns()
-	re.initializeRules()
+	re, err := NewRoutingEngineFromConfig(config.RoutingConfig{}, defaultRoles(), AgentRoleEM)
+	if err != nil {
+		panic(fmt.Sprintf("built-in routing rules are invalid: %v", err))
+	}
 	return re
 }
 
-func (re *RoutingEngine) initializeErrorPatterns() {
-	re.errorPatterns = []ErrorPattern{
+// defaultErrorPatterns is the built-in error classification table
+func defaultErrorPatterns() []config.ErrorPatternConfig {
+	return []config.ErrorPatternConfig{
 		// Critical build errors
 		{
-			Pattern:  regexp.MustCompile(`(?i)(undefined:\s*\w+|undeclared name:\s*\w+|cannot find[\s\w]*:\s*\w+)`),
+			Pattern:  `(?i)(undefined:\s*\w+|undeclared name:\s*\w+|cannot find[\s\w]*:\s*\w+)`,
 			Category: "undefined_symbol",
 			Severity: 4,
 			Suggestions: []string{
@@ -64,7 +80,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 			},
 		},
 		{
-			Pattern:  regexp.MustCompile(`(?i)(syntax error|unexpected \w+|expected \w+)`),
+			Pattern:  `(?i)(syntax error|unexpected \w+|expected \w+)`,
 			Category: "syntax_error",
 			Severity: 4,
 			Suggestions: []string{
@@ -74,7 +90,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 			},
 		},
 		{
-			Pattern:  regexp.MustCompile(`(?i)(import cycle|circular import|cyclic import)`),
+			Pattern:  `(?i)(import cycle|circular import|cyclic import)`,
 			Category: "import_cycle",
 			Severity: 3,
 			Suggestions: []string{
@@ -86,7 +102,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 		
 		// Dependency and module errors
 		{
-			Pattern:  regexp.MustCompile(`(?i)(module\s+\w+\s+not found|no such file or directory|package \w+ is not in GOROOT)`),
+			Pattern:  `(?i)(module\s+\w+\s+not found|no such file or directory|package \w+ is not in GOROOT)`,
 			Category: "missing_dependency",
 			Severity: 3,
 			Suggestions: []string{
@@ -96,7 +112,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 			},
 		},
 		{
-			Pattern:  regexp.MustCompile(`(?i)(permission denied|access denied|operation not permitted)`),
+			Pattern:  `(?i)(permission denied|access denied|operation not permitted)`,
 			Category: "permission_error",
 			Severity: 3,
 			Suggestions: []string{
@@ -108,7 +124,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 		
 		// Test-related errors
 		{
-			Pattern:  regexp.MustCompile(`(?i)(test failed|assertion failed|panic: test timed out)`),
+			Pattern:  `(?i)(test failed|assertion failed|panic: test timed out)`,
 			Category: "test_failure",
 			Severity: 2,
 			Suggestions: []string{
@@ -118,7 +134,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 			},
 		},
 		{
-			Pattern:  regexp.MustCompile(`(?i)(no tests to run|no test files|testing: warning: no tests to run)`),
+			Pattern:  `(?i)(no tests to run|no test files|testing: warning: no tests to run)`,
 			Category: "no_tests",
 			Severity: 1,
 			Suggestions: []string{
@@ -130,7 +146,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 		
 		// Runtime and logic errors
 		{
-			Pattern:  regexp.MustCompile(`(?i)(panic:|runtime error|nil pointer dereference|index out of range)`),
+			Pattern:  `(?i)(panic:|runtime error|nil pointer dereference|index out of range)`,
 			Category: "runtime_error",
 			Severity: 4,
 			Suggestions: []string{
@@ -140,7 +156,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 			},
 		},
 		{
-			Pattern:  regexp.MustCompile(`(?i)(type \w+ has no field \w+|cannot use \w+ as \w+ value)`),
+			Pattern:  `(?i)(type \w+ has no field \w+|cannot use \w+ as \w+ value)`,
 			Category: "type_error",
 			Severity: 3,
 			Suggestions: []string{
@@ -152,7 +168,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 		
 		// Network and external service errors
 		{
-			Pattern:  regexp.MustCompile(`(?i)(connection refused|timeout|no route to host|dial tcp.*refused)`),
+			Pattern:  `(?i)(connection refused|timeout|no route to host|dial tcp.*refused)`,
 			Category: "network_error",
 			Severity: 2,
 			Suggestions: []string{
@@ -164,7 +180,7 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 		
 		// Git and version control errors
 		{
-			Pattern:  regexp.MustCompile(`(?i)(fatal: not a git repository|git.*error|merge conflict)`),
+			Pattern:  `(?i)(fatal: not a git repository|git.*error|merge conflict)`,
 			Category: "git_error",
 			Severity: 2,
 			Suggestions: []string{
@@ -176,225 +192,68 @@ func (re *RoutingEngine) initializeErrorPatterns() {
 	}
 }
 
-func (re *RoutingEngine) initializeRules() {
-	re.rules = []RoutingRule{
+// defaultRoutingRules is the built-in transition table
+func defaultRoutingRules() []config.RoutingRuleConfig {
+	return []config.RoutingRuleConfig{
 		// Engineering Manager Rules
-		{
-			FromAgent: AgentRoleEM,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return result.Success
-			},
-			NextAgent: AgentRoleEngineer,
-			Reason:    "Plan approved, starting implementation",
-			Priority:  10,
-		},
-		{
-			FromAgent: AgentRoleEM,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success
-			},
-			NextAgent: AgentRoleEM, // Stay in EM for replanning
-			Reason:    "Planning failed, retrying",
-			Priority:  5,
-		},
+		{From: "engineering_manager", When: "success", To: "senior_engineer", Priority: 10,
+			Reason: "Plan approved, starting implementation"},
+		{From: "engineering_manager", When: "!success", To: "engineering_manager", Priority: 5,
+			Reason: "Planning failed, retrying"},
 
 		// Senior Engineer Rules
-		{
-			FromAgent: AgentRoleEngineer,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return result.Success && len(result.FilesModified) > 0
-			},
-			NextAgent: AgentRoleQA,
-			Reason:    "Implementation complete, needs testing",
-			Priority:  20,
-		},
-		{
-			FromAgent: AgentRoleEngineer,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				// Task completed successfully without file changes usually means:
-				// 1. It was a maintenance task (like dependency resolution)
-				// 2. The implementation failed but agent reported success
-				// Route to Tech Lead for final assessment
-				return result.Success && len(result.FilesModified) == 0 && 
-					   !strings.Contains(strings.ToLower(result.Message), "failed") &&
-					   !strings.Contains(strings.ToLower(result.Error), "failed")
-			},
-			NextAgent: AgentRoleTechLead,
-			Reason:    "Task completed without file changes, skip to quality review",
-			Priority:  19,
-		},
-		{
-			FromAgent: AgentRoleEngineer,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				if result.Success {
-					return false
-				}
-				errorCtx := re.analyzeErrorContext(result)
-				return errorCtx.Category == "syntax_error" || errorCtx.Category == "undefined_symbol" || 
-					   errorCtx.Category == "type_error" || errorCtx.Category == "import_cycle"
-			},
-			NextAgent: AgentRoleEngineer, // Stay in Engineer to fix critical build issues
-			Reason:    "Critical build errors detected, continuing implementation fixes",
-			Priority:  18,
-		},
-		{
-			FromAgent: AgentRoleEngineer,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				if result.Success {
-					return false
-				}
-				errorCtx := re.analyzeErrorContext(result)
-				return errorCtx.Category == "missing_dependency" || errorCtx.Category == "permission_error"
-			},
-			NextAgent: AgentRoleEM,
-			Reason:    "Dependency or permission issues detected, need planning support",
-			Priority:  15,
-		},
-		{
-			FromAgent: AgentRoleEngineer,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				if result.Success {
-					return false
-				}
-				errorCtx := re.analyzeErrorContext(result)
-				return errorCtx.Category == "runtime_error" && errorCtx.Severity >= 3
-			},
-			NextAgent: AgentRoleEngineer, // Stay to fix runtime issues
-			Reason:    "Runtime errors detected, applying targeted fixes",
-			Priority:  16,
-		},
-		{
-			FromAgent: AgentRoleEngineer,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				if result.Success {
-					return false
-				}
-				errorCtx := re.analyzeErrorContext(result)
-				return errorCtx.Category == "network_error" || errorCtx.Category == "git_error"
-			},
-			NextAgent: AgentRoleEM,
-			Reason:    "External system issues detected, need guidance",
-			Priority:  12,
-		},
-		{
-			FromAgent: AgentRoleEngineer,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success
-			},
-			NextAgent: AgentRoleEM,
-			Reason:    "Implementation failed, need replanning",
-			Priority:  5,
-		},
+		{From: "senior_engineer", When: "success && files_modified > 0", To: "senior_qa", Priority: 20,
+			Reason: "Implementation complete, needs testing"},
+		// Success without file changes is usually a maintenance task such as dependency resolution
+		{From: "senior_engineer", When: `success && files_modified == 0 && !contains(message, "failed") && !contains(error, "failed")`, To: "senior_tech_lead", Priority: 19,
+			Reason: "Task completed without file changes, skip to quality review"},
+		{From: "senior_engineer", When: `success`, To: "engineering_manager", Priority: 1,
+			Reason: "Engineer reported success alongside failures, need guidance"},
+		{From: "senior_engineer", When: `!success && error.category in ["syntax_error", "undefined_symbol", "type_error", "import_cycle"]`, To: "senior_engineer", Priority: 18,
+			Reason: "Critical build errors detected, continuing implementation fixes"},
+		{From: "senior_engineer", When: `!success && error.category in ["missing_dependency", "permission_error"]`, To: "engineering_manager", Priority: 15,
+			Reason: "Dependency or permission issues detected, need planning support"},
+		{From: "senior_engineer", When: `!success && error.category == "runtime_error" && error.severity >= 3`, To: "senior_engineer", Priority: 16,
+			Reason: "Runtime errors detected, applying targeted fixes"},
+		{From: "senior_engineer", When: `!success && error.category in ["network_error", "git_error"]`, To: "engineering_manager", Priority: 12,
+			Reason: "External system issues detected, need guidance"},
+		{From: "senior_engineer", When: "!success", To: "engineering_manager", Priority: 5,
+			Reason: "Implementation failed, need replanning"},
 
 		// Senior QA Engineer Rules
-		{
-			FromAgent: AgentRoleQA,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return result.Success && re.hasTestsAdded(result)
-			},
-			NextAgent: AgentRoleTechLead,
-			Reason:    "Tests added and passing, ready for quality review",
-			Priority:  20,
-		},
-		{
-			FromAgent: AgentRoleQA,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				if result.Success {
-					return false
-				}
-				errorCtx := re.analyzeErrorContext(result)
-				return errorCtx.Category == "test_failure" && errorCtx.Severity >= 2
-			},
-			NextAgent: AgentRoleEngineer,
-			Reason:    "Significant test failures found, implementation needs fixes",
-			Priority:  17,
-		},
-		{
-			FromAgent: AgentRoleQA,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				if result.Success {
-					return false
-				}
-				errorCtx := re.analyzeErrorContext(result)
-				return errorCtx.Category == "no_tests" || errorCtx.Severity <= 1
-			},
-			NextAgent: AgentRoleQA, // Stay to create proper tests
-			Reason:    "Missing or insufficient tests, continuing test development",
-			Priority:  10,
-		},
-		{
-			FromAgent: AgentRoleQA,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success && re.isNonTestableCode(result)
-			},
-			NextAgent: AgentRoleTechLead,
-			Reason:    "Code determined non-testable, skip to quality review",
-			Priority:  10,
-		},
-		{
-			FromAgent: AgentRoleQA,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success
-			},
-			NextAgent: AgentRoleEM,
-			Reason:    "QA process failed, need guidance",
-			Priority:  5,
-		},
+		{From: "senior_qa", When: "success && tests_added", To: "senior_tech_lead", Priority: 20,
+			Reason: "Tests added and passing, ready for quality review"},
+		{From: "senior_qa", When: "success", To: "senior_tech_lead", Priority: 5,
+			Reason: "QA passed without new tests, ready for quality review"},
+		{From: "senior_qa", When: `!success && error.category == "test_failure" && error.severity >= 2`, To: "senior_engineer", Priority: 17,
+			Reason: "Significant test failures found, implementation needs fixes"},
+		{From: "senior_qa", When: `!success && (error.category == "no_tests" || error.severity <= 1)`, To: "senior_qa", Priority: 10,
+			Reason: "Missing or insufficient tests, continuing test development"},
+		{From: "senior_qa", When: "!success && non_testable", To: "senior_tech_lead", Priority: 10,
+			Reason: "Code determined non-testable, skip to quality review"},
+		{From: "senior_qa", When: "!success", To: "engineering_manager", Priority: 5,
+			Reason: "QA process failed, need guidance"},
 
 		// Senior Tech Lead Rules
-		{
-			FromAgent: AgentRoleTechLead,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return result.Success
-			},
-			NextAgent: AgentRoleTechLead, // Workflow complete (handled elsewhere)
-			Reason:    "Quality review passed, workflow complete",
-			Priority:  20,
-		},
-		{
-			FromAgent: AgentRoleTechLead,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success && re.isQualityIssue(result)
-			},
-			NextAgent: AgentRoleEngineer,
-			Reason:    "Quality issues found, need implementation fixes",
-			Priority:  15,
-		},
-		{
-			FromAgent: AgentRoleTechLead,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success && re.isStructuredRejection(result)
-			},
-			NextAgent: AgentRoleEM,
-			Reason:    "Tech Lead structured rejection - routing to EM as requested",
-			Priority:  20,
-		},
-		{
-			FromAgent: AgentRoleTechLead,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success && re.isArchitectureIssue(result)
-			},
-			NextAgent: AgentRoleEM,
-			Reason:    "Architecture concerns, need replanning",
-			Priority:  15,
-		},
-		{
-			FromAgent: AgentRoleTechLead,
-			Condition: func(result *agent.ImplementFeatureResponse) bool {
-				return !result.Success
-			},
-			NextAgent: AgentRoleEM,
-			Reason:    "Tech lead review failed, need guidance",
-			Priority:  5,
-		},
+		{From: "senior_tech_lead", When: "success", To: "senior_tech_lead", Priority: 20, // Workflow complete (handled elsewhere)
+			Reason: "Quality review passed, workflow complete"},
+		{From: "senior_tech_lead", When: "!success && quality_issue", To: "senior_engineer", Priority: 15,
+			Reason: "Quality issues found, need implementation fixes"},
+		{From: "senior_tech_lead", When: "!success && structured_rejection", To: "engineering_manager", Priority: 20,
+			Reason: "Tech Lead structured rejection - routing to EM as requested"},
+		{From: "senior_tech_lead", When: "!success && architecture_issue", To: "engineering_manager", Priority: 15,
+			Reason: "Architecture concerns, need replanning"},
+		{From: "senior_tech_lead", When: "!success", To: "engineering_manager", Priority: 5,
+			Reason: "Tech lead review failed, need guidance"},
 	}
 }
 
 func (re *RoutingEngine) RouteAgent(currentAgent AgentRole, result *agent.ImplementFeatureResponse) (AgentRole, string, error) {
 	var bestDecision *RoutingDecision
-	
+	facts := re.factsFor(result)
+
 	for _, rule := range re.rules {
-		if rule.FromAgent == currentAgent && rule.Condition(result) {
+		if rule.FromAgent == currentAgent && rule.condition.eval(facts).b {
 			decision := &RoutingDecision{
 				NextAgent: rule.NextAgent,
 				Reason:    rule.Reason,
@@ -415,16 +274,58 @@ func (re *RoutingEngine) RouteAgent(currentAgent AgentRole, result *agent.Implem
 	return bestDecision.NextAgent, bestDecision.Reason, nil
 }
 
+// Terminal returns the first terminal condition the result of role satisfies, or nil
+func (re *RoutingEngine) Terminal(role AgentRole, result *agent.ImplementFeatureResponse) *TerminalCondition {
+	var facts *routingFacts
+	for i := range re.terminals {
+		terminal := &re.terminals[i]
+		if terminal.Role != role {
+			continue
+		}
+		if facts == nil {
+			facts = re.factsFor(result)
+		}
+		if terminal.condition.eval(facts).b {
+			return terminal
+		}
+	}
+	return nil
+}
+
+// factsFor extracts everything routing conditions can observe from a result
+func (re *RoutingEngine) factsFor(result *agent.ImplementFeatureResponse) *routingFacts {
+	errorCtx := re.analyzeErrorContext(result)
+	return &routingFacts{
+		Success:             result.Success,
+		FilesModified:       len(result.FilesModified),
+		CommandsExecuted:    len(result.CommandsExecuted),
+		Message:             result.Message,
+		Error:               result.Error,
+		BuildOutput:         result.BuildOutput,
+		NextSteps:           result.NextSteps,
+		ErrorCategory:       errorCtx.Category,
+		ErrorSeverity:       errorCtx.Severity,
+		ErrorRecoverable:    errorCtx.IsRecoverable,
+		ErrorRequiresHelp:   errorCtx.RequiresHelp,
+		TestsAdded:          re.hasTestsAdded(result),
+		NonTestable:         re.isNonTestableCode(result),
+		QualityIssue:        re.isQualityIssue(result),
+		ArchitectureIssue:   re.isArchitectureIssue(result),
+		StructuredRejection: re.isStructuredRejection(result),
+	}
+}
+
 // Enhanced error analysis for smarter routing decisions
 func (re *RoutingEngine) analyzeErrorContext(result *agent.ImplementFeatureResponse) ErrorContext {
 	errorText := strings.ToLower(result.Error + " " + result.BuildOutput + " " + result.Message)
 	
 	// Find the most severe matching pattern
 	var bestMatch *ErrorPattern
-	for _, pattern := range re.errorPatterns {
+	for i := range re.errorPatterns {
+		pattern := &re.errorPatterns[i]
 		if pattern.Pattern.MatchString(errorText) {
 			if bestMatch == nil || pattern.Severity > bestMatch.Severity {
-				bestMatch = &pattern
+				bestMatch = pattern
 			}
 		}
 	}
@@ -511,18 +412,7 @@ func (re *RoutingEngine) hasTestsAdded(result *agent.ImplementFeatureResponse) b
 }
 
 func (re *RoutingEngine) isTestFile(filename string) bool {
-	testPatterns := []string{
-		"_test.go", ".test.js", ".test.ts", ".spec.js", ".spec.ts",
-		"test_", "_test.py", "/test/", "/tests/",
-	}
-	
-	for _, pattern := range testPatterns {
-		if strings.Contains(strings.ToLower(filename), pattern) {
-			return true
-		}
-	}
-	
-	return false
+	return langpack.IsTestFile(filename)
 }
 
 func (re *RoutingEngine) isTestFailure(result *agent.ImplementFeatureResponse) bool {
@@ -613,6 +503,9 @@ func (re *RoutingEngine) isStructuredRejection(result *agent.ImplementFeatureRes
 		"security_concerns", 
 		"unnecessary_duplication",
 		"pattern_deviation",
+		"lint_findings",
+		"build_or_tests_failing",
+		"quality_score_below_threshold",
 	}
 	
 	for _, pattern := range structuredPatterns {
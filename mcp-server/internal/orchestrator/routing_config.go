@@ -0,0 +1,311 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/langpack"
+)
+
+// defaultRoles are the roles the built-in routing table expects
+func defaultRoles() []AgentRole {
+	return []AgentRole{AgentRoleEM, AgentRoleEngineer, AgentRoleQA, AgentRoleTechLead}
+}
+
+// configuredRoles lists the roles declared in the workflow config
+func configuredRoles(cfg *config.WorkflowConfig) []AgentRole {
+	var roles []AgentRole
+	for _, agentCfg := range cfg.Agents {
+		roles = append(roles, AgentRole(agentCfg.Role))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// NewRoutingEngineFromConfig compiles routing rules and error patterns from
+// config, falling back to the built-in tables for anything left empty, and
+// validates that every role is reachable from start and always has a rule to follow
+func NewRoutingEngineFromConfig(cfg config.RoutingConfig, roles []AgentRole, start AgentRole) (*RoutingEngine, error) {
+	rules := cfg.Rules
+	if len(rules) == 0 {
+		rules = defaultRoutingRules()
+	}
+	return buildRoutingEngine(cfg.ErrorPatterns, rules, nil, roles, start)
+}
+
+// buildRoutingEngine compiles and validates one routing graph; terminal
+// conditions count as handled outcomes when checking for fallback rules
+func buildRoutingEngine(patterns []config.ErrorPatternConfig, rules []config.RoutingRuleConfig, terminals []config.TerminalConfig, roles []AgentRole, start AgentRole) (*RoutingEngine, error) {
+	re := &RoutingEngine{}
+
+	if len(patterns) == 0 {
+		patterns = defaultErrorPatterns()
+	}
+	// Language packs contribute the compiler and test runner errors of their stacks
+	patterns = append(append([]config.ErrorPatternConfig(nil), patterns...), langpack.ErrorPatterns()...)
+	for i, patternCfg := range patterns {
+		pattern, err := compileErrorPattern(patternCfg)
+		if err != nil {
+			return nil, fmt.Errorf("error pattern %d: %w", i+1, err)
+		}
+		re.errorPatterns = append(re.errorPatterns, pattern)
+	}
+
+	known := make(map[AgentRole]bool, len(roles))
+	for _, role := range roles {
+		known[role] = true
+	}
+	if !known[start] {
+		return nil, fmt.Errorf("start role %s is not one of the routed roles", start)
+	}
+
+	for i, ruleCfg := range rules {
+		rule, err := compileRoutingRule(ruleCfg, known)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule %d (%s -> %s): %w", i+1, ruleCfg.From, ruleCfg.To, err)
+		}
+		re.rules = append(re.rules, rule)
+	}
+
+	for i, terminalCfg := range terminals {
+		terminal, err := compileTerminal(terminalCfg, known)
+		if err != nil {
+			return nil, fmt.Errorf("terminal %d (%s): %w", i+1, terminalCfg.Role, err)
+		}
+		re.terminals = append(re.terminals, terminal)
+	}
+
+	if err := re.validate(roles, start); err != nil {
+		return nil, err
+	}
+	return re, nil
+}
+
+func compileErrorPattern(cfg config.ErrorPatternConfig) (ErrorPattern, error) {
+	if cfg.Category == "" {
+		return ErrorPattern{}, fmt.Errorf("category is required")
+	}
+	if cfg.Severity < 1 || cfg.Severity > 4 {
+		return ErrorPattern{}, fmt.Errorf("category %s severity must be between 1 and 4", cfg.Category)
+	}
+	pattern, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return ErrorPattern{}, fmt.Errorf("category %s pattern is invalid: %w", cfg.Category, err)
+	}
+	return ErrorPattern{
+		Pattern:     pattern,
+		Category:    cfg.Category,
+		Severity:    cfg.Severity,
+		Suggestions: cfg.Suggestions,
+	}, nil
+}
+
+func compileRoutingRule(cfg config.RoutingRuleConfig, known map[AgentRole]bool) (RoutingRule, error) {
+	from, to := AgentRole(cfg.From), AgentRole(cfg.To)
+	if !known[from] {
+		return RoutingRule{}, fmt.Errorf("unknown from role %q", cfg.From)
+	}
+	if !known[to] {
+		return RoutingRule{}, fmt.Errorf("unknown target role %q", cfg.To)
+	}
+
+	condition, err := compileCondition(cfg.When)
+	if err != nil {
+		return RoutingRule{}, fmt.Errorf("condition %q: %w", cfg.When, err)
+	}
+
+	reason := cfg.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("Routing %s to %s", cfg.From, cfg.To)
+	}
+	return RoutingRule{
+		FromAgent: from,
+		When:      cfg.When,
+		NextAgent: to,
+		Reason:    reason,
+		Priority:  cfg.Priority,
+		condition: condition,
+	}, nil
+}
+
+func compileTerminal(cfg config.TerminalConfig, known map[AgentRole]bool) (TerminalCondition, error) {
+	role := AgentRole(cfg.Role)
+	if !known[role] {
+		return TerminalCondition{}, fmt.Errorf("unknown role %q", cfg.Role)
+	}
+
+	condition, err := compileCondition(cfg.When)
+	if err != nil {
+		return TerminalCondition{}, fmt.Errorf("condition %q: %w", cfg.When, err)
+	}
+
+	success := cfg.Outcome != "failure"
+	reason := cfg.Reason
+	if reason == "" {
+		if success {
+			reason = fmt.Sprintf("%s finished, workflow complete", cfg.Role)
+		} else {
+			reason = fmt.Sprintf("%s finished, workflow stopped", cfg.Role)
+		}
+	}
+	return TerminalCondition{
+		Role:      role,
+		When:      cfg.When,
+		Success:   success,
+		Reason:    reason,
+		condition: condition,
+	}, nil
+}
+
+// validate rejects routing tables with unreachable roles or outcomes no rule handles
+func (re *RoutingEngine) validate(roles []AgentRole, start AgentRole) error {
+	reachable := map[AgentRole]bool{start: true}
+	queue := []AgentRole{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, rule := range re.rules {
+			if rule.FromAgent == current && !reachable[rule.NextAgent] {
+				reachable[rule.NextAgent] = true
+				queue = append(queue, rule.NextAgent)
+			}
+		}
+	}
+
+	var unreachable []string
+	for _, role := range roles {
+		if !reachable[role] {
+			unreachable = append(unreachable, string(role))
+		}
+	}
+	if len(unreachable) > 0 {
+		return fmt.Errorf("roles unreachable from %s: %s", start, strings.Join(unreachable, ", "))
+	}
+
+	probes := re.routingProbes()
+	for _, role := range roles {
+		if err := re.checkFallback(role, probes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFallback makes sure some rule or terminal condition for role matches every probe outcome
+func (re *RoutingEngine) checkFallback(role AgentRole, probes []*routingFacts) error {
+	var conditions []exprNode
+	for _, terminal := range re.terminals {
+		if terminal.Role == role {
+			conditions = append(conditions, terminal.condition)
+		}
+	}
+	for _, rule := range re.rules {
+		if rule.FromAgent == role {
+			conditions = append(conditions, rule.condition)
+		}
+	}
+	if len(conditions) == 0 {
+		return fmt.Errorf("role %s has no routing rules", role)
+	}
+
+	for _, probe := range probes {
+		matched := false
+		for _, condition := range conditions {
+			if condition.eval(probe).b {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("role %s has no fallback rule for %s", role, describeProbe(probe))
+		}
+	}
+	return nil
+}
+
+// routingProbes enumerates representative agent outcomes: success or failure,
+// no files / source files / test files, every error category, every heuristic
+// flag, and text that either contains none or all of the rule substrings
+func (re *RoutingEngine) routingProbes() []*routingFacts {
+	type category struct {
+		name     string
+		severity int
+	}
+	categories := []category{{"unknown", 2}}
+	for _, pattern := range re.errorPatterns {
+		categories = append(categories, category{pattern.Category, pattern.Severity})
+	}
+
+	var literals []string
+	conditions := make([]string, 0, len(re.rules)+len(re.terminals))
+	for _, rule := range re.rules {
+		conditions = append(conditions, rule.When)
+	}
+	for _, terminal := range re.terminals {
+		conditions = append(conditions, terminal.When)
+	}
+	for _, condition := range conditions {
+		for _, match := range containsLiteralPattern.FindAllStringSubmatch(condition, -1) {
+			literals = append(literals, match[2])
+		}
+	}
+	texts := []string{"", strings.Join(literals, " ")}
+
+	var probes []*routingFacts
+	for _, success := range []bool{true, false} {
+		for files := 0; files < 3; files++ {
+			for _, cat := range categories {
+				for flags := 0; flags < 16; flags++ {
+					for _, text := range texts {
+						probes = append(probes, &routingFacts{
+							Success:             success,
+							FilesModified:       files,
+							TestsAdded:          files == 2,
+							Message:             text,
+							Error:               text,
+							BuildOutput:         text,
+							NextSteps:           text,
+							ErrorCategory:       cat.name,
+							ErrorSeverity:       cat.severity,
+							ErrorRecoverable:    cat.severity <= 3,
+							NonTestable:         flags&1 != 0,
+							QualityIssue:        flags&2 != 0,
+							ArchitectureIssue:   flags&4 != 0,
+							StructuredRejection: flags&8 != 0,
+						})
+					}
+				}
+			}
+		}
+	}
+	return probes
+}
+
+var containsLiteralPattern = regexp.MustCompile(`contains\(\s*[\w.]+\s*,\s*(["'])(.*?)["']\s*\)`)
+
+func describeProbe(f *routingFacts) string {
+	parts := []string{
+		fmt.Sprintf("success=%v", f.Success),
+		fmt.Sprintf("files_modified=%d", f.FilesModified),
+		fmt.Sprintf("tests_added=%v", f.TestsAdded),
+		fmt.Sprintf("error.category=%s", f.ErrorCategory),
+	}
+	flags := map[string]bool{
+		"non_testable":         f.NonTestable,
+		"quality_issue":        f.QualityIssue,
+		"architecture_issue":   f.ArchitectureIssue,
+		"structured_rejection": f.StructuredRejection,
+	}
+	for _, name := range []string{"non_testable", "quality_issue", "architecture_issue", "structured_rejection"} {
+		if flags[name] {
+			parts = append(parts, name)
+		}
+	}
+	if f.Message != "" {
+		parts = append(parts, fmt.Sprintf("message=%q", f.Message))
+	}
+	return strings.Join(parts, " ")
+}
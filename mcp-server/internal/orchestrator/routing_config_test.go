@@ -0,0 +1,148 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"mcp-server/internal/config"
+)
+
+func TestNewRoutingEngineFromConfigValidates(t *testing.T) {
+	em, engineer := string(AgentRoleEM), string(AgentRoleEngineer)
+	roles := []AgentRole{AgentRoleEM, AgentRoleEngineer}
+	// A minimal table that handles every outcome of both roles
+	valid := []config.RoutingRuleConfig{
+		{From: em, When: "success", To: engineer},
+		{From: em, When: "!success", To: em},
+		{From: engineer, To: em},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.RoutingConfig
+		roles    []AgentRole
+		start    AgentRole
+		terminal []config.TerminalConfig
+		wantErr  string
+	}{
+		{name: "built-in defaults", roles: defaultRoles(), start: AgentRoleEM},
+		{name: "every outcome handled", cfg: config.RoutingConfig{Rules: valid}, roles: roles, start: AgentRoleEM},
+		{
+			name:     "terminal conditions count as fallback",
+			cfg:      config.RoutingConfig{Rules: valid[:2]},
+			roles:    roles,
+			start:    AgentRoleEM,
+			terminal: []config.TerminalConfig{{Role: engineer}},
+		},
+		{
+			name:    "unknown start role",
+			cfg:     config.RoutingConfig{Rules: valid},
+			roles:   roles,
+			start:   AgentRoleQA,
+			wantErr: "start role senior_qa is not one of the routed roles",
+		},
+		{
+			name: "unreachable role",
+			cfg: config.RoutingConfig{Rules: []config.RoutingRuleConfig{
+				{From: em, To: em},
+				{From: engineer, To: em},
+			}},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: "roles unreachable from engineering_manager: senior_engineer",
+		},
+		{
+			name: "role without rules",
+			cfg: config.RoutingConfig{Rules: []config.RoutingRuleConfig{
+				{From: em, When: "success", To: engineer},
+				{From: em, When: "!success", To: em},
+			}},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: "role senior_engineer has no routing rules",
+		},
+		{
+			name: "missing fallback",
+			cfg: config.RoutingConfig{Rules: []config.RoutingRuleConfig{
+				{From: em, When: "success", To: engineer},
+				{From: em, When: "!success", To: em},
+				{From: engineer, When: "success", To: em},
+				{From: engineer, When: `!success && error.category == "syntax_error"`, To: engineer},
+			}},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: "role senior_engineer has no fallback rule for success=false",
+		},
+		{
+			name:    "unknown from role",
+			cfg:     config.RoutingConfig{Rules: append([]config.RoutingRuleConfig{{From: "architect", To: em}}, valid...)},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: `routing rule 1 (architect -> engineering_manager): unknown from role "architect"`,
+		},
+		{
+			name:    "unknown target role",
+			cfg:     config.RoutingConfig{Rules: append(append([]config.RoutingRuleConfig(nil), valid...), config.RoutingRuleConfig{From: engineer, To: "architect"})},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: `routing rule 4 (senior_engineer -> architect): unknown target role "architect"`,
+		},
+		{
+			name:    "invalid condition",
+			cfg:     config.RoutingConfig{Rules: append([]config.RoutingRuleConfig{{From: em, When: "success &&", To: engineer}}, valid...)},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: `routing rule 1 (engineering_manager -> senior_engineer): condition "success &&"`,
+		},
+		{
+			name: "pattern severity out of range",
+			cfg: config.RoutingConfig{
+				Rules:         valid,
+				ErrorPatterns: []config.ErrorPatternConfig{{Pattern: "boom", Category: "explosion", Severity: 5}},
+			},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: "error pattern 1: category explosion severity must be between 1 and 4",
+		},
+		{
+			name: "pattern without category",
+			cfg: config.RoutingConfig{
+				Rules:         valid,
+				ErrorPatterns: []config.ErrorPatternConfig{{Pattern: "boom", Severity: 2}},
+			},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: "error pattern 1: category is required",
+		},
+		{
+			name: "invalid pattern",
+			cfg: config.RoutingConfig{
+				Rules:         valid,
+				ErrorPatterns: []config.ErrorPatternConfig{{Pattern: "(boom", Category: "explosion", Severity: 2}},
+			},
+			roles:   roles,
+			start:   AgentRoleEM,
+			wantErr: "error pattern 1: category explosion pattern is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.terminal != nil {
+				_, err = buildRoutingEngine(tt.cfg.ErrorPatterns, tt.cfg.Rules, tt.terminal, tt.roles, tt.start)
+			} else {
+				_, err = NewRoutingEngineFromConfig(tt.cfg, tt.roles, tt.start)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
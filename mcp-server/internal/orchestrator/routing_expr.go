@@ -0,0 +1,519 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Routing conditions are small boolean expressions over an agent result, e.g.
+//
+//	!success && error.category in ["syntax_error", "type_error"] && error.severity >= 3
+//	success && files_modified > 0
+//	contains(message, "non-testable") || structured_rejection
+//
+// Operators: ! && || == != < <= > >= in, parentheses, string/number/bool
+// literals and string lists. Functions: contains(text, substr) which is case
+// insensitive, and matches(text, regex).
+
+// routingFacts is what a condition can observe about an agent result
+type routingFacts struct {
+	Success             bool
+	FilesModified       int
+	CommandsExecuted    int
+	Message             string
+	Error               string
+	BuildOutput         string
+	NextSteps           string
+	ErrorCategory       string
+	ErrorSeverity       int
+	ErrorRecoverable    bool
+	ErrorRequiresHelp   bool
+	TestsAdded          bool
+	NonTestable         bool
+	QualityIssue        bool
+	ArchitectureIssue   bool
+	StructuredRejection bool
+}
+
+type exprType int
+
+const (
+	typeBool exprType = iota
+	typeNumber
+	typeString
+	typeList
+)
+
+func (t exprType) String() string {
+	return [...]string{"bool", "number", "string", "list"}[t]
+}
+
+type exprValue struct {
+	b    bool
+	n    float64
+	s    string
+	list []string
+}
+
+type exprNode interface {
+	typ() exprType
+	eval(f *routingFacts) exprValue
+}
+
+// routingFields maps identifiers to their type and accessor
+var routingFields = map[string]struct {
+	t   exprType
+	get func(f *routingFacts) exprValue
+}{
+	"success":              {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.Success} }},
+	"files_modified":       {typeNumber, func(f *routingFacts) exprValue { return exprValue{n: float64(f.FilesModified)} }},
+	"commands_executed":    {typeNumber, func(f *routingFacts) exprValue { return exprValue{n: float64(f.CommandsExecuted)} }},
+	"message":              {typeString, func(f *routingFacts) exprValue { return exprValue{s: f.Message} }},
+	"error":                {typeString, func(f *routingFacts) exprValue { return exprValue{s: f.Error} }},
+	"build_output":         {typeString, func(f *routingFacts) exprValue { return exprValue{s: f.BuildOutput} }},
+	"next_steps":           {typeString, func(f *routingFacts) exprValue { return exprValue{s: f.NextSteps} }},
+	"error.category":       {typeString, func(f *routingFacts) exprValue { return exprValue{s: f.ErrorCategory} }},
+	"error.severity":       {typeNumber, func(f *routingFacts) exprValue { return exprValue{n: float64(f.ErrorSeverity)} }},
+	"error.recoverable":    {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.ErrorRecoverable} }},
+	"error.requires_help":  {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.ErrorRequiresHelp} }},
+	"tests_added":          {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.TestsAdded} }},
+	"non_testable":         {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.NonTestable} }},
+	"quality_issue":        {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.QualityIssue} }},
+	"architecture_issue":   {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.ArchitectureIssue} }},
+	"structured_rejection": {typeBool, func(f *routingFacts) exprValue { return exprValue{b: f.StructuredRejection} }},
+}
+
+// compileCondition parses a condition expression; an empty expression always holds
+func compileCondition(expression string) (exprNode, error) {
+	if strings.TrimSpace(expression) == "" {
+		return literalNode{t: typeBool, v: exprValue{b: true}}, nil
+	}
+
+	tokens, err := tokenizeCondition(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &conditionParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at end of condition", p.tokens[p.pos].text)
+	}
+	if node.typ() != typeBool {
+		return nil, fmt.Errorf("condition must be boolean, got %s", node.typ())
+	}
+	return node, nil
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokOperator
+)
+
+type conditionToken struct {
+	kind tokenKind
+	text string
+}
+
+func tokenizeCondition(expression string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			end := i + 1
+			var text strings.Builder
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' && end+1 < len(runes) {
+					end++
+				}
+				text.WriteRune(runes[end])
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string starting at %d", i)
+			}
+			tokens = append(tokens, conditionToken{tokString, text.String()})
+			i = end + 1
+
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, conditionToken{tokNumber, string(runes[i:end])})
+			i = end
+
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, conditionToken{tokIdent, string(runes[i:end])})
+			i = end
+
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					tokens = append(tokens, conditionToken{tokOperator, two})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("!<>()[],", r) {
+				tokens = append(tokens, conditionToken{tokOperator, string(r)})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q at %d", r, i)
+		}
+	}
+
+	return tokens, nil
+}
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) peek() *conditionToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *conditionParser) accept(text string) bool {
+	if tok := p.peek(); tok != nil && (tok.kind == tokOperator || tok.kind == tokIdent) && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) expect(text string) error {
+	if !p.accept(text) {
+		if tok := p.peek(); tok != nil {
+			return fmt.Errorf("expected %q, got %q", text, tok.text)
+		}
+		return fmt.Errorf("expected %q at end of condition", text)
+	}
+	return nil
+}
+
+func (p *conditionParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool("||", left, right); err != nil {
+			return nil, err
+		}
+		left = logicalNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool("&&", left, right); err != nil {
+			return nil, err
+		}
+		left = logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool("!", operand); err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *conditionParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok == nil {
+		return left, nil
+	}
+
+	switch tok.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != right.typ() {
+			return nil, fmt.Errorf("cannot compare %s with %s using %s", left.typ(), right.typ(), tok.text)
+		}
+		if tok.text != "==" && tok.text != "!=" && left.typ() != typeNumber {
+			return nil, fmt.Errorf("operator %s needs numbers, got %s", tok.text, left.typ())
+		}
+		return compareNode{op: tok.text, left: left, right: right}, nil
+
+	case "in":
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != typeString || right.typ() != typeList {
+			return nil, fmt.Errorf("'in' needs a string on the left and a list on the right")
+		}
+		return inNode{value: left, list: right}, nil
+	}
+
+	return left, nil
+}
+
+func (p *conditionParser) parsePrimary() (exprNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokString:
+		return literalNode{t: typeString, v: exprValue{s: tok.text}}, nil
+
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return literalNode{t: typeNumber, v: exprValue{n: n}}, nil
+
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return literalNode{t: typeBool, v: exprValue{b: tok.text == "true"}}, nil
+		case "contains", "matches":
+			return p.parseCall(tok.text)
+		}
+		field, ok := routingFields[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", tok.text)
+		}
+		return fieldNode{t: field.t, get: field.get}, nil
+	}
+
+	switch tok.text {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+
+	case "[":
+		var items []string
+		for !p.accept("]") {
+			item := p.peek()
+			if item == nil || item.kind != tokString {
+				return nil, fmt.Errorf("lists may only contain strings")
+			}
+			p.pos++
+			items = append(items, item.text)
+			if !p.accept(",") {
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		return literalNode{t: typeList, v: exprValue{list: items}}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+func (p *conditionParser) parseCall(name string) (exprNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	text, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+	arg := p.peek()
+	if arg == nil || arg.kind != tokString {
+		return nil, fmt.Errorf("%s needs a string literal as its second argument", name)
+	}
+	p.pos++
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if text.typ() != typeString {
+		return nil, fmt.Errorf("%s needs a string as its first argument, got %s", name, text.typ())
+	}
+
+	if name == "matches" {
+		re, err := regexp.Compile(arg.text)
+		if err != nil {
+			return nil, fmt.Errorf("matches: invalid regex: %w", err)
+		}
+		return matchNode{text: text, re: re}, nil
+	}
+	return containsNode{text: text, substr: strings.ToLower(arg.text)}, nil
+}
+
+func requireBool(op string, nodes ...exprNode) error {
+	for _, node := range nodes {
+		if node.typ() != typeBool {
+			return fmt.Errorf("operator %s needs booleans, got %s", op, node.typ())
+		}
+	}
+	return nil
+}
+
+type literalNode struct {
+	t exprType
+	v exprValue
+}
+
+func (n literalNode) typ() exprType                  { return n.t }
+func (n literalNode) eval(f *routingFacts) exprValue { return n.v }
+
+type fieldNode struct {
+	t   exprType
+	get func(f *routingFacts) exprValue
+}
+
+func (n fieldNode) typ() exprType                  { return n.t }
+func (n fieldNode) eval(f *routingFacts) exprValue { return n.get(f) }
+
+type notNode struct{ operand exprNode }
+
+func (n notNode) typ() exprType { return typeBool }
+func (n notNode) eval(f *routingFacts) exprValue {
+	return exprValue{b: !n.operand.eval(f).b}
+}
+
+type logicalNode struct {
+	or          bool
+	left, right exprNode
+}
+
+func (n logicalNode) typ() exprType { return typeBool }
+func (n logicalNode) eval(f *routingFacts) exprValue {
+	left := n.left.eval(f).b
+	if n.or {
+		return exprValue{b: left || n.right.eval(f).b}
+	}
+	return exprValue{b: left && n.right.eval(f).b}
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n compareNode) typ() exprType { return typeBool }
+func (n compareNode) eval(f *routingFacts) exprValue {
+	left, right := n.left.eval(f), n.right.eval(f)
+	var equal bool
+	switch n.left.typ() {
+	case typeBool:
+		equal = left.b == right.b
+	case typeString:
+		equal = strings.EqualFold(left.s, right.s)
+	default:
+		equal = left.n == right.n
+	}
+
+	switch n.op {
+	case "==":
+		return exprValue{b: equal}
+	case "!=":
+		return exprValue{b: !equal}
+	case "<":
+		return exprValue{b: left.n < right.n}
+	case "<=":
+		return exprValue{b: left.n <= right.n}
+	case ">":
+		return exprValue{b: left.n > right.n}
+	default:
+		return exprValue{b: left.n >= right.n}
+	}
+}
+
+type inNode struct{ value, list exprNode }
+
+func (n inNode) typ() exprType { return typeBool }
+func (n inNode) eval(f *routingFacts) exprValue {
+	value := n.value.eval(f).s
+	for _, item := range n.list.eval(f).list {
+		if strings.EqualFold(value, item) {
+			return exprValue{b: true}
+		}
+	}
+	return exprValue{b: false}
+}
+
+type containsNode struct {
+	text   exprNode
+	substr string
+}
+
+func (n containsNode) typ() exprType { return typeBool }
+func (n containsNode) eval(f *routingFacts) exprValue {
+	return exprValue{b: strings.Contains(strings.ToLower(n.text.eval(f).s), n.substr)}
+}
+
+type matchNode struct {
+	text exprNode
+	re   *regexp.Regexp
+}
+
+func (n matchNode) typ() exprType { return typeBool }
+func (n matchNode) eval(f *routingFacts) exprValue {
+	return exprValue{b: n.re.MatchString(n.text.eval(f).s)}
+}
@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileConditionEvaluates(t *testing.T) {
+	failed := &routingFacts{
+		Message:       "Build failed: Non-Testable change",
+		ErrorCategory: "syntax_error",
+		ErrorSeverity: 3,
+		BuildOutput:   "calc.go:12:2: undefined: Subtract",
+	}
+	succeeded := &routingFacts{Success: true, FilesModified: 2, TestsAdded: true}
+
+	tests := []struct {
+		condition string
+		facts     *routingFacts
+		want      bool
+	}{
+		{"", failed, true},
+		{"success", succeeded, true},
+		{"!success", succeeded, false},
+		{"success && files_modified > 0", succeeded, true},
+		{"files_modified >= 2 && files_modified <= 2 && files_modified != 3", succeeded, true},
+		{"files_modified < 1.5", succeeded, false},
+
+		// && binds tighter than ||, and ! tighter than both
+		{"success || tests_added && false", succeeded, true},
+		{"(success || tests_added) && false", succeeded, false},
+		{"!success && tests_added", succeeded, false},
+		{"!(success && false)", succeeded, true},
+		{"!!success", succeeded, true},
+
+		{`!success && error.category in ["syntax_error", "type_error"] && error.severity >= 3`, failed, true},
+		{`error.category in ["type_error"]`, failed, false},
+		{`error.category in []`, failed, false},
+		{`error.category in ["SYNTAX_ERROR",]`, failed, true},
+		{`error.category == "Syntax_Error"`, failed, true},
+		{`error.category != 'syntax_error'`, failed, false},
+		{`message == "say \"hi\""`, &routingFacts{Message: `say "hi"`}, true},
+		{`success == false`, failed, true},
+
+		{`contains(message, "non-testable")`, failed, true},
+		{`contains(next_steps, "anything")`, failed, false},
+		{`matches(build_output, "undefined: \\w+")`, failed, true},
+		{`matches(build_output, "^undefined")`, failed, false},
+		{`contains(message, "build") || structured_rejection`, &routingFacts{StructuredRejection: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			node, err := compileCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("compileCondition: %v", err)
+			}
+			if got := node.eval(tt.facts).b; got != tt.want {
+				t.Errorf("eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileConditionErrors(t *testing.T) {
+	tests := []struct {
+		condition string
+		err       string
+	}{
+		// Unknown fields and functions
+		{"succeeded", `unknown field "succeeded"`},
+		{"error.kind == \"x\"", `unknown field "error.kind"`},
+		{"length(message) > 3", `unknown field "length"`},
+
+		// Type errors
+		{"files_modified", "condition must be boolean, got number"},
+		{`message`, "condition must be boolean, got string"},
+		{`success && files_modified`, "operator && needs booleans, got number"},
+		{`message || success`, "operator || needs booleans, got string"},
+		{`!message`, "operator ! needs booleans, got string"},
+		{`files_modified == "2"`, "cannot compare number with string using =="},
+		{`message > "a"`, "operator > needs numbers, got string"},
+		{`success < true`, "operator < needs numbers, got bool"},
+		{`files_modified in ["2"]`, "'in' needs a string on the left and a list on the right"},
+		{`message in "abc"`, "'in' needs a string on the left and a list on the right"},
+		{`contains(files_modified, "x")`, "contains needs a string as its first argument, got number"},
+		{`contains(message, next_steps)`, "contains needs a string literal as its second argument"},
+
+		// Malformed input
+		{`message == "open`, "unterminated string starting at 11"},
+		{`success & tests_added`, "unexpected character '&' at 8"},
+		{`success ||`, "unexpected end of condition"},
+		{`(success`, `expected ")" at end of condition`},
+		{`success)`, `unexpected ")" at end of condition`},
+		{`success tests_added`, `unexpected "tests_added" at end of condition`},
+		{`error.category in ["a" "b"]`, `expected "]", got "b"`},
+		{`error.category in [1]`, "lists may only contain strings"},
+		{`error.category in ["a"`, `expected "]" at end of condition`},
+		{`error.category in [`, "lists may only contain strings"},
+		{`files_modified > 1.2.3`, `invalid number "1.2.3"`},
+		{`contains message`, `expected "(", got "message"`},
+		{`contains(message "x")`, `expected ",", got "x"`},
+		{`matches(message, "(")`, "matches: invalid regex"},
+		{`==`, `unexpected "=="`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			_, err := compileCondition(tt.condition)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("compileCondition error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mcp-server/internal/tools"
+)
+
+// snapshot is a private copy of the working tree that one parallel member works in
+type snapshot struct {
+	role AgentRole
+	dir  string
+}
+
+// fileChange is what a member did to one file: new content, or a deletion
+type fileChange struct {
+	role    AgentRole
+	content []byte
+	mode    fs.FileMode
+	deleted bool
+}
+
+func (c fileChange) sameAs(other fileChange) bool {
+	if c.deleted || other.deleted {
+		return c.deleted == other.deleted
+	}
+	return bytes.Equal(c.content, other.content)
+}
+
+// newSnapshot copies workingDir, including its git metadata, into a temporary
+// directory for role
+func newSnapshot(role AgentRole, workingDir string) (*snapshot, error) {
+	dir, err := os.MkdirTemp("", "parallel-"+string(role)+"-")
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot for %s: %w", role, err)
+	}
+	if err := tools.CopyTree(workingDir, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("copying working tree for %s: %w", role, err)
+	}
+	return &snapshot{role: role, dir: dir}, nil
+}
+
+func (s *snapshot) remove() {
+	os.RemoveAll(s.dir)
+}
+
+// changes compares the snapshot with workingDir, which nothing else writes to
+// while members run, and returns every file the member wrote or deleted keyed by
+// its relative path. Git metadata is not project content and is left out.
+func (s *snapshot) changes(workingDir string) (map[string]fileChange, error) {
+	changes := make(map[string]fileChange)
+
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		original, err := os.ReadFile(filepath.Join(workingDir, rel))
+		if err == nil && bytes.Equal(original, content) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		changes[rel] = fileChange{role: s.role, content: content, mode: info.Mode().Perm()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(workingDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(workingDir, path)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(filepath.Join(s.dir, rel)); os.IsNotExist(err) {
+			changes[rel] = fileChange{role: s.role, deleted: true}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// mergeSnapshots applies what every member changed in its snapshot to
+// workingDir. Members that make the same change agree; a file changed
+// differently by two members is a conflict and nothing is applied.
+func mergeSnapshots(workingDir string, snapshots []*snapshot) error {
+	merged := make(map[string]fileChange)
+	var conflicts []string
+	for _, s := range snapshots {
+		changes, err := s.changes(workingDir)
+		if err != nil {
+			return fmt.Errorf("collecting changes of %s: %w", s.role, err)
+		}
+		for rel, change := range changes {
+			existing, seen := merged[rel]
+			if !seen {
+				merged[rel] = change
+				continue
+			}
+			if !existing.sameAs(change) {
+				conflicts = append(conflicts, fmt.Sprintf("%s (%s, %s)", rel, existing.role, change.role))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("parallel members changed the same files differently: %s", strings.Join(conflicts, ", "))
+	}
+
+	for rel, change := range merged {
+		target := filepath.Join(workingDir, rel)
+		if change.deleted {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("deleting %s: %w", rel, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("writing %s: %w", rel, err)
+		}
+		if err := os.WriteFile(target, change.content, change.mode); err != nil {
+			return fmt.Errorf("writing %s: %w", rel, err)
+		}
+	}
+	return nil
+}
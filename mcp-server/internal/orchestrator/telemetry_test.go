@@ -0,0 +1,166 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"mcp-server/internal/config"
+	"mcp-server/internal/telemetry"
+)
+
+// exportedSpan is the part of a span the stdout and file exporters write that the test reads
+type exportedSpan struct {
+	Name   string
+	Parent struct {
+		SpanID string
+	}
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Attributes []struct {
+		Key   string
+		Value struct {
+			Value interface{}
+		}
+	}
+}
+
+func (s exportedSpan) attribute(key string) string {
+	for _, attr := range s.Attributes {
+		if attr.Key == key {
+			return fmt.Sprint(attr.Value.Value)
+		}
+	}
+	return ""
+}
+
+func TestWorkflowTelemetryFileExport(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spans.jsonl")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_TRACES_EXPORTER", "file")
+	t.Setenv("OTEL_TRACES_FILE", file)
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	shutdown, err := telemetry.Setup(context.Background(), config.GetTelemetryConfig(), io.Discard)
+	if err != nil {
+		t.Fatalf("telemetry.Setup: %v", err)
+	}
+
+	_, result := runScenario(t, e2eScenario{
+		name:        "add_function",
+		fixture:     "calculator",
+		description: "Add a Subtract function to the calculator package that returns a minus b",
+	})
+	if !result.Success {
+		t.Fatalf("workflow failed: %s (%s)", result.Error, result.FailureReason)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("flushing spans: %v", err)
+	}
+
+	data, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	var spans []exportedSpan
+	decoder := json.NewDecoder(data)
+	for {
+		var span exportedSpan
+		if err := decoder.Decode(&span); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("decoding span %d: %v", len(spans)+1, err)
+		}
+		spans = append(spans, span)
+	}
+
+	byID := make(map[string]exportedSpan, len(spans))
+	var workflow *exportedSpan
+	for i, span := range spans {
+		byID[span.SpanContext.SpanID] = span
+		if span.Name == "workflow" {
+			workflow = &spans[i]
+		}
+	}
+	if workflow == nil {
+		t.Fatalf("no workflow span among %d exported spans", len(spans))
+	}
+	if got := workflow.attribute(telemetry.AttrFlow); got != DefaultFlowName {
+		t.Errorf("workflow %s = %q, want %q", telemetry.AttrFlow, got, DefaultFlowName)
+	}
+
+	// Every span belongs to the run, and the model and tool calls nest under an agent
+	agentOf := func(span exportedSpan) string {
+		for span.Parent.SpanID != "" {
+			parent, ok := byID[span.Parent.SpanID]
+			if !ok {
+				return ""
+			}
+			if strings.HasPrefix(parent.Name, "agent ") {
+				return parent.attribute(telemetry.AttrRole)
+			}
+			span = parent
+		}
+		return ""
+	}
+	agents := make(map[string]bool)
+	models := make(map[string]bool)
+	var llmCalls, toolCalls int
+	for _, span := range spans {
+		if span.SpanContext.TraceID != workflow.SpanContext.TraceID {
+			t.Errorf("span %q is not part of the workflow trace", span.Name)
+		}
+		switch {
+		case strings.HasPrefix(span.Name, "agent "):
+			agents[span.attribute(telemetry.AttrRole)] = true
+		case span.Name == "llm generate":
+			llmCalls++
+			models[span.attribute(telemetry.AttrModel)] = true
+			role := span.attribute(telemetry.AttrRole)
+			// The EM documents the finished run directly under the workflow
+			documenting := role == string(AgentRoleEM) && byID[span.Parent.SpanID].Name == "workflow"
+			if owner := agentOf(span); owner != role && !documenting {
+				t.Errorf("llm span of %s nests under agent %q", role, owner)
+			}
+			for _, key := range []string{telemetry.AttrPromptTokens, telemetry.AttrCompletionTokens} {
+				if tokens := span.attribute(key); tokens == "" || tokens == "0" {
+					t.Errorf("llm span of %s has %s = %q", role, key, tokens)
+				}
+			}
+		case strings.HasPrefix(span.Name, "tool "):
+			toolCalls++
+			if span.attribute(telemetry.AttrTool) == "" || agentOf(span) != span.attribute(telemetry.AttrRole) {
+				t.Errorf("tool span %q has tool %q and role %q under agent %q", span.Name,
+					span.attribute(telemetry.AttrTool), span.attribute(telemetry.AttrRole), agentOf(span))
+			}
+		}
+	}
+
+	for _, role := range []AgentRole{AgentRoleEM, AgentRoleEngineer, AgentRoleQA, AgentRoleTechLead} {
+		if !agents[string(role)] {
+			t.Errorf("no agent span for %s", role)
+		}
+	}
+	if llmCalls != len(result.LLMCalls) {
+		t.Errorf("exported %d llm spans, the workflow made %d calls", llmCalls, len(result.LLMCalls))
+	}
+	if models[""] || len(models) == 0 {
+		t.Errorf("llm span models = %v", models)
+	}
+	if toolCalls == 0 {
+		t.Error("no tool spans exported")
+	}
+}
@@ -0,0 +1,264 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/internal/debug"
+	"mcp-server/internal/telemetry"
+	"mcp-server/internal/tools"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxTracedOutput caps how much of a tool's output a trace event keeps
+const maxTracedOutput = 4000
+
+// spanTrace is a part of a run traced both to the debug log and to OpenTelemetry
+type spanTrace struct {
+	debug *debug.Span
+	otel  trace.Span
+}
+
+// startRunTrace opens the root span of a workflow run
+func (wo *WorkflowOrchestrator) startRunTrace(ctx context.Context, flow *Flow, req WorkflowRequest) (context.Context, *spanTrace) {
+	ctx, otelSpan := telemetry.Start(ctx, "workflow",
+		attribute.String(telemetry.AttrFlow, flow.Name),
+		attribute.String(telemetry.AttrWorkingDir, req.WorkingDirectory),
+		attribute.String(telemetry.AttrProjectType, string(req.ProjectType)),
+	)
+	ctx, debugSpan := wo.debugLogger.StartRun(ctx, req.Description, map[string]string{
+		"flow":              flow.Name,
+		"working_directory": req.WorkingDirectory,
+	})
+	return ctx, &spanTrace{debug: debugSpan, otel: otelSpan}
+}
+
+// startTrace opens a span of kind under the one ctx carries
+func startTrace(ctx context.Context, kind, name, role string, attributes ...attribute.KeyValue) (context.Context, *spanTrace) {
+	if role != "" {
+		attributes = append(attributes, attribute.String(telemetry.AttrRole, role))
+	}
+	ctx, otelSpan := telemetry.Start(ctx, kind+" "+name, attributes...)
+	ctx, debugSpan := debug.StartSpan(ctx, kind, name, role)
+	return ctx, &spanTrace{debug: debugSpan, otel: otelSpan}
+}
+
+// end closes the span with status, failing it when err is set
+func (st *spanTrace) end(err error, status string) {
+	var attributes map[string]string
+	if status != "" {
+		attributes = map[string]string{"status": status}
+		st.otel.SetAttributes(attribute.String(telemetry.AttrStatus, status))
+	}
+	st.debug.End(err, attributes)
+	telemetry.End(st.otel, err)
+}
+
+// agentContexts holds the context of each agent invocation in progress, so tool
+// calls made by that agent are traced under it
+type agentContexts struct {
+	mu       sync.Mutex
+	contexts map[AgentRole]context.Context
+}
+
+func newAgentContexts() *agentContexts {
+	return &agentContexts{contexts: make(map[AgentRole]context.Context)}
+}
+
+func (ac *agentContexts) set(role AgentRole, ctx context.Context) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if ctx == nil {
+		delete(ac.contexts, role)
+		return
+	}
+	ac.contexts[role] = ctx
+}
+
+func (ac *agentContexts) get(role AgentRole) context.Context {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return ac.contexts[role]
+}
+
+// tracedToolSet records every tool call one agent makes in the run's trace
+type tracedToolSet struct {
+	AgentTools
+	role     AgentRole
+	contexts *agentContexts
+}
+
+// toolCall is a traced tool call in progress
+type toolCall struct {
+	ctx   context.Context
+	span  trace.Span
+	role  AgentRole
+	tool  string
+	start time.Time
+}
+
+// begin opens a span for a call to tool under the agent's current invocation
+func (t *tracedToolSet) begin(tool string, attributes ...attribute.KeyValue) *toolCall {
+	ctx := t.contexts.get(t.role)
+	if ctx == nil {
+		return nil
+	}
+	attributes = append(attributes, attribute.String(telemetry.AttrTool, tool), attribute.String(telemetry.AttrRole, string(t.role)))
+	ctx, span := telemetry.Start(ctx, "tool "+tool, attributes...)
+	return &toolCall{ctx: ctx, span: span, role: t.role, tool: tool, start: time.Now()}
+}
+
+// finish closes the call's span and logs it to the debug trace
+func (tc *toolCall) finish(command, path, output string, err error) {
+	if tc == nil {
+		return
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		tc.span.SetAttributes(attribute.Int(telemetry.AttrExitCode, exitErr.ExitCode()))
+	} else if command != "" && err == nil {
+		tc.span.SetAttributes(attribute.Int(telemetry.AttrExitCode, 0))
+	}
+	telemetry.End(tc.span, err)
+
+	if len(output) > maxTracedOutput {
+		output = output[:maxTracedOutput] + fmt.Sprintf("\n... [%d bytes not traced]", len(output)-maxTracedOutput)
+	}
+	action := debug.AgentAction{
+		Timestamp:  tc.start,
+		Agent:      string(tc.role),
+		ActionType: tc.tool,
+		Command:    command,
+		FilePath:   path,
+		Result:     output,
+		Success:    err == nil,
+	}
+	if err != nil {
+		action.Error = err.Error()
+	}
+	debug.SpanFromContext(tc.ctx).LogToolCall(action, time.Since(tc.start))
+}
+
+func (t *tracedToolSet) ReadFile(path string) (string, error) {
+	call := t.begin("read_file", attribute.String(telemetry.AttrFilePath, path))
+	content, err := t.AgentTools.ReadFile(path)
+	call.finish("", path, fmt.Sprintf("%d bytes", len(content)), err)
+	return content, err
+}
+
+func (t *tracedToolSet) WriteFile(path, content string) error {
+	call := t.begin("write_file", attribute.String(telemetry.AttrFilePath, path))
+	err := t.AgentTools.WriteFile(path, content)
+	call.finish("", path, fmt.Sprintf("%d bytes", len(content)), err)
+	return err
+}
+
+func (t *tracedToolSet) ExecuteCommand(command string) (string, error) {
+	call := t.begin("execute_command", attribute.String(telemetry.AttrCommand, command))
+	output, err := t.AgentTools.ExecuteCommand(command)
+	call.finish(command, "", output, err)
+	return output, err
+}
+
+func (t *tracedToolSet) ExecuteCommandIn(dir, command string) (string, error) {
+	call := t.begin("execute_command", attribute.String(telemetry.AttrCommand, command), attribute.String(telemetry.AttrFilePath, dir))
+	output, err := t.AgentTools.ExecuteCommandIn(dir, command)
+	call.finish(command, dir, output, err)
+	return output, err
+}
+
+func (t *tracedToolSet) GetGitStatus() (string, error) {
+	call := t.begin("git_status")
+	output, err := t.AgentTools.GetGitStatus()
+	call.finish("", "", output, err)
+	return output, err
+}
+
+func (t *tracedToolSet) GetGitDiff() (string, error) {
+	call := t.begin("git_diff")
+	output, err := t.AgentTools.GetGitDiff()
+	call.finish("", "", output, err)
+	return output, err
+}
+
+func (t *tracedToolSet) GetGitUntrackedFiles() ([]string, error) {
+	call := t.begin("git_untracked")
+	files, err := t.AgentTools.GetGitUntrackedFiles()
+	call.finish("", "", strings.Join(files, "\n"), err)
+	return files, err
+}
+
+func (t *tracedToolSet) GetGitLog(limit int) (string, error) {
+	call := t.begin("git_log")
+	output, err := t.AgentTools.GetGitLog(limit)
+	call.finish("", "", output, err)
+	return output, err
+}
+
+func (t *tracedToolSet) ListFiles(path string) ([]string, error) {
+	call := t.begin("list_files", attribute.String(telemetry.AttrFilePath, path))
+	files, err := t.AgentTools.ListFiles(path)
+	call.finish("", path, strings.Join(files, "\n"), err)
+	return files, err
+}
+
+func (t *tracedToolSet) FindFiles(pattern string, searchPath string) ([]string, error) {
+	call := t.begin("find_files", attribute.String(telemetry.AttrFilePath, searchPath))
+	files, err := t.AgentTools.FindFiles(pattern, searchPath)
+	call.finish(pattern, searchPath, strings.Join(files, "\n"), err)
+	return files, err
+}
+
+func (t *tracedToolSet) SearchForSolution(query string) (*tools.SearchResponse, error) {
+	call := t.begin("search_solution")
+	response, err := t.AgentTools.SearchForSolution(query)
+	call.finish(query, "", "", err)
+	return response, err
+}
+
+func (t *tracedToolSet) SearchForError(errorMessage string) (*tools.SearchResponse, error) {
+	call := t.begin("search_error")
+	response, err := t.AgentTools.SearchForError(errorMessage)
+	call.finish(errorMessage, "", "", err)
+	return response, err
+}
+
+func (t *tracedToolSet) SearchCode(pattern, path string, contextLines int) ([]tools.CodeMatch, error) {
+	call := t.begin("search_code", attribute.String(telemetry.AttrFilePath, path))
+	matches, err := t.AgentTools.SearchCode(pattern, path, contextLines)
+	call.finish(pattern, path, fmt.Sprintf("%d matches", len(matches)), err)
+	return matches, err
+}
+
+func (t *tracedToolSet) FindSymbol(name string) (*tools.SymbolLookup, error) {
+	call := t.begin("find_symbol")
+	lookup, err := t.AgentTools.FindSymbol(name)
+	output := ""
+	if lookup != nil {
+		output = fmt.Sprintf("%d definitions, %d references", len(lookup.Definitions), len(lookup.References))
+	}
+	call.finish(name, "", output, err)
+	return lookup, err
+}
+
+func (t *tracedToolSet) SearchSymbols(query string, limit int) ([]tools.Symbol, error) {
+	call := t.begin("search_symbols")
+	symbols, err := t.AgentTools.SearchSymbols(query, limit)
+	call.finish(query, "", fmt.Sprintf("%d symbols", len(symbols)), err)
+	return symbols, err
+}
+
+// tracedTools wraps toolSet for role when the run is traced
+func tracedTools(toolSet AgentTools, role AgentRole, contexts *agentContexts) AgentTools {
+	if contexts == nil {
+		return toolSet
+	}
+	return &tracedToolSet{AgentTools: toolSet, role: role, contexts: contexts}
+}
@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -9,6 +10,12 @@ import (
 
 	"mcp-server/internal/agent"
 	"mcp-server/internal/config"
+	"mcp-server/internal/debug"
+	"mcp-server/internal/metrics"
+	"mcp-server/internal/prompt"
+	"mcp-server/internal/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Use agent types directly
@@ -26,16 +33,24 @@ type WorkflowOrchestrator struct {
 	llmClient     agent.LLMClient
 	toolSet       agent.ToolSet
 	config        *config.WorkflowConfig
-	routingEngine *RoutingEngine
+	flows         map[string]*Flow
+	agentBuilder  AgentBuilder
+	debugLogger   *debug.DebugLogger
 }
 
 type WorkflowState struct {
-	CurrentAgent    AgentRole
-	IterationCounts map[AgentRole]int
-	TaskDescription string
-	ProjectContext  *ProjectContext
-	WorkflowHistory []agent.AgentTransition
-	StartTime       time.Time
+	Flow              *Flow
+	ToolSet           agent.ToolSet  // this run's tools, see newWorkspace
+	Agents            map[AgentRole]agent.Agent
+	MemberAgents      map[AgentRole]agent.Agent // isolated agents for parallel node members, nil without an agent builder
+	CurrentAgent      AgentRole
+	IterationCounts   map[AgentRole]int
+	TaskDescription   string
+	ProjectContext    *ProjectContext
+	WorkflowHistory   []agent.AgentTransition
+	StartTime         time.Time
+	AgentContexts     *agentContexts // contexts of the agents running in a traced run, nil otherwise
+	ConnectionRetries int            // agent re-runs after connection errors, see maxConnectionRetries
 }
 
 
@@ -54,14 +69,24 @@ type WorkflowResult = agent.WorkflowResult
 type AgentSummary = agent.AgentSummary
 type AgentTransition = agent.AgentTransition
 
-func NewWorkflowOrchestrator(llmClient agent.LLMClient, toolSet agent.ToolSet, config *config.WorkflowConfig) *WorkflowOrchestrator {
+func NewWorkflowOrchestrator(llmClient agent.LLMClient, toolSet agent.ToolSet, config *config.WorkflowConfig) (*WorkflowOrchestrator, error) {
+	flows, err := compileFlows(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routing configuration: %w", err)
+	}
+
 	return &WorkflowOrchestrator{
 		agents:        make(map[AgentRole]agent.Agent),
 		llmClient:     llmClient,
 		toolSet:       toolSet,
 		config:        config,
-		routingEngine: NewRoutingEngine(),
-	}
+		flows:         flows,
+	}, nil
+}
+
+// SetDebugLogger traces every workflow run to logger
+func (wo *WorkflowOrchestrator) SetDebugLogger(logger *debug.DebugLogger) {
+	wo.debugLogger = logger
 }
 
 func (wo *WorkflowOrchestrator) RegisterAgent(role agent.AgentRole, agentInstance agent.Agent) {
@@ -69,23 +94,81 @@ func (wo *WorkflowOrchestrator) RegisterAgent(role agent.AgentRole, agentInstanc
 }
 
 func (wo *WorkflowOrchestrator) ExecuteWorkflow(ctx context.Context, req agent.WorkflowRequest) (*agent.WorkflowResult, error) {
+	flow, err := wo.selectFlow(req.Flow)
+	if err != nil {
+		metrics.WorkflowStarted("unknown")
+		metrics.WorkflowFinished("unknown", false, "unknown_flow")
+		return &WorkflowResult{
+			Success:       false,
+			Error:         err.Error(),
+			FailureReason: "unknown_flow",
+		}, nil
+	}
+
+	// Every agent call made during this run records its token usage here
+	usage := agent.NewUsageRecorder()
+	ctx = agent.WithUsageRecorder(ctx, usage)
+
+	// Trace the run; agents' LLM and tool calls nest under it
+	ctx, run := wo.startRunTrace(ctx, flow, req)
+	metrics.WorkflowStarted(flow.Name)
+
 	// Initialize workflow state
 	state := &WorkflowState{
-		CurrentAgent:    AgentRoleEM, // Always start with Engineering Manager
+		Flow:            flow,
+		CurrentAgent:    flow.Start,
 		IterationCounts: make(map[AgentRole]int),
 		TaskDescription: req.Description,
 		WorkflowHistory: []agent.AgentTransition{},
 		StartTime:       time.Now(),
 	}
 
-	// Set working directory
-	if req.WorkingDirectory != "" {
-		wo.toolSet.SetWorkingDirectory(req.WorkingDirectory)
+	// Build this run's tools and agents, rooted at the working directory
+	ws, err := wo.newWorkspace(flow, req.WorkingDirectory)
+	if err != nil {
+		run.end(err, "failed")
+		metrics.WorkflowFinished(flow.Name, false, "agent_unavailable")
+		return &WorkflowResult{
+			Success:       false,
+			Error:         err.Error(),
+			FailureReason: "agent_unavailable",
+		}, nil
+	}
+	state.ToolSet = ws.toolSet
+	state.Agents = ws.agents
+	state.MemberAgents = ws.members
+	state.AgentContexts = ws.agentContexts
+
+	// Load the project's own prompt templates for this run, failing it up front on a broken one
+	if err := agent.LoadProjectPrompts(state.ToolSet.GetWorkingDirectory()); err != nil {
+		run.end(err, "failed")
+		metrics.WorkflowFinished(flow.Name, false, "invalid_prompt_template")
+		return &WorkflowResult{
+			Success:       false,
+			Error:         fmt.Sprintf("Invalid project prompt template: %v", err),
+			FailureReason: "invalid_prompt_template",
+		}, nil
+	}
+
+	// Infer the project type from the working directory when the caller left it out
+	detection := agent.DetectProject(state.ToolSet, req.ProjectType)
+	if req.ProjectType == "" {
+		req.ProjectType = detection.ProjectType
+		log.Printf("Detected project type %q (mixed: %v) from %d stack(s)", req.ProjectType, detection.Mixed, len(detection.Stacks))
+	}
+
+	// Index the code so agents can search it instead of guessing paths
+	if stats, err := state.ToolSet.IndexCode(); err != nil {
+		log.Printf("Code index unavailable: %v", err)
+	} else {
+		log.Printf("Indexed %d files with %d symbols", stats.Files, stats.Symbols)
 	}
 
 	// Gather project context
-	projectContext, err := wo.gatherProjectContext(req)
+	projectContext, err := wo.gatherProjectContext(state.ToolSet, req)
 	if err != nil {
+		run.end(err, "failed")
+		metrics.WorkflowFinished(flow.Name, false, "context_gathering_failed")
 		return &WorkflowResult{
 			Success:       false,
 			Error:         fmt.Sprintf("Failed to gather project context: %v", err),
@@ -94,9 +177,27 @@ func (wo *WorkflowOrchestrator) ExecuteWorkflow(ctx context.Context, req agent.W
 	}
 	state.ProjectContext = projectContext
 
+	// Let agents capture pre-existing state, such as lint findings, before anything changes
+	for _, agents := range []map[AgentRole]agent.Agent{state.Agents, state.MemberAgents} {
+		for role, agentInstance := range agents {
+			if !flow.involves(role) {
+				continue
+			}
+			if recorder, ok := agentInstance.(agent.BaselineRecorder); ok {
+				if err := recorder.RecordBaseline(ctx, req.WorkingDirectory); err != nil {
+					log.Printf("Agent %s failed to record baseline: %v", role, err)
+				}
+			}
+		}
+	}
+
 	// Initialize result
 	result := &agent.WorkflowResult{
 		Success:         true,
+		Flow:            flow.Name,
+		TraceRun:        run.debug.RunID(),
+		ProjectType:     req.ProjectType,
+		ProjectDetection: detection,
 		CompletedPhases: []string{},
 		FilesModified:   []string{},
 		TestsAdded:      []string{},
@@ -124,13 +225,19 @@ func (wo *WorkflowOrchestrator) ExecuteWorkflow(ctx context.Context, req agent.W
 			break
 		}
 
-		// Execute current agent with error recovery
-		agentResult, err := wo.executeCurrentAgent(ctx, state, req)
+		// Execute current agent (or parallel node) with error recovery
+		agentResult, err := wo.executeCurrentNode(ctx, state, req, result)
 		if err != nil {
 			// Try to handle recoverable errors
 			recoveryAction := wo.analyzeAndRecoverFromError(err, state)
+			if recoveryAction.CanRecover && !flow.hasRole(recoveryAction.NextAgent) {
+				// Flows without the EM restart from their own entry point
+				recoveryAction.NextAgent = flow.Start
+			}
+			wo.debugLogger.LogRecoveryAttempt(ctx, string(state.CurrentAgent), wo.categorizeFailure(err), recoveryAction.Reason, recoveryAction.CanRecover)
 			if recoveryAction.CanRecover {
 				// Log the error and continue with recovery
+				metrics.RoutingTransition(string(state.CurrentAgent), string(recoveryAction.NextAgent), recoveryAction.Reason)
 				state.WorkflowHistory = append(state.WorkflowHistory, AgentTransition{
 					FromAgent: state.CurrentAgent,
 					ToAgent:   recoveryAction.NextAgent,
@@ -148,9 +255,6 @@ func (wo *WorkflowOrchestrator) ExecuteWorkflow(ctx context.Context, req agent.W
 			break
 		}
 
-		// Update result with agent output
-		wo.updateResultWithAgent(result, state.CurrentAgent, agentResult)
-
 		// Validate workflow health
 		if err := wo.validateWorkflowHealth(state); err != nil {
 			result.Success = false
@@ -159,9 +263,18 @@ func (wo *WorkflowOrchestrator) ExecuteWorkflow(ctx context.Context, req agent.W
 			break
 		}
 		
-		// Check if workflow is complete
-		if wo.isWorkflowComplete(state, agentResult) {
-			result.CompletedPhases = append(result.CompletedPhases, "workflow_complete")
+		// Check if the flow reached one of its terminal conditions
+		if terminal := wo.terminalFor(state, agentResult); terminal != nil {
+			if terminal.Success {
+				result.CompletedPhases = append(result.CompletedPhases, "workflow_complete")
+			} else {
+				result.Success = false
+				result.Error = terminal.Reason
+				if agentResult.Error != "" {
+					result.Error += ": " + agentResult.Error
+				}
+				result.FailureReason = "flow_terminated"
+			}
 			break
 		}
 
@@ -182,9 +295,13 @@ func (wo *WorkflowOrchestrator) ExecuteWorkflow(ctx context.Context, req agent.W
 			Timestamp: time.Now(),
 		}
 		state.WorkflowHistory = append(state.WorkflowHistory, transition)
+		wo.debugLogger.LogWorkflowTransition(ctx, string(transition.FromAgent), string(transition.ToAgent), reason)
+		metrics.RoutingTransition(string(transition.FromAgent), string(transition.ToAgent), reason)
 		
 		// Update state
-		state.IterationCounts[state.CurrentAgent]++
+		for _, role := range flow.members(state.CurrentAgent) {
+			state.IterationCounts[role]++
+		}
 		state.CurrentAgent = nextAgent
 		// Pass the output of the previous agent as the task for the next one.
 		if agentResult.NextSteps != "" {
@@ -195,28 +312,43 @@ func (wo *WorkflowOrchestrator) ExecuteWorkflow(ctx context.Context, req agent.W
 	// Finalize result with diagnostics
 	result.WorkflowHistory = state.WorkflowHistory
 	wo.enhanceResultWithDiagnostics(result, state)
+	wo.exportFindings(state.ToolSet, result, req)
 
 	// If workflow was successful, call EM to document the task
 	if result.Success {
-		if em, ok := wo.agents[AgentRoleEM]; ok {
+		if em, ok := state.Agents[AgentRoleEM]; ok {
 			if err := em.DocumentTask(ctx, result); err != nil {
 				// Log the documentation failure, but don't fail the whole workflow
 				log.Printf("EM failed to document task: %v", err)
 			}
 		}
 	}
+
+	result.TokenUsage = usage.Totals()
+	result.LLMCalls = usage.Calls()
+
+	var runErr error
+	if !result.Success {
+		runErr = fmt.Errorf("%s: %s", result.FailureReason, result.Error)
+	}
+	run.end(runErr, runStatus(result))
+
+	metrics.WorkflowFinished(flow.Name, result.Success, result.FailureReason)
+	for role, count := range state.IterationCounts {
+		metrics.AgentIterations(string(role), count)
+	}
 	
 	return result, nil
 }
 
-func (wo *WorkflowOrchestrator) gatherProjectContext(req WorkflowRequest) (*ProjectContext, error) {
+func (wo *WorkflowOrchestrator) gatherProjectContext(toolSet agent.ToolSet, req WorkflowRequest) (*ProjectContext, error) {
 	ctx := &ProjectContext{
 		WorkingDir:  req.WorkingDirectory,
 		ProjectType: req.ProjectType,
 	}
 
 	// Get git status
-	gitStatus, err := wo.toolSet.GetGitStatus()
+	gitStatus, err := toolSet.GetGitStatus()
 	if err == nil {
 		ctx.GitStatus = gitStatus
 	} else {
@@ -224,19 +356,19 @@ func (wo *WorkflowOrchestrator) gatherProjectContext(req WorkflowRequest) (*Proj
 	}
 
 	// Get git log
-	gitLog, err := wo.toolSet.GetGitDiff() // Using existing GetGitDiff, will extend later
+	gitLog, err := toolSet.GetGitDiff() // Using existing GetGitDiff, will extend later
 	if err == nil {
 		ctx.GitLog = gitLog
 	}
 
 	// Try to read CLAUDE.md
-	claudeMd, err := wo.toolSet.ReadFile("CLAUDE.md")
+	claudeMd, err := toolSet.ReadFile("CLAUDE.md")
 	if err == nil {
 		ctx.ClaudeMd = claudeMd
 	}
 
 	// Try to read AGENTS.md  
-	agentsMd, err := wo.toolSet.ReadFile("AGENTS.md")
+	agentsMd, err := toolSet.ReadFile("AGENTS.md")
 	if err == nil {
 		ctx.AgentsMd = agentsMd
 	}
@@ -255,10 +387,12 @@ func (wo *WorkflowOrchestrator) checkIterationLimits(state *WorkflowState) error
 		return fmt.Errorf("maximum total iterations (%d) exceeded", wo.config.Workflow.MaxTotalIterations)
 	}
 
-	// Check current agent iteration limit
-	maxForAgent := wo.getMaxIterationsForAgent(state.CurrentAgent)
-	if state.IterationCounts[state.CurrentAgent] >= maxForAgent {
-		return fmt.Errorf("maximum iterations for agent %s (%d) exceeded", state.CurrentAgent, maxForAgent)
+	// Check current agent iteration limit, for every member of a parallel node
+	for _, role := range state.Flow.members(state.CurrentAgent) {
+		maxForAgent := wo.getMaxIterationsForAgent(role)
+		if state.IterationCounts[role] >= maxForAgent {
+			return fmt.Errorf("maximum iterations for agent %s (%d) exceeded", role, maxForAgent)
+		}
 	}
 
 	return nil
@@ -271,38 +405,127 @@ func (wo *WorkflowOrchestrator) getMaxIterationsForAgent(role AgentRole) int {
 	return 2 // default
 }
 
-func (wo *WorkflowOrchestrator) executeCurrentAgent(ctx context.Context, state *WorkflowState, req WorkflowRequest) (*agent.ImplementFeatureResponse, error) {
-	currentAgent, exists := wo.agents[state.CurrentAgent]
+// executeCurrentNode runs the current role, or fans out a parallel node, and
+// records every agent's output in result
+func (wo *WorkflowOrchestrator) executeCurrentNode(ctx context.Context, state *WorkflowState, req WorkflowRequest, result *WorkflowResult) (*agent.ImplementFeatureResponse, error) {
+	node, parallel := state.Flow.Parallel[state.CurrentAgent]
+	if !parallel {
+		agentResult, err := wo.executeAgent(ctx, state.CurrentAgent, state, req)
+		if err != nil {
+			return nil, err
+		}
+		wo.updateResultWithAgent(result, state.CurrentAgent, agentResult)
+		return agentResult, nil
+	}
+
+	ctx, span := startTrace(ctx, "parallel", string(node.Name), "")
+	members, err := wo.executeParallel(ctx, node, state, req)
+	span.end(err, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.err == nil {
+			wo.updateResultWithAgent(result, member.role, member.result)
+		}
+	}
+	return mergeParallelResults(node, members)
+}
+
+func (wo *WorkflowOrchestrator) executeAgent(ctx context.Context, role AgentRole, state *WorkflowState, req WorkflowRequest) (*agent.ImplementFeatureResponse, error) {
+	currentAgent, exists := state.Agents[role]
 	if !exists {
-		return nil, fmt.Errorf("agent %s not registered", state.CurrentAgent)
+		return nil, fmt.Errorf("agent %s not registered", role)
 	}
+	return wo.runAgent(ctx, role, currentAgent, state, req)
+}
 
+// runAgent hands the current task to currentAgent, working in req.WorkingDirectory
+func (wo *WorkflowOrchestrator) runAgent(ctx context.Context, role AgentRole, currentAgent agent.Agent, state *WorkflowState, req WorkflowRequest) (*agent.ImplementFeatureResponse, error) {
 	// Convert workflow request to agent request
 	agentReq := agent.ImplementFeatureRequest{
-		Description:      wo.buildAgentPrompt(state, req),
+		Description:      wo.buildAgentPrompt(role, state, req),
 		ProjectType:      req.ProjectType,
 		WorkingDirectory: req.WorkingDirectory,
 	}
 
-	return currentAgent.ImplementFeature(ctx, agentReq)
+	ctx, span := startTrace(ctx, "agent", string(role), string(role), attribute.String(telemetry.AttrModel, wo.agentConfig(role).Model))
+	if state.AgentContexts != nil {
+		state.AgentContexts.set(role, ctx)
+		defer state.AgentContexts.set(role, nil)
+	}
+
+	agentResult, err := currentAgent.ImplementFeature(ctx, agentReq)
+	spanErr, status := err, "failed"
+	if err == nil && agentResult != nil {
+		if agentResult.Success {
+			status = "succeeded"
+		} else if agentResult.Error != "" {
+			spanErr = errors.New(agentResult.Error)
+		}
+	}
+	span.end(spanErr, status)
+
+	if err != nil {
+		status = "error"
+	}
+	metrics.AgentInvoked(string(role), wo.agentConfig(role).Model, status)
+
+	return agentResult, err
+}
+
+// runStatus names how a run ended in its trace
+func runStatus(result *WorkflowResult) string {
+	if result.Success {
+		return "succeeded"
+	}
+	return "failed"
 }
 
-func (wo *WorkflowOrchestrator) buildAgentPrompt(state *WorkflowState, req WorkflowRequest) string {
+// taskPromptShare is the part of an agent's prompt budget its task description may
+// take; the rest is left for the agent's own instructions and the code it reads
+const taskPromptShare = 3
+
+func (wo *WorkflowOrchestrator) buildAgentPrompt(role AgentRole, state *WorkflowState, req WorkflowRequest) string {
 	// The TaskDescription in the state is the source of truth for the current task.
 	// The EM updates this field, and subsequent agents use the updated description.
-	basePrompt := state.TaskDescription
+	agentCfg := wo.agentConfig(role)
+	asm := prompt.NewAssembler(agentCfg.Model, agentCfg.PromptTokens()/taskPromptShare)
+	asm.Add(prompt.Section{Name: "task", Content: state.TaskDescription, Priority: prompt.Required})
 
 	// Add project context, which is always useful
 	if state.ProjectContext != nil {
 		if state.ProjectContext.ClaudeMd != "" {
-			basePrompt += "\n\nProject Instructions (CLAUDE.md):\n" + state.ProjectContext.ClaudeMd
+			asm.Add(prompt.Section{
+				Name:     "CLAUDE.md",
+				Content:  "\n\nProject Instructions (CLAUDE.md):\n" + state.ProjectContext.ClaudeMd,
+				Priority: prompt.High,
+			})
 		}
 		if state.ProjectContext.AgentsMd != "" {
-			basePrompt += "\n\nAgent Instructions (AGENTS.md):\n" + state.ProjectContext.AgentsMd
+			asm.Add(prompt.Section{
+				Name:     "AGENTS.md",
+				Content:  "\n\nAgent Instructions (AGENTS.md):\n" + state.ProjectContext.AgentsMd,
+				Priority: prompt.Medium,
+			})
 		}
 	}
 
-	return basePrompt
+	sections, report := asm.Fit()
+	if changed := report.Changed(); len(changed) > 0 {
+		log.Printf("Task for %s fitted to %d of %d tokens: %s", role, report.Tokens, report.Budget, strings.Join(changed, ", "))
+	}
+
+	var basePrompt strings.Builder
+	for _, section := range sections {
+		basePrompt.WriteString(section.Content)
+	}
+	return basePrompt.String()
+}
+
+// agentConfig returns the configuration of role, or zero values that fall back to defaults
+func (wo *WorkflowOrchestrator) agentConfig(role AgentRole) config.WorkflowAgentConfig {
+	return wo.config.Agents[string(role)]
 }
 
 func (wo *WorkflowOrchestrator) updateResultWithAgent(result *WorkflowResult, role AgentRole, agentResult *agent.ImplementFeatureResponse) {
@@ -335,16 +558,20 @@ func (wo *WorkflowOrchestrator) updateResultWithAgent(result *WorkflowResult, ro
 	case AgentRoleTechLead:
 		// Tech Lead adds quality checks
 		result.QualityChecks = append(result.QualityChecks, agentResult.CommandsExecuted...)
+		if agentResult.Review != nil {
+			result.ReviewDecision = agentResult.Review
+			result.Findings = agentResult.Findings
+		}
 	}
 }
 
-func (wo *WorkflowOrchestrator) isWorkflowComplete(state *WorkflowState, agentResult *agent.ImplementFeatureResponse) bool {
-	// Workflow is complete when Tech Lead finishes successfully
-	return state.CurrentAgent == AgentRoleTechLead && agentResult.Success
+// terminalFor returns the terminal condition of the current flow the agent result satisfies, if any
+func (wo *WorkflowOrchestrator) terminalFor(state *WorkflowState, agentResult *agent.ImplementFeatureResponse) *TerminalCondition {
+	return state.Flow.routing.Terminal(state.CurrentAgent, agentResult)
 }
 
 func (wo *WorkflowOrchestrator) routeToNextAgent(state *WorkflowState, agentResult *agent.ImplementFeatureResponse) (AgentRole, string, error) {
-	return wo.routingEngine.RouteAgent(state.CurrentAgent, agentResult)
+	return state.Flow.routing.RouteAgent(state.CurrentAgent, agentResult)
 }
 
 // Error Recovery and Analysis
@@ -355,11 +582,30 @@ type RecoveryAction struct {
 	Reason     string
 }
 
+// maxConnectionRetries bounds how often a run re-runs an agent after connection
+// errors; the LLM client already retries and falls back on every call
+const maxConnectionRetries = 2
+
 func (wo *WorkflowOrchestrator) analyzeAndRecoverFromError(err error, state *WorkflowState) RecoveryAction {
 	errorMsg := strings.ToLower(err.Error())
 	
+	// Every model failed or is behind an open breaker - rerunning won't help
+	if isModelUnavailable(errorMsg) {
+		return RecoveryAction{
+			CanRecover: false,
+			Reason:     "No model available",
+		}
+	}
+	
 	// LLM connection errors - can often retry
 	if strings.Contains(errorMsg, "connection") || strings.Contains(errorMsg, "timeout") {
+		if state.ConnectionRetries >= maxConnectionRetries {
+			return RecoveryAction{
+				CanRecover: false,
+				Reason:     "Connection error, retries exhausted",
+			}
+		}
+		state.ConnectionRetries++
 		return RecoveryAction{
 			CanRecover: true,
 			NextAgent:  state.CurrentAgent, // Retry same agent
@@ -410,9 +656,18 @@ func (wo *WorkflowOrchestrator) analyzeAndRecoverFromError(err error, state *Wor
 	}
 }
 
+// isModelUnavailable reports whether a lowercased error says the LLM client ran
+// out of models to try
+func isModelUnavailable(errorMsg string) bool {
+	return strings.Contains(errorMsg, "all models failed") || strings.Contains(errorMsg, "circuit breaker open")
+}
+
 func (wo *WorkflowOrchestrator) categorizeFailure(err error) string {
 	errorMsg := strings.ToLower(err.Error())
 	
+	if isModelUnavailable(errorMsg) {
+		return "llm_unavailable"
+	}
 	if strings.Contains(errorMsg, "timeout") || strings.Contains(errorMsg, "deadline") {
 		return "timeout"
 	}
@@ -489,6 +744,11 @@ func (wo *WorkflowOrchestrator) enhanceResultWithDiagnostics(result *WorkflowRes
 			lastTransition.FromAgent, lastTransition.ToAgent, lastTransition.Reason)
 	}
 	
+	// Explain the last review verdict when the workflow did not finish cleanly
+	if !result.Success && result.ReviewDecision != nil && !result.ReviewDecision.Approved {
+		result.Error += fmt.Sprintf(" (last Tech Lead review: %s)", result.ReviewDecision.Summary)
+	}
+
 	// Add performance metrics
 	totalDuration := time.Since(state.StartTime)
 	result.BuildOutput += fmt.Sprintf("\n\n=== Workflow Diagnostics ===\n")
@@ -507,4 +767,25 @@ func (wo *WorkflowOrchestrator) enhanceResultWithDiagnostics(result *WorkflowRes
 			result.AgentSummaries[string(role)] = summary
 		}
 	}
-}
\ No newline at end of file
+}
+// exportFindings writes the Tech Lead findings as SARIF when a path is configured
+func (wo *WorkflowOrchestrator) exportFindings(toolSet agent.ToolSet, result *WorkflowResult, req WorkflowRequest) {
+	sarifPath := req.SarifPath
+	if sarifPath == "" {
+		sarifPath = wo.config.Workflow.SarifPath
+	}
+	if sarifPath == "" || result.ReviewDecision == nil {
+		return
+	}
+
+	report, err := agent.BuildSARIF(result.Findings, toolSet.GetWorkingDirectory())
+	if err != nil {
+		log.Printf("Failed to build SARIF report: %v", err)
+		return
+	}
+	if err := toolSet.WriteFile(sarifPath, string(report)); err != nil {
+		log.Printf("Failed to write SARIF report to %s: %v", sarifPath, err)
+		return
+	}
+	result.SarifFile = sarifPath
+}
@@ -0,0 +1,213 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/tools"
+)
+
+// recordingAgent writes a marker file into whatever directory its ToolSet points at
+type recordingAgent struct {
+	role  agent.AgentRole
+	tools agent.ToolSet
+	llm   agent.LLMClient
+}
+
+func (ra *recordingAgent) ImplementFeature(ctx context.Context, req agent.ImplementFeatureRequest) (*agent.ImplementFeatureResponse, error) {
+	ra.tools.SetWorkingDirectory(req.WorkingDirectory)
+	// Give concurrent runs a chance to interleave between choosing and using the directory
+	time.Sleep(5 * time.Millisecond)
+
+	file := string(ra.role) + ".txt"
+	if ra.role == agent.AgentRoleQA {
+		file = "marker_test.go"
+	}
+	owner := filepath.Base(req.WorkingDirectory)
+	if err := ra.tools.WriteFile(file, owner); err != nil {
+		return nil, err
+	}
+	return &agent.ImplementFeatureResponse{
+		Success:       true,
+		Message:       "done",
+		FilesModified: []string{file},
+		NextSteps:     req.Description,
+	}, nil
+}
+
+func (ra *recordingAgent) DocumentTask(ctx context.Context, result *agent.WorkflowResult) error {
+	return nil
+}
+
+type recordingFactory struct{}
+
+func (recordingFactory) CreateAgent(role agent.AgentRole, llmClient agent.LLMClient, toolSet agent.ToolSet, restrictions agent.CommandRestrictions, cfg config.WorkflowAgentConfig) (agent.Agent, error) {
+	return &recordingAgent{role: role, tools: toolSet, llm: llmClient}, nil
+}
+
+// namedClient is a model client that only tells instances apart
+type namedClient struct{ name string }
+
+func (c *namedClient) Generate(ctx context.Context, prompt string) (string, error) {
+	return c.name, nil
+}
+
+func testWorkflowConfig() *config.WorkflowConfig {
+	cfg := &config.WorkflowConfig{
+		Workflow: config.WorkflowSection{MaxTotalIterations: 10, TimeoutMinutes: 1},
+		Agents:   make(map[string]config.WorkflowAgentConfig),
+		Commands: config.CommandsSection{Allowed: []string{"ls"}},
+	}
+	for _, role := range config.BuiltinRoles {
+		cfg.Agents[role] = config.WorkflowAgentConfig{Role: role, Model: "test", MaxIterations: 2}
+	}
+	return cfg
+}
+
+// newTestOrchestrator mirrors main: one shared ToolSet rooted at sharedDir plus an agent builder
+func newTestOrchestrator(t *testing.T, sharedDir string) *WorkflowOrchestrator {
+	t.Helper()
+	cfg := testWorkflowConfig()
+
+	shared := tools.NewToolSet(cfg.Commands, cfg.Restrictions, sharedDir)
+	wo, err := NewWorkflowOrchestrator(nil, shared, cfg)
+	if err != nil {
+		t.Fatalf("NewWorkflowOrchestrator: %v", err)
+	}
+
+	builder := NewAgentBuilder(cfg, recordingFactory{}, func(config.WorkflowAgentConfig) agent.LLMClient { return nil })
+	if err := wo.SetAgentBuilder(builder); err != nil {
+		t.Fatalf("SetAgentBuilder: %v", err)
+	}
+	return wo
+}
+
+func TestConcurrentWorkflowsUseIsolatedToolSets(t *testing.T) {
+	root := t.TempDir()
+	wo := newTestOrchestrator(t, root)
+
+	const runs = 8
+	dirs := make([]string, runs)
+	for i := range dirs {
+		dirs[i] = filepath.Join(root, fmt.Sprintf("project-%d", i))
+		if err := os.MkdirAll(dirs[i], 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := make([]*agent.WorkflowResult, runs)
+	errs := make([]error, runs)
+	var wg sync.WaitGroup
+	for i := range dirs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = wo.ExecuteWorkflow(context.Background(), agent.WorkflowRequest{
+				Description:      fmt.Sprintf("task %d", i),
+				ProjectType:      agent.ProjectTypeGo,
+				WorkingDirectory: dirs[i],
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if entries, err := os.ReadDir(root); err != nil || len(entries) != runs {
+		t.Fatalf("shared working directory was written to: %v entries, err %v", len(entries), err)
+	}
+
+	for i, dir := range dirs {
+		if errs[i] != nil {
+			t.Fatalf("run %d: %v", i, errs[i])
+		}
+		if !results[i].Success {
+			t.Fatalf("run %d failed: %s (%s)", i, results[i].Error, results[i].FailureReason)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 4 {
+			t.Errorf("run %d wrote %d files into %s, want 4", i, len(entries), dir)
+		}
+		for _, entry := range entries {
+			content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if owner := strings.TrimSpace(string(content)); owner != filepath.Base(dir) {
+				t.Errorf("%s in %s was written by the run for %s", entry.Name(), dir, owner)
+			}
+		}
+	}
+}
+
+func TestWorkflowsDoNotShareAgentInstances(t *testing.T) {
+	wo := newTestOrchestrator(t, t.TempDir())
+	flow, err := wo.selectFlow("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := wo.newWorkspace(flow, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := wo.newWorkspace(flow, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.toolSet == second.toolSet {
+		t.Fatal("workflow runs share a ToolSet")
+	}
+	if first.toolSet.GetWorkingDirectory() == second.toolSet.GetWorkingDirectory() {
+		t.Fatal("workflow runs share a working directory")
+	}
+	for role, instance := range first.agents {
+		if second.agents[role] == instance {
+			t.Errorf("workflow runs share the %s agent", role)
+		}
+	}
+}
+
+func TestWorkflowsDoNotShareLLMClients(t *testing.T) {
+	wo := newTestOrchestrator(t, t.TempDir())
+	built := 0
+	builder := NewAgentBuilder(wo.config, recordingFactory{}, func(agentCfg config.WorkflowAgentConfig) agent.LLMClient {
+		built++
+		return &namedClient{name: fmt.Sprintf("%s-%d", agentCfg.Role, built)}
+	})
+	if err := wo.SetAgentBuilder(builder); err != nil {
+		t.Fatalf("SetAgentBuilder: %v", err)
+	}
+	flow, err := wo.selectFlow("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := wo.newWorkspace(flow, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := wo.newWorkspace(flow, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A client remembers its last response for ReportOutput, so runs must not share one
+	for role, instance := range first.agents {
+		client := instance.(*recordingAgent).llm
+		if client == nil || client == second.agents[role].(*recordingAgent).llm {
+			t.Errorf("workflow runs share the %s agent's model client", role)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"mcp-server/internal/agent"
+	"mcp-server/internal/config"
+	"mcp-server/internal/telemetry"
+	"mcp-server/internal/tools"
+)
+
+// AgentTools is what an agent is given to work with: its tools and the command
+// restrictions that apply to them
+type AgentTools interface {
+	agent.ToolSet
+	agent.CommandRestrictions
+}
+
+// AgentBuilder creates the agent for role bound to one workflow run's ToolSet
+type AgentBuilder func(role AgentRole, toolSet AgentTools) (agent.Agent, error)
+
+// workspace is the ToolSet and agent instances a single workflow run works with
+type workspace struct {
+	toolSet       agent.ToolSet
+	agents        map[AgentRole]agent.Agent
+	members       map[AgentRole]agent.Agent // parallel node members, each with its own ToolSet
+	agentContexts *agentContexts            // nil unless the run is traced
+}
+
+// NewAgentBuilder creates agents through factory using each role's configured model
+// and context window. Every agent gets its own client from llmFor, as a client's
+// report on its last response must not reach the agent of another run.
+func NewAgentBuilder(cfg *config.WorkflowConfig, factory agent.AgentFactory, llmFor func(agentCfg config.WorkflowAgentConfig) agent.LLMClient) AgentBuilder {
+	agentConfigs := make(map[AgentRole]config.WorkflowAgentConfig, len(cfg.Agents))
+	for _, agentCfg := range cfg.Agents {
+		agentConfigs[AgentRole(agentCfg.Role)] = agentCfg
+	}
+
+	return func(role AgentRole, toolSet AgentTools) (agent.Agent, error) {
+		agentCfg, ok := agentConfigs[role]
+		if !ok {
+			return nil, fmt.Errorf("no agent configured for role %s", role)
+		}
+		return factory.CreateAgent(role, llmFor(agentCfg), toolSet, toolSet, agentCfg)
+	}
+}
+
+// SetAgentBuilder gives every workflow run its own ToolSet and agent instances,
+// so concurrent runs on different directories cannot interfere. Each configured
+// role is built once up front so configuration errors surface at startup.
+func (wo *WorkflowOrchestrator) SetAgentBuilder(builder AgentBuilder) error {
+	probe := tools.NewToolSet(wo.config.Commands, wo.config.Restrictions, wo.toolSet.GetWorkingDirectory())
+	for _, role := range configuredRoles(wo.config) {
+		if _, err := builder(role, probe); err != nil {
+			return fmt.Errorf("failed to create agent %s: %w", role, err)
+		}
+	}
+	wo.agentBuilder = builder
+	return nil
+}
+
+// newWorkspace builds the tools and agents for one run of flow; without an agent
+// builder every run shares the registered agents and the orchestrator's ToolSet.
+// Members of parallel nodes get agents of their own, so each can be pointed at
+// its own snapshot of the working tree while they run side by side.
+func (wo *WorkflowOrchestrator) newWorkspace(flow *Flow, workingDir string) (*workspace, error) {
+	if wo.agentBuilder == nil {
+		if workingDir != "" {
+			wo.toolSet.SetWorkingDirectory(workingDir)
+		}
+		return &workspace{toolSet: wo.toolSet, agents: wo.agents}, nil
+	}
+
+	if workingDir == "" {
+		workingDir = wo.toolSet.GetWorkingDirectory()
+	}
+	toolSet := tools.NewToolSet(wo.config.Commands, wo.config.Restrictions, workingDir)
+
+	ws := &workspace{toolSet: toolSet, agents: make(map[AgentRole]agent.Agent)}
+	if wo.debugLogger.IsEnabled() || telemetry.Enabled() {
+		ws.agentContexts = newAgentContexts()
+	}
+	for _, role := range configuredRoles(wo.config) {
+		if !flow.involves(role) && role != AgentRoleEM {
+			continue
+		}
+		agentInstance, err := wo.agentBuilder(role, tracedTools(toolSet, role, ws.agentContexts))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create agent %s: %w", role, err)
+		}
+		ws.agents[role] = agentInstance
+	}
+
+	for _, node := range flow.Parallel {
+		for _, role := range node.Roles {
+			if _, built := ws.members[role]; built {
+				continue
+			}
+			memberTools := tools.NewToolSet(wo.config.Commands, wo.config.Restrictions, workingDir)
+			member, err := wo.agentBuilder(role, tracedTools(memberTools, role, ws.agentContexts))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create agent %s: %w", role, err)
+			}
+			if ws.members == nil {
+				ws.members = make(map[AgentRole]agent.Agent)
+			}
+			ws.members[role] = member
+		}
+	}
+	return ws, nil
+}
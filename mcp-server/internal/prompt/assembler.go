@@ -0,0 +1,219 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Priority decides which sections give way first when a prompt is over budget
+type Priority int
+
+const (
+	Low Priority = iota
+	Medium
+	High
+	Required // never trimmed
+)
+
+// Trim says which part of a section survives trimming
+type Trim int
+
+const (
+	KeepHead Trim = iota // documents and file contents: the beginning matters most
+	KeepTail             // logs and command output: the end matters most
+	KeepEnds             // diffs: keep both ends and drop the middle
+)
+
+// minSectionTokens is the smallest trimmed section worth keeping; anything less is dropped
+const minSectionTokens = 64
+
+// Section is a named part of a prompt
+type Section struct {
+	Name     string
+	Content  string
+	Priority Priority
+	Trim     Trim
+	Summary  string // shorter stand-in used when most of the content would have to go
+}
+
+// SectionReport records what fitting did to one section
+type SectionReport struct {
+	Name           string `json:"name"`
+	OriginalTokens int    `json:"original_tokens"`
+	Tokens         int    `json:"tokens"`
+	Action         string `json:"action"` // kept, trimmed, summarized or dropped
+}
+
+// Report describes a fitted prompt
+type Report struct {
+	Model    string          `json:"model"`
+	Budget   int             `json:"budget"`
+	Tokens   int             `json:"tokens"`
+	Overflow bool            `json:"overflow,omitempty"` // required text alone exceeds the budget
+	Sections []SectionReport `json:"sections"`
+}
+
+// Changed lists the sections that were trimmed, summarized or dropped
+func (r Report) Changed() []string {
+	var changed []string
+	for _, section := range r.Sections {
+		if section.Action != "kept" {
+			changed = append(changed, fmt.Sprintf("%s %s (%d -> %d tokens)", section.Name, section.Action, section.OriginalTokens, section.Tokens))
+		}
+	}
+	return changed
+}
+
+// Assembler fits prompt sections into a token budget for one model
+type Assembler struct {
+	model    string
+	budget   int
+	fixed    int
+	sections []Section
+}
+
+// NewAssembler creates an assembler for prompts of at most budget tokens; a budget of
+// zero or less disables fitting
+func NewAssembler(model string, budget int) *Assembler {
+	return &Assembler{model: model, budget: budget}
+}
+
+// Reserve accounts for template text that is part of every prompt
+func (a *Assembler) Reserve(text string) {
+	a.fixed += EstimateTokens(a.model, text)
+}
+
+// Add appends a section; sections are returned by Fit in the order they were added
+func (a *Assembler) Add(section Section) {
+	a.sections = append(a.sections, section)
+}
+
+// Fit trims, summarizes or drops sections, lowest priority and latest added first,
+// until the prompt fits the budget
+func (a *Assembler) Fit() ([]Section, Report) {
+	sections := append([]Section(nil), a.sections...)
+	report := Report{Model: a.model, Budget: a.budget, Sections: make([]SectionReport, len(sections))}
+
+	total := a.fixed
+	tokens := make([]int, len(sections))
+	for i, section := range sections {
+		tokens[i] = EstimateTokens(a.model, section.Content)
+		total += tokens[i]
+		report.Sections[i] = SectionReport{Name: section.Name, OriginalTokens: tokens[i], Tokens: tokens[i], Action: "kept"}
+	}
+
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = len(sections) - 1 - i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return sections[order[i]].Priority < sections[order[j]].Priority })
+
+	shrink := func(i int, content, action string) {
+		after := EstimateTokens(a.model, content)
+		total -= tokens[i] - after
+		tokens[i] = after
+		sections[i].Content = content
+		report.Sections[i].Tokens = after
+		report.Sections[i].Action = action
+	}
+
+	if a.budget > 0 {
+		// First pass: give each section what is left after the ones above it
+		for _, i := range order {
+			over := total - a.budget
+			if over <= 0 {
+				break
+			}
+			section := sections[i]
+			if section.Priority == Required || tokens[i] == 0 {
+				continue
+			}
+			keep := tokens[i] - over
+			summaryTokens := EstimateTokens(a.model, section.Summary)
+			switch {
+			case keep >= tokens[i]/2 && keep >= minSectionTokens:
+				shrink(i, a.trim(section, keep), "trimmed")
+			case section.Summary != "" && summaryTokens < tokens[i]:
+				shrink(i, section.Summary, "summarized")
+			case keep >= minSectionTokens:
+				shrink(i, a.trim(section, keep), "trimmed")
+			default:
+				shrink(i, omitted(section.Name), "dropped")
+			}
+		}
+
+		// Second pass: summaries that still did not fit make way entirely
+		for _, i := range order {
+			if total <= a.budget {
+				break
+			}
+			if sections[i].Priority != Required && report.Sections[i].Action != "dropped" && tokens[i] > 0 {
+				shrink(i, omitted(sections[i].Name), "dropped")
+			}
+		}
+	}
+
+	report.Tokens = total
+	report.Overflow = a.budget > 0 && total > a.budget
+	return sections, report
+}
+
+// trim cuts a section down to about keep tokens on line boundaries where possible
+func (a *Assembler) trim(section Section, keep int) string {
+	marker := "\n... [%d tokens trimmed to fit the context budget] ...\n"
+	keepTokens := keep - EstimateTokens(a.model, marker)
+	content := section.Content
+	total := EstimateTokens(a.model, content)
+	if keepTokens <= 0 || keepTokens >= total {
+		return content
+	}
+	note := fmt.Sprintf(marker, total-keep)
+
+	switch section.Trim {
+	case KeepTail:
+		return note + tailOf(content, bytesFor(a.model, content, keepTokens, true))
+	case KeepEnds:
+		headTokens := keepTokens / 2
+		return headOf(content, bytesFor(a.model, content, headTokens, false)) + note +
+			tailOf(content, bytesFor(a.model, content, keepTokens-headTokens, true))
+	default:
+		return headOf(content, bytesFor(a.model, content, keepTokens, false)) + note
+	}
+}
+
+func omitted(name string) string {
+	return fmt.Sprintf("\n[%s omitted to fit the context budget]\n", name)
+}
+
+// headOf returns at most n bytes from the start of s, ending at a line break when one is near
+func headOf(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	head := s[:n]
+	if cut := strings.LastIndexByte(head, '\n'); cut > n/2 {
+		head = head[:cut]
+	}
+	return head
+}
+
+// tailOf returns at most n bytes from the end of s, starting after a line break when one is near
+func tailOf(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	tail := s[start:]
+	if cut := strings.IndexByte(tail, '\n'); cut >= 0 && cut < len(tail)/2 {
+		tail = tail[cut+1:]
+	}
+	return tail
+}
@@ -0,0 +1,156 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// numberedLines returns n lines of text followed by up to six x's
+func numberedLines(n int, text string) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteString(text)
+		sb.WriteString(strings.Repeat("x", i%7))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func actions(report Report) map[string]string {
+	out := make(map[string]string)
+	for _, section := range report.Sections {
+		out[section.Name] = section.Action
+	}
+	return out
+}
+
+func TestFit(t *testing.T) {
+	task := Section{Name: "task", Content: "Add a subtract function.", Priority: Required}
+	docs := Section{Name: "docs", Content: numberedLines(200, "documentation line "), Priority: Medium, Summary: "docs: see the README"}
+	logs := Section{Name: "logs", Content: numberedLines(200, "test output line "), Priority: Low, Trim: KeepTail}
+	code := Section{Name: "code", Content: numberedLines(100, "func example() {} "), Priority: High}
+
+	tests := []struct {
+		name     string
+		budget   int
+		want     map[string]string
+		overflow bool
+	}{
+		{"no budget", 0, map[string]string{"task": "kept", "docs": "kept", "logs": "kept", "code": "kept"}, false},
+		{"room for everything", 100000, map[string]string{"task": "kept", "docs": "kept", "logs": "kept", "code": "kept"}, false},
+		{"lowest priority trimmed first", 2500, map[string]string{"task": "kept", "docs": "kept", "logs": "trimmed", "code": "kept"}, false},
+		{"summary stands in when most would go", 600, map[string]string{"task": "kept", "docs": "summarized", "logs": "dropped", "code": "trimmed"}, false},
+		{"required text alone overflows", 5, map[string]string{"task": "kept", "docs": "dropped", "logs": "dropped", "code": "dropped"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assembler := NewAssembler("llama3", tt.budget)
+			assembler.Reserve("You are an engineer.")
+			for _, section := range []Section{task, docs, logs, code} {
+				assembler.Add(section)
+			}
+			sections, report := assembler.Fit()
+
+			if got := actions(report); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("actions = %v, want %v", got, tt.want)
+			}
+			if report.Overflow != tt.overflow {
+				t.Errorf("overflow = %v, want %v", report.Overflow, tt.overflow)
+			}
+			if !tt.overflow && tt.budget > 0 && report.Tokens > tt.budget {
+				t.Errorf("prompt takes %d tokens, over the budget of %d", report.Tokens, tt.budget)
+			}
+			if sections[0].Content != task.Content {
+				t.Errorf("required section changed to %q", sections[0].Content)
+			}
+			for i, section := range sections {
+				if got := EstimateTokens("llama3", section.Content); got != report.Sections[i].Tokens {
+					t.Errorf("%s reports %d tokens, content has %d", section.Name, report.Sections[i].Tokens, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	// Every rune of these lines costs a token but takes two bytes
+	multibyte := numberedLines(300, "éèêëàâäôöûü ")
+	ascii := numberedLines(300, "line ")
+
+	tests := []struct {
+		name    string
+		content string
+		trim    Trim
+		keep    string // text the trimmed section must still contain
+	}{
+		{"head", ascii, KeepHead, "line \n"},
+		{"tail", ascii, KeepTail, "line xxxxx\n"},
+		{"ends", ascii, KeepEnds, "line \n"},
+		{"multibyte head", multibyte, KeepHead, "éèêëàâäôöûü \n"},
+		{"multibyte tail", multibyte, KeepTail, "éèêëàâäôöûü xxxxx\n"},
+		{"multibyte ends", multibyte, KeepEnds, "éèêëàâäôöûü \n"},
+	}
+	const keep = 200
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assembler := NewAssembler("llama3", 0)
+			trimmed := assembler.trim(Section{Name: tt.name, Content: tt.content, Trim: tt.trim}, keep)
+
+			if got := EstimateTokens("llama3", trimmed); got > keep || got < keep/2 {
+				t.Errorf("trimmed to %d tokens, want at most %d and not far below", got, keep)
+			}
+			if !utf8.ValidString(trimmed) {
+				t.Error("trimming split a rune")
+			}
+			if !strings.Contains(trimmed, "tokens trimmed to fit the context budget") {
+				t.Error("trimmed section has no marker")
+			}
+			if !strings.Contains(trimmed, tt.keep) {
+				t.Errorf("trimmed section lost %q", tt.keep)
+			}
+			switch tt.trim {
+			case KeepHead:
+				if !strings.HasPrefix(trimmed, tt.content[:20]) {
+					t.Error("head trim did not keep the beginning")
+				}
+			case KeepTail:
+				if !strings.HasSuffix(trimmed, tt.content[len(tt.content)-20:]) {
+					t.Error("tail trim did not keep the end")
+				}
+			case KeepEnds:
+				if !strings.HasPrefix(trimmed, tt.content[:20]) || !strings.HasSuffix(trimmed, tt.content[len(tt.content)-20:]) {
+					t.Error("ends trim did not keep both ends")
+				}
+			}
+		})
+	}
+
+	short := Section{Name: "short", Content: "fits already"}
+	if got := NewAssembler("llama3", 0).trim(short, keep); got != short.Content {
+		t.Errorf("trim of a section under the budget = %q, want it unchanged", got)
+	}
+}
+
+func TestBytesFor(t *testing.T) {
+	tests := []struct {
+		text    string
+		tokens  int
+		fromEnd bool
+		want    int
+	}{
+		{"abcdefgh", 1, false, 3}, // three characters per token by default
+		{"abcdefgh", 2, true, 6},
+		{"abcdefgh", 10, false, 8},
+		{"héllo", 1, false, 1},
+		{"héllo", 2, false, 5}, // h, the two bytes of é and both l's
+		{"日本語", 2, true, 6},
+		{"abc", 0, false, 0},
+	}
+	for _, tt := range tests {
+		if got := bytesFor("unknown", tt.text, tt.tokens, tt.fromEnd); got != tt.want {
+			t.Errorf("bytesFor(%q, %d, %v) = %d, want %d", tt.text, tt.tokens, tt.fromEnd, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is the average number of ASCII characters per token of each model
+// family's tokenizer over a mix of prose and code; the first matching prefix wins
+var charsPerToken = []struct {
+	prefix string
+	chars  float64
+}{
+	{"codellama", 3.3},
+	{"llama3", 3.8},
+	{"llama", 3.4},
+	{"qwen", 3.6},
+	{"deepseek", 3.5},
+	{"mistral", 3.4},
+	{"mixtral", 3.4},
+	{"codestral", 3.4},
+	{"gemma", 3.8},
+	{"phi", 3.3},
+	{"starcoder", 3.3},
+}
+
+// defaultCharsPerToken is deliberately pessimistic for models not listed above
+const defaultCharsPerToken = 3.0
+
+// EstimateTokens estimates how many tokens text takes for model. Non-ASCII characters
+// are counted as a token each, which over-estimates for most tokenizers.
+func EstimateTokens(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	ascii, other := 0, 0
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			ascii++
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		other++
+		i += size
+	}
+	tokens := int(float64(ascii)/ratioFor(model)+0.999) + other
+	if tokens == 0 {
+		tokens = 1
+	}
+	return tokens
+}
+
+// ratioFor looks up the characters per token of model, ignoring registry namespaces and tags
+func ratioFor(model string) float64 {
+	name := strings.ToLower(path.Base(model))
+	for _, family := range charsPerToken {
+		if strings.HasPrefix(name, family.prefix) {
+			return family.chars
+		}
+	}
+	return defaultCharsPerToken
+}
+
+// bytesFor returns the length in bytes of the longest start of text, or end of text
+// when fromEnd is set, that fits in tokens for model, costing runes as EstimateTokens does
+func bytesFor(model, text string, tokens int, fromEnd bool) int {
+	perASCII := 1 / ratioFor(model)
+	used, n := 0.0, 0
+	for n < len(text) {
+		var r rune
+		var size int
+		if fromEnd {
+			r, size = utf8.DecodeLastRuneInString(text[:len(text)-n])
+		} else {
+			r, size = utf8.DecodeRuneInString(text[n:])
+		}
+		cost := 1.0
+		if r < utf8.RuneSelf {
+			cost = perASCII
+		}
+		if used+cost > float64(tokens) {
+			break
+		}
+		used += cost
+		n += size
+	}
+	return n
+}
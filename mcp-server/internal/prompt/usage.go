@@ -0,0 +1,7 @@
+package prompt
+
+// Usage is the number of tokens one model call consumed
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
@@ -0,0 +1,139 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"mcp-server/internal/config"
+)
+
+const instrumentationName = "mcp-server"
+
+// Span attribute keys, following the OpenTelemetry semantic conventions where one exists
+const (
+	AttrRole             = "agent.role"
+	AttrFlow             = "workflow.flow"
+	AttrWorkingDir       = "workflow.working_directory"
+	AttrProjectType      = "workflow.project_type"
+	AttrStatus           = "workflow.status"
+	AttrModel            = "gen_ai.request.model"
+	AttrPromptTokens     = "gen_ai.usage.input_tokens"
+	AttrCompletionTokens = "gen_ai.usage.output_tokens"
+	AttrTokensEstimated  = "gen_ai.usage.estimated"
+	AttrTool             = "tool.name"
+	AttrFilePath         = "file.path"
+	AttrCommand          = "process.command_line"
+	AttrExitCode         = "process.exit.code"
+)
+
+var enabled atomic.Bool
+
+// Setup installs the global tracer provider selected by cfg. The stdout exporter
+// writes to console, so servers speaking MCP over stdout can pass stderr. The
+// returned function flushes and stops the exporter.
+func Setup(ctx context.Context, cfg config.TelemetryConfig, console io.Writer) (func(context.Context) error, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+		err      error
+	)
+	switch cfg.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var options []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(console))
+	case "file":
+		file, openErr := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if openErr != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", openErr)
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (want otlp, stdout, file or none)", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled.Store(true)
+
+	return func(ctx context.Context) error {
+		enabled.Store(false)
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// Enabled reports whether spans are being exported
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start opens a span under the one ctx carries
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End closes span, marking it failed when err is set
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ShutdownOnSignal flushes the exporter before the process exits on SIGINT or
+// SIGTERM, for servers that never return from main
+func ShutdownOnSignal(shutdown func(context.Context) error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to flush traces: %v\n", err)
+		}
+		os.Exit(0)
+	}()
+}
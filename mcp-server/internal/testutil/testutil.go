@@ -0,0 +1,149 @@
+// Package testutil starts the server binaries against a fake Ollama for the cmd
+// tests, so each transport is exercised as it is deployed. Only tests import it.
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-server/internal/fakeollama"
+	"mcp-server/internal/fixture"
+)
+
+// SubtractRequest is the feature the testdata/integration/subtract.yaml script
+// implements on the calculator fixture
+const SubtractRequest = "Add a Subtract function to the calculator package that returns a minus b"
+
+// Env is a fake Ollama and a project for one test
+type Env struct {
+	Ollama    *fakeollama.Server
+	OllamaURL string
+	Project   string
+}
+
+// Root is the module directory, where the binaries find config/agents.toml
+func Root() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..")
+}
+
+// Setup serves the subtract script and copies the calculator fixture into a
+// fresh git repository
+func Setup(t *testing.T) *Env {
+	t.Helper()
+	script, err := fakeollama.LoadScript(filepath.Join(Root(), "testdata", "integration", "subtract.yaml"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	project := filepath.Join(t.TempDir(), "calculator")
+	if err := fixture.Repo(filepath.Join(Root(), "testdata", "fixtures", "calculator"), project); err != nil {
+		t.Fatalf("%v", err)
+	}
+	fake := fakeollama.NewServer(script)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return &Env{
+		Ollama:    fake,
+		OllamaURL: server.URL,
+		Project:   project,
+	}
+}
+
+// Build compiles the command in cmd/name and returns the binary's path
+func Build(t *testing.T, name string) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), name)
+	cmd := exec.Command("go", "build", "-o", binary, "./cmd/"+name)
+	cmd.Dir = Root()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building %s: %v\n%s", name, err, output)
+	}
+	return binary
+}
+
+// Command runs binary from the module root with OLLAMA_URL and PROJECT_ROOT
+// pointing at the environment, plus any extra variables. Its stderr is logged
+// when the test fails.
+func (e *Env) Command(t *testing.T, binary string, env ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(binary)
+	cmd.Dir = Root()
+	cmd.Env = append(os.Environ(),
+		"OLLAMA_URL="+e.OllamaURL,
+		"PROJECT_ROOT="+e.Project,
+		"AGENT_DEBUG=false",
+		"OTEL_TRACES_EXPORTER=none",
+	)
+	cmd.Env = append(cmd.Env, env...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	t.Cleanup(func() {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}
+		if t.Failed() {
+			t.Logf("%s stderr:\n%s", filepath.Base(binary), stderr.String())
+		}
+	})
+	return cmd
+}
+
+// CheckSubtract fails the test unless the workflow made the changes the script
+// describes and every model call was scripted
+func (e *Env) CheckSubtract(t *testing.T) {
+	t.Helper()
+	for file, want := range map[string]string{
+		"calc.go":          "func Subtract(a, b int) int",
+		"subtract_test.go": "func TestSubtract",
+	} {
+		content, err := os.ReadFile(filepath.Join(e.Project, file))
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("%s does not contain %q", file, want)
+		}
+	}
+	if unmatched := e.Ollama.Unmatched(); len(unmatched) > 0 {
+		t.Errorf("%d prompt(s) had no scripted response, first:\n%s", len(unmatched), unmatched[0])
+	}
+}
+
+// FreePort returns a port nothing is listening on
+func FreePort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("finding a free port: %v", err)
+	}
+	defer listener.Close()
+	return fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+}
+
+// WaitForPort waits until something accepts connections on port
+func WaitForPort(t *testing.T, port string) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("nothing listening on port %s after 30s", port)
+}
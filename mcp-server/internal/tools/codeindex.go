@@ -0,0 +1,502 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/internal/langpack"
+)
+
+const (
+	maxIndexedFileSize = 1 << 20 // larger files are generated or data, not code worth searching
+	maxIndexedFiles    = 50000
+	maxSearchResults   = 50
+)
+
+// CodeIndex is an in-memory index of a source tree: symbol definitions and a trigram
+// index over file contents that narrows regex searches to the files that can match
+type CodeIndex struct {
+	mu       sync.RWMutex
+	root     string
+	files    map[string]*indexedSource // keyed by slash-separated path relative to root
+	postings map[uint32]map[string]struct{}
+}
+
+// indexedSource is one file of the index
+type indexedSource struct {
+	content  string
+	size     int64
+	modTime  time.Time
+	trigrams []uint32
+	symbols  []Symbol
+}
+
+// CodeIndexStats summarises an index
+type CodeIndexStats struct {
+	Files   int `json:"files"`
+	Symbols int `json:"symbols"`
+}
+
+// CodeMatch is a line matching a search, with the lines around it
+type CodeMatch struct {
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// String renders the match the way grep -n does with context lines
+func (m CodeMatch) String() string {
+	var sb strings.Builder
+	for i, line := range m.Before {
+		sb.WriteString(fmt.Sprintf("%s-%d- %s\n", m.File, m.Line-len(m.Before)+i, line))
+	}
+	sb.WriteString(fmt.Sprintf("%s:%d: %s", m.File, m.Line, m.Text))
+	for i, line := range m.After {
+		sb.WriteString(fmt.Sprintf("\n%s-%d- %s", m.File, m.Line+i+1, line))
+	}
+	return sb.String()
+}
+
+// SymbolLookup is where a symbol is defined and used
+type SymbolLookup struct {
+	Name        string      `json:"name"`
+	Definitions []Symbol    `json:"definitions"`
+	References  []CodeMatch `json:"references"`
+}
+
+// BuildCodeIndex indexes the text files below root, skipping hidden, dependency and
+// build output directories
+func BuildCodeIndex(root string) (*CodeIndex, error) {
+	ci := &CodeIndex{
+		root:     root,
+		files:    make(map[string]*indexedSource),
+		postings: make(map[uint32]map[string]struct{}),
+	}
+	if err := ci.Refresh(); err != nil {
+		return nil, err
+	}
+	return ci, nil
+}
+
+// Refresh re-indexes files whose size or modification time changed and drops
+// files that no longer exist
+func (ci *CodeIndex) Refresh() error {
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(ci.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if p == ci.root {
+				return err
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			if p != ci.root && langpack.SkipDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || len(seen) >= maxIndexedFiles {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() > maxIndexedFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(ci.root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		ci.mu.RLock()
+		current := ci.files[rel]
+		ci.mu.RUnlock()
+		if current != nil && current.size == info.Size() && current.modTime.Equal(info.ModTime()) {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil || isBinary(data) {
+			ci.remove(rel)
+			return nil
+		}
+		ci.put(rel, string(data), info.Size(), info.ModTime())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", ci.root, err)
+	}
+
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	for rel := range ci.files {
+		if !seen[rel] {
+			ci.removeLocked(rel)
+		}
+	}
+	return nil
+}
+
+// Update re-indexes one file after it was written; path may be absolute or relative to the root
+func (ci *CodeIndex) Update(file, content string) {
+	rel, ok := ci.relative(file)
+	if !ok {
+		return
+	}
+	var size int64
+	var modTime time.Time
+	if info, err := os.Stat(filepath.Join(ci.root, filepath.FromSlash(rel))); err == nil {
+		size, modTime = info.Size(), info.ModTime()
+	}
+	if len(content) > maxIndexedFileSize || isBinary([]byte(content)) {
+		ci.remove(rel)
+		return
+	}
+	ci.put(rel, content, size, modTime)
+}
+
+// Stats reports the size of the index
+func (ci *CodeIndex) Stats() CodeIndexStats {
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
+	stats := CodeIndexStats{Files: len(ci.files)}
+	for _, source := range ci.files {
+		stats.Symbols += len(source.symbols)
+	}
+	return stats
+}
+
+// Search finds lines matching the regular expression pattern. within limits the search
+// to a directory, or to paths matching it when it is a glob; contextLines lines are
+// included before and after each match.
+func (ci *CodeIndex) Search(pattern, within string, contextLines, limit int) ([]CodeMatch, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
+
+	var matches []CodeMatch
+	for _, file := range ci.candidates(requiredTrigrams(parsed.Simplify())) {
+		if !withinPath(file, within) {
+			continue
+		}
+		lines := strings.Split(ci.files[file].content, "\n")
+		for i, line := range lines {
+			if !re.MatchString(line) {
+				continue
+			}
+			matches = append(matches, CodeMatch{
+				File:   file,
+				Line:   i + 1,
+				Text:   strings.TrimRight(line, "\r"),
+				Before: contextOf(lines, i-contextLines, i),
+				After:  contextOf(lines, i+1, i+1+contextLines),
+			})
+			if len(matches) == limit {
+				return matches, nil
+			}
+		}
+	}
+	return matches, nil
+}
+
+// FindSymbol returns the definitions of name, or of Type.Method, and the lines that
+// refer to it outside those definitions
+func (ci *CodeIndex) FindSymbol(name string, limit int) *SymbolLookup {
+	name = strings.TrimSpace(name)
+	container, member := "", name
+	if idx := strings.LastIndex(name, "."); idx > 0 {
+		container, member = name[:idx], name[idx+1:]
+	}
+	lookup := &SymbolLookup{Name: name, Definitions: []Symbol{}, References: []CodeMatch{}}
+	if member == "" {
+		return lookup
+	}
+
+	defined := make(map[string]bool)
+	ci.mu.RLock()
+	for _, file := range sortedKeys(ci.files) {
+		for _, symbol := range ci.files[file].symbols {
+			if symbol.Name == member && (container == "" || symbol.Container == container) {
+				lookup.Definitions = append(lookup.Definitions, symbol)
+				defined[fmt.Sprintf("%s:%d", symbol.File, symbol.Line)] = true
+			}
+		}
+	}
+	ci.mu.RUnlock()
+
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+	references, _ := ci.Search(`\b`+regexp.QuoteMeta(member)+`\b`, "", 0, limit+len(defined))
+	for _, reference := range references {
+		if !defined[fmt.Sprintf("%s:%d", reference.File, reference.Line)] && len(lookup.References) < limit {
+			lookup.References = append(lookup.References, reference)
+		}
+	}
+	return lookup
+}
+
+// SearchSymbols returns symbols whose name contains query, ignoring case; exact
+// matches come first, then prefixes, then the rest
+func (ci *CodeIndex) SearchSymbols(query string, limit int) []Symbol {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	ci.mu.RLock()
+	defer ci.mu.RUnlock()
+
+	type ranked struct {
+		symbol Symbol
+		rank   int
+	}
+	var found []ranked
+	for _, file := range sortedKeys(ci.files) {
+		for _, symbol := range ci.files[file].symbols {
+			name := strings.ToLower(symbol.Name)
+			switch {
+			case name == query:
+				found = append(found, ranked{symbol, 0})
+			case strings.HasPrefix(name, query):
+				found = append(found, ranked{symbol, 1})
+			case strings.Contains(name, query):
+				found = append(found, ranked{symbol, 2})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].rank < found[j].rank })
+
+	var symbols []Symbol
+	for _, candidate := range found {
+		if limit > 0 && len(symbols) == limit {
+			break
+		}
+		symbols = append(symbols, candidate.symbol)
+	}
+	return symbols
+}
+
+func (ci *CodeIndex) put(rel, content string, size int64, modTime time.Time) {
+	source := &indexedSource{
+		content:  content,
+		size:     size,
+		modTime:  modTime,
+		trigrams: trigramsOf(content),
+		symbols:  extractSymbols(rel, content),
+	}
+
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	ci.removeLocked(rel)
+	ci.files[rel] = source
+	for _, trigram := range source.trigrams {
+		files := ci.postings[trigram]
+		if files == nil {
+			files = make(map[string]struct{})
+			ci.postings[trigram] = files
+		}
+		files[rel] = struct{}{}
+	}
+}
+
+func (ci *CodeIndex) remove(rel string) {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	ci.removeLocked(rel)
+}
+
+func (ci *CodeIndex) removeLocked(rel string) {
+	source, ok := ci.files[rel]
+	if !ok {
+		return
+	}
+	for _, trigram := range source.trigrams {
+		if files := ci.postings[trigram]; files != nil {
+			delete(files, rel)
+			if len(files) == 0 {
+				delete(ci.postings, trigram)
+			}
+		}
+	}
+	delete(ci.files, rel)
+}
+
+// candidates returns, in path order, the files containing every required trigram
+func (ci *CodeIndex) candidates(required []uint32) []string {
+	if len(required) == 0 {
+		return sortedKeys(ci.files)
+	}
+	var smallest map[string]struct{}
+	for _, trigram := range required {
+		files := ci.postings[trigram]
+		if len(files) == 0 {
+			return nil
+		}
+		if smallest == nil || len(files) < len(smallest) {
+			smallest = files
+		}
+	}
+
+	var result []string
+	for file := range smallest {
+		matchesAll := true
+		for _, trigram := range required {
+			if _, ok := ci.postings[trigram][file]; !ok {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			result = append(result, file)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// relative converts a path to the index's root-relative form
+func (ci *CodeIndex) relative(file string) (string, bool) {
+	if filepath.IsAbs(file) {
+		rel, err := filepath.Rel(ci.root, file)
+		if err != nil {
+			return "", false
+		}
+		file = rel
+	}
+	file = path.Clean(filepath.ToSlash(file))
+	if file == "." || strings.HasPrefix(file, "../") || file == ".." {
+		return "", false
+	}
+	return file, true
+}
+
+// trigramsOf returns the distinct lowercased byte trigrams of content
+func trigramsOf(content string) []uint32 {
+	lower := strings.ToLower(content)
+	seen := make(map[uint32]struct{})
+	for i := 0; i+3 <= len(lower); i++ {
+		seen[trigram(lower[i:i+3])] = struct{}{}
+	}
+	trigrams := make([]uint32, 0, len(seen))
+	for t := range seen {
+		trigrams = append(trigrams, t)
+	}
+	return trigrams
+}
+
+func trigram(s string) uint32 {
+	return uint32(s[0])<<16 | uint32(s[1])<<8 | uint32(s[2])
+}
+
+// requiredTrigrams returns trigrams that any text matching re must contain, taken
+// from the literal runs of the expression; nil means the index cannot narrow the search
+func requiredTrigrams(re *syntax.Regexp) []uint32 {
+	var literals []string
+	collectLiterals(re, &literals)
+
+	seen := make(map[uint32]bool)
+	var required []uint32
+	for _, literal := range literals {
+		literal = strings.ToLower(literal)
+		for i := 0; i+3 <= len(literal); i++ {
+			if t := trigram(literal[i : i+3]); !seen[t] {
+				seen[t] = true
+				required = append(required, t)
+			}
+		}
+	}
+	return required
+}
+
+// collectLiterals appends the literal strings every match of re contains
+func collectLiterals(re *syntax.Regexp, literals *[]string) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		*literals = append(*literals, string(re.Rune))
+	case syntax.OpCapture, syntax.OpPlus:
+		collectLiterals(re.Sub[0], literals)
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			collectLiterals(re.Sub[0], literals)
+		}
+	case syntax.OpConcat:
+		var run strings.Builder
+		flush := func() {
+			if run.Len() > 0 {
+				*literals = append(*literals, run.String())
+				run.Reset()
+			}
+		}
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				run.WriteString(string(sub.Rune))
+				continue
+			}
+			flush()
+			collectLiterals(sub, literals)
+		}
+		flush()
+	}
+}
+
+// withinPath reports whether file lies in the directory within, or matches it as a glob
+func withinPath(file, within string) bool {
+	within = strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(within)), "./")
+	if within == "" || within == "." {
+		return true
+	}
+	if strings.ContainsAny(within, "*?[") {
+		if ok, _ := path.Match(within, file); ok {
+			return true
+		}
+		ok, _ := path.Match(within, path.Base(file))
+		return ok
+	}
+	within = strings.TrimSuffix(within, "/")
+	return file == within || strings.HasPrefix(file, within+"/")
+}
+
+func contextOf(lines []string, from, to int) []string {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+	var context []string
+	for i := from; i < to; i++ {
+		context = append(context, strings.TrimRight(lines[i], "\r"))
+	}
+	return context
+}
+
+// isBinary treats content with a NUL byte near the start as binary
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
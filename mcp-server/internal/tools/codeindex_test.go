@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+	"testing"
+)
+
+// writeTree writes files, keyed by slash-separated path, below root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func matchStrings(matches []CodeMatch) []string {
+	var out []string
+	for _, match := range matches {
+		out = append(out, match.File+":"+strings.TrimSpace(match.Text))
+	}
+	return out
+}
+
+func newTestIndex(t *testing.T) (*CodeIndex, string) {
+	t.Helper()
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"calc/calc.go": `package calc
+
+// Calculator adds numbers
+type Calculator struct{}
+
+func (c *Calculator) Add(a, b int) int { return a + b }
+
+func NewCalculator() *Calculator { return &Calculator{} }
+`,
+		"cmd/main.go": `package main
+
+func main() {
+	calc := NewCalculator()
+	calc.Add(1, 2)
+}
+`,
+		"web/app.ts":                    "export function addTotals(a: number) {\n  return a\n}\n",
+		"web/node_modules/lib/index.js": "function Add() {}\n",
+		".git/config":                   "[core] Add\n",
+		"assets/logo.png":               "PNG\x00Add",
+		"scripts/report.py":             "def add_rows(rows):\n    return sum(rows)\n",
+	})
+	index, err := BuildCodeIndex(root)
+	if err != nil {
+		t.Fatalf("BuildCodeIndex: %v", err)
+	}
+	return index, root
+}
+
+func TestBuildCodeIndex(t *testing.T) {
+	index, _ := newTestIndex(t)
+	// Dependencies, hidden directories and binaries are left out
+	if stats := index.Stats(); stats != (CodeIndexStats{Files: 4, Symbols: 6}) {
+		t.Errorf("stats = %+v, want 4 files and 6 symbols", stats)
+	}
+
+	if _, err := BuildCodeIndex(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("BuildCodeIndex indexed a directory that does not exist")
+	}
+}
+
+func TestCodeIndexSearch(t *testing.T) {
+	index, _ := newTestIndex(t)
+
+	tests := []struct {
+		name    string
+		pattern string
+		within  string
+		limit   int
+		want    []string
+	}{
+		{"literal", `Add\(`, "", 0, []string{"calc/calc.go:func (c *Calculator) Add(a, b int) int { return a + b }", "cmd/main.go:calc.Add(1, 2)"}},
+		{"case-insensitive", `(?i)add`, "web", 0, []string{"web/app.ts:export function addTotals(a: number) {"}},
+		{"directory", `NewCalculator`, "cmd/", 0, []string{"cmd/main.go:calc := NewCalculator()"}},
+		{"glob on the base name", `return`, "*.py", 0, []string{"scripts/report.py:return sum(rows)"}},
+		{"glob on the path", `package`, "calc/*.go", 0, []string{"calc/calc.go:package calc"}},
+		{"alternation searches every file", `addTotals|add_rows`, "", 0, []string{"scripts/report.py:def add_rows(rows):", "web/app.ts:export function addTotals(a: number) {"}},
+		{"limit", `package`, "", 1, []string{"calc/calc.go:package calc"}},
+		{"no file has the trigrams", `Subtract`, "", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := index.Search(tt.pattern, tt.within, 0, tt.limit)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if got := matchStrings(matches); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := index.Search(`Add(`, "", 0, 0); err == nil {
+		t.Error("Search accepted an invalid pattern")
+	}
+}
+
+func TestCodeIndexSearchContext(t *testing.T) {
+	index, _ := newTestIndex(t)
+	matches, err := index.Search(`calc\.Add`, "", 1, 0)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("Search = %v, %v, want one match", matches, err)
+	}
+	want := "cmd/main.go-4- \tcalc := NewCalculator()\ncmd/main.go:5: \tcalc.Add(1, 2)\ncmd/main.go-6- }"
+	if got := matches[0].String(); got != want {
+		t.Errorf("match =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRequiredTrigrams(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{`Calc`, []string{"cal", "alc"}},
+		{`foo\d+bar`, []string{"foo", "bar"}},
+		{`(abc)+d`, []string{"abc"}},
+		{`(?:xyz){2,}`, []string{"xyz"}},
+		{`a|bcd`, nil},
+		{`ab*`, nil},
+	}
+	for _, tt := range tests {
+		parsed, err := syntax.Parse(tt.pattern, syntax.Perl)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.pattern, err)
+		}
+		var want []uint32
+		for _, s := range tt.want {
+			want = append(want, trigram(s))
+		}
+		if got := requiredTrigrams(parsed.Simplify()); !reflect.DeepEqual(got, want) {
+			t.Errorf("requiredTrigrams(%q) = %v, want trigrams of %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestCodeIndexUpdateAndRefresh(t *testing.T) {
+	index, root := newTestIndex(t)
+
+	index.Update(filepath.Join(root, "cmd", "main.go"), "package main\n\nfunc main() { Subtract() }\n")
+	if matches, _ := index.Search(`Subtract`, "", 0, 0); !reflect.DeepEqual(matchStrings(matches), []string{"cmd/main.go:func main() { Subtract() }"}) {
+		t.Errorf("after Update matches = %v, want the new content", matchStrings(matches))
+	}
+	if matches, _ := index.Search(`calc\.Add`, "", 0, 0); len(matches) != 0 {
+		t.Errorf("after Update the old content still matches: %v", matchStrings(matches))
+	}
+	// Paths outside the root are ignored
+	index.Update("../elsewhere.go", "package elsewhere\n")
+
+	if err := os.Remove(filepath.Join(root, "scripts", "report.py")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, root, map[string]string{"calc/sub.go": "package calc\n\nfunc Subtract(a, b int) int { return a - b }\n"})
+	if err := index.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if stats := index.Stats(); stats.Files != 4 {
+		t.Errorf("files after Refresh = %d, want 4", stats.Files)
+	}
+	if matches, _ := index.Search(`add_rows`, "", 0, 0); len(matches) != 0 {
+		t.Errorf("deleted file still matches: %v", matchStrings(matches))
+	}
+	if matches, _ := index.Search(`Subtract`, "calc", 0, 0); len(matches) != 1 {
+		t.Errorf("new file matches = %v, want one", matchStrings(matches))
+	}
+}
+
+func TestFindSymbol(t *testing.T) {
+	index, _ := newTestIndex(t)
+
+	lookup := index.FindSymbol("Calculator.Add", 0)
+	var definitions []string
+	for _, symbol := range lookup.Definitions {
+		definitions = append(definitions, symbol.String())
+	}
+	if want := []string{"calc/calc.go:6 method Calculator.Add"}; !reflect.DeepEqual(definitions, want) {
+		t.Errorf("definitions = %v, want %v", definitions, want)
+	}
+	// The definition line is not its own reference, and \b keeps addTotals out
+	if got, want := matchStrings(lookup.References), []string{"cmd/main.go:calc.Add(1, 2)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("references = %v, want %v", got, want)
+	}
+
+	if lookup := index.FindSymbol("Other.Add", 0); len(lookup.Definitions) != 0 {
+		t.Errorf("definitions of Other.Add = %v, want none", lookup.Definitions)
+	}
+	if lookup := index.FindSymbol("Calculator.", 0); len(lookup.Definitions) != 0 || len(lookup.References) != 0 {
+		t.Errorf("lookup without a member = %+v, want empty", lookup)
+	}
+}
+
+func TestSearchSymbols(t *testing.T) {
+	index, _ := newTestIndex(t)
+
+	var got []string
+	for _, symbol := range index.SearchSymbols("ADD", 0) {
+		got = append(got, symbol.Name)
+	}
+	// Exact match first, then prefixes, then names containing the query
+	if want := []string{"Add", "add_rows", "addTotals"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchSymbols = %v, want %v", got, want)
+	}
+	if symbols := index.SearchSymbols("calc", 1); len(symbols) != 1 || symbols[0].Name != "Calculator" {
+		t.Errorf("SearchSymbols with limit 1 = %v, want Calculator", symbols)
+	}
+	if symbols := index.SearchSymbols("  ", 0); symbols != nil {
+		t.Errorf("SearchSymbols of a blank query = %v, want none", symbols)
+	}
+}
@@ -1,9 +1,14 @@
 package tools
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+
+	"mcp-server/internal/metrics"
 )
 
 type CommandValidator struct {
@@ -47,14 +52,64 @@ func (cv *CommandValidator) ValidateCommand(command string) error {
 	return nil
 }
 
+// allowedEntry returns the longest allowlist entry command starts with, or "other"
+func (cv *CommandValidator) allowedEntry(command string) string {
+	command = strings.TrimSpace(command)
+	entry := ""
+	for _, allowedCmd := range cv.allowed {
+		if strings.HasPrefix(command, allowedCmd) && len(allowedCmd) > len(entry) {
+			entry = allowedCmd
+		}
+	}
+	if entry == "" {
+		return "other"
+	}
+	return entry
+}
+
+// recordExecution counts command by allowlist entry and exit status
+func (cv *CommandValidator) recordExecution(command string, err error) {
+	status := "0"
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status = strconv.Itoa(exitErr.ExitCode())
+	} else if err != nil {
+		status = "error"
+	}
+	metrics.CommandExecuted(cv.allowedEntry(command), status)
+}
+
 func (cv *CommandValidator) ExecuteCommand(command string) (string, error) {
 	if err := cv.ValidateCommand(command); err != nil {
+		metrics.CommandExecuted(cv.allowedEntry(command), "blocked")
 		return "", err
 	}
 
 	cmd := exec.Command("sh", "-c", command)
 	cmd.Dir = cv.workingDir
 	output, err := cmd.CombinedOutput()
+	cv.recordExecution(command, err)
+
+	return string(output), err
+}
+
+// ExecuteCommandIn runs command in dir, which must be inside the working directory
+func (cv *CommandValidator) ExecuteCommandIn(dir, command string) (string, error) {
+	if err := cv.ValidateCommand(command); err != nil {
+		metrics.CommandExecuted(cv.allowedEntry(command), "blocked")
+		return "", err
+	}
+
+	runDir := filepath.Join(cv.workingDir, dir)
+	rel, err := filepath.Rel(cv.workingDir, runDir)
+	if err != nil || filepath.IsAbs(dir) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("access denied: %s is outside working directory", dir)
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = runDir
+	output, err := cmd.CombinedOutput()
+	cv.recordExecution(command, err)
 
 	return string(output), err
-}
\ No newline at end of file
+}
@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Generated documentation is written in marked regions; regeneration replaces the
+// regions and keeps everything outside them, so hand-written sections survive
+const (
+	regionBegin = "<!-- generated:begin "
+	regionEnd   = "<!-- generated:end "
+)
+
+// docSegment is a run of lines that is either a generated region or human text
+type docSegment struct {
+	region string // empty for human text
+	text   string
+}
+
+// generatedRegion wraps body in the markers MergeGeneratedRegions looks for
+func generatedRegion(name, body string) string {
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	return fmt.Sprintf("%s%s (replaced by initialize_project_patterns, edit outside this region) -->\n%s%s%s -->\n",
+		regionBegin, name, body, regionEnd, name)
+}
+
+// legacySections are the "## " sections written before generated output was marked
+// with regions; they are replaced, not kept, when such a document is merged
+var legacySections = map[string]bool{
+	"Project Overview":    true,
+	"Architecture":        true,
+	"Discovered Patterns": true,
+	"Dependencies":        true,
+	"Examples":            true,
+	"Files":               true,
+}
+
+// MergeGeneratedRegions refreshes the generated regions of an existing document.
+// Text outside regions is kept as is, regions that are no longer generated are
+// dropped, and new regions are placed after the region generated before them. A
+// document generated before regions existed has its generated sections replaced
+// and any sections added by hand kept after the regions; any other document without
+// regions is kept whole with the generated regions appended.
+func MergeGeneratedRegions(existing, generated string) string {
+	if isLegacyGenerated(existing) {
+		existing = handWrittenSections(existing)
+		if existing == "" {
+			return generated
+		}
+		existing = generated + "\n" + existing
+	}
+	if strings.TrimSpace(existing) == "" {
+		return generated
+	}
+
+	fresh := make(map[string]string)
+	var order []string
+	for _, segment := range splitRegions(generated) {
+		if segment.region != "" {
+			fresh[segment.region] = segment.text
+			order = append(order, segment.region)
+		}
+	}
+
+	var merged []docSegment
+	placed := make(map[string]bool)
+	for _, segment := range splitRegions(existing) {
+		if segment.region == "" {
+			merged = append(merged, segment)
+			continue
+		}
+		if text, ok := fresh[segment.region]; ok && !placed[segment.region] {
+			merged = append(merged, docSegment{region: segment.region, text: text})
+			placed[segment.region] = true
+		}
+	}
+
+	for i, name := range order {
+		if placed[name] {
+			continue
+		}
+		at := len(merged)
+		if i > 0 {
+			if after := regionIndex(merged, order[i-1]); after >= 0 {
+				at = after + 1
+			}
+		} else if len(order) > 1 {
+			if before := regionIndex(merged, order[1]); before >= 0 {
+				at = before
+			}
+		}
+		segment := docSegment{region: name, text: fresh[name]}
+		if at == len(merged) && len(merged) > 0 && !strings.HasSuffix(merged[len(merged)-1].text, "\n\n") {
+			segment.text = "\n" + segment.text
+		}
+		merged = append(merged[:at], append([]docSegment{segment}, merged[at:]...)...)
+		placed[name] = true
+	}
+
+	var sb strings.Builder
+	for _, segment := range merged {
+		sb.WriteString(segment.text)
+	}
+	return sb.String()
+}
+
+// isLegacyGenerated reports whether doc was written whole by the generator before
+// it marked its output with regions: the project overview or a pattern document
+func isLegacyGenerated(doc string) bool {
+	if strings.Contains(doc, regionBegin) {
+		return false
+	}
+	var lines []string
+	for _, line := range strings.Split(doc, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) == 2 {
+			break
+		}
+	}
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "# ") {
+		return false
+	}
+	return (lines[0] == "# Project Patterns Documentation" && lines[1] == "## Project Overview") ||
+		strings.HasPrefix(lines[1], "**Type**: ")
+}
+
+// handWrittenSections returns the "## " sections of a legacy document that the
+// generator never wrote, dropping its title and generated sections
+func handWrittenSections(doc string) string {
+	var sb strings.Builder
+	keep := false
+	for _, line := range strings.SplitAfter(doc, "\n") {
+		if heading, ok := strings.CutPrefix(strings.TrimSpace(line), "## "); ok {
+			keep = !legacySections[strings.TrimSpace(heading)]
+		}
+		if keep {
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
+
+// splitRegions cuts a document into generated regions and the text between them;
+// a begin marker without its end marker is treated as human text
+func splitRegions(doc string) []docSegment {
+	var segments []docSegment
+	lines := strings.SplitAfter(doc, "\n")
+	var human strings.Builder
+	for i := 0; i < len(lines); i++ {
+		name := markerName(lines[i], regionBegin)
+		end := -1
+		if name != "" {
+			for j := i + 1; j < len(lines); j++ {
+				if markerName(lines[j], regionEnd) == name {
+					end = j
+					break
+				}
+			}
+		}
+		if end < 0 {
+			human.WriteString(lines[i])
+			continue
+		}
+		if human.Len() > 0 {
+			segments = append(segments, docSegment{text: human.String()})
+			human.Reset()
+		}
+		segments = append(segments, docSegment{region: name, text: strings.Join(lines[i:end+1], "")})
+		i = end
+	}
+	if human.Len() > 0 {
+		segments = append(segments, docSegment{text: human.String()})
+	}
+	return segments
+}
+
+// markerName returns the region name of a marker line of the given kind
+func markerName(line, marker string) string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, marker) || !strings.HasSuffix(line, "-->") {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, marker))
+	if len(fields) == 0 || fields[0] == "-->" {
+		return ""
+	}
+	return fields[0]
+}
+
+func regionIndex(segments []docSegment, name string) int {
+	for i, segment := range segments {
+		if segment.region == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,72 @@
+package tools
+
+import "testing"
+
+func TestMergeGeneratedRegions(t *testing.T) {
+	generated := "# Project Patterns Documentation\n\n" +
+		generatedRegion("overview", "## Project Overview\n\n- **Language**: Go\n") + "\n" +
+		generatedRegion("dependencies", "## Dependencies\n\n- github.com/google/uuid v1.6.0\n")
+
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{
+			name:     "new document",
+			existing: "",
+			want:     generated,
+		},
+		{
+			name: "regions are replaced and text around them kept",
+			existing: "# Project Patterns Documentation\n\nRead this first.\n\n" +
+				generatedRegion("overview", "## Project Overview\n\n- **Language**: Python\n") + "\n" +
+				"## Team notes\n\nKeep handlers thin.\n\n" +
+				generatedRegion("dependencies", "## Dependencies\n") +
+				generatedRegion("removed", "## Gone\n"),
+			want: "# Project Patterns Documentation\n\nRead this first.\n\n" +
+				generatedRegion("overview", "## Project Overview\n\n- **Language**: Go\n") + "\n" +
+				"## Team notes\n\nKeep handlers thin.\n\n" +
+				generatedRegion("dependencies", "## Dependencies\n\n- github.com/google/uuid v1.6.0\n"),
+		},
+		{
+			name:     "new region goes after the one generated before it",
+			existing: "# Project Patterns Documentation\n\n" + generatedRegion("overview", "## Project Overview\n") + "\n## Team notes\n",
+			want: "# Project Patterns Documentation\n\n" + generatedRegion("overview", "## Project Overview\n\n- **Language**: Go\n") +
+				generatedRegion("dependencies", "## Dependencies\n\n- github.com/google/uuid v1.6.0\n") + "\n## Team notes\n",
+		},
+		{
+			name: "legacy overview is replaced and hand-written sections kept",
+			existing: "# Project Patterns Documentation\n\n## Project Overview\n\n- **Language**: Python\n\n" +
+				"## Discovered Patterns\n\n### Handlers\n- **Type**: handler\n\n" +
+				"## Team notes\n\nKeep handlers thin.\n\n### Naming\nShort names.\n\n" +
+				"## Dependencies\n\n- flask\n",
+			want: generated + "\n## Team notes\n\nKeep handlers thin.\n\n### Naming\nShort names.\n\n",
+		},
+		{
+			name:     "legacy pattern document is replaced",
+			existing: "# Pointer constructors\n\n**Type**: constructor\n\n**Frequency**: 3 occurrences in 2 files\n\n## Files\n\n- a.go\n\n",
+			want:     generated,
+		},
+		{
+			name:     "hand-written document without regions is appended to",
+			existing: "# Our conventions\n\n## Architecture\n\nHexagonal.\n",
+			want:     "# Our conventions\n\n## Architecture\n\nHexagonal.\n\n" + generated[len("# Project Patterns Documentation\n\n"):],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeGeneratedRegions(tt.existing, generated); got != tt.want {
+				t.Errorf("merged =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitRegionsUnterminated(t *testing.T) {
+	doc := "intro\n<!-- generated:begin overview -->\nbody\n"
+	segments := splitRegions(doc)
+	if len(segments) != 1 || segments[0].region != "" || segments[0].text != doc {
+		t.Errorf("segments = %+v, want the whole document as human text", segments)
+	}
+}
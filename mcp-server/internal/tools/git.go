@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os/exec"
 	"strconv"
+	"strings"
 )
 
 type GitOperations struct {
@@ -54,6 +55,25 @@ func (g *GitOperations) GetDiffNameOnly() (string, error) {
 	return string(output), nil
 }
 
+// GetUntrackedFiles lists new files git does not track yet, honouring .gitignore
+func (g *GitOperations) GetUntrackedFiles() ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
+	cmd.Dir = g.workingDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
 // GetDiffCached returns staged changes
 func (g *GitOperations) GetDiffCached() (string, error) {
 	cmd := exec.Command("git", "diff", "--cached")
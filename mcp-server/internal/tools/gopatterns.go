@@ -0,0 +1,614 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GoConvention is one observation of a coding convention in a Go file
+type GoConvention struct {
+	Type    string `json:"type"`            // handler, constructor, error_handling, receiver, test, model or interface
+	Value   string `json:"value"`           // the cluster the observation belongs to, e.g. "pointer" for a constructor
+	Name    string `json:"name"`            // function, method or type it was observed on
+	Owner   string `json:"owner,omitempty"` // receiver type for methods
+	Line    int    `json:"line"`
+	Example string `json:"example,omitempty"` // source excerpt
+}
+
+// ConventionViolation is a place where a file departs from a documented rule
+type ConventionViolation struct {
+	Rule     string
+	Expected string
+	Actual   string
+	Name     string
+	Line     int
+}
+
+// goPatternRules maps a pattern type to the rule that enforces its dominant cluster
+// and the cluster values that may become that rule
+var goPatternRules = map[string]struct {
+	rule   string
+	values map[string]bool
+}{
+	"handler":        {"handler_signature", nil},
+	"constructor":    {"constructor_returns", map[string]bool{"pointer": true, "interface": true, "value": true}},
+	"error_handling": {"error_wrapping", map[string]bool{"%w": true, "errors.Wrap": true}},
+	"receiver":       {"receiver_naming", map[string]bool{"initials": true, "first_letter": true, "self": true}},
+	"test":           {"test_style", map[string]bool{"table_subtests": true, "table": true}},
+}
+
+// goClusterNames describe cluster values in generated documentation
+var goClusterNames = map[string]string{
+	"pointer":        "Constructors return a pointer to their struct",
+	"interface":      "Constructors return an interface",
+	"value":          "Constructors return a struct value",
+	"%w":             "Errors are wrapped with fmt.Errorf and %w",
+	"%v":             "Errors are formatted into new errors with fmt.Errorf without %w",
+	"errors.Wrap":    "Errors are wrapped with errors.Wrap",
+	"errors.New":     "Sentinel and ad-hoc errors are created with errors.New",
+	"initials":       "Receivers are named after the initials of their type",
+	"first_letter":   "Receivers are named with the first letter of their type",
+	"self":           "Receivers are named self or this",
+	"other":          "Receivers use free-form names",
+	"ambiguous":      "Receivers named with the one letter that is both the initials and the first letter of their type",
+	"table_subtests": "Table-driven tests running each case with t.Run",
+	"table":          "Table-driven tests looping over cases without subtests",
+	"inline_cases":   "Tests checking several cases inline",
+	"single":         "Single-case tests",
+}
+
+// minRuleOccurrences and minRuleShare decide when a dominant cluster is a convention
+const (
+	minRuleOccurrences = 3
+	minRuleShare       = 0.7
+	maxExampleLines    = 14
+)
+
+var handlerParamTypes = map[string]bool{
+	"http.ResponseWriter": true, "*fiber.Ctx": true, "*gin.Context": true, "echo.Context": true,
+}
+
+// goAnalyzer inspects one parsed Go file
+type goAnalyzer struct {
+	fset       *token.FileSet
+	file       *ast.File
+	src        string
+	filename   string
+	interfaces map[string]bool // interface type names known to the analysis
+	structs    map[string]bool
+}
+
+// AnalyzeGoFile reports the conventions a Go source file follows; interfaces names
+// interface types declared elsewhere in the project and may be nil
+func AnalyzeGoFile(filename, content string, interfaces map[string]bool) ([]GoConvention, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	ga := &goAnalyzer{fset: fset, file: file, src: content, filename: filename,
+		interfaces: make(map[string]bool), structs: make(map[string]bool)}
+	for name := range interfaces {
+		ga.interfaces[name] = true
+	}
+	for name, kind := range declaredTypes(file) {
+		if kind == "interface" {
+			ga.interfaces[name] = true
+		} else if kind == "struct" {
+			ga.structs[name] = true
+		}
+	}
+
+	var found []GoConvention
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			found = append(found, ga.function(decl)...)
+		case *ast.GenDecl:
+			found = append(found, ga.typeDecls(decl)...)
+		}
+	}
+	found = append(found, ga.errorHandling()...)
+	return found, nil
+}
+
+// declaredTypes maps the type names a file declares to "interface", "struct" or "other"
+func declaredTypes(file *ast.File) map[string]string {
+	kinds := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			switch typeSpec.Type.(type) {
+			case *ast.InterfaceType:
+				kinds[typeSpec.Name.Name] = "interface"
+			case *ast.StructType:
+				kinds[typeSpec.Name.Name] = "struct"
+			default:
+				kinds[typeSpec.Name.Name] = "other"
+			}
+		}
+	}
+	return kinds
+}
+
+func (ga *goAnalyzer) function(fn *ast.FuncDecl) []GoConvention {
+	var found []GoConvention
+	line := ga.fset.Position(fn.Pos()).Line
+
+	if shape := ga.handlerShape(fn.Type); shape != "" {
+		found = append(found, GoConvention{Type: "handler", Value: shape, Name: fn.Name.Name, Line: line, Example: ga.excerpt(fn)})
+	}
+
+	if fn.Recv == nil && (fn.Name.Name == "New" || strings.HasPrefix(fn.Name.Name, "New") && len(fn.Name.Name) > 3 && unicode.IsUpper(rune(fn.Name.Name[3]))) {
+		if kind := ga.constructorKind(fn.Type); kind != "" {
+			found = append(found, GoConvention{Type: "constructor", Value: kind, Name: fn.Name.Name, Line: line, Example: ga.signature(fn)})
+		}
+	}
+
+	if fn.Recv != nil && len(fn.Recv.List) == 1 && len(fn.Recv.List[0].Names) == 1 {
+		receiver := fn.Recv.List[0]
+		owner := strings.TrimPrefix(types.ExprString(receiver.Type), "*")
+		if idx := strings.Index(owner, "["); idx >= 0 {
+			owner = owner[:idx]
+		}
+		name := receiver.Names[0].Name
+		if name != "_" {
+			found = append(found, GoConvention{Type: "receiver", Value: receiverStyle(name, owner), Name: name, Owner: owner, Line: line, Example: ga.signature(fn)})
+		}
+	}
+
+	if strings.HasSuffix(ga.filename, "_test.go") && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Test") && fn.Body != nil {
+		found = append(found, GoConvention{Type: "test", Value: testStyle(fn.Body), Name: fn.Name.Name, Line: line, Example: ga.excerpt(fn)})
+	}
+	return found
+}
+
+// handlerShape renders a function type such as "func(http.ResponseWriter, *http.Request)"
+// when one of its parameters belongs to a known HTTP framework
+func (ga *goAnalyzer) handlerShape(fnType *ast.FuncType) string {
+	isHandler := false
+	var params, results []string
+	for _, field := range fnType.Params.List {
+		typ := types.ExprString(field.Type)
+		if handlerParamTypes[typ] {
+			isHandler = true
+		}
+		for i := 0; i < max(1, len(field.Names)); i++ {
+			params = append(params, typ)
+		}
+	}
+	if !isHandler {
+		return ""
+	}
+	if fnType.Results != nil {
+		for _, field := range fnType.Results.List {
+			for i := 0; i < max(1, len(field.Names)); i++ {
+				results = append(results, types.ExprString(field.Type))
+			}
+		}
+	}
+
+	shape := "func(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+	case 1:
+		shape += " " + results[0]
+	default:
+		shape += " (" + strings.Join(results, ", ") + ")"
+	}
+	return shape
+}
+
+// constructorKind classifies what a NewX function returns first
+func (ga *goAnalyzer) constructorKind(fnType *ast.FuncType) string {
+	if fnType.Results == nil || len(fnType.Results.List) == 0 {
+		return ""
+	}
+	switch result := fnType.Results.List[0].Type.(type) {
+	case *ast.StarExpr:
+		return "pointer"
+	case *ast.Ident:
+		if ga.interfaces[result.Name] {
+			return "interface"
+		}
+		if ga.structs[result.Name] {
+			return "value"
+		}
+	case *ast.SelectorExpr:
+		if ga.interfaces[result.Sel.Name] {
+			return "interface"
+		}
+	}
+	return ""
+}
+
+// receiverStyle classifies a receiver name relative to its type name. For a
+// single-word type such as Calculator, c is both its initials and its first
+// letter, so it says nothing about which of the two a project prefers.
+func receiverStyle(name, owner string) string {
+	switch {
+	case name == "self" || name == "this":
+		return "self"
+	case len(name) == 1 && name == initials(owner):
+		return "ambiguous"
+	case name == initials(owner) || len(name) > 1 && strings.HasSuffix(initials(owner), name):
+		// tl for SeniorTechLead abbreviates the type the same way
+		return "initials"
+	case len(owner) > 0 && name == strings.ToLower(owner[:1]):
+		return "first_letter"
+	default:
+		return "other"
+	}
+}
+
+// initials lowercases the first letter of every word of a CamelCase name
+func initials(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if i == 0 || unicode.IsUpper(r) && !unicode.IsUpper(rune(name[i-1])) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+// testStyle tells table-driven tests from tests that check cases inline
+func testStyle(body *ast.BlockStmt) string {
+	style := ""
+	checks := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.RangeStmt:
+			if style == "" {
+				style = "table"
+			}
+			if callsMethod(n.Body, "Run") {
+				style = "table_subtests"
+			}
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok {
+				switch sel.Sel.Name {
+				case "Error", "Errorf", "Fatal", "Fatalf":
+					checks++
+				}
+			}
+		}
+		return true
+	})
+	if style != "" {
+		return style
+	}
+	if checks >= 3 {
+		return "inline_cases"
+	}
+	return "single"
+}
+
+func callsMethod(node ast.Node, method string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == method {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+func (ga *goAnalyzer) typeDecls(gen *ast.GenDecl) []GoConvention {
+	if gen.Tok != token.TYPE {
+		return nil
+	}
+	var found []GoConvention
+	for _, spec := range gen.Specs {
+		typeSpec := spec.(*ast.TypeSpec)
+		name := typeSpec.Name.Name
+		line := ga.fset.Position(typeSpec.Pos()).Line
+		switch typeSpec.Type.(type) {
+		case *ast.StructType:
+			for _, suffix := range []string{"Request", "Response", "DTO", "Model"} {
+				if strings.HasSuffix(name, suffix) && name != suffix {
+					found = append(found, GoConvention{Type: "model", Value: suffix, Name: name, Line: line, Example: ga.source(typeSpec)})
+					break
+				}
+			}
+		case *ast.InterfaceType:
+			for _, suffix := range []string{"Service", "Repository", "Store", "Client"} {
+				if strings.HasSuffix(name, suffix) && name != suffix {
+					found = append(found, GoConvention{Type: "interface", Value: suffix, Name: name, Line: line, Example: ga.source(typeSpec)})
+					break
+				}
+			}
+		}
+	}
+	return found
+}
+
+// errorHandling classifies every call that creates or wraps an error
+func (ga *goAnalyzer) errorHandling() []GoConvention {
+	var found []GoConvention
+	ast.Inspect(ga.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		value := ""
+		switch {
+		case pkg.Name == "fmt" && sel.Sel.Name == "Errorf" && len(call.Args) > 1:
+			format, _ := call.Args[0].(*ast.BasicLit)
+			if format != nil && strings.Contains(format.Value, "%w") {
+				value = "%w"
+			} else if passesError(call.Args[1:]) {
+				value = "%v"
+			}
+		case pkg.Name == "errors" && (sel.Sel.Name == "Wrap" || sel.Sel.Name == "Wrapf"):
+			value = "errors.Wrap"
+		case pkg.Name == "errors" && sel.Sel.Name == "New":
+			value = "errors.New"
+		}
+		if value != "" {
+			line := ga.fset.Position(call.Pos()).Line
+			found = append(found, GoConvention{Type: "error_handling", Value: value, Name: ga.enclosingFunc(call.Pos()), Line: line, Example: ga.line(line)})
+		}
+		return true
+	})
+	return found
+}
+
+// passesError reports whether an argument looks like an error value
+func passesError(args []ast.Expr) bool {
+	for _, arg := range args {
+		if ident, ok := arg.(*ast.Ident); ok && (ident.Name == "err" || strings.HasSuffix(ident.Name, "Err")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ga *goAnalyzer) enclosingFunc(pos token.Pos) string {
+	for _, decl := range ga.file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Pos() <= pos && pos <= fn.End() {
+			return fn.Name.Name
+		}
+	}
+	return ""
+}
+
+// source returns the text of node
+func (ga *goAnalyzer) source(node ast.Node) string {
+	start, end := ga.fset.Position(node.Pos()).Offset, ga.fset.Position(node.End()).Offset
+	if start < 0 || end > len(ga.src) || start >= end {
+		return ""
+	}
+	return ga.src[start:end]
+}
+
+// excerpt returns a declaration cut down to its first lines
+func (ga *goAnalyzer) excerpt(node ast.Node) string {
+	lines := strings.Split(ga.source(node), "\n")
+	if len(lines) > maxExampleLines {
+		lines = append(lines[:maxExampleLines-1], "\t// ...", "}")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// signature returns a function declaration without its body
+func (ga *goAnalyzer) signature(fn *ast.FuncDecl) string {
+	text := ga.source(fn)
+	if fn.Body != nil {
+		if idx := ga.fset.Position(fn.Body.Pos()).Offset - ga.fset.Position(fn.Pos()).Offset; idx > 0 && idx <= len(text) {
+			text = strings.TrimSpace(text[:idx])
+		}
+	}
+	return text
+}
+
+func (ga *goAnalyzer) line(n int) string {
+	lines := strings.Split(ga.src, "\n")
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[n-1])
+}
+
+// goInterfaces lists the interface types a Go file declares
+func goInterfaces(filename, content string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for typeName, kind := range declaredTypes(file) {
+		if kind == "interface" {
+			names = append(names, typeName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// clusterGoConventions groups the conventions observed per file into patterns;
+// each type's dominant cluster becomes a rule when it is followed widely enough
+func clusterGoConventions(observed map[string][]GoConvention) []DiscoveredPattern {
+	clusters := make(map[string]*DiscoveredPattern)
+	totals := make(map[string]int)
+	for _, name := range sortedKeys(observed) {
+		for _, convention := range observed[name] {
+			if convention.Value == "ambiguous" {
+				continue
+			}
+			key := convention.Type + "|" + convention.Value
+			pattern := clusters[key]
+			if pattern == nil {
+				pattern = &DiscoveredPattern{
+					Name:        clusterName(convention),
+					Type:        convention.Type,
+					Description: clusterName(convention),
+					Examples:    []string{},
+					Files:       []string{},
+					Value:       convention.Value,
+				}
+				clusters[key] = pattern
+			}
+			pattern.Frequency++
+			totals[convention.Type]++
+			if len(pattern.Examples) < 5 && convention.Example != "" {
+				pattern.Examples = append(pattern.Examples, fmt.Sprintf("// %s:%d\n%s", name, convention.Line, convention.Example))
+			}
+			if len(pattern.Files) == 0 || pattern.Files[len(pattern.Files)-1] != name {
+				pattern.Files = append(pattern.Files, name)
+			}
+		}
+	}
+
+	var patterns []DiscoveredPattern
+	for _, pattern := range clusters {
+		pattern.Share = float64(pattern.Frequency) / float64(totals[pattern.Type])
+		if spec, ok := goPatternRules[pattern.Type]; ok && pattern.Frequency >= minRuleOccurrences && pattern.Share >= minRuleShare {
+			if spec.values == nil || spec.values[pattern.Value] {
+				pattern.Rule = spec.rule + ": " + pattern.Value
+			}
+		}
+		patterns = append(patterns, *pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Type != patterns[j].Type {
+			return patterns[i].Type < patterns[j].Type
+		}
+		return patterns[i].Frequency > patterns[j].Frequency
+	})
+	return patterns
+}
+
+func clusterName(convention GoConvention) string {
+	switch convention.Type {
+	case "handler":
+		return "HTTP handlers: " + convention.Value
+	case "model":
+		return "Data types named *" + convention.Value
+	case "interface":
+		return "Interfaces named *" + convention.Value
+	}
+	if name, ok := goClusterNames[convention.Value]; ok {
+		return name
+	}
+	return convention.Type + ": " + convention.Value
+}
+
+// ParsePatternRules reads the "- rule: value" lines of a pattern document's Rules section
+func ParsePatternRules(doc string) map[string]string {
+	rules := make(map[string]string)
+	inRules := false
+	for _, line := range strings.Split(doc, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "## ") {
+			inRules = trimmed == "## Rules"
+			continue
+		}
+		if !inRules || !strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		rule, value, ok := strings.Cut(strings.TrimPrefix(trimmed, "- "), ":")
+		if ok {
+			rules[strings.Trim(strings.TrimSpace(rule), "`")] = strings.Trim(strings.TrimSpace(value), "`")
+		}
+	}
+	return rules
+}
+
+// CheckGoConventions reports where a Go file departs from the documented rules,
+// plus methods of one type that disagree on the receiver name
+func CheckGoConventions(filename, content string, rules map[string]string) ([]ConventionViolation, error) {
+	found, err := AnalyzeGoFile(filename, content, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []ConventionViolation
+	receivers := make(map[string]string)
+	for _, convention := range found {
+		if convention.Type == "receiver" {
+			if previous, ok := receivers[convention.Owner]; ok && previous != convention.Name {
+				violations = append(violations, ConventionViolation{
+					Rule:     "receiver_consistency",
+					Expected: fmt.Sprintf("methods of %s use the receiver name %s", convention.Owner, previous),
+					Actual:   fmt.Sprintf("receiver %s", convention.Name),
+					Name:     convention.Owner,
+					Line:     convention.Line,
+				})
+			} else if !ok {
+				receivers[convention.Owner] = convention.Name
+			}
+		}
+
+		spec, ok := goPatternRules[convention.Type]
+		if !ok {
+			continue
+		}
+		expected, ok := rules[spec.rule]
+		if !ok || expected == convention.Value {
+			continue
+		}
+		if spec.values != nil && !relevantViolation(convention, expected) {
+			continue
+		}
+		violations = append(violations, ConventionViolation{
+			Rule:     spec.rule,
+			Expected: describeRule(spec.rule, expected),
+			Actual:   clusterName(convention),
+			Name:     convention.Name,
+			Line:     convention.Line,
+		})
+	}
+	return violations, nil
+}
+
+// relevantViolation filters observations that do not contradict a rule: creating
+// sentinel errors is fine under any wrapping rule, test style rules are about how
+// tables run their cases so tests without a table are fine, and an ambiguous
+// receiver meets both the initials and the first letter rule
+func relevantViolation(convention GoConvention, expected string) bool {
+	switch convention.Value {
+	case "errors.New", "single", "inline_cases":
+		return false
+	case "ambiguous":
+		return expected != "initials" && expected != "first_letter"
+	}
+	return true
+}
+
+func describeRule(rule, value string) string {
+	if name, ok := goClusterNames[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("%s: %s", rule, value)
+}
+
+// isGoSource reports whether path is Go code pattern discovery should read
+func isGoSource(path string) bool {
+	path = filepath.ToSlash(path)
+	return strings.HasSuffix(path, ".go") && !strings.Contains(path, "vendor/") && !strings.Contains(path, ".git/") && !strings.Contains(path, "testdata/")
+}
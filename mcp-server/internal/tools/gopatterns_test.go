@@ -0,0 +1,304 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const conventionsSource = `package shop
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type OrderService interface {
+	Place(o Order) error
+}
+
+type CreateOrderRequest struct {
+	Item string
+}
+
+type Order struct {
+	Item string
+}
+
+type orderService struct{}
+
+func NewOrderService() OrderService {
+	return &orderService{}
+}
+
+func NewOrder(item string) Order {
+	return Order{Item: item}
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+type Handler struct {
+	svc OrderService
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Place(Order{}); err != nil {
+		http.Error(w, fmt.Errorf("placing order: %w", err).Error(), 500)
+	}
+}
+
+func (s *orderService) Place(o Order) error {
+	if err := validate(o); err != nil {
+		return fmt.Errorf("invalid order: %v", err)
+	}
+	return nil
+}
+
+func validate(o Order) error { return nil }
+`
+
+func TestAnalyzeGoFile(t *testing.T) {
+	found, err := AnalyzeGoFile("shop/orders.go", conventionsSource, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeGoFile: %v", err)
+	}
+
+	var got []string
+	for _, convention := range found {
+		got = append(got, convention.Type+" "+convention.Value+" "+convention.Name)
+	}
+	want := []string{
+		"interface Service OrderService",
+		"model Request CreateOrderRequest",
+		"constructor interface NewOrderService",
+		"constructor value NewOrder",
+		"constructor pointer NewHandler",
+		"handler func(http.ResponseWriter, *http.Request) Create",
+		"receiver ambiguous h",
+		"receiver other s",
+		"error_handling errors.New ",
+		"error_handling %w Create",
+		"error_handling %v Place",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("conventions =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReceiverStyle(t *testing.T) {
+	tests := []struct {
+		name, owner string
+		want        string
+	}{
+		{"self", "Server", "self"},
+		{"this", "Server", "self"},
+		{"c", "Calculator", "ambiguous"},
+		{"stl", "SeniorTechLead", "initials"},
+		{"tl", "SeniorTechLead", "initials"},
+		{"oc", "OrderClient", "initials"},
+		{"s", "SeniorTechLead", "first_letter"},
+		{"calc", "Calculator", "other"},
+		{"x", "Calculator", "other"},
+	}
+	for _, tt := range tests {
+		if got := receiverStyle(tt.name, tt.owner); got != tt.want {
+			t.Errorf("receiverStyle(%q, %q) = %q, want %q", tt.name, tt.owner, got, tt.want)
+		}
+	}
+}
+
+func TestTestStyle(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "table with subtests",
+			body: `for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {})
+	}`,
+			want: "table_subtests",
+		},
+		{
+			name: "table without subtests",
+			body: `for _, tt := range tests {
+		if got := f(tt.in); got != tt.want {
+			t.Errorf("f(%d) = %d", tt.in, got)
+		}
+	}`,
+			want: "table",
+		},
+		{
+			name: "inline cases",
+			body: `if f(1) != 1 {
+		t.Error("f(1)")
+	}
+	if f(2) != 4 {
+		t.Error("f(2)")
+	}
+	if f(3) != 9 {
+		t.Fatal("f(3)")
+	}`,
+			want: "inline_cases",
+		},
+		{name: "single", body: `if f(1) != 1 { t.Fatal("f(1)") }`, want: "single"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "package calc\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) {\n\t" + tt.body + "\n}\n"
+			found, err := AnalyzeGoFile("calc_test.go", source, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeGoFile: %v", err)
+			}
+			if len(found) != 1 || found[0].Type != "test" || found[0].Value != tt.want {
+				t.Errorf("conventions = %+v, want one %s test", found, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterGoConventions(t *testing.T) {
+	observed := map[string][]GoConvention{
+		"a.go": {
+			{Type: "constructor", Value: "pointer", Name: "NewA"},
+			{Type: "constructor", Value: "pointer", Name: "NewB"},
+			{Type: "receiver", Value: "first_letter", Name: "s", Owner: "SeniorTechLead"},
+			{Type: "receiver", Value: "ambiguous", Name: "c", Owner: "Calculator"},
+		},
+		"b.go": {
+			{Type: "constructor", Value: "pointer", Name: "NewC"},
+			{Type: "receiver", Value: "first_letter", Name: "h", Owner: "HTTPHandler"},
+			{Type: "receiver", Value: "first_letter", Name: "o", Owner: "OrderStore"},
+			{Type: "receiver", Value: "ambiguous", Name: "r", Owner: "Repo"},
+			{Type: "receiver", Value: "ambiguous", Name: "s", Owner: "Server"},
+			{Type: "error_handling", Value: "%w", Name: "Load"},
+			{Type: "error_handling", Value: "%w", Name: "Save"},
+			{Type: "error_handling", Value: "%v", Name: "Close"},
+		},
+	}
+
+	rules := make(map[string]string)
+	byValue := make(map[string]DiscoveredPattern)
+	for _, pattern := range clusterGoConventions(observed) {
+		byValue[pattern.Type+"|"+pattern.Value] = pattern
+		if pattern.Rule != "" {
+			rule, value, _ := strings.Cut(pattern.Rule, ": ")
+			rules[rule] = value
+		}
+	}
+
+	// Ambiguous receivers neither vote nor dilute the first-letter share
+	want := map[string]string{"constructor_returns": "pointer", "receiver_naming": "first_letter"}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %v, want %v", rules, want)
+	}
+	if _, ok := byValue["receiver|ambiguous"]; ok {
+		t.Error("ambiguous receivers formed a cluster")
+	}
+	if pattern := byValue["constructor|pointer"]; pattern.Frequency != 3 || pattern.Share != 1 || !reflect.DeepEqual(pattern.Files, []string{"a.go", "b.go"}) {
+		t.Errorf("pointer constructors = %+v, want 3 in a.go and b.go with share 1", pattern)
+	}
+	// Two of three wraps with %w stay below the share a rule needs
+	if pattern := byValue["error_handling|%w"]; pattern.Rule != "" {
+		t.Errorf("error wrapping became rule %q at share %.2f", pattern.Rule, pattern.Share)
+	}
+}
+
+func TestParsePatternRules(t *testing.T) {
+	doc := `# Patterns
+
+## Rules
+- ` + "`receiver_naming`: `initials`" + `
+- constructor_returns: pointer
+- not a rule
+
+## Examples
+- error_wrapping: %v
+`
+	want := map[string]string{"receiver_naming": "initials", "constructor_returns": "pointer"}
+	if got := ParsePatternRules(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePatternRules = %v, want %v", got, want)
+	}
+}
+
+func TestCheckGoConventions(t *testing.T) {
+	source := `package calc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type Calculator struct{}
+
+type SeniorTechLead struct{}
+
+func (c *Calculator) Add(a, b int) int { return a + b }
+
+func (calc *Calculator) Sub(a, b int) int { return a - b }
+
+func (s *SeniorTechLead) Review() error {
+	if err := errors.New("x"); err != nil {
+		return fmt.Errorf("review: %v", err)
+	}
+	return nil
+}
+
+func TestInline(t *testing.T) {
+	if 1 != 1 {
+		t.Error("a")
+	}
+	if 2 != 2 {
+		t.Error("b")
+	}
+	if 3 != 3 {
+		t.Error("c")
+	}
+}
+
+func TestLoop(t *testing.T) {
+	for _, n := range []int{1, 2} {
+		if n == 0 {
+			t.Errorf("n = %d", n)
+		}
+	}
+}
+`
+	rules := map[string]string{
+		"receiver_naming": "initials",
+		"error_wrapping":  "%w",
+		"test_style":      "table_subtests",
+	}
+
+	violations, err := CheckGoConventions("calc_test.go", source, rules)
+	if err != nil {
+		t.Fatalf("CheckGoConventions: %v", err)
+	}
+	var got []string
+	for _, violation := range violations {
+		got = append(got, violation.Rule+" "+violation.Name)
+	}
+	// c on Calculator meets the initials rule; calc breaks it and the receiver
+	// consistency; s on SeniorTechLead is its first letter; the inline test uses no table
+	want := []string{
+		"receiver_consistency Calculator",
+		"receiver_naming calc",
+		"receiver_naming s",
+		"test_style TestLoop",
+		"error_wrapping Review",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := CheckGoConventions("broken.go", "package", rules); err == nil {
+		t.Error("CheckGoConventions accepted a file that does not parse")
+	}
+}
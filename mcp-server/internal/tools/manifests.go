@@ -0,0 +1,474 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Dependency is a package a project manifest declares
+type Dependency struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint,omitempty"` // version or range as declared
+	Version    string `json:"version,omitempty"`    // resolved version from a lock file
+	Scope      string `json:"scope"`                // runtime, dev, peer, optional or indirect
+	Group      string `json:"group,omitempty"`      // optional-dependency extra or Poetry group
+	Replace    string `json:"replace,omitempty"`    // go.mod replacement target
+	Source     string `json:"source"`               // manifest the dependency came from
+}
+
+// String renders the dependency the way it appears in prompts and documentation
+func (d Dependency) String() string {
+	var sb strings.Builder
+	sb.WriteString(d.Name)
+	if d.Constraint != "" {
+		sb.WriteString(" " + d.Constraint)
+	}
+	if d.Version != "" && d.Version != d.Constraint {
+		sb.WriteString(" (locked " + d.Version + ")")
+	}
+	if d.Replace != "" {
+		sb.WriteString(" => " + d.Replace)
+	}
+	if d.Scope != "runtime" {
+		scope := d.Scope
+		if d.Group != "" && d.Group != d.Scope {
+			scope += ": " + d.Group
+		}
+		sb.WriteString(" [" + scope + "]")
+	}
+	return sb.String()
+}
+
+// Manifests reads the files dependency analysis needs
+type Manifests interface {
+	ReadFile(path string) (string, error)
+	ListFiles(path string) ([]string, error)
+}
+
+// ReadDependencies parses every manifest in the project root. Lock files only add
+// resolved versions, and a manifest that fails to parse is reported in errs while
+// the others are still read.
+func ReadDependencies(files Manifests) (deps []Dependency, errs []error) {
+	parse := func(name string, parser func(string) ([]Dependency, error)) {
+		content, err := files.ReadFile(name)
+		if err != nil {
+			return
+		}
+		parsed, err := parser(content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		for i := range parsed {
+			parsed[i].Source = name
+		}
+		deps = append(deps, parsed...)
+	}
+
+	parse("go.mod", func(content string) ([]Dependency, error) {
+		mod, err := ParseGoMod(content)
+		if err != nil {
+			return nil, err
+		}
+		return mod.Require, nil
+	})
+	parse("package.json", ParsePackageJSON)
+	parse("pyproject.toml", ParsePyproject)
+	parse("Pipfile", ParsePipfile)
+
+	if entries, err := files.ListFiles("."); err == nil {
+		for _, entry := range entries {
+			if ok, _ := path.Match("*requirements*.txt", entry); ok {
+				scope := "runtime"
+				if strings.Contains(entry, "dev") || strings.Contains(entry, "test") {
+					scope = "dev"
+				}
+				parse(entry, func(content string) ([]Dependency, error) {
+					return ParseRequirements(content, scope)
+				})
+			}
+		}
+	}
+
+	locked := make(map[string]string)
+	for name, parser := range map[string]func(string) (map[string]string, error){
+		"poetry.lock":  ParsePoetryLock,
+		"Pipfile.lock": ParsePipfileLock,
+	} {
+		content, err := files.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		versions, err := parser(content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		for pkg, version := range versions {
+			locked[normalizePythonName(pkg)] = version
+		}
+	}
+	for i := range deps {
+		if version, ok := locked[normalizePythonName(deps[i].Name)]; ok && isPythonManifest(deps[i].Source) {
+			deps[i].Version = version
+		}
+	}
+
+	return deps, errs
+}
+
+func isPythonManifest(source string) bool {
+	return source == "pyproject.toml" || source == "Pipfile" || strings.HasSuffix(source, ".txt")
+}
+
+// GoModFile is the part of a go.mod file dependency analysis uses
+type GoModFile struct {
+	Module    string
+	GoVersion string
+	Require   []Dependency
+}
+
+// ParseGoMod reads module, go, require and replace directives in both their
+// single-line and block forms; other directives are ignored
+func ParseGoMod(content string) (*GoModFile, error) {
+	mod := &GoModFile{}
+	replaces := make(map[string]string)
+	block := ""
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := raw
+		comment := ""
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			comment = strings.TrimSpace(line[idx+2:])
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			if err := mod.directive(block, fields, comment, replaces); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			continue
+		}
+
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if err := mod.directive(fields[0], fields[1:], comment, replaces); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	if block != "" {
+		return nil, fmt.Errorf("unterminated %s block", block)
+	}
+
+	for i := range mod.Require {
+		mod.Require[i].Replace = replaces[mod.Require[i].Name]
+	}
+	return mod, nil
+}
+
+func (mod *GoModFile) directive(verb string, args []string, comment string, replaces map[string]string) error {
+	for i, arg := range args {
+		if unquoted, err := strconv.Unquote(arg); err == nil {
+			args[i] = unquoted
+		}
+	}
+
+	switch verb {
+	case "module":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: module path")
+		}
+		mod.Module = args[0]
+	case "go":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: go 1.23")
+		}
+		mod.GoVersion = args[0]
+	case "require":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: require module/path v1.2.3")
+		}
+		scope := "runtime"
+		if comment == "indirect" || strings.HasPrefix(comment, "indirect;") {
+			scope = "indirect"
+		}
+		mod.Require = append(mod.Require, Dependency{Name: args[0], Constraint: args[1], Scope: scope})
+	case "replace":
+		arrow := -1
+		for i, arg := range args {
+			if arg == "=>" {
+				arrow = i
+			}
+		}
+		if arrow < 1 || arrow == len(args)-1 {
+			return fmt.Errorf("usage: replace module/path [v1.2.3] => other/module v1.4.5 | ../local/dir")
+		}
+		replaces[args[0]] = strings.Join(args[arrow+1:], " ")
+	}
+	return nil
+}
+
+// ParsePackageJSON reads runtime, dev, peer and optional dependencies
+func ParsePackageJSON(content string) ([]Dependency, error) {
+	var pkg struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for _, section := range []struct {
+		scope string
+		deps  map[string]string
+	}{
+		{"runtime", pkg.Dependencies},
+		{"dev", pkg.DevDependencies},
+		{"peer", pkg.PeerDependencies},
+		{"optional", pkg.OptionalDependencies},
+	} {
+		for _, name := range sortedKeys(section.deps) {
+			deps = append(deps, Dependency{Name: name, Constraint: section.deps[name], Scope: section.scope})
+		}
+	}
+	return deps, nil
+}
+
+// ParsePyproject reads PEP 621 [project] dependencies and Poetry's dependency tables
+func ParsePyproject(content string) ([]Dependency, error) {
+	var doc struct {
+		Project struct {
+			Dependencies         []string            `toml:"dependencies"`
+			OptionalDependencies map[string][]string `toml:"optional-dependencies"`
+		} `toml:"project"`
+		Tool struct {
+			Poetry struct {
+				Dependencies    map[string]interface{} `toml:"dependencies"`
+				DevDependencies map[string]interface{} `toml:"dev-dependencies"`
+				Group           map[string]struct {
+					Dependencies map[string]interface{} `toml:"dependencies"`
+				} `toml:"group"`
+			} `toml:"poetry"`
+		} `toml:"tool"`
+	}
+	if _, err := toml.Decode(content, &doc); err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for _, requirement := range doc.Project.Dependencies {
+		if dep, ok := parsePEP508(requirement); ok {
+			dep.Scope = "runtime"
+			deps = append(deps, dep)
+		}
+	}
+	for _, extra := range sortedKeys(doc.Project.OptionalDependencies) {
+		for _, requirement := range doc.Project.OptionalDependencies[extra] {
+			if dep, ok := parsePEP508(requirement); ok {
+				dep.Scope, dep.Group = "optional", extra
+				deps = append(deps, dep)
+			}
+		}
+	}
+
+	poetry := doc.Tool.Poetry
+	deps = append(deps, poetryTable(poetry.Dependencies, "runtime", "")...)
+	deps = append(deps, poetryTable(poetry.DevDependencies, "dev", "")...)
+	for _, group := range sortedKeys(poetry.Group) {
+		deps = append(deps, poetryTable(poetry.Group[group].Dependencies, "dev", group)...)
+	}
+	return deps, nil
+}
+
+// poetryTable reads a Poetry or Pipfile table whose values are a constraint string,
+// a table with a version key, or a list of such tables
+func poetryTable(table map[string]interface{}, scope, group string) []Dependency {
+	var deps []Dependency
+	for _, name := range sortedKeys(table) {
+		if strings.EqualFold(name, "python") {
+			continue
+		}
+		dep := Dependency{Name: name, Scope: scope, Group: group}
+		switch value := table[name].(type) {
+		case string:
+			dep.Constraint = value
+		case map[string]interface{}:
+			dep.Constraint = tableConstraint(value)
+			if optional, _ := value["optional"].(bool); optional && scope == "runtime" {
+				dep.Scope = "optional"
+			}
+		case []interface{}:
+			// Multiple constraints, one table per environment
+			var constraints []string
+			for _, alternative := range value {
+				table, _ := alternative.(map[string]interface{})
+				if constraint := tableConstraint(table); constraint != "" {
+					constraints = append(constraints, constraint)
+				}
+			}
+			dep.Constraint = strings.Join(constraints, " | ")
+		}
+		if dep.Constraint == "*" {
+			dep.Constraint = ""
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+func tableConstraint(table map[string]interface{}) string {
+	if version, ok := table["version"].(string); ok {
+		return version
+	}
+	for _, key := range []string{"git", "path", "url"} {
+		if location, ok := table[key].(string); ok {
+			return key + "+" + location
+		}
+	}
+	return ""
+}
+
+// ParsePipfile reads [packages] and [dev-packages]
+func ParsePipfile(content string) ([]Dependency, error) {
+	var doc struct {
+		Packages    map[string]interface{} `toml:"packages"`
+		DevPackages map[string]interface{} `toml:"dev-packages"`
+	}
+	if _, err := toml.Decode(content, &doc); err != nil {
+		return nil, err
+	}
+	return append(poetryTable(doc.Packages, "runtime", ""), poetryTable(doc.DevPackages, "dev", "")...), nil
+}
+
+// ParsePoetryLock returns the locked version of every package
+func ParsePoetryLock(content string) (map[string]string, error) {
+	var doc struct {
+		Package []struct {
+			Name    string `toml:"name"`
+			Version string `toml:"version"`
+		} `toml:"package"`
+	}
+	if _, err := toml.Decode(content, &doc); err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string, len(doc.Package))
+	for _, pkg := range doc.Package {
+		versions[pkg.Name] = pkg.Version
+	}
+	return versions, nil
+}
+
+// ParsePipfileLock returns the locked version of every default and develop package
+func ParsePipfileLock(content string) (map[string]string, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	for _, section := range []string{"default", "develop"} {
+		raw, ok := doc[section]
+		if !ok {
+			continue
+		}
+		var packages map[string]struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(raw, &packages); err != nil {
+			return nil, fmt.Errorf("%s: %w", section, err)
+		}
+		for name, pkg := range packages {
+			versions[name] = strings.TrimPrefix(pkg.Version, "==")
+		}
+	}
+	return versions, nil
+}
+
+// ParseRequirements reads a pip requirements file; options, includes and
+// editable installs are skipped
+func ParseRequirements(content, scope string) ([]Dependency, error) {
+	var deps []Dependency
+	content = strings.ReplaceAll(content, "\\\n", " ")
+	for _, line := range strings.Split(content, "\n") {
+		if idx := strings.Index(line, " #"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if dep, ok := parsePEP508(line); ok {
+			dep.Scope = scope
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// parsePEP508 splits a requirement such as `requests[socks]>=2.31,<3; python_version>"3.8"`
+// into its name and version constraint; extras and environment markers are dropped
+func parsePEP508(requirement string) (Dependency, bool) {
+	if idx := strings.Index(requirement, ";"); idx >= 0 {
+		requirement = requirement[:idx]
+	}
+	requirement = strings.TrimSpace(requirement)
+
+	end := 0
+	for end < len(requirement) && isPythonNameChar(requirement[end]) {
+		end++
+	}
+	if end == 0 {
+		return Dependency{}, false
+	}
+	dep := Dependency{Name: requirement[:end]}
+
+	rest := strings.TrimSpace(requirement[end:])
+	if strings.HasPrefix(rest, "[") {
+		if idx := strings.Index(rest, "]"); idx >= 0 {
+			rest = strings.TrimSpace(rest[idx+1:])
+		}
+	}
+	rest = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(rest, "("), ")"))
+	dep.Constraint = strings.ReplaceAll(rest, " ", "")
+	if strings.HasPrefix(rest, "@") {
+		dep.Constraint = rest
+	}
+	return dep, true
+}
+
+func isPythonNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+}
+
+// normalizePythonName applies PEP 503 normalization so lock entries match declarations
+func normalizePythonName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "-", ".", "-").Replace(name)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// manifestFiles is a project root given as file contents by name
+type manifestFiles map[string]string
+
+func (m manifestFiles) ReadFile(path string) (string, error) {
+	content, ok := m[path]
+	if !ok {
+		return "", fmt.Errorf("%s: no such file", path)
+	}
+	return content, nil
+}
+
+func (m manifestFiles) ListFiles(path string) ([]string, error) {
+	return sortedKeys(m), nil
+}
+
+func dependencyStrings(deps []Dependency) []string {
+	var out []string
+	for _, dep := range deps {
+		out = append(out, dep.String())
+	}
+	return out
+}
+
+func TestParseGoMod(t *testing.T) {
+	content := `module example.com/shop // the shop
+
+go 1.22
+
+require github.com/google/uuid v1.6.0
+
+require (
+	"github.com/lib/pq" v1.10.9
+	golang.org/x/text v0.14.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect; for text
+)
+
+replace github.com/lib/pq => ../pq
+replace (
+	golang.org/x/text v0.14.0 => golang.org/x/text v0.15.0
+)
+
+exclude golang.org/x/net v0.1.0
+`
+	mod, err := ParseGoMod(content)
+	if err != nil {
+		t.Fatalf("ParseGoMod: %v", err)
+	}
+	if mod.Module != "example.com/shop" || mod.GoVersion != "1.22" {
+		t.Errorf("module %q go %q, want example.com/shop go 1.22", mod.Module, mod.GoVersion)
+	}
+	want := []string{
+		"github.com/google/uuid v1.6.0",
+		"github.com/lib/pq v1.10.9 => ../pq",
+		"golang.org/x/text v0.14.0 => golang.org/x/text v0.15.0 [indirect]",
+		"golang.org/x/sys v0.18.0 [indirect]",
+	}
+	if got := dependencyStrings(mod.Require); !reflect.DeepEqual(got, want) {
+		t.Errorf("require = %v, want %v", got, want)
+	}
+}
+
+func TestParseGoModErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"module a b", "line 1: usage: module path"},
+		{"module a\nrequire x", "line 2: usage: require"},
+		{"module a\nreplace x =>", "line 2: usage: replace"},
+		{"module a\nrequire (\n\tx v1.0.0\n", "unterminated require block"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseGoMod(tt.content); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseGoMod(%q) error = %v, want %q", tt.content, err, tt.err)
+		}
+	}
+}
+
+func TestParsePackageJSON(t *testing.T) {
+	content := `{
+		"name": "web",
+		"dependencies": {"react": "^18.2.0", "axios": "1.6.0"},
+		"devDependencies": {"vitest": "~1.2.0"},
+		"peerDependencies": {"react-dom": ">=18"},
+		"optionalDependencies": {"fsevents": "*"}
+	}`
+	deps, err := ParsePackageJSON(content)
+	if err != nil {
+		t.Fatalf("ParsePackageJSON: %v", err)
+	}
+	want := []string{"axios 1.6.0", "react ^18.2.0", "vitest ~1.2.0 [dev]", "react-dom >=18 [peer]", "fsevents * [optional]"}
+	if got := dependencyStrings(deps); !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies = %v, want %v", got, want)
+	}
+
+	if _, err := ParsePackageJSON(`{"dependencies": ["react"]}`); err == nil {
+		t.Error("ParsePackageJSON accepted a dependency list")
+	}
+}
+
+func TestParsePyproject(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "PEP 621",
+			content: `[project]
+dependencies = ["requests[socks]>=2.31, <3; python_version > '3.8'", "click"]
+
+[project.optional-dependencies]
+test = ["pytest (>=8)"]
+docs = ["mkdocs @ https://example.com/mkdocs.zip"]
+`,
+			want: []string{"requests >=2.31,<3", "click", "mkdocs @ https://example.com/mkdocs.zip [optional: docs]", "pytest >=8 [optional: test]"},
+		},
+		{
+			name: "Poetry",
+			content: `[tool.poetry.dependencies]
+python = "^3.11"
+fastapi = "^0.110"
+uvicorn = { version = "^0.29", optional = true }
+internal-lib = { path = "../lib" }
+numpy = [{ version = "<2", python = "<3.9" }, { version = ">=2", python = ">=3.9" }]
+anything = "*"
+
+[tool.poetry.dev-dependencies]
+black = "^24"
+
+[tool.poetry.group.test.dependencies]
+pytest = "^8"
+`,
+			want: []string{
+				"anything", "fastapi ^0.110", "internal-lib path+../lib", "numpy <2 | >=2", "uvicorn ^0.29 [optional]",
+				"black ^24 [dev]", "pytest ^8 [dev: test]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := ParsePyproject(tt.content)
+			if err != nil {
+				t.Fatalf("ParsePyproject: %v", err)
+			}
+			if got := dependencyStrings(deps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dependencies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePipfile(t *testing.T) {
+	content := `[packages]
+flask = "==3.0.0"
+requests = { version = ">=2.31", extras = ["socks"] }
+mylib = { git = "https://example.com/mylib.git", ref = "main" }
+
+[dev-packages]
+pytest = "*"
+`
+	deps, err := ParsePipfile(content)
+	if err != nil {
+		t.Fatalf("ParsePipfile: %v", err)
+	}
+	want := []string{"flask ==3.0.0", "mylib git+https://example.com/mylib.git", "requests >=2.31", "pytest [dev]"}
+	if got := dependencyStrings(deps); !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies = %v, want %v", got, want)
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	content := `# runtime
+Django>=4.2,<5.0
+requests[security] == 2.31.0  # pinned
+numpy; python_version >= "3.9"
+-r base.txt
+--index-url https://pypi.example.com
+-e ./local
+long-name \
+    >=1.0
+`
+	deps, err := ParseRequirements(content, "dev")
+	if err != nil {
+		t.Fatalf("ParseRequirements: %v", err)
+	}
+	want := []string{"Django >=4.2,<5.0 [dev]", "requests ==2.31.0 [dev]", "numpy [dev]", "long-name >=1.0 [dev]"}
+	if got := dependencyStrings(deps); !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies = %v, want %v", got, want)
+	}
+}
+
+func TestParseLockFiles(t *testing.T) {
+	poetry, err := ParsePoetryLock(`[[package]]
+name = "fastapi"
+version = "0.110.1"
+
+[[package]]
+name = "Typing_Extensions"
+version = "4.10.0"
+`)
+	if err != nil {
+		t.Fatalf("ParsePoetryLock: %v", err)
+	}
+	if want := map[string]string{"fastapi": "0.110.1", "Typing_Extensions": "4.10.0"}; !reflect.DeepEqual(poetry, want) {
+		t.Errorf("poetry.lock = %v, want %v", poetry, want)
+	}
+
+	pipfile, err := ParsePipfileLock(`{
+		"_meta": {"hash": {"sha256": "abc"}},
+		"default": {"flask": {"version": "==3.0.0"}},
+		"develop": {"pytest": {"version": "==8.1.1"}}
+	}`)
+	if err != nil {
+		t.Fatalf("ParsePipfileLock: %v", err)
+	}
+	if want := map[string]string{"flask": "3.0.0", "pytest": "8.1.1"}; !reflect.DeepEqual(pipfile, want) {
+		t.Errorf("Pipfile.lock = %v, want %v", pipfile, want)
+	}
+
+	if _, err := ParsePipfileLock(`{"default": []}`); err == nil || !strings.Contains(err.Error(), "default") {
+		t.Errorf("ParsePipfileLock error = %v, want one naming the default section", err)
+	}
+}
+
+func TestReadDependencies(t *testing.T) {
+	files := manifestFiles{
+		"go.mod":               "module example.com/app\n\nrequire github.com/google/uuid v1.6.0\n",
+		"package.json":         `{"devDependencies": {"typescript": "^5.4.0"}}`,
+		"pyproject.toml":       "[tool.poetry.dependencies]\nfastapi = \"^0.110\"\ntyping-extensions = \"^4\"\n",
+		"poetry.lock":          "[[package]]\nname = \"fastapi\"\nversion = \"0.110.1\"\n\n[[package]]\nname = \"Typing_Extensions\"\nversion = \"4.10.0\"\n",
+		"requirements-dev.txt": "pytest>=8\n",
+		"requirements.txt":     "fastapi==0.110.1\n",
+		"Pipfile":              "[packages\n",
+	}
+
+	deps, errs := ReadDependencies(files)
+
+	var got []string
+	for _, dep := range deps {
+		got = append(got, dep.Source+": "+dep.String())
+	}
+	sort.Strings(got)
+	want := []string{
+		"go.mod: github.com/google/uuid v1.6.0",
+		"package.json: typescript ^5.4.0 [dev]",
+		"pyproject.toml: fastapi ^0.110 (locked 0.110.1)",
+		"pyproject.toml: typing-extensions ^4 (locked 4.10.0)",
+		"requirements-dev.txt: pytest >=8 [dev]", // Not in poetry.lock
+		"requirements.txt: fastapi ==0.110.1 (locked 0.110.1)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencies =\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "Pipfile: ") {
+		t.Errorf("errors = %v, want only the broken Pipfile", errs)
+	}
+}
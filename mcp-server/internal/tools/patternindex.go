@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// patternIndexDir is where analysis indexes are kept, one per project, below the user
+// cache directory so they never land in the project tree; deleting one forces a full
+// re-analysis of its project
+const patternIndexDir = "mcp-server/patterns"
+
+// patternIndexVersion invalidates indexes written by an incompatible analyzer
+const patternIndexVersion = 1
+
+// PatternIndex records the content hash and conventions of every analyzed file so
+// re-analysis only parses files that changed
+type PatternIndex struct {
+	Version    int                    `json:"version"`
+	Interfaces []string               `json:"interfaces"` // project-wide interfaces the conventions were classified against
+	Files      map[string]IndexedFile `json:"files"`
+}
+
+// IndexedFile is the analysis of one file at the content hash it was analyzed at
+type IndexedFile struct {
+	Hash        string         `json:"hash"`
+	Interfaces  []string       `json:"interfaces,omitempty"`
+	Conventions []GoConvention `json:"conventions,omitempty"`
+}
+
+// AnalysisStats reports how much of the project an analysis actually re-read
+type AnalysisStats struct {
+	Analyzed int `json:"analyzed"`
+	Reused   int `json:"reused"`
+	Removed  int `json:"removed"`
+}
+
+// patternIndexPath returns the index file of the project at projectDir
+func patternIndexPath(projectDir string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(projectDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, filepath.FromSlash(patternIndexDir), contentHash(abs)[:16]+".json"), nil
+}
+
+// loadPatternIndex reads the index at path, starting afresh when it is missing or outdated
+func loadPatternIndex(path string) *PatternIndex {
+	index := &PatternIndex{Version: patternIndexVersion, Files: make(map[string]IndexedFile)}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return index
+	}
+	var stored PatternIndex
+	if err := json.Unmarshal(content, &stored); err != nil || stored.Version != patternIndexVersion || stored.Files == nil {
+		return index
+	}
+	return &stored
+}
+
+// save writes the index to path
+func (idx *PatternIndex) save(path string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// update brings the index in line with the current file contents, re-analyzing only
+// files whose hash changed. Constructors are classified against every interface in
+// the project, so a change to that set re-analyzes everything.
+func (idx *PatternIndex) update(files map[string]string) AnalysisStats {
+	var stats AnalysisStats
+	next := make(map[string]IndexedFile, len(files))
+	var changed []string
+	for name, content := range files {
+		hash := contentHash(content)
+		if entry, ok := idx.Files[name]; ok && entry.Hash == hash {
+			next[name] = entry
+			continue
+		}
+		next[name] = IndexedFile{Hash: hash, Interfaces: goInterfaces(name, content)}
+		changed = append(changed, name)
+	}
+	for name := range idx.Files {
+		if _, ok := next[name]; !ok {
+			stats.Removed++
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, entry := range next {
+		for _, name := range entry.Interfaces {
+			known[name] = true
+		}
+	}
+	interfaces := sortedKeys(known)
+	if !equalStrings(interfaces, idx.Interfaces) {
+		changed = sortedKeys(next)
+	}
+
+	for _, name := range changed {
+		entry := next[name]
+		// Files that fail to parse stay indexed without conventions until they change
+		entry.Conventions, _ = AnalyzeGoFile(name, files[name], known)
+		next[name] = entry
+	}
+
+	stats.Analyzed = len(changed)
+	stats.Reused = len(next) - len(changed)
+	idx.Version = patternIndexVersion
+	idx.Interfaces = interfaces
+	idx.Files = next
+	return stats
+}
+
+// conventions returns the observations of every indexed file
+func (idx *PatternIndex) conventions() map[string][]GoConvention {
+	observed := make(map[string][]GoConvention, len(idx.Files))
+	for name, entry := range idx.Files {
+		observed[name] = entry.Conventions
+	}
+	return observed
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// equalStrings compares two sorted lists
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
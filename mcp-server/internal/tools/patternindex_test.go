@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPatternIndexUpdate(t *testing.T) {
+	files := map[string]string{
+		"store.go":   "package shop\n\ntype Store interface{ Get() }\n",
+		"service.go": "package shop\n\ntype service struct{}\n\nfunc NewService() *service { return &service{} }\n",
+		"order.go":   "package shop\n\ntype Order struct{}\n",
+	}
+
+	index := loadPatternIndex(filepath.Join(t.TempDir(), "missing.json"))
+	if stats := index.update(files); stats != (AnalysisStats{Analyzed: 3}) {
+		t.Errorf("first update = %+v, want every file analyzed", stats)
+	}
+	if !reflect.DeepEqual(index.Interfaces, []string{"Store"}) {
+		t.Errorf("interfaces = %v, want [Store]", index.Interfaces)
+	}
+
+	if stats := index.update(files); stats != (AnalysisStats{Reused: 3}) {
+		t.Errorf("unchanged update = %+v, want every file reused", stats)
+	}
+
+	files["order.go"] = "package shop\n\ntype Order struct{ ID int }\n"
+	delete(files, "service.go")
+	if stats := index.update(files); stats != (AnalysisStats{Analyzed: 1, Reused: 1, Removed: 1}) {
+		t.Errorf("update after an edit and a delete = %+v, want order.go analyzed and service.go removed", stats)
+	}
+	if _, ok := index.Files["service.go"]; ok {
+		t.Error("deleted file is still indexed")
+	}
+
+	// A new interface changes how constructors classify, so every file is re-read
+	files["service.go"] = "package shop\n\ntype Service interface{ Run() }\n"
+	if stats := index.update(files); stats != (AnalysisStats{Analyzed: 3}) {
+		t.Errorf("update adding an interface = %+v, want every file analyzed", stats)
+	}
+}
+
+func TestPatternIndexSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "patterns", "project.json")
+	index := loadPatternIndex(path)
+	index.update(map[string]string{"handler.go": "package api\n\ntype Handler struct{}\n\nfunc NewHandler() *Handler { return &Handler{} }\n"})
+	if err := index.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded := loadPatternIndex(path)
+	if !reflect.DeepEqual(loaded, index) {
+		t.Errorf("loaded index = %+v, want %+v", loaded, index)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 0, "files": {"a.go": {"hash": "x"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loaded := loadPatternIndex(path); len(loaded.Files) != 0 || loaded.Version != patternIndexVersion {
+		t.Errorf("index of an older version = %+v, want a fresh one", loaded)
+	}
+}
+
+func TestPatternIndexPath(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		t.Skipf("no user cache directory: %v", err)
+	}
+
+	project := t.TempDir()
+	path, err := patternIndexPath(project)
+	if err != nil {
+		t.Fatalf("patternIndexPath: %v", err)
+	}
+	if !strings.HasPrefix(path, filepath.Join(cache, filepath.FromSlash(patternIndexDir))) {
+		t.Errorf("index path %s is not in the user cache directory %s", path, cache)
+	}
+	if other, _ := patternIndexPath(t.TempDir()); other == path {
+		t.Error("two projects share an index")
+	}
+	if again, _ := patternIndexPath(project + "/."); again != path {
+		t.Errorf("the same project resolves to %s and %s", path, again)
+	}
+}
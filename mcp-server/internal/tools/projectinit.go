@@ -3,9 +3,11 @@ package tools
 import (
 	"context"
 	"fmt"
+	"log"
 	"path/filepath"
-	"regexp"
 	"strings"
+
+	"mcp-server/internal/langpack"
 )
 
 // ProjectInitializer discovers and documents patterns from existing codebases
@@ -25,6 +27,9 @@ type DiscoveredPattern struct {
 	Examples    []string `json:"examples"`
 	Files       []string `json:"files"`
 	Frequency   int      `json:"frequency"`
+	Value       string   `json:"value,omitempty"` // cluster within the type, e.g. "pointer" constructors
+	Share       float64  `json:"share,omitempty"` // fraction of the type's occurrences in this cluster
+	Rule        string   `json:"rule,omitempty"`  // "key: value" enforced in review, set for dominant clusters
 }
 
 // ProjectType represents the type of project being analyzed
@@ -44,8 +49,9 @@ type ProjectAnalysis struct {
 	Framework       string               `json:"framework"`
 	Patterns        []DiscoveredPattern  `json:"patterns"`
 	Architecture    ArchitectureAnalysis `json:"architecture"`
-	Dependencies    []string             `json:"dependencies"`
+	Dependencies    []Dependency         `json:"dependencies"`
 	TestingFramework string              `json:"testing_framework"`
+	Files           *AnalysisStats       `json:"files,omitempty"` // set when the analysis used the file index
 }
 
 type ArchitectureAnalysis struct {
@@ -170,6 +176,13 @@ func (pi *ProjectInitializer) detectProjectType(analysis *ProjectAnalysis) error
 		return nil
 	}
 
+	// Any other stack a language pack recognises
+	if packs := langpack.Detect(pi.toolSet, "."); len(packs) > 0 {
+		analysis.Language = packs[0].Name
+		analysis.ProjectType = ProjectType(packs[0].Name)
+		return nil
+	}
+
 	return fmt.Errorf("unable to detect project type")
 }
 
@@ -228,7 +241,7 @@ func (pi *ProjectInitializer) analyzeArchitecture(analysis *ProjectAnalysis) err
 	return nil
 }
 
-// discoverGoPatterns analyzes Go-specific patterns
+// discoverGoPatterns clusters the conventions Go files follow, re-parsing only changed files
 func (pi *ProjectInitializer) discoverGoPatterns(analysis *ProjectAnalysis) error {
 	// Find all Go files
 	output, err := pi.toolSet.ExecuteCommand("find . -name '*.go' -type f")
@@ -236,179 +249,36 @@ func (pi *ProjectInitializer) discoverGoPatterns(analysis *ProjectAnalysis) erro
 		return err
 	}
 
-	goFiles := strings.Split(strings.TrimSpace(output), "\n")
-	if len(goFiles) == 0 || goFiles[0] == "" {
-		return nil
-	}
-
-	// Analyze patterns in Go files
-	patterns := make(map[string]*DiscoveredPattern)
-
-	for _, file := range goFiles {
-		if strings.Contains(file, "vendor/") || strings.Contains(file, ".git/") {
+	files := make(map[string]string)
+	for _, file := range strings.Split(strings.TrimSpace(output), "\n") {
+		file = strings.TrimPrefix(strings.TrimSpace(file), "./")
+		if file == "" || !isGoSource(file) {
 			continue
 		}
 
-		content, err := pi.toolSet.ReadFile(strings.TrimPrefix(file, "./"))
+		content, err := pi.toolSet.ReadFile(file)
 		if err != nil {
 			continue
 		}
-
-		// Discover handler patterns
-		pi.discoverGoHandlerPatterns(content, file, patterns)
-		
-		// Discover struct patterns
-		pi.discoverGoStructPatterns(content, file, patterns)
-		
-		// Discover interface patterns
-		pi.discoverGoInterfacePatterns(content, file, patterns)
-		
-		// Discover error handling patterns
-		pi.discoverGoErrorPatterns(content, file, patterns)
+		files[file] = content
 	}
 
-	// Convert map to slice
-	for _, pattern := range patterns {
-		analysis.Patterns = append(analysis.Patterns, *pattern)
-	}
-
-	return nil
-}
-
-// discoverGoHandlerPatterns finds HTTP handler patterns
-func (pi *ProjectInitializer) discoverGoHandlerPatterns(content, file string, patterns map[string]*DiscoveredPattern) {
-	// Pattern for Fiber handlers
-	fiberPattern := regexp.MustCompile(`func\s+(\w+)\s*\([^)]*\*fiber\.Ctx[^)]*\)\s*error`)
-	matches := fiberPattern.FindAllStringSubmatch(content, -1)
-	
-	if len(matches) > 0 {
-		patternKey := "fiber_handlers"
-		if patterns[patternKey] == nil {
-			patterns[patternKey] = &DiscoveredPattern{
-				Name:        "Fiber HTTP Handlers",
-				Type:        "handler",
-				Description: "Standard Fiber HTTP handler functions",
-				Examples:    []string{},
-				Files:       []string{},
-				Frequency:   0,
-			}
-		}
-		
-		for _, match := range matches {
-			if len(match) > 1 {
-				example := fmt.Sprintf("func %s(c *fiber.Ctx) error", match[1])
-				patterns[patternKey].Examples = append(patterns[patternKey].Examples, example)
-				patterns[patternKey].Frequency++
-			}
-		}
-		patterns[patternKey].Files = append(patterns[patternKey].Files, file)
-	}
-
-	// Pattern for standard HTTP handlers
-	httpPattern := regexp.MustCompile(`func\s+(\w+)\s*\([^)]*http\.ResponseWriter[^)]*\*http\.Request[^)]*\)`)
-	matches = httpPattern.FindAllStringSubmatch(content, -1)
-	
-	if len(matches) > 0 {
-		patternKey := "http_handlers"
-		if patterns[patternKey] == nil {
-			patterns[patternKey] = &DiscoveredPattern{
-				Name:        "Standard HTTP Handlers",
-				Type:        "handler",
-				Description: "Standard library HTTP handler functions",
-				Examples:    []string{},
-				Files:       []string{},
-				Frequency:   0,
-			}
-		}
-		
-		for _, match := range matches {
-			if len(match) > 1 {
-				example := fmt.Sprintf("func %s(w http.ResponseWriter, r *http.Request)", match[1])
-				patterns[patternKey].Examples = append(patterns[patternKey].Examples, example)
-				patterns[patternKey].Frequency++
-			}
-		}
-		patterns[patternKey].Files = append(patterns[patternKey].Files, file)
-	}
-}
-
-// discoverGoStructPatterns finds struct definition patterns
-func (pi *ProjectInitializer) discoverGoStructPatterns(content, file string, patterns map[string]*DiscoveredPattern) {
-	// Request/Response struct patterns
-	structPattern := regexp.MustCompile(`type\s+(\w+(?:Request|Response|DTO|Model))\s+struct\s*{`)
-	matches := structPattern.FindAllStringSubmatch(content, -1)
-	
-	if len(matches) > 0 {
-		patternKey := "dto_structs"
-		if patterns[patternKey] == nil {
-			patterns[patternKey] = &DiscoveredPattern{
-				Name:        "Data Transfer Objects",
-				Type:        "model",
-				Description: "Request/Response/DTO struct definitions",
-				Examples:    []string{},
-				Files:       []string{},
-				Frequency:   0,
-			}
-		}
-		
-		for _, match := range matches {
-			if len(match) > 1 {
-				example := fmt.Sprintf("type %s struct", match[1])
-				patterns[patternKey].Examples = append(patterns[patternKey].Examples, example)
-				patterns[patternKey].Frequency++
-			}
-		}
-		patterns[patternKey].Files = append(patterns[patternKey].Files, file)
+	// Only files whose content changed since the last analysis are parsed again
+	indexPath, err := patternIndexPath(pi.toolSet.GetWorkingDirectory())
+	if err != nil {
+		log.Printf("No pattern index, analyzing every file: %v", err)
 	}
-}
-
-// discoverGoInterfacePatterns finds interface patterns
-func (pi *ProjectInitializer) discoverGoInterfacePatterns(content, file string, patterns map[string]*DiscoveredPattern) {
-	interfacePattern := regexp.MustCompile(`type\s+(\w+(?:Service|Repository|Client|Interface))\s+interface\s*{`)
-	matches := interfacePattern.FindAllStringSubmatch(content, -1)
-	
-	if len(matches) > 0 {
-		patternKey := "service_interfaces"
-		if patterns[patternKey] == nil {
-			patterns[patternKey] = &DiscoveredPattern{
-				Name:        "Service Interfaces",
-				Type:        "interface",
-				Description: "Service and repository interface definitions",
-				Examples:    []string{},
-				Files:       []string{},
-				Frequency:   0,
-			}
+	index := loadPatternIndex(indexPath)
+	stats := index.update(files)
+	analysis.Files = &stats
+	if indexPath != "" {
+		if err := index.save(indexPath); err != nil {
+			log.Printf("Failed to save pattern index: %v", err)
 		}
-		
-		for _, match := range matches {
-			if len(match) > 1 {
-				example := fmt.Sprintf("type %s interface", match[1])
-				patterns[patternKey].Examples = append(patterns[patternKey].Examples, example)
-				patterns[patternKey].Frequency++
-			}
-		}
-		patterns[patternKey].Files = append(patterns[patternKey].Files, file)
 	}
-}
 
-// discoverGoErrorPatterns finds error handling patterns
-func (pi *ProjectInitializer) discoverGoErrorPatterns(content, file string, patterns map[string]*DiscoveredPattern) {
-	// Check for error wrapping patterns
-	if strings.Contains(content, "fmt.Errorf") || strings.Contains(content, "errors.Wrap") {
-		patternKey := "error_wrapping"
-		if patterns[patternKey] == nil {
-			patterns[patternKey] = &DiscoveredPattern{
-				Name:        "Error Wrapping",
-				Type:        "error_handling",
-				Description: "Error wrapping and context preservation patterns",
-				Examples:    []string{"fmt.Errorf(\"failed to process: %w\", err)", "errors.Wrap(err, \"context\")"},
-				Files:       []string{},
-				Frequency:   0,
-			}
-		}
-		patterns[patternKey].Files = append(patterns[patternKey].Files, file)
-		patterns[patternKey].Frequency++
-	}
+	analysis.Patterns = append(analysis.Patterns, clusterGoConventions(index.conventions())...)
+	return nil
 }
 
 // discoverJSPatterns analyzes JavaScript/TypeScript patterns
@@ -439,40 +309,14 @@ func (pi *ProjectInitializer) discoverPythonPatterns(analysis *ProjectAnalysis)
 	return nil
 }
 
-// analyzeDependencies analyzes project dependencies
+// analyzeDependencies parses every dependency manifest in the project root, so
+// mixed projects report the dependencies of each stack
 func (pi *ProjectInitializer) analyzeDependencies(analysis *ProjectAnalysis) error {
-	switch analysis.Language {
-	case "go":
-		if content, err := pi.toolSet.ReadFile("go.mod"); err == nil {
-			// Extract dependencies from go.mod
-			lines := strings.Split(content, "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "github.com/") || strings.HasPrefix(line, "golang.org/") {
-					parts := strings.Fields(line)
-					if len(parts) >= 1 {
-						analysis.Dependencies = append(analysis.Dependencies, parts[0])
-					}
-				}
-			}
-		}
-	case "javascript", "typescript":
-		if content, err := pi.toolSet.ReadFile("package.json"); err == nil {
-			// TODO: Parse package.json dependencies
-			_ = content
-		}
-	case "python":
-		if content, err := pi.toolSet.ReadFile("requirements.txt"); err == nil {
-			lines := strings.Split(content, "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if line != "" && !strings.HasPrefix(line, "#") {
-					analysis.Dependencies = append(analysis.Dependencies, line)
-				}
-			}
-		}
+	deps, errs := ReadDependencies(pi.toolSet)
+	for _, err := range errs {
+		log.Printf("Skipping unparseable manifest %v", err)
 	}
-	
+	analysis.Dependencies = deps
 	return nil
 }
 
@@ -512,27 +356,69 @@ func (pi *ProjectInitializer) detectTestingFramework(analysis *ProjectAnalysis)
 				analysis.TestingFramework = "unittest"
 			}
 		}
+	case "rust":
+		analysis.TestingFramework = "cargo test"
+	case "maven", "gradle":
+		for _, file := range []string{"pom.xml", "build.gradle", "build.gradle.kts"} {
+			content, err := pi.toolSet.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			if strings.Contains(content, "junit-jupiter") {
+				analysis.TestingFramework = "junit5"
+			} else if strings.Contains(content, "testng") {
+				analysis.TestingFramework = "testng"
+			} else if strings.Contains(content, "junit") {
+				analysis.TestingFramework = "junit"
+			}
+			break
+		}
+	case "dotnet":
+		analysis.TestingFramework = "dotnet test"
 	}
 	
 	return nil
 }
 
-// GenerateProjectDocumentation creates documentation based on discovered patterns
+// GenerateProjectDocumentation creates documentation based on discovered patterns.
+// Existing documents are merged: generated regions are refreshed and hand-written
+// text outside them is kept.
 func (pi *ProjectInitializer) GenerateProjectDocumentation(analysis *ProjectAnalysis, outputPath string) error {
 	// Note: We'll create the patterns directory implicitly when writing files
 
 	// Generate project overview
 	overview := pi.generateProjectOverview(analysis)
-	if err := pi.toolSet.WriteFile(filepath.Join(outputPath, "PROJECT_PATTERNS.md"), overview); err != nil {
+	if err := pi.writeGeneratedDoc(filepath.Join(outputPath, "PROJECT_PATTERNS.md"), overview); err != nil {
 		return err
 	}
 
-	// Generate pattern-specific documentation
+	// Generate pattern-specific documentation, one file per type covering all its clusters
 	patternsDir := filepath.Join(outputPath, "patterns")
+	var patternTypes []string
+	byType := make(map[string][]DiscoveredPattern)
 	for _, pattern := range analysis.Patterns {
-		content := pi.generatePatternDocumentation(pattern)
-		filename := fmt.Sprintf("%s.md", strings.ToLower(strings.ReplaceAll(pattern.Type, " ", "_")))
-		if err := pi.toolSet.WriteFile(filepath.Join(patternsDir, filename), content); err != nil {
+		if _, ok := byType[pattern.Type]; !ok {
+			patternTypes = append(patternTypes, pattern.Type)
+		}
+		byType[pattern.Type] = append(byType[pattern.Type], pattern)
+	}
+	written := make(map[string]bool)
+	for _, patternType := range patternTypes {
+		content := pi.generatePatternDocumentation(patternType, byType[patternType])
+		filename := fmt.Sprintf("%s.md", strings.ToLower(strings.ReplaceAll(patternType, " ", "_")))
+		written[filename] = true
+		if err := pi.writeGeneratedDoc(filepath.Join(patternsDir, filename), content); err != nil {
+			return err
+		}
+	}
+
+	// Pattern types no longer found lose their generated regions, and so their rules
+	entries, _ := pi.toolSet.ListFiles(patternsDir)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry, ".md") || written[entry] {
+			continue
+		}
+		if err := pi.writeGeneratedDoc(filepath.Join(patternsDir, entry), ""); err != nil {
 			return err
 		}
 	}
@@ -540,73 +426,131 @@ func (pi *ProjectInitializer) GenerateProjectDocumentation(analysis *ProjectAnal
 	return nil
 }
 
+// writeGeneratedDoc writes generated content into path, merging it with the regions
+// of an existing document; see MergeGeneratedRegions for documents without regions
+func (pi *ProjectInitializer) writeGeneratedDoc(path, generated string) error {
+	existing, err := pi.toolSet.ReadFile(path)
+	if err != nil {
+		if generated == "" {
+			return nil
+		}
+		return pi.toolSet.WriteFile(path, generated)
+	}
+
+	merged := MergeGeneratedRegions(existing, generated)
+	if merged == existing {
+		return nil
+	}
+	return pi.toolSet.WriteFile(path, merged)
+}
+
 // generateProjectOverview creates a comprehensive project overview
 func (pi *ProjectInitializer) generateProjectOverview(analysis *ProjectAnalysis) string {
-	var sb strings.Builder
-	
-	sb.WriteString("# Project Patterns Documentation\n\n")
+	var doc, sb strings.Builder
+
+	doc.WriteString("# Project Patterns Documentation\n\n")
 	sb.WriteString("## Project Overview\n\n")
 	sb.WriteString(fmt.Sprintf("- **Language**: %s\n", analysis.Language))
 	sb.WriteString(fmt.Sprintf("- **Framework**: %s\n", analysis.Framework))
 	sb.WriteString(fmt.Sprintf("- **Architecture**: %s\n", analysis.Architecture.Style))
-	sb.WriteString(fmt.Sprintf("- **Testing Framework**: %s\n\n", analysis.TestingFramework))
-	
+	sb.WriteString(fmt.Sprintf("- **Testing Framework**: %s\n", analysis.TestingFramework))
+	doc.WriteString(generatedRegion("overview", sb.String()) + "\n")
+	sb.Reset()
+
 	sb.WriteString("## Architecture\n\n")
 	sb.WriteString(fmt.Sprintf("**Style**: %s\n\n", analysis.Architecture.Style))
 	sb.WriteString("**Entry Points**:\n")
 	for _, entry := range analysis.Architecture.EntryPoints {
 		sb.WriteString(fmt.Sprintf("- %s\n", entry))
 	}
-	sb.WriteString("\n")
-	
-	sb.WriteString("## Discovered Patterns\n\n")
+	doc.WriteString(generatedRegion("architecture", sb.String()) + "\n")
+	sb.Reset()
+
+	sb.WriteString("## Discovered Patterns\n")
 	for _, pattern := range analysis.Patterns {
-		sb.WriteString(fmt.Sprintf("### %s\n", pattern.Name))
+		sb.WriteString(fmt.Sprintf("\n### %s\n", pattern.Name))
 		sb.WriteString(fmt.Sprintf("- **Type**: %s\n", pattern.Type))
 		sb.WriteString(fmt.Sprintf("- **Frequency**: %d occurrences\n", pattern.Frequency))
 		sb.WriteString(fmt.Sprintf("- **Files**: %d files\n", len(pattern.Files)))
-		sb.WriteString(fmt.Sprintf("- **Description**: %s\n\n", pattern.Description))
+		sb.WriteString(fmt.Sprintf("- **Description**: %s\n", pattern.Description))
 	}
-	
-	sb.WriteString("## Dependencies\n\n")
+	doc.WriteString(generatedRegion("patterns", sb.String()) + "\n")
+	sb.Reset()
+
+	sb.WriteString("## Dependencies\n")
+	source := ""
 	for _, dep := range analysis.Dependencies {
+		if dep.Source != source {
+			source = dep.Source
+			sb.WriteString(fmt.Sprintf("\n**%s**:\n\n", source))
+		}
 		sb.WriteString(fmt.Sprintf("- %s\n", dep))
 	}
-	
-	return sb.String()
+	doc.WriteString(generatedRegion("dependencies", sb.String()))
+
+	return doc.String()
 }
 
-// generatePatternDocumentation creates detailed documentation for a specific pattern
-func (pi *ProjectInitializer) generatePatternDocumentation(pattern DiscoveredPattern) string {
-	var sb strings.Builder
-	
-	sb.WriteString(fmt.Sprintf("# %s\n\n", pattern.Name))
-	sb.WriteString(fmt.Sprintf("**Type**: %s\n\n", pattern.Type))
-	sb.WriteString(fmt.Sprintf("**Description**: %s\n\n", pattern.Description))
-	sb.WriteString(fmt.Sprintf("**Frequency**: %d occurrences in %d files\n\n", pattern.Frequency, len(pattern.Files)))
-	
-	if len(pattern.Examples) > 0 {
-		sb.WriteString("## Examples\n\n")
-		for i, example := range pattern.Examples {
-			if i >= 5 { // Limit to first 5 examples
-				sb.WriteString("...\n")
-				break
-			}
-			sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", example))
+// generatePatternDocumentation documents every cluster of one pattern type, most common first;
+// the rules of dominant clusters are listed under "## Rules" for the tech lead to enforce.
+// Rules added by hand belong in a separate "## Rules" section outside the generated regions.
+func (pi *ProjectInitializer) generatePatternDocumentation(patternType string, patterns []DiscoveredPattern) string {
+	var doc, sb strings.Builder
+
+	doc.WriteString(fmt.Sprintf("# %s patterns\n\n", patternType))
+
+	var rules []string
+	for _, pattern := range patterns {
+		if pattern.Rule != "" {
+			rules = append(rules, pattern.Rule)
 		}
 	}
-	
-	if len(pattern.Files) > 0 {
-		sb.WriteString("## Files\n\n")
-		for i, file := range pattern.Files {
-			if i >= 10 { // Limit to first 10 files
-				sb.WriteString("...\n")
-				break
-			}
-			sb.WriteString(fmt.Sprintf("- %s\n", file))
+	if len(rules) > 0 {
+		sb.WriteString("## Rules\n\n")
+		for _, rule := range rules {
+			sb.WriteString(fmt.Sprintf("- %s\n", rule))
+		}
+	}
+	doc.WriteString(generatedRegion("rules", sb.String()) + "\n")
+	sb.Reset()
+
+	for i, pattern := range patterns {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("## %s\n\n", pattern.Name))
+		if pattern.Description != pattern.Name {
+			sb.WriteString(fmt.Sprintf("**Description**: %s\n\n", pattern.Description))
+		}
+		sb.WriteString(fmt.Sprintf("**Frequency**: %d occurrences in %d files", pattern.Frequency, len(pattern.Files)))
+		if pattern.Share > 0 {
+			sb.WriteString(fmt.Sprintf(" (%.0f%% of %s)", pattern.Share*100, patternType))
 		}
 		sb.WriteString("\n")
+
+		if len(pattern.Examples) > 0 {
+			sb.WriteString("\n### Examples\n")
+			for i, example := range pattern.Examples {
+				if i >= 5 { // Limit to first 5 examples
+					sb.WriteString("\n...\n")
+					break
+				}
+				sb.WriteString(fmt.Sprintf("\n```\n%s\n```\n", example))
+			}
+		}
+
+		if len(pattern.Files) > 0 {
+			sb.WriteString("\n### Files\n\n")
+			for i, file := range pattern.Files {
+				if i >= 10 { // Limit to first 10 files
+					sb.WriteString("...\n")
+					break
+				}
+				sb.WriteString(fmt.Sprintf("- %s\n", file))
+			}
+		}
 	}
-	
-	return sb.String()
-}
\ No newline at end of file
+	doc.WriteString(generatedRegion("clusters", sb.String()))
+
+	return doc.String()
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"fmt"
 	"strings"
+	"sync"
 )
 
 // ThoughtData represents a single thought in the sequential thinking process
@@ -26,6 +27,7 @@ type ThoughtHistory struct {
 
 // SequentialThinkingTool provides structured thinking capabilities
 type SequentialThinkingTool struct {
+	mu      sync.Mutex
 	history *ThoughtHistory
 }
 
@@ -52,6 +54,9 @@ func (st *SequentialThinkingTool) ProcessThought(args map[string]interface{}) (i
 		thoughtData.TotalThoughts = thoughtData.ThoughtNumber
 	}
 
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
 	// Add to history
 	st.history.Thoughts = append(st.history.Thoughts, thoughtData)
 
@@ -178,13 +183,25 @@ func (st *SequentialThinkingTool) getBranchNames() []string {
 	return names
 }
 
-// GetThoughtHistory returns the complete thought history
+// GetThoughtHistory returns a snapshot of the complete thought history
 func (st *SequentialThinkingTool) GetThoughtHistory() *ThoughtHistory {
-	return st.history
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	snapshot := &ThoughtHistory{
+		Thoughts: append([]ThoughtData(nil), st.history.Thoughts...),
+		Branches: make(map[string][]ThoughtData, len(st.history.Branches)),
+	}
+	for name, thoughts := range st.history.Branches {
+		snapshot.Branches[name] = append([]ThoughtData(nil), thoughts...)
+	}
+	return snapshot
 }
 
 // Reset clears the thought history
 func (st *SequentialThinkingTool) Reset() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	st.history.Thoughts = make([]ThoughtData, 0)
 	st.history.Branches = make(map[string][]ThoughtData)
 }
@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Symbol is a definition found in the code index
+type Symbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`                // func, method, type, interface, struct, const, var, class, module
+	Container string `json:"container,omitempty"` // receiver or enclosing type
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Signature string `json:"signature,omitempty"` // the defining line
+}
+
+// String renders the symbol as "file:line kind Container.Name"
+func (s Symbol) String() string {
+	name := s.Name
+	if s.Container != "" {
+		name = s.Container + "." + s.Name
+	}
+	return fmt.Sprintf("%s:%d %s %s", s.File, s.Line, s.Kind, name)
+}
+
+// symbolPattern is a ctags-style rule: the first group of re names a definition of kind
+type symbolPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+var (
+	jsSymbolPatterns = []symbolPattern{
+		{"func", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\*?\s+([A-Za-z_$][\w$]*)`)},
+		{"class", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)},
+		{"type", regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:interface|type|enum)\s+([A-Za-z_$][\w$]*)`)},
+		{"func", regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s*)?(?:\([^)]*\)|[A-Za-z_$][\w$]*)\s*(?::[^=]+)?=>`)},
+	}
+	jvmSymbolPatterns = []symbolPattern{
+		{"class", regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|static|final|abstract|sealed|data|open|partial)\s+)*(?:class|interface|enum|record|object|struct)\s+(\w+)`)},
+		{"func", regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|static|final|abstract|override|open|suspend|async|virtual)\s+)*fun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(\w+)\s*\(`)},
+		{"method", regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|static|final|abstract|synchronized|override|async|virtual)\s+)+[\w<>\[\],.?]+(?:\s*<[^>]*>)?\s+(\w+)\s*\(`)},
+	}
+
+	// symbolPatterns maps file extensions to the rules for languages without a parser here
+	symbolPatterns = map[string][]symbolPattern{
+		".py": {
+			{"func", regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)`)},
+			{"class", regexp.MustCompile(`^\s*class\s+(\w+)`)},
+		},
+		".rs": {
+			{"func", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+(\w+)`)},
+			{"type", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:struct|enum|trait|type|union)\s+(\w+)`)},
+			{"module", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+(\w+)`)},
+		},
+		".rb": {
+			{"func", regexp.MustCompile(`^\s*def\s+(?:self\.)?(\w+[?!=]?)`)},
+			{"class", regexp.MustCompile(`^\s*class\s+([A-Z]\w*)`)},
+			{"module", regexp.MustCompile(`^\s*module\s+([A-Z]\w*)`)},
+		},
+		".js": jsSymbolPatterns, ".jsx": jsSymbolPatterns, ".mjs": jsSymbolPatterns, ".cjs": jsSymbolPatterns,
+		".ts": jsSymbolPatterns, ".tsx": jsSymbolPatterns,
+		".java": jvmSymbolPatterns, ".kt": jvmSymbolPatterns, ".cs": jvmSymbolPatterns, ".scala": jvmSymbolPatterns,
+	}
+)
+
+// extractSymbols finds the definitions in a file, parsing Go and matching the
+// ctags-style rules for other languages
+func extractSymbols(file, content string) []Symbol {
+	ext := path.Ext(file)
+	if ext == ".go" {
+		return goSymbols(file, content)
+	}
+	patterns := symbolPatterns[ext]
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var symbols []Symbol
+	for i, line := range strings.Split(content, "\n") {
+		for _, pattern := range patterns {
+			if match := pattern.re.FindStringSubmatch(line); match != nil {
+				symbols = append(symbols, Symbol{
+					Name:      match[1],
+					Kind:      pattern.kind,
+					File:      file,
+					Line:      i + 1,
+					Signature: strings.TrimSpace(line),
+				})
+				break
+			}
+		}
+	}
+	return symbols
+}
+
+// goSymbols lists the top-level declarations of a Go file; files that do not parse
+// yield whatever the parser recovered
+func goSymbols(file, content string) []Symbol {
+	fset := token.NewFileSet()
+	parsed, _ := parser.ParseFile(fset, file, content, parser.SkipObjectResolution)
+	if parsed == nil {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	symbol := func(name, kind, container string, pos token.Pos) Symbol {
+		line := fset.Position(pos).Line
+		signature := ""
+		if line >= 1 && line <= len(lines) {
+			signature = strings.TrimSpace(lines[line-1])
+		}
+		return Symbol{Name: name, Kind: kind, Container: container, File: file, Line: line, Signature: signature}
+	}
+
+	var symbols []Symbol
+	for _, decl := range parsed.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.Name == "_" {
+				continue
+			}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				receiver := strings.TrimPrefix(types.ExprString(decl.Recv.List[0].Type), "*")
+				if idx := strings.Index(receiver, "["); idx >= 0 {
+					receiver = receiver[:idx]
+				}
+				symbols = append(symbols, symbol(decl.Name.Name, "method", receiver, decl.Pos()))
+			} else {
+				symbols = append(symbols, symbol(decl.Name.Name, "func", "", decl.Pos()))
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					kind := "type"
+					switch spec.Type.(type) {
+					case *ast.InterfaceType:
+						kind = "interface"
+					case *ast.StructType:
+						kind = "struct"
+					}
+					symbols = append(symbols, symbol(spec.Name.Name, kind, "", spec.Pos()))
+				case *ast.ValueSpec:
+					kind := "var"
+					if decl.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range spec.Names {
+						if name.Name != "_" {
+							symbols = append(symbols, symbol(name.Name, kind, "", name.Pos()))
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// Outline lists the definitions of a file one per line, as a short stand-in for
+// its content; files without recognized definitions give an empty outline
+func Outline(file, content string) string {
+	var outline strings.Builder
+	for _, symbol := range extractSymbols(file, content) {
+		fmt.Fprintf(&outline, "%d: %s\n", symbol.Line, symbol.Signature)
+	}
+	return outline.String()
+}
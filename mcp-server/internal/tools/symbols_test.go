@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractSymbols(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    []string
+	}{
+		{
+			file: "store/store.go",
+			content: `package store
+
+const Limit = 10
+
+var _, ErrMissing = 0, error(nil)
+
+type Store interface{ Get() }
+
+type memory[T any] struct{}
+
+type ID string
+
+func (m *memory[T]) Get() {}
+
+func New() Store { return nil }
+`,
+			want: []string{
+				"store/store.go:3 const Limit",
+				"store/store.go:5 var ErrMissing",
+				"store/store.go:7 interface Store",
+				"store/store.go:9 struct memory",
+				"store/store.go:11 type ID",
+				"store/store.go:13 method memory.Get",
+				"store/store.go:15 func New",
+			},
+		},
+		{
+			file:    "broken.go",
+			content: "package broken\n\nfunc Kept() {}\n\nfunc (",
+			want:    []string{"broken.go:3 func Kept"},
+		},
+		{
+			file: "web/api.ts",
+			content: `export default async function load() {}
+export abstract class Client {}
+export interface Options {}
+const handler = async (req) => req
+`,
+			want: []string{"web/api.ts:1 func load", "web/api.ts:2 class Client", "web/api.ts:3 type Options", "web/api.ts:4 func handler"},
+		},
+		{
+			file:    "app.py",
+			content: "class Cart:\n    async def total(self):\n        pass\n",
+			want:    []string{"app.py:1 class Cart", "app.py:2 func total"},
+		},
+		{
+			file:    "lib.rs",
+			content: "pub(crate) mod cart;\npub struct Cart;\npub async fn total() {}\n",
+			want:    []string{"lib.rs:1 module cart", "lib.rs:2 type Cart", "lib.rs:3 func total"},
+		},
+		{
+			file:    "Cart.java",
+			content: "public final class Cart {\n    public int total(int a) {\n        return a;\n    }\n}\n",
+			want:    []string{"Cart.java:1 class Cart", "Cart.java:2 method total"},
+		},
+		{
+			file:    "README.md",
+			content: "# def not_code():\n",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var got []string
+			for _, symbol := range extractSymbols(tt.file, tt.content) {
+				got = append(got, symbol.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("symbols = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutline(t *testing.T) {
+	content := "package calc\n\n// Add sums\nfunc Add(a, b int) int {\n\treturn a + b\n}\n"
+	if got, want := Outline("calc.go", content), "4: func Add(a, b int) int {\n"; got != want {
+		t.Errorf("Outline = %q, want %q", got, want)
+	}
+	if got := Outline("notes.txt", "func Add() {}"); got != "" {
+		t.Errorf("Outline of a text file = %q, want empty", got)
+	}
+}
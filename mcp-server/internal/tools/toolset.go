@@ -3,9 +3,13 @@ package tools
 import (
 	"context"
 	"mcp-server/internal/config"
+	"sync"
 )
 
+// ToolSet is safe for concurrent use: the working-directory bound helpers are
+// immutable and swapped as a whole under mu when the directory changes
 type ToolSet struct {
+	mu                sync.RWMutex
 	filesystem        *FileSystem
 	git               *GitOperations
 	commands          *CommandValidator
@@ -13,6 +17,10 @@ type ToolSet struct {
 	projectInit       *ProjectInitializer
 	sequentialThinking *SequentialThinkingTool
 	workingDir        string
+
+	indexMu    sync.Mutex
+	index      *CodeIndex // built for index.root, rebuilt when the working directory changes
+	indexStale bool       // a command ran and may have changed files behind the index
 }
 
 func NewToolSet(commands config.CommandsSection, restrictions config.RestrictionsSection, workingDir string) *ToolSet {
@@ -35,84 +43,134 @@ func NewToolSet(commands config.CommandsSection, restrictions config.Restriction
 	return ts
 }
 
+// bound returns the helpers for the current working directory
+func (ts *ToolSet) bound() (*FileSystem, *GitOperations, *CommandValidator) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.filesystem, ts.git, ts.commands
+}
+
+func (ts *ToolSet) fs() *FileSystem {
+	filesystem, _, _ := ts.bound()
+	return filesystem
+}
+
+func (ts *ToolSet) gitOps() *GitOperations {
+	_, git, _ := ts.bound()
+	return git
+}
+
+func (ts *ToolSet) validator() *CommandValidator {
+	_, _, commands := ts.bound()
+	return commands
+}
+
 func (ts *ToolSet) ReadFile(path string) (string, error) {
-	return ts.filesystem.ReadFile(path)
+	return ts.fs().ReadFile(path)
 }
 
 func (ts *ToolSet) WriteFile(path, content string) error {
-	return ts.filesystem.WriteFile(path, content)
+	filesystem := ts.fs()
+	if err := filesystem.WriteFile(path, content); err != nil {
+		return err
+	}
+	ts.indexMu.Lock()
+	index := ts.index
+	ts.indexMu.Unlock()
+	if index != nil && index.root == filesystem.workingDir {
+		index.Update(path, content)
+	}
+	return nil
 }
 
 func (ts *ToolSet) ExecuteCommand(command string) (string, error) {
-	return ts.commands.ExecuteCommand(command)
+	defer ts.markIndexStale()
+	return ts.validator().ExecuteCommand(command)
+}
+
+func (ts *ToolSet) ExecuteCommandIn(dir, command string) (string, error) {
+	defer ts.markIndexStale()
+	return ts.validator().ExecuteCommandIn(dir, command)
 }
 
 func (ts *ToolSet) GetGitStatus() (string, error) {
-	return ts.git.GetStatus()
+	return ts.gitOps().GetStatus()
 }
 
 func (ts *ToolSet) GetGitDiff() (string, error) {
-	return ts.git.GetDiff()
+	return ts.gitOps().GetDiff()
 }
 
 func (ts *ToolSet) GetGitLog(limit int) (string, error) {
-	return ts.git.GetLog(limit)
+	return ts.gitOps().GetLog(limit)
 }
 
 func (ts *ToolSet) GetGitDiffNameOnly() (string, error) {
-	return ts.git.GetDiffNameOnly()
+	return ts.gitOps().GetDiffNameOnly()
+}
+
+func (ts *ToolSet) GetGitUntrackedFiles() ([]string, error) {
+	return ts.gitOps().GetUntrackedFiles()
 }
 
 func (ts *ToolSet) GetGitDiffCached() (string, error) {
-	return ts.git.GetDiffCached()
+	return ts.gitOps().GetDiffCached()
 }
 
 func (ts *ToolSet) GetGitShow(commitHash string) (string, error) {
-	return ts.git.GetShow(commitHash)
+	return ts.gitOps().GetShow(commitHash)
 }
 
 func (ts *ToolSet) GetGitBranch() (string, error) {
-	return ts.git.GetBranch()
+	return ts.gitOps().GetBranch()
 }
 
 func (ts *ToolSet) IsGitRepo() bool {
-	return ts.git.IsGitRepo()
+	return ts.gitOps().IsGitRepo()
 }
 
 func (ts *ToolSet) SetWorkingDirectory(dir string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if dir == ts.workingDir {
+		return
+	}
 	ts.workingDir = dir
 	ts.filesystem = NewFileSystem(dir)
 	ts.git = NewGitOperations(dir)
 	ts.commands = NewCommandValidator(ts.commands.allowed, ts.commands.blockedPatterns, dir)
-	ts.projectInit = NewProjectInitializer(ts)
 }
 
 func (ts *ToolSet) GetWorkingDirectory() string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
 	return ts.workingDir
 }
 
 func (ts *ToolSet) UpdateRestrictions(restrictions config.RestrictionsSection) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
 	ts.commands = NewCommandValidator(ts.commands.allowed, restrictions.BlockedPatterns, ts.workingDir)
 }
 
 func (ts *ToolSet) IsAllowed(command string) bool {
-	return ts.commands.IsAllowed(command)
+	return ts.validator().IsAllowed(command)
 }
 
 func (ts *ToolSet) ValidateCommand(command string) error {
-	return ts.commands.ValidateCommand(command)
+	return ts.validator().ValidateCommand(command)
 }
 
 func (ts *ToolSet) GetAllowedCommands() []string {
-	return ts.commands.allowed
+	return ts.validator().allowed
 }
 
 func (ts *ToolSet) ListFiles(path string) ([]string, error) {
-	return ts.filesystem.ListFiles(path)
+	return ts.fs().ListFiles(path)
 }
 
 func (ts *ToolSet) FindFiles(pattern string, searchPath string) ([]string, error) {
-	return ts.filesystem.FindFiles(pattern, searchPath)
+	return ts.fs().FindFiles(pattern, searchPath)
 }
 
 func (ts *ToolSet) SearchForSolution(query string) (*SearchResponse, error) {
@@ -131,6 +189,74 @@ func (ts *ToolSet) GenerateProjectDocumentation(analysis *ProjectAnalysis, outpu
 	return ts.projectInit.GenerateProjectDocumentation(analysis, outputPath)
 }
 
+// IndexCode builds the code index for the working directory, replacing any earlier one
+func (ts *ToolSet) IndexCode() (*CodeIndexStats, error) {
+	root := ts.GetWorkingDirectory()
+	index, err := BuildCodeIndex(root)
+	if err != nil {
+		return nil, err
+	}
+	ts.indexMu.Lock()
+	ts.index, ts.indexStale = index, false
+	ts.indexMu.Unlock()
+	stats := index.Stats()
+	return &stats, nil
+}
+
+// SearchCode finds lines matching a regular expression in the working directory
+func (ts *ToolSet) SearchCode(pattern, path string, contextLines int) ([]CodeMatch, error) {
+	index, err := ts.codeIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.Search(pattern, path, contextLines, maxSearchResults)
+}
+
+// FindSymbol returns the definitions of a symbol and the lines referring to it
+func (ts *ToolSet) FindSymbol(name string) (*SymbolLookup, error) {
+	index, err := ts.codeIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.FindSymbol(name, maxSearchResults), nil
+}
+
+// SearchSymbols returns symbols whose name contains query
+func (ts *ToolSet) SearchSymbols(query string, limit int) ([]Symbol, error) {
+	index, err := ts.codeIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.SearchSymbols(query, limit), nil
+}
+
+// codeIndex returns the index of the working directory, building it on first use and
+// refreshing it after commands may have changed files
+func (ts *ToolSet) codeIndex() (*CodeIndex, error) {
+	root := ts.GetWorkingDirectory()
+	ts.indexMu.Lock()
+	defer ts.indexMu.Unlock()
+	if ts.index == nil || ts.index.root != root {
+		index, err := BuildCodeIndex(root)
+		if err != nil {
+			return nil, err
+		}
+		ts.index, ts.indexStale = index, false
+	} else if ts.indexStale {
+		if err := ts.index.Refresh(); err != nil {
+			return nil, err
+		}
+		ts.indexStale = false
+	}
+	return ts.index, nil
+}
+
+func (ts *ToolSet) markIndexStale() {
+	ts.indexMu.Lock()
+	ts.indexStale = true
+	ts.indexMu.Unlock()
+}
+
 // Sequential thinking methods
 func (ts *ToolSet) ProcessThought(args map[string]interface{}) (interface{}, error) {
 	return ts.sequentialThinking.ProcessThought(args)
@@ -0,0 +1,12 @@
+// Package calculator implements basic integer arithmetic.
+package calculator
+
+// Add returns the sum of a and b.
+func Add(a, b int) int {
+	return a + b
+}
+
+// Multiply returns the product of a and b.
+func Multiply(a, b int) int {
+	return a * b
+}